@@ -0,0 +1,212 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	loginTypeManagedIdentity     = "managed_identity"
+	loginTypeAKSWorkloadIdentity = "aks_workload_identity"
+)
+
+// serviceAccountSigningAlgs are the algorithms AKS uses to sign service
+// account tokens.
+var serviceAccountSigningAlgs = []jose.SignatureAlgorithm{
+	jose.RS256,
+	jose.ES256,
+}
+
+// kubernetesClaims are the claims of a projected Kubernetes service account
+// token.
+type kubernetesClaims struct {
+	Subject    string `json:"sub"`
+	Kubernetes struct {
+		Namespace      string `json:"namespace"`
+		ServiceAccount struct {
+			Name string `json:"name"`
+			UID  string `json:"uid"`
+		} `json:"serviceaccount"`
+		Pod *struct {
+			Name string `json:"name"`
+			UID  string `json:"uid"`
+		} `json:"pod"`
+	} `json:"kubernetes.io"`
+}
+
+// aksVerifier returns a token verifier for the named AKS cluster. Verifiers are
+// cached so that discovery is only performed once per cluster configuration.
+func (b *azureAuthBackend) aksVerifier(ctx context.Context, name string, cluster *aksCluster) (*oidc.IDTokenVerifier, error) {
+	b.aksLock.RLock()
+	verifier, ok := b.aksVerifiers[name]
+	b.aksLock.RUnlock()
+	if ok {
+		return verifier, nil
+	}
+
+	b.aksLock.Lock()
+	defer b.aksLock.Unlock()
+
+	if verifier, ok := b.aksVerifiers[name]; ok {
+		return verifier, nil
+	}
+
+	// The provider keeps the HTTP client from the context to refresh the key
+	// set, so it must not be tied to the lifetime of the request
+	providerCtx := oidc.ClientContext(context.Background(), cleanhttp.DefaultClient())
+	provider, err := oidc.NewProvider(providerCtx, cluster.OIDCIssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to discover OIDC issuer %q: %w", cluster.OIDCIssuerURL, err)
+	}
+
+	verifier = provider.Verifier(&oidc.Config{
+		ClientID:             cluster.Audience,
+		SupportedSigningAlgs: []string{oidc.RS256, oidc.ES256},
+	})
+	b.aksVerifiers[name] = verifier
+
+	return verifier, nil
+}
+
+func (b *azureAuthBackend) resetAKSVerifier(name string) {
+	b.aksLock.Lock()
+	defer b.aksLock.Unlock()
+
+	delete(b.aksVerifiers, name)
+}
+
+// aksClusterForIssuer returns the registered AKS cluster whose OIDC issuer
+// matches the issuer of the token.
+func (b *azureAuthBackend) aksClusterForIssuer(ctx context.Context, s logical.Storage, issuer string) (string, *aksCluster, error) {
+	names, err := s.List(ctx, aksClusterStoragePrefix)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, name := range names {
+		cluster, err := b.aksCluster(ctx, s, name)
+		if err != nil {
+			return "", nil, err
+		}
+		if cluster != nil && cluster.OIDCIssuerURL == issuer {
+			return name, cluster, nil
+		}
+	}
+
+	return "", nil, nil
+}
+
+// unverifiedIssuer returns the 'iss' claim of the token without verifying
+// its signature. It is only used to select the key set to verify against.
+func unverifiedIssuer(signedJwt string) (string, error) {
+	token, err := jwt.ParseSigned(signedJwt, serviceAccountSigningAlgs)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse signed JWT: %w", err)
+	}
+
+	var claims jwt.Claims
+	if err := token.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return "", fmt.Errorf("unable to parse claims from JWT: %w", err)
+	}
+	if claims.Issuer == "" {
+		return "", errors.New("expected JWT to have an 'iss' claim")
+	}
+	return claims.Issuer, nil
+}
+
+// verifyServiceAccount returns an error if the service account is not
+// authorized for the role.
+func verifyServiceAccount(role *azureRole, claims *kubernetesClaims) error {
+	namespace := claims.Kubernetes.Namespace
+	name := claims.Kubernetes.ServiceAccount.Name
+	if namespace == "" || name == "" {
+		return errors.New("token does not contain kubernetes.io service account claims")
+	}
+	if claims.Subject != fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name) {
+		return errors.New("token subject does not match the service account claims")
+	}
+
+	if !(len(role.BoundServiceAccountNamespaces) == 1 && role.BoundServiceAccountNamespaces[0] == "*") &&
+		!strListContains(role.BoundServiceAccountNamespaces, namespace) {
+		return fmt.Errorf("namespace not authorized: %s", namespace)
+	}
+
+	if !(len(role.BoundServiceAccountNames) == 1 && role.BoundServiceAccountNames[0] == "*") &&
+		!strListContains(role.BoundServiceAccountNames, name) {
+		return fmt.Errorf("service account name not authorized: %s", name)
+	}
+
+	return nil
+}
+
+// pathLoginAKSWorkloadIdentity logs in with a Kubernetes service account
+// token projected into a pod of a registered AKS cluster.
+func (b *azureAuthBackend) pathLoginAKSWorkloadIdentity(ctx context.Context, req *logical.Request, signedJwt, roleName string, role *azureRole, config *azureConfig) (*logical.Response, error) {
+	issuer, err := unverifiedIssuer(signedJwt)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	clusterName, cluster, err := b.aksClusterForIssuer(ctx, req.Storage, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if cluster == nil {
+		return nil, fmt.Errorf("no AKS cluster is registered for issuer %q", issuer)
+	}
+
+	if len(role.BoundAKSClusterIDs) > 0 && !strListContains(role.BoundAKSClusterIDs, cluster.ResourceID) {
+		return nil, errors.New("AKS cluster not authorized")
+	}
+
+	verifier, err := b.aksVerifier(ctx, clusterName, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	// The OIDC verifier verifies the signature and checks the 'aud' and 'iss'
+	// claims and expiration time
+	idToken, err := verifier.Verify(ctx, signedJwt)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := new(additionalClaims)
+	if err := idToken.Claims(claims); err != nil {
+		return nil, err
+	}
+	k8sClaims := new(kubernetesClaims)
+	if err := idToken.Claims(k8sClaims); err != nil {
+		return nil, err
+	}
+
+	if err := b.verifyClaims(claims, role); err != nil {
+		return nil, err
+	}
+	if err := verifyServiceAccount(role, k8sClaims); err != nil {
+		return nil, err
+	}
+
+	namespace := k8sClaims.Kubernetes.Namespace
+	name := k8sClaims.Kubernetes.ServiceAccount.Name
+	input := &aliasInput{
+		RoleName:                roleName,
+		RoleID:                  role.RoleID,
+		ObjectID:                k8sClaims.Kubernetes.ServiceAccount.UID,
+		ResourceID:              cluster.ResourceID,
+		ServiceAccountNamespace: namespace,
+		ServiceAccountName:      name,
+	}
+
+	return b.loginResponse(ctx, req, config, role, input, claims, idToken.Expiry, fmt.Sprintf("%s/%s", namespace, name))
+}
@@ -0,0 +1,308 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const testAKSClusterID = "/subscriptions/eb936495-7356-4a35-af3e-ea68af201f0c/resourceGroups/aks-rg/providers/Microsoft.ContainerService/managedClusters/aks-cluster"
+
+// testOIDCIssuer is a local stand-in for an AKS cluster OIDC issuer.
+type testOIDCIssuer struct {
+	server *httptest.Server
+	signer jose.Signer
+}
+
+func newTestOIDCIssuer(t *testing.T) *testOIDCIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk := jose.JSONWebKey{Key: key, KeyID: "test-key", Algorithm: string(jose.RS256), Use: "sig"}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jwk}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &testOIDCIssuer{signer: signer}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.server.URL,
+			"jwks_uri":                              issuer.server.URL + "/openid/v1/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/openid/v1/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{jwk.Public()}})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (i *testOIDCIssuer) token(t *testing.T, namespace, name string, modify func(map[string]interface{})) string {
+	t.Helper()
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": i.server.URL,
+		"aud": []string{defaultAKSAudience},
+		"sub": "system:serviceaccount:" + namespace + ":" + name,
+		"iat": now.Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+		"kubernetes.io": map[string]interface{}{
+			"namespace": namespace,
+			"serviceaccount": map[string]interface{}{
+				"name": name,
+				"uid":  "sa-uid-" + name,
+			},
+		},
+	}
+	if modify != nil {
+		modify(claims)
+	}
+
+	token, err := jwt.Signed(i.signer).Claims(claims).Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func testAKSLogin(t *testing.T, b *azureAuthBackend, s logical.Storage, roleName, token string) (*logical.Response, error) {
+	t.Helper()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Data: map[string]interface{}{
+			"role": roleName,
+			"jwt":  token,
+		},
+		Storage: s,
+		Connection: &logical.Connection{
+			RemoteAddr: "127.0.0.1",
+		},
+	})
+	if err == nil && resp.IsError() {
+		err = resp.Error()
+	}
+	return resp, err
+}
+
+func TestLogin_AKSWorkloadIdentity(t *testing.T) {
+	b, s := getTestBackend(t)
+	issuer := newTestOIDCIssuer(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/aks-cluster/prod",
+		Data: map[string]interface{}{
+			"resource_id":     testAKSClusterID,
+			"oidc_issuer_url": issuer.server.URL,
+		},
+		Storage: s,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	roleName := "aks"
+	testRoleCreate(t, b, s, map[string]interface{}{
+		"name":                             roleName,
+		"policies":                         []string{"dev"},
+		"login_type":                       loginTypeAKSWorkloadIdentity,
+		"bound_aks_cluster_ids":            []string{testAKSClusterID},
+		"bound_service_account_namespaces": []string{"payments"},
+		"bound_service_account_names":      []string{"api"},
+	})
+
+	resp, err = testAKSLogin(t, b, s, roleName, issuer.token(t, "payments", "api", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Auth.Alias.Name != "sa-uid-api" {
+		t.Fatalf("expected alias %q, got %q", "sa-uid-api", resp.Auth.Alias.Name)
+	}
+	if resp.Auth.Metadata["service_account_namespace"] != "payments" ||
+		resp.Auth.Metadata["service_account_name"] != "api" ||
+		resp.Auth.Metadata["resource_id"] != testAKSClusterID {
+		t.Fatalf("unexpected metadata: %v", resp.Auth.Metadata)
+	}
+
+	failures := map[string]string{
+		"namespace not bound": issuer.token(t, "other", "api", nil),
+		"name not bound":      issuer.token(t, "payments", "other", nil),
+		"wrong audience": issuer.token(t, "payments", "api", func(c map[string]interface{}) {
+			c["aud"] = []string{"api://other"}
+		}),
+		"subject mismatch": issuer.token(t, "payments", "api", func(c map[string]interface{}) {
+			c["sub"] = "system:serviceaccount:payments:other"
+		}),
+		"unregistered issuer": issuer.token(t, "payments", "api", func(c map[string]interface{}) {
+			c["iss"] = "https://unknown.example.com"
+		}),
+		"expired": issuer.token(t, "payments", "api", func(c map[string]interface{}) {
+			c["exp"] = time.Now().Add(-time.Minute).Unix()
+		}),
+	}
+	for name, token := range failures {
+		t.Run(name, func(t *testing.T) {
+			if _, err := testAKSLogin(t, b, s, roleName, token); err == nil {
+				t.Fatal("expected login to fail")
+			}
+		})
+	}
+
+	// a token signed by a different key with the same issuer must fail
+	other := newTestOIDCIssuer(t)
+	forged := other.token(t, "payments", "api", func(c map[string]interface{}) {
+		c["iss"] = issuer.server.URL
+	})
+	if _, err := testAKSLogin(t, b, s, roleName, forged); err == nil {
+		t.Fatal("expected login with forged token to fail")
+	}
+
+	// rebinding the role to another cluster rejects the token
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/" + roleName,
+		Data: map[string]interface{}{
+			"bound_aks_cluster_ids": []string{"/subscriptions/eb936495-7356-4a35-af3e-ea68af201f0c/resourceGroups/aks-rg/providers/Microsoft.ContainerService/managedClusters/other"},
+		},
+		Storage: s,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if _, err := testAKSLogin(t, b, s, roleName, issuer.token(t, "payments", "api", nil)); err == nil {
+		t.Fatal("expected login to fail for unbound cluster")
+	}
+}
+
+func TestAKSClusterConfig(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	tests := map[string]struct {
+		data    map[string]interface{}
+		wantErr bool
+	}{
+		"valid": {
+			data: map[string]interface{}{
+				"resource_id":     testAKSClusterID,
+				"oidc_issuer_url": "https://eastus.oic.prod-aks.azure.com/tenant/cluster/",
+			},
+		},
+		"missing resource_id": {
+			data: map[string]interface{}{
+				"oidc_issuer_url": "https://eastus.oic.prod-aks.azure.com/tenant/cluster/",
+			},
+			wantErr: true,
+		},
+		"wrong resource type": {
+			data: map[string]interface{}{
+				"resource_id":     "/subscriptions/eb936495-7356-4a35-af3e-ea68af201f0c/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm",
+				"oidc_issuer_url": "https://eastus.oic.prod-aks.azure.com/tenant/cluster/",
+			},
+			wantErr: true,
+		},
+		"missing issuer": {
+			data: map[string]interface{}{
+				"resource_id": testAKSClusterID,
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "config/aks-cluster/" + strings.ReplaceAll(name, " ", "-"),
+				Data:      tc.data,
+				Storage:   s,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantErr != (resp != nil && resp.IsError()) {
+				t.Fatalf("expected error: %t, got resp: %#v", tc.wantErr, resp)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			resp, err = b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      "config/aks-cluster/" + strings.ReplaceAll(name, " ", "-"),
+				Storage:   s,
+			})
+			if err != nil || resp == nil {
+				t.Fatalf("err: %v resp: %#v", err, resp)
+			}
+			if resp.Data["audience"] != defaultAKSAudience {
+				t.Fatalf("expected default audience, got %v", resp.Data["audience"])
+			}
+		})
+	}
+}
+
+func TestRole_AKSWorkloadIdentityValidation(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	tests := map[string]map[string]interface{}{
+		"missing names": {
+			"login_type":                       loginTypeAKSWorkloadIdentity,
+			"bound_service_account_namespaces": []string{"payments"},
+		},
+		"managed identity bindings": {
+			"login_type":                       loginTypeAKSWorkloadIdentity,
+			"bound_service_account_namespaces": []string{"payments"},
+			"bound_service_account_names":      []string{"api"},
+			"bound_resource_groups":            []string{"rg"},
+		},
+		"aks bindings on managed identity role": {
+			"bound_resource_groups":       []string{"rg"},
+			"bound_service_account_names": []string{"api"},
+		},
+		"invalid login type": {
+			"login_type":            "bogus",
+			"bound_resource_groups": []string{"rg"},
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "role/test",
+				Data:      data,
+				Storage:   s,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp == nil || !resp.IsError() {
+				t.Fatalf("expected error, got: %#v", resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openbao/openbao/sdk/v2/helper/template"
+)
+
+// aliasInput contains the values available when generating an alias for a
+// login. The exported fields are also made available to alias_template.
+type aliasInput struct {
+	RoleName          string
+	RoleID            string
+	ObjectID          string
+	AppID             string
+	SubscriptionID    string
+	ResourceGroupName string
+	VMName            string
+	VMSSName          string
+	ResourceID        string
+
+	ServiceAccountNamespace string
+	ServiceAccountName      string
+}
+
+type aliaser func(config *azureConfig, input *aliasInput) (alias string, err error)
+
+const (
+	aliasTypeObjectID   = "object_id"
+	aliasTypeAppID      = "app_id"
+	aliasTypeResourceID = "resource_id"
+	aliasTypeVMSSID     = "vmss_id"
+	aliasTypeRoleID     = "role_id"
+	aliasTypeTemplate   = "template"
+
+	defaultAliasType = aliasTypeObjectID
+)
+
+var (
+	allowedAliases = map[string]aliaser{
+		defaultAliasType: getObjectIDAlias,
+		"":               getObjectIDAlias, // For backwards compatibility
+
+		aliasTypeAppID:      getAppIDAlias,
+		aliasTypeResourceID: getResourceIDAlias,
+		aliasTypeVMSSID:     getVMSSIDAlias,
+		aliasTypeRoleID:     getRoleIDAlias,
+		aliasTypeTemplate:   getTemplateAlias,
+	}
+
+	allowedAliasesSlice = aliasMapKeyToSlice(allowedAliases)
+)
+
+func aliasMapKeyToSlice(m map[string]aliaser) (s []string) {
+	for key := range m {
+		if key == "" {
+			continue
+		}
+		s = append(s, key)
+	}
+	sort.Strings(s)
+	return s
+}
+
+func getObjectIDAlias(_ *azureConfig, input *aliasInput) (string, error) {
+	return input.ObjectID, nil
+}
+
+func getAppIDAlias(_ *azureConfig, input *aliasInput) (string, error) {
+	if input.AppID == "" {
+		return "", errors.New("token does not contain an appid claim")
+	}
+	return input.AppID, nil
+}
+
+// getResourceIDAlias returns the resource ID supplied on login, or the
+// resource ID of the virtual machine or scale set if only names were given.
+func getResourceIDAlias(config *azureConfig, input *aliasInput) (string, error) {
+	switch {
+	case input.VMSSName != "":
+		return getVMSSIDAlias(config, input)
+	case input.VMName != "":
+		if input.SubscriptionID == "" || input.ResourceGroupName == "" {
+			return "", errors.New("subscription_id and resource_group_name are required")
+		}
+		return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s",
+			input.SubscriptionID, input.ResourceGroupName, input.VMName), nil
+	case input.ResourceID != "":
+		return input.ResourceID, nil
+	default:
+		return "", errors.New("one of vm_name, vmss_name or resource_id must be provided")
+	}
+}
+
+func getVMSSIDAlias(_ *azureConfig, input *aliasInput) (string, error) {
+	if input.VMSSName == "" {
+		return "", errors.New("vmss_name must be provided")
+	}
+	if input.SubscriptionID == "" || input.ResourceGroupName == "" {
+		return "", errors.New("subscription_id and resource_group_name are required")
+	}
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachineScaleSets/%s",
+		input.SubscriptionID, input.ResourceGroupName, input.VMSSName), nil
+}
+
+func getRoleIDAlias(_ *azureConfig, input *aliasInput) (string, error) {
+	if input.RoleID == "" {
+		return "", fmt.Errorf("role %q has no role_id, update the role to generate one", input.RoleName)
+	}
+	return input.RoleID, nil
+}
+
+func getTemplateAlias(config *azureConfig, input *aliasInput) (string, error) {
+	tmpl, err := template.NewTemplate(template.Template(config.AliasTemplate))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse alias_template: %w", err)
+	}
+
+	alias, err := tmpl.Generate(input)
+	if err != nil {
+		return "", fmt.Errorf("unable to render alias_template: %w", err)
+	}
+	if alias == "" {
+		return "", errors.New("alias_template rendered an empty alias")
+	}
+	return alias, nil
+}
+
+// validateAliasType ensures the configured alias type is known and, for
+// template aliases, that the template parses.
+func validateAliasType(aliasType, aliasTemplate string) error {
+	if _, ok := allowedAliases[aliasType]; !ok {
+		return fmt.Errorf("invalid alias_type %q: must be one of: %s", aliasType, strings.Join(allowedAliasesSlice, ", "))
+	}
+	if aliasType != aliasTypeTemplate {
+		return nil
+	}
+	if aliasTemplate == "" {
+		return errors.New("alias_template is required when alias_type is \"template\"")
+	}
+	if _, err := template.NewTemplate(template.Template(aliasTemplate)); err != nil {
+		return fmt.Errorf("unable to parse alias_template: %w", err)
+	}
+	return nil
+}
+
+// aliasFromResource returns true if the alias is built from the resource given
+// on login. The resource is then always verified against the identity of the
+// token, so that callers can't claim the alias of another resource.
+func (c *azureConfig) aliasFromResource() bool {
+	switch c.AliasType {
+	case aliasTypeResourceID, aliasTypeVMSSID:
+		return true
+	case aliasTypeTemplate:
+		for _, field := range []string{"SubscriptionID", "ResourceGroupName", "VMName", "VMSSName", "ResourceID"} {
+			if strings.Contains(c.AliasTemplate, field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *azureConfig) getAlias(input *aliasInput) (string, error) {
+	aliaser, exists := allowedAliases[c.AliasType]
+	if !exists {
+		return "", fmt.Errorf("invalid alias type: must be one of: %s", strings.Join(allowedAliasesSlice, ", "))
+	}
+	return aliaser(c, input)
+}
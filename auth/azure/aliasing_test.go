@@ -0,0 +1,160 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"testing"
+)
+
+func TestGetAlias(t *testing.T) {
+	vmInput := &aliasInput{
+		RoleName:          "test-role",
+		RoleID:            "testRoleID",
+		ObjectID:          "testObjectID",
+		AppID:             "testAppID",
+		SubscriptionID:    "testSub",
+		ResourceGroupName: "testRG",
+		VMName:            "testVM",
+	}
+	vmssInput := &aliasInput{
+		RoleName:          "test-role",
+		RoleID:            "testRoleID",
+		ObjectID:          "testObjectID",
+		SubscriptionID:    "testSub",
+		ResourceGroupName: "testRG",
+		VMSSName:          "testVMSS",
+	}
+
+	tests := map[string]struct {
+		config        *azureConfig
+		input         *aliasInput
+		expectedAlias string
+		expectErr     bool
+	}{
+		"invalid type": {
+			config:    &azureConfig{AliasType: "bogus"},
+			input:     vmInput,
+			expectErr: true,
+		},
+		"empty type goes to default": {
+			config:        &azureConfig{},
+			input:         vmInput,
+			expectedAlias: "testObjectID",
+		},
+		"object_id": {
+			config:        &azureConfig{AliasType: aliasTypeObjectID},
+			input:         vmInput,
+			expectedAlias: "testObjectID",
+		},
+		"app_id": {
+			config:        &azureConfig{AliasType: aliasTypeAppID},
+			input:         vmInput,
+			expectedAlias: "testAppID",
+		},
+		"app_id without claim": {
+			config:    &azureConfig{AliasType: aliasTypeAppID},
+			input:     vmssInput,
+			expectErr: true,
+		},
+		"resource_id from vm": {
+			config:        &azureConfig{AliasType: aliasTypeResourceID},
+			input:         vmInput,
+			expectedAlias: "/subscriptions/testSub/resourceGroups/testRG/providers/Microsoft.Compute/virtualMachines/testVM",
+		},
+		"resource_id from vmss": {
+			config:        &azureConfig{AliasType: aliasTypeResourceID},
+			input:         vmssInput,
+			expectedAlias: "/subscriptions/testSub/resourceGroups/testRG/providers/Microsoft.Compute/virtualMachineScaleSets/testVMSS",
+		},
+		"resource_id": {
+			config:        &azureConfig{AliasType: aliasTypeResourceID},
+			input:         &aliasInput{ResourceID: "/subscriptions/testSub/resourceGroups/testRG/providers/Microsoft.Web/sites/func"},
+			expectedAlias: "/subscriptions/testSub/resourceGroups/testRG/providers/Microsoft.Web/sites/func",
+		},
+		"resource_id without resource": {
+			config:    &azureConfig{AliasType: aliasTypeResourceID},
+			input:     &aliasInput{ObjectID: "testObjectID"},
+			expectErr: true,
+		},
+		"vmss_id": {
+			config:        &azureConfig{AliasType: aliasTypeVMSSID},
+			input:         vmssInput,
+			expectedAlias: "/subscriptions/testSub/resourceGroups/testRG/providers/Microsoft.Compute/virtualMachineScaleSets/testVMSS",
+		},
+		"vmss_id without scale set": {
+			config:    &azureConfig{AliasType: aliasTypeVMSSID},
+			input:     vmInput,
+			expectErr: true,
+		},
+		"role_id": {
+			config:        &azureConfig{AliasType: aliasTypeRoleID},
+			input:         vmInput,
+			expectedAlias: "testRoleID",
+		},
+		"role_id missing": {
+			config:    &azureConfig{AliasType: aliasTypeRoleID},
+			input:     &aliasInput{RoleName: "legacy"},
+			expectErr: true,
+		},
+		"template": {
+			config: &azureConfig{
+				AliasType:     aliasTypeTemplate,
+				AliasTemplate: "{{ .SubscriptionID }}/{{ .VMSSName | lowercase }}",
+			},
+			input:         vmssInput,
+			expectedAlias: "testSub/testvmss",
+		},
+		"template rendering empty": {
+			config: &azureConfig{
+				AliasType:     aliasTypeTemplate,
+				AliasTemplate: "{{ .VMSSName }}",
+			},
+			input:     vmInput,
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualAlias, err := test.config.getAlias(test.input)
+			if test.expectErr && err == nil {
+				t.Fatalf("err expected, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+			if actualAlias != test.expectedAlias {
+				t.Fatalf("Actual alias: %s Expected Alias: %s", actualAlias, test.expectedAlias)
+			}
+		})
+	}
+}
+
+func TestValidateAliasType(t *testing.T) {
+	tests := map[string]struct {
+		aliasType     string
+		aliasTemplate string
+		expectErr     bool
+	}{
+		"default":               {aliasType: ""},
+		"object_id":             {aliasType: aliasTypeObjectID},
+		"bogus":                 {aliasType: "bogus", expectErr: true},
+		"template":              {aliasType: aliasTypeTemplate, aliasTemplate: "{{ .ObjectID }}"},
+		"template missing":      {aliasType: aliasTypeTemplate, expectErr: true},
+		"template unparseable":  {aliasType: aliasTypeTemplate, aliasTemplate: "{{ .ObjectID", expectErr: true},
+		"template not required": {aliasType: aliasTypeVMSSID, aliasTemplate: "{{ .ObjectID"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateAliasType(test.aliasType, test.aliasTemplate)
+			if test.expectErr && err == nil {
+				t.Fatalf("err expected, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+		})
+	}
+}
@@ -12,7 +12,9 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -51,6 +53,7 @@ type provider interface {
 	MSGraphClient() (client.MSGraphClient, error)
 	ResourceClient(subscriptionID string) (client.ResourceClient, error)
 	ProvidersClient(subscriptionID string) (client.ProvidersClient, error)
+	LookupCacheStats() lookupCacheStats
 }
 
 type azureProvider struct {
@@ -59,6 +62,13 @@ type azureProvider struct {
 	httpClient   *http.Client
 	logger       hclog.Logger
 	systemView   logical.SystemView
+
+	// cache holds the results of ARM lookups made by the clients of the
+	// provider
+	cache *lookupCache
+	// throttle is shared by the clients of the provider so that a throttled
+	// response delays all subsequent requests
+	throttle *throttle
 }
 
 type oidcDiscoveryInfo struct {
@@ -71,6 +81,7 @@ type oidcDiscoveryInfo struct {
 type transporter struct {
 	pluginEnv *logical.PluginEnvironment
 	sender    *http.Client
+	throttle  *throttle
 }
 
 func (tp transporter) Do(req *http.Request) (*http.Response, error) {
@@ -83,13 +94,92 @@ func (tp transporter) Do(req *http.Request) (*http.Response, error) {
 	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		return http.ErrUseLastResponse
 	}
+
+	// hold off while ARM has asked us to back off
+	if err := tp.throttle.wait(req.Context()); err != nil {
+		return nil, err
+	}
+
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		tp.throttle.backoff(retryAfter(resp.Header, time.Now()))
+	}
 	return resp, nil
 }
 
+// throttle delays requests until the time requested by the Retry-After
+// header of a throttled response has passed.
+type throttle struct {
+	lock     sync.Mutex
+	until    time.Time
+	maxDelay time.Duration
+}
+
+func (t *throttle) backoff(delay time.Duration) {
+	if t == nil || delay <= 0 {
+		return
+	}
+	if t.maxDelay > 0 && delay > t.maxDelay {
+		delay = t.maxDelay
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if until := time.Now().Add(delay); until.After(t.until) {
+		t.until = until
+	}
+}
+
+func (t *throttle) wait(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	t.lock.Lock()
+	delay := time.Until(t.until)
+	t.lock.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryAfter returns the delay requested by the headers of a throttled
+// response. ARM sets retry-after-ms or x-ms-retry-after-ms in addition to
+// the standard Retry-After header, which may be in seconds or a date.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	for _, name := range []string{"retry-after-ms", "x-ms-retry-after-ms"} {
+		if ms, err := strconv.Atoi(header.Get(name)); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now)
+	}
+	return 0
+}
+
 func (b *azureAuthBackend) newAzureProvider(ctx context.Context, config *azureConfig) (*azureProvider, error) {
 	httpClient := cleanhttp.DefaultClient()
 	settings, err := b.getAzureSettings(ctx, config)
@@ -143,6 +233,8 @@ func (b *azureAuthBackend) newAzureProvider(ctx context.Context, config *azureCo
 		httpClient:   httpClient,
 		logger:       b.Logger(),
 		systemView:   b.System(),
+		cache:        newLookupCache(settings.LookupCacheTTL, settings.LookupCacheMaxEntries),
+		throttle:     &throttle{maxDelay: settings.MaxRetryDelay},
 	}, nil
 }
 
@@ -176,7 +268,7 @@ func (p *azureProvider) ComputeClient(subscriptionID string) (client.ComputeClie
 		return nil, fmt.Errorf("failed to create virtual machines client: %w", err)
 	}
 
-	return client, nil
+	return &cachedComputeClient{ComputeClient: client, cache: p.cache, subscriptionID: subscriptionID}, nil
 }
 
 func (p *azureProvider) VMSSClient(subscriptionID string) (client.VMSSClient, error) {
@@ -191,7 +283,7 @@ func (p *azureProvider) VMSSClient(subscriptionID string) (client.VMSSClient, er
 		return nil, fmt.Errorf("failed to create virtual machine scale sets client: %w", err)
 	}
 
-	return client, nil
+	return &cachedVMSSClient{VMSSClient: client, cache: p.cache, subscriptionID: subscriptionID}, nil
 }
 
 func (p *azureProvider) MSIClient(subscriptionID string) (client.MSIClient, error) {
@@ -206,7 +298,7 @@ func (p *azureProvider) MSIClient(subscriptionID string) (client.MSIClient, erro
 		return nil, fmt.Errorf("failed to create user assigned identity client: %w", err)
 	}
 
-	return client, nil
+	return &cachedMSIClient{MSIClient: client, cache: p.cache, subscriptionID: subscriptionID}, nil
 }
 
 func (p *azureProvider) ProvidersClient(subscriptionID string) (client.ProvidersClient, error) {
@@ -221,7 +313,7 @@ func (p *azureProvider) ProvidersClient(subscriptionID string) (client.Providers
 		return nil, fmt.Errorf("failed to create providers client: %w", err)
 	}
 
-	return client, nil
+	return &cachedProvidersClient{ProvidersClient: client, cache: p.cache, subscriptionID: subscriptionID}, nil
 }
 
 func (p *azureProvider) ResourceClient(subscriptionID string) (client.ResourceClient, error) {
@@ -236,7 +328,11 @@ func (p *azureProvider) ResourceClient(subscriptionID string) (client.ResourceCl
 		return nil, fmt.Errorf("failed to create resource client: %w", err)
 	}
 
-	return client, nil
+	return &cachedResourceClient{ResourceClient: client, cache: p.cache}, nil
+}
+
+func (p *azureProvider) LookupCacheStats() lookupCacheStats {
+	return p.cache.stats()
 }
 
 func (p *azureProvider) getClientOptions() *arm.ClientOptions {
@@ -246,6 +342,7 @@ func (p *azureProvider) getClientOptions() *arm.ClientOptions {
 			Transport: transporter{
 				pluginEnv: p.settings.PluginEnv,
 				sender:    p.httpClient,
+				throttle:  p.throttle,
 			},
 			Retry: policy.RetryOptions{
 				MaxRetries:    p.settings.MaxRetries,
@@ -298,6 +395,9 @@ type azureSettings struct {
 	MaxRetries    int32
 	MaxRetryDelay time.Duration
 	RetryDelay    time.Duration
+
+	LookupCacheTTL        time.Duration
+	LookupCacheMaxEntries int
 }
 
 func (b *azureAuthBackend) getAzureSettings(ctx context.Context, config *azureConfig) (*azureSettings, error) {
@@ -305,6 +405,9 @@ func (b *azureAuthBackend) getAzureSettings(ctx context.Context, config *azureCo
 		MaxRetries:    config.MaxRetries,
 		MaxRetryDelay: config.MaxRetryDelay,
 		RetryDelay:    config.RetryDelay,
+
+		LookupCacheTTL:        config.LookupCacheTTL,
+		LookupCacheMaxEntries: config.LookupCacheMaxEntries,
 	}
 
 	envTenantID := os.Getenv("AZURE_TENANT_ID")
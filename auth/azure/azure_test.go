@@ -191,6 +191,31 @@ func (p *mockProvider) ProvidersClient(subscriptionID string) (client.ProvidersC
 	}, nil
 }
 
+func (*mockProvider) LookupCacheStats() lookupCacheStats {
+	return lookupCacheStats{}
+}
+
+// cachingMockProvider caches lookups of the mock clients in the same way
+// as azureProvider.
+type cachingMockProvider struct {
+	*mockProvider
+	cache *lookupCache
+}
+
+func (p *cachingMockProvider) ComputeClient(subscriptionID string) (client.ComputeClient, error) {
+	c, _ := p.mockProvider.ComputeClient(subscriptionID)
+	return &cachedComputeClient{ComputeClient: c, cache: p.cache, subscriptionID: subscriptionID}, nil
+}
+
+func (p *cachingMockProvider) ProvidersClient(subscriptionID string) (client.ProvidersClient, error) {
+	c, _ := p.mockProvider.ProvidersClient(subscriptionID)
+	return &cachedProvidersClient{ProvidersClient: c, cache: p.cache, subscriptionID: subscriptionID}, nil
+}
+
+func (p *cachingMockProvider) LookupCacheStats() lookupCacheStats {
+	return p.cache.stats()
+}
+
 func TestValidationRegex(t *testing.T) {
 	cases := []struct {
 		name    string
@@ -5,9 +5,11 @@ package azure
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/google/uuid"
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/helper/consts"
@@ -17,6 +19,9 @@ import (
 const (
 	userAgentPluginName = "auth-azure"
 
+	// replayCacheTidyPeriod is how often the periodicFunc tidies the replay cache
+	replayCacheTidyPeriod = time.Hour
+
 	// operationPrefixAzure is used as a prefix for OpenAPI operation id's.
 	operationPrefixAzure = "azure"
 )
@@ -43,11 +48,25 @@ type azureAuthBackend struct {
 	// a given resource type
 	resourceAPIVersionCache map[string]string
 	cacheLock               sync.RWMutex
+
+	// aksVerifiers is a mapping of AKS cluster configuration name to the
+	// verifier for its service account tokens
+	aksVerifiers map[string]*oidc.IDTokenVerifier
+	aksLock      sync.RWMutex
+
+	// replayLock serializes the check and write of replay cache entries
+	replayLock sync.Mutex
+	// tidyReplayCacheCASGuard guards the replay cache tidy function
+	tidyReplayCacheCASGuard *uint32
+	// nextTidyTime is the time at which the periodicFunc will next tidy the
+	// replay cache
+	nextTidyTime time.Time
 }
 
 func backend() *azureAuthBackend {
 	b := azureAuthBackend{
-		updatePassword: true,
+		updatePassword:          true,
+		tidyReplayCacheCASGuard: new(uint32),
 	}
 
 	b.Backend = &framework.Backend{
@@ -58,6 +77,7 @@ func backend() *azureAuthBackend {
 		PathsSpecial: &logical.Paths{
 			LocalStorage: []string{
 				framework.WALPrefix,
+				replayCacheStoragePrefix,
 			},
 			Unauthenticated: []string{
 				"login",
@@ -71,8 +91,11 @@ func backend() *azureAuthBackend {
 				pathLogin(&b),
 				pathConfig(&b),
 				pathRotateRoot(&b),
+				pathTidyReplayCache(&b),
+				pathLookupCacheStats(&b),
 			},
 			pathsRole(&b),
+			pathsConfigAKSCluster(&b),
 		),
 		// Root rotation can take up to a few minutes, so ensure we do not
 		// roll back a root credential rotation that is currently in flight
@@ -83,15 +106,23 @@ func backend() *azureAuthBackend {
 	}
 
 	b.resourceAPIVersionCache = make(map[string]string)
+	b.aksVerifiers = make(map[string]*oidc.IDTokenVerifier)
 
 	return &b
 }
 
-// The periodicFunc is responsible for eventually swapping out the root credential for rotation
-// operations. Due to Azure's eventual consistency model, the new credential will not be
+// The periodicFunc is responsible for tidying the replay cache and for eventually swapping
+// out the root credential for rotation operations. Due to Azure's eventual consistency model, the new credential will not be
 // available immediately, and hence we check periodically and delete the old credential
 // only once the new credential is at least a minute old
 func (b *azureAuthBackend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	// The replay cache is stored locally, so it is tidied regardless of the
+	// replication state. Tidying is done once an hour rather than every run.
+	if b.nextTidyTime.IsZero() || !time.Now().Before(b.nextTidyTime) {
+		b.tidyReplayCacheAsync(req, defaultReplayCacheSafetyBuffer)
+		b.nextTidyTime = time.Now().Add(replayCacheTidyPeriod)
+	}
+
 	// Root rotation through the periodic func writes to storage. Only run this on the
 	// active instance in the primary cluster or local mounts. The periodic func doesn't
 	// run on perf standbys or DR secondaries, but we still protect against this here.
@@ -173,9 +204,11 @@ func (b *azureAuthBackend) periodicFunc(ctx context.Context, req *logical.Reques
 }
 
 func (b *azureAuthBackend) invalidate(ctx context.Context, key string) {
-	switch key {
-	case "config":
+	switch {
+	case key == "config":
 		b.reset()
+	case strings.HasPrefix(key, aksClusterStoragePrefix):
+		b.resetAKSVerifier(strings.TrimPrefix(key, aksClusterStoragePrefix))
 	}
 }
 
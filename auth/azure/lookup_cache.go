@@ -0,0 +1,232 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/msi/armmsi"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+
+	"github.com/openbao/openbao-plugins/auth/azure/client"
+)
+
+// lookupCache is a bounded cache of ARM lookups. Entries expire after the
+// TTL of the cache, and the least recently used entry is evicted when the
+// cache is full.
+type lookupCache struct {
+	lock       sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+type lookupCacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// lookupCacheStats are the counters of a lookup cache.
+type lookupCacheStats struct {
+	Entries    int
+	MaxEntries int
+	TTL        time.Duration
+	Hits       uint64
+	Misses     uint64
+	Evictions  uint64
+}
+
+func newLookupCache(ttl time.Duration, maxEntries int) *lookupCache {
+	return &lookupCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// enabled returns false if the cache is configured to not store any entries.
+func (c *lookupCache) enabled() bool {
+	return c != nil && c.ttl > 0 && c.maxEntries > 0
+}
+
+func (c *lookupCache) get(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*lookupCacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(elem)
+		c.misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits++
+	return entry.value, true
+}
+
+func (c *lookupCache) add(key string, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lookupCacheEntry)
+		entry.value = value
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	for c.order.Len() >= c.maxEntries {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+
+	c.entries[key] = c.order.PushFront(&lookupCacheEntry{
+		key:     key,
+		value:   value,
+		expires: expires,
+	})
+}
+
+// remove must be called with the lock held.
+func (c *lookupCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lookupCacheEntry).key)
+}
+
+func (c *lookupCache) stats() lookupCacheStats {
+	if c == nil {
+		return lookupCacheStats{}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return lookupCacheStats{
+		Entries:    c.order.Len(),
+		MaxEntries: c.maxEntries,
+		TTL:        c.ttl,
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+	}
+}
+
+// cachedLookup returns the cached result of a lookup, or performs the lookup
+// and caches its result. Errors are never cached.
+func cachedLookup[T any](c *lookupCache, key string, lookup func() (T, error)) (T, error) {
+	if !c.enabled() {
+		return lookup()
+	}
+
+	if value, ok := c.get(key); ok {
+		return value.(T), nil
+	}
+
+	value, err := lookup()
+	if err != nil {
+		return value, err
+	}
+	c.add(key, value)
+	return value, nil
+}
+
+type cachedComputeClient struct {
+	client.ComputeClient
+	cache          *lookupCache
+	subscriptionID string
+}
+
+func (c *cachedComputeClient) Get(ctx context.Context, resourceGroupName string, vmName string, options *armcompute.VirtualMachinesClientGetOptions) (armcompute.VirtualMachinesClientGetResponse, error) {
+	var expand string
+	if options != nil && options.Expand != nil {
+		expand = string(*options.Expand)
+	}
+	key := fmt.Sprintf("vm/%s/%s/%s/%s", c.subscriptionID, resourceGroupName, vmName, expand)
+	return cachedLookup(c.cache, key, func() (armcompute.VirtualMachinesClientGetResponse, error) {
+		return c.ComputeClient.Get(ctx, resourceGroupName, vmName, options)
+	})
+}
+
+type cachedVMSSClient struct {
+	client.VMSSClient
+	cache          *lookupCache
+	subscriptionID string
+}
+
+func (c *cachedVMSSClient) Get(ctx context.Context, resourceGroupName string, vmScaleSetName string, options *armcompute.VirtualMachineScaleSetsClientGetOptions) (armcompute.VirtualMachineScaleSetsClientGetResponse, error) {
+	var expand string
+	if options != nil && options.Expand != nil {
+		expand = string(*options.Expand)
+	}
+	key := fmt.Sprintf("vmss/%s/%s/%s/%s", c.subscriptionID, resourceGroupName, vmScaleSetName, expand)
+	return cachedLookup(c.cache, key, func() (armcompute.VirtualMachineScaleSetsClientGetResponse, error) {
+		return c.VMSSClient.Get(ctx, resourceGroupName, vmScaleSetName, options)
+	})
+}
+
+type cachedMSIClient struct {
+	client.MSIClient
+	cache          *lookupCache
+	subscriptionID string
+}
+
+func (c *cachedMSIClient) Get(ctx context.Context, resourceGroupName string, resourceName string, options *armmsi.UserAssignedIdentitiesClientGetOptions) (armmsi.UserAssignedIdentitiesClientGetResponse, error) {
+	key := fmt.Sprintf("msi/%s/%s/%s", c.subscriptionID, resourceGroupName, resourceName)
+	return cachedLookup(c.cache, key, func() (armmsi.UserAssignedIdentitiesClientGetResponse, error) {
+		return c.MSIClient.Get(ctx, resourceGroupName, resourceName, options)
+	})
+}
+
+// NewListByResourceGroupPager is not cached, since pages are fetched lazily
+// by the caller.
+func (c *cachedMSIClient) NewListByResourceGroupPager(resourceGroupName string, options *armmsi.UserAssignedIdentitiesClientListByResourceGroupOptions) *runtime.Pager[armmsi.UserAssignedIdentitiesClientListByResourceGroupResponse] {
+	return c.MSIClient.NewListByResourceGroupPager(resourceGroupName, options)
+}
+
+type cachedResourceClient struct {
+	client.ResourceClient
+	cache *lookupCache
+}
+
+func (c *cachedResourceClient) GetByID(ctx context.Context, resourceID, apiVersion string, options *armresources.ClientGetByIDOptions) (armresources.ClientGetByIDResponse, error) {
+	key := fmt.Sprintf("resource/%s/%s", resourceID, apiVersion)
+	return cachedLookup(c.cache, key, func() (armresources.ClientGetByIDResponse, error) {
+		return c.ResourceClient.GetByID(ctx, resourceID, apiVersion, options)
+	})
+}
+
+type cachedProvidersClient struct {
+	client.ProvidersClient
+	cache          *lookupCache
+	subscriptionID string
+}
+
+func (c *cachedProvidersClient) Get(ctx context.Context, resourceProviderNamespace string, options *armresources.ProvidersClientGetOptions) (armresources.ProvidersClientGetResponse, error) {
+	key := fmt.Sprintf("provider/%s/%s", c.subscriptionID, resourceProviderNamespace)
+	return cachedLookup(c.cache, key, func() (armresources.ProvidersClientGetResponse, error) {
+		return c.ProvidersClient.Get(ctx, resourceProviderNamespace, options)
+	})
+}
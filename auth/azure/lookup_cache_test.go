@@ -0,0 +1,245 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestLookupCache(t *testing.T) {
+	c := newLookupCache(time.Minute, 2)
+
+	var calls int
+	lookup := func(value string) func() (string, error) {
+		return func() (string, error) {
+			calls++
+			return value, nil
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if v, err := cachedLookup(c, "a", lookup("a")); err != nil || v != "a" {
+			t.Fatalf("unexpected result %q, %v", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 lookup, got %d", calls)
+	}
+
+	// errors are not cached
+	if _, err := cachedLookup(c, "b", func() (string, error) { return "", errors.New("throttled") }); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, ok := c.get("b"); ok {
+		t.Fatal("expected error to not be cached")
+	}
+
+	// the least recently used entry is evicted when the cache is full
+	cachedLookup(c, "b", lookup("b"))
+	cachedLookup(c, "a", lookup("a"))
+	cachedLookup(c, "c", lookup("c"))
+	if _, ok := c.get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+
+	stats := c.stats()
+	if stats.Entries != 2 || stats.MaxEntries != 2 || stats.Evictions != 1 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+	if stats.Hits != 3 || stats.Misses != 6 {
+		t.Fatalf("unexpected hits and misses: %#v", stats)
+	}
+
+	// expired entries are looked up again
+	c.ttl = -time.Second
+	c.add("a", "stale")
+	if _, ok := c.get("a"); ok {
+		t.Fatal("expected expired entry to be a miss")
+	}
+
+	// a zero TTL disables the cache
+	calls = 0
+	disabled := newLookupCache(0, 10)
+	cachedLookup(disabled, "a", lookup("a"))
+	cachedLookup(disabled, "a", lookup("a"))
+	if calls != 2 {
+		t.Fatalf("expected disabled cache to not cache lookups, got %d lookups", calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	tests := map[string]struct {
+		header http.Header
+		want   time.Duration
+	}{
+		"none": {
+			header: http.Header{},
+		},
+		"seconds": {
+			header: http.Header{"Retry-After": []string{"17"}},
+			want:   17 * time.Second,
+		},
+		"date": {
+			header: http.Header{"Retry-After": []string{now.Add(30 * time.Second).UTC().Format(http.TimeFormat)}},
+			want:   30 * time.Second,
+		},
+		"milliseconds": {
+			header: http.Header{
+				"Retry-After":    []string{"17"},
+				"Retry-After-Ms": []string{"1500"},
+			},
+			want: 1500 * time.Millisecond,
+		},
+		"ms header": {
+			header: http.Header{"X-Ms-Retry-After-Ms": []string{"250"}},
+			want:   250 * time.Millisecond,
+		},
+		"invalid": {
+			header: http.Header{"Retry-After": []string{"soon"}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := retryAfter(tc.header, now); got != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTransporter_RetryAfter(t *testing.T) {
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests = append(requests, time.Now())
+		if len(requests) == 1 {
+			w.Header().Set("Retry-After-Ms", "300")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tp := transporter{
+		sender:   server.Client(),
+		throttle: &throttle{maxDelay: time.Minute},
+	}
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := tp.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	if delay := requests[1].Sub(requests[0]); delay < 300*time.Millisecond {
+		t.Fatalf("expected the second request to wait for Retry-After, waited %s", delay)
+	}
+
+	// the wait is capped by the max retry delay and cancelled with the request
+	tp.throttle = &throttle{maxDelay: 50 * time.Millisecond}
+	tp.throttle.backoff(time.Hour)
+	start := time.Now()
+	if err := tp.throttle.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Fatalf("expected wait to be capped, waited %s", waited)
+	}
+
+	tp.throttle = &throttle{}
+	tp.throttle.backoff(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tp.throttle.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context cancellation, got %v", err)
+	}
+}
+
+func TestLookupCacheStats(t *testing.T) {
+	principalID := "123e4567-e89b-12d3-a456-426655440000"
+	var lookups int
+	c := func(_ string) (armcompute.VirtualMachinesClientGetResponse, error) {
+		lookups++
+		return armcompute.VirtualMachinesClientGetResponse{VirtualMachine: armcompute.VirtualMachine{
+			Identity: &armcompute.VirtualMachineIdentity{
+				PrincipalID: &principalID,
+			},
+		}}, nil
+	}
+	b, s := getTestBackendWithComputeClient(t, c, nil, nil, nil, getTestMSGraphClient())
+	b.provider = &cachingMockProvider{
+		mockProvider: b.provider.(*mockProvider),
+		cache:        newLookupCache(time.Minute, 10),
+	}
+
+	roleName := "testrole"
+	roleData := map[string]interface{}{
+		"name":                  roleName,
+		"policies":              []string{"dev"},
+		"bound_resource_groups": []string{"rg"},
+	}
+	testRoleCreate(t, b, s, roleData)
+
+	claims := map[string]interface{}{
+		"exp": time.Now().Add(60 * time.Second).Unix(),
+		"nbf": time.Now().Add(-60 * time.Second).Unix(),
+		"oid": principalID,
+	}
+	loginData := map[string]interface{}{
+		"role":                roleName,
+		"subscription_id":     "1234abcd-1234-abcd-1234-abcd1234ef90",
+		"resource_group_name": "rg",
+		"vm_name":             "vm",
+	}
+	testLoginSuccess(t, b, s, loginData, claims, roleData)
+	testLoginSuccess(t, b, s, loginData, claims, roleData)
+	if lookups != 1 {
+		t.Fatalf("expected the virtual machine lookup to be cached, got %d lookups", lookups)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "lookup-cache/stats",
+		Storage:   s,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	expected := map[string]interface{}{
+		"entries":     1,
+		"max_entries": 10,
+		"ttl":         int64(60),
+		"hits":        uint64(1),
+		"misses":      uint64(1),
+		"evictions":   uint64(0),
+	}
+	for k, v := range expected {
+		if resp.Data[k] != v {
+			t.Fatalf("expected %s to be %v, got %v", k, v, resp.Data[k])
+		}
+	}
+}
@@ -5,12 +5,36 @@ package azure
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/authmetadata"
 	"github.com/openbao/openbao/sdk/v2/logical"
 )
 
+// The default alias_type is "object_id". The default fields below match
+// the metadata historically attached to logins.
+var aliasMetadataFields = &authmetadata.Fields{
+	FieldName: "alias_metadata",
+	Default: []string{
+		"app_id",
+		"resource_group_name",
+		"resource_id",
+		"role",
+		"service_account_name",
+		"service_account_namespace",
+		"subscription_id",
+		"vm_name",
+		"vmss_name",
+	},
+	AvailableToAdd: []string{
+		"object_id",
+		"role_id",
+		"vmss_id",
+	},
+}
+
 func pathConfig(b *azureAuthBackend) *framework.Path {
 	p := &framework.Path{
 		Pattern: "config",
@@ -68,6 +92,26 @@ func pathConfig(b *azureAuthBackend) *framework.Path {
 				Description: "The initial amount of delay to use before retrying an operation, increasing exponentially.",
 				Required:    false,
 			},
+			"alias_type": {
+				Type:        framework.TypeString,
+				Default:     defaultAliasType,
+				Description: "Indicates what value to use when generating an alias for logins. One of: object_id, app_id, resource_id, vmss_id, role_id, template.",
+			},
+			"alias_template": {
+				Type:        framework.TypeString,
+				Description: "Template used to generate the alias when alias_type is \"template\". Available fields: .ObjectID, .AppID, .RoleName, .RoleID, .SubscriptionID, .ResourceGroupName, .VMName, .VMSSName, .ResourceID, .ServiceAccountNamespace, .ServiceAccountName.",
+			},
+			aliasMetadataFields.FieldName: authmetadata.FieldSchema(aliasMetadataFields),
+			"lookup_cache_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Default:     int(defaultLookupCacheTTL.Seconds()),
+				Description: "The time the results of Azure Resource Manager lookups made on login are cached. Set to 0 to disable the cache.",
+			},
+			"lookup_cache_max_entries": {
+				Type:        framework.TypeInt,
+				Default:     defaultLookupCacheMaxEntries,
+				Description: "The maximum number of Azure Resource Manager lookups to cache.",
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ReadOperation: &framework.PathOperation{
@@ -109,21 +153,36 @@ func pathConfig(b *azureAuthBackend) *framework.Path {
 }
 
 type azureConfig struct {
-	TenantID                      string        `json:"tenant_id"`
-	Resource                      string        `json:"resource"`
-	Environment                   string        `json:"environment"`
-	ClientID                      string        `json:"client_id"`
-	ClientSecret                  string        `json:"client_secret"`
-	ClientSecretKeyID             string        `json:"client_secret_key_id"`
-	NewClientSecret               string        `json:"new_client_secret"`
-	NewClientSecretCreated        time.Time     `json:"new_client_secret_created"`
-	NewClientSecretExpirationDate time.Time     `json:"new_client_secret_expiration_date"`
-	NewClientSecretKeyID          string        `json:"new_client_secret_key_id"`
-	RootPasswordTTL               time.Duration `json:"root_password_ttl"`
-	RootPasswordExpirationDate    time.Time     `json:"root_password_expiration_date"`
-	MaxRetries                    int32         `json:"max_retries"`
-	MaxRetryDelay                 time.Duration `json:"max_retry_delay"`
-	RetryDelay                    time.Duration `json:"retry_delay"`
+	TenantID                      string                `json:"tenant_id"`
+	Resource                      string                `json:"resource"`
+	Environment                   string                `json:"environment"`
+	ClientID                      string                `json:"client_id"`
+	ClientSecret                  string                `json:"client_secret"`
+	ClientSecretKeyID             string                `json:"client_secret_key_id"`
+	NewClientSecret               string                `json:"new_client_secret"`
+	NewClientSecretCreated        time.Time             `json:"new_client_secret_created"`
+	NewClientSecretExpirationDate time.Time             `json:"new_client_secret_expiration_date"`
+	NewClientSecretKeyID          string                `json:"new_client_secret_key_id"`
+	RootPasswordTTL               time.Duration         `json:"root_password_ttl"`
+	RootPasswordExpirationDate    time.Time             `json:"root_password_expiration_date"`
+	MaxRetries                    int32                 `json:"max_retries"`
+	MaxRetryDelay                 time.Duration         `json:"max_retry_delay"`
+	RetryDelay                    time.Duration         `json:"retry_delay"`
+	AliasType                     string                `json:"alias_type"`
+	AliasTemplate                 string                `json:"alias_template"`
+	AliasMetadata                 *authmetadata.Handler `json:"alias_metadata_handler"`
+	LookupCacheTTL                time.Duration         `json:"lookup_cache_ttl"`
+	LookupCacheMaxEntries         int                   `json:"lookup_cache_max_entries"`
+}
+
+// newAzureConfig returns a config with the defaults of fields that may be
+// missing from stored configs, such as those written by earlier versions.
+func newAzureConfig() *azureConfig {
+	return &azureConfig{
+		AliasMetadata:         authmetadata.NewHandler(aliasMetadataFields),
+		LookupCacheTTL:        defaultLookupCacheTTL,
+		LookupCacheMaxEntries: defaultLookupCacheMaxEntries,
+	}
 }
 
 func (b *azureAuthBackend) config(ctx context.Context, s logical.Storage) (*azureConfig, error) {
@@ -135,7 +194,7 @@ func (b *azureAuthBackend) config(ctx context.Context, s logical.Storage) (*azur
 		return nil, nil
 	}
 
-	config := new(azureConfig)
+	config := newAzureConfig()
 	if err := entry.DecodeJSON(config); err != nil {
 		return nil, err
 	}
@@ -157,7 +216,7 @@ func (b *azureAuthBackend) pathConfigWrite(ctx context.Context, req *logical.Req
 	}
 
 	if config == nil {
-		config = new(azureConfig)
+		config = newAzureConfig()
 	}
 
 	tenantID, ok := data.GetOk("tenant_id")
@@ -209,6 +268,42 @@ func (b *azureAuthBackend) pathConfigWrite(ctx context.Context, req *logical.Req
 		config.RetryDelay = time.Second * time.Duration(retryDelayRaw.(int))
 	}
 
+	config.LookupCacheTTL = defaultLookupCacheTTL
+	lookupCacheTTLRaw, ok := data.GetOk("lookup_cache_ttl")
+	if ok {
+		config.LookupCacheTTL = time.Second * time.Duration(lookupCacheTTLRaw.(int))
+	}
+	if config.LookupCacheTTL < 0 {
+		return logical.ErrorResponse("lookup_cache_ttl cannot be negative"), nil
+	}
+
+	config.LookupCacheMaxEntries = defaultLookupCacheMaxEntries
+	lookupCacheMaxEntriesRaw, ok := data.GetOk("lookup_cache_max_entries")
+	if ok {
+		config.LookupCacheMaxEntries = lookupCacheMaxEntriesRaw.(int)
+	}
+	if config.LookupCacheMaxEntries < 0 {
+		return logical.ErrorResponse("lookup_cache_max_entries cannot be negative"), nil
+	}
+
+	aliasType, ok := data.GetOk("alias_type")
+	if ok {
+		config.AliasType = aliasType.(string)
+	}
+
+	aliasTemplate, ok := data.GetOk("alias_template")
+	if ok {
+		config.AliasTemplate = aliasTemplate.(string)
+	}
+
+	if err := validateAliasType(config.AliasType, config.AliasTemplate); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if err := config.AliasMetadata.ParseAuthMetadata(data); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to parse alias metadata: %s", err)), nil
+	}
+
 	// Create a settings object to validate all required settings
 	// are available
 	if _, err := b.getAzureSettings(ctx, config); err != nil {
@@ -248,9 +343,20 @@ func (b *azureAuthBackend) pathConfigRead(ctx context.Context, req *logical.Requ
 			"retry_delay":       config.RetryDelay,
 			"max_retry_delay":   config.MaxRetryDelay,
 			"max_retries":       config.MaxRetries,
+			"alias_type":        defaultAliasType,
+			"alias_template":    config.AliasTemplate,
+
+			"lookup_cache_ttl":         int64(config.LookupCacheTTL.Seconds()),
+			"lookup_cache_max_entries": config.LookupCacheMaxEntries,
+
+			aliasMetadataFields.FieldName: config.AliasMetadata.AuthMetadata(),
 		},
 	}
 
+	if config.AliasType != "" {
+		resp.Data["alias_type"] = config.AliasType
+	}
+
 	if !config.RootPasswordExpirationDate.IsZero() {
 		resp.Data["root_password_expiration_date"] = config.RootPasswordExpirationDate
 	}
@@ -286,6 +392,14 @@ func (b *azureAuthBackend) saveConfig(ctx context.Context, config *azureConfig,
 	return nil
 }
 
+const (
+	// ARM lookups are only cached briefly, so that changes to a resource
+	// are seen quickly, but long enough to absorb bursts of logins from a
+	// scale set rollout
+	defaultLookupCacheTTL        = time.Minute
+	defaultLookupCacheMaxEntries = 1000
+)
+
 const (
 	// The default password expiration duration is 6 months in
 	// the Azure UI, so we're setting it to 6 months (in hours)
@@ -0,0 +1,229 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	aksClusterStoragePrefix = "config/aks-cluster/"
+
+	// defaultAKSAudience is the audience of service account tokens projected
+	// by the AKS workload identity webhook.
+	defaultAKSAudience = "api://AzureADTokenExchange"
+
+	aksClusterResourceType = "Microsoft.ContainerService/managedClusters"
+)
+
+// pathsConfigAKSCluster returns the paths used to register the AKS clusters
+// whose service account tokens may be used to log in.
+func pathsConfigAKSCluster(b *azureAuthBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "config/aks-cluster/?$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixAzure,
+				OperationSuffix: "aks-clusters",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathAKSClusterList,
+				},
+			},
+			HelpSynopsis:    aksClusterListHelpSyn,
+			HelpDescription: aksClusterListHelpDesc,
+		},
+		{
+			Pattern: "config/aks-cluster/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixAzure,
+				OperationSuffix: "aks-cluster",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the AKS cluster configuration.",
+				},
+				"resource_id": {
+					Type:        framework.TypeString,
+					Description: `The resource ID of the AKS cluster, in the format /subscriptions/{guid}/resourceGroups/{resource-group-name}/providers/Microsoft.ContainerService/managedClusters/{cluster-name}.`,
+				},
+				"oidc_issuer_url": {
+					Type:        framework.TypeString,
+					Description: `The OIDC issuer URL of the AKS cluster. Service account tokens are verified using the keys published by this issuer.`,
+				},
+				"audience": {
+					Type:        framework.TypeString,
+					Default:     defaultAKSAudience,
+					Description: `The audience expected in service account tokens.`,
+				},
+			},
+			ExistenceCheck: b.pathAKSClusterExistenceCheck,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathAKSClusterWrite,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathAKSClusterWrite,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathAKSClusterRead,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.pathAKSClusterDelete,
+				},
+			},
+			HelpSynopsis:    aksClusterHelpSyn,
+			HelpDescription: aksClusterHelpDesc,
+		},
+	}
+}
+
+type aksCluster struct {
+	ResourceID    string `json:"resource_id"`
+	OIDCIssuerURL string `json:"oidc_issuer_url"`
+	Audience      string `json:"audience"`
+}
+
+func (b *azureAuthBackend) aksCluster(ctx context.Context, s logical.Storage, name string) (*aksCluster, error) {
+	entry, err := s.Get(ctx, aksClusterStoragePrefix+strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	cluster := new(aksCluster)
+	if err := entry.DecodeJSON(cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+func (b *azureAuthBackend) pathAKSClusterExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+	cluster, err := b.aksCluster(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return cluster != nil, nil
+}
+
+func (b *azureAuthBackend) pathAKSClusterList(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	clusters, err := req.Storage.List(ctx, aksClusterStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(clusters), nil
+}
+
+func (b *azureAuthBackend) pathAKSClusterRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	cluster, err := b.aksCluster(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if cluster == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"resource_id":     cluster.ResourceID,
+			"oidc_issuer_url": cluster.OIDCIssuerURL,
+			"audience":        cluster.Audience,
+		},
+	}, nil
+}
+
+func (b *azureAuthBackend) pathAKSClusterWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := strings.ToLower(data.Get("name").(string))
+	if name == "" {
+		return logical.ErrorResponse("missing name"), nil
+	}
+
+	cluster, err := b.aksCluster(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if cluster == nil {
+		cluster = &aksCluster{
+			Audience: defaultAKSAudience,
+		}
+	}
+
+	if resourceID, ok := data.GetOk("resource_id"); ok {
+		cluster.ResourceID = resourceID.(string)
+	}
+	if issuer, ok := data.GetOk("oidc_issuer_url"); ok {
+		cluster.OIDCIssuerURL = issuer.(string)
+	}
+	if audience, ok := data.GetOk("audience"); ok {
+		cluster.Audience = audience.(string)
+	}
+
+	if cluster.ResourceID == "" {
+		return logical.ErrorResponse("resource_id is required"), nil
+	}
+	id, err := arm.ParseResourceID(cluster.ResourceID)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid resource_id %q: %s", cluster.ResourceID, err)), nil
+	}
+	if !strings.EqualFold(id.ResourceType.String(), aksClusterResourceType) {
+		return logical.ErrorResponse(fmt.Sprintf("resource_id must be of type %s, got %s", aksClusterResourceType, id.ResourceType)), nil
+	}
+
+	if cluster.OIDCIssuerURL == "" {
+		return logical.ErrorResponse("oidc_issuer_url is required"), nil
+	}
+	if u, err := url.Parse(cluster.OIDCIssuerURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return logical.ErrorResponse(fmt.Sprintf("invalid oidc_issuer_url %q", cluster.OIDCIssuerURL)), nil
+	}
+
+	if cluster.Audience == "" {
+		return logical.ErrorResponse("audience cannot be empty"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(aksClusterStoragePrefix+name, cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	b.resetAKSVerifier(name)
+
+	return nil, nil
+}
+
+func (b *azureAuthBackend) pathAKSClusterDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := strings.ToLower(data.Get("name").(string))
+	if err := req.Storage.Delete(ctx, aksClusterStoragePrefix+name); err != nil {
+		return nil, err
+	}
+
+	b.resetAKSVerifier(name)
+
+	return nil, nil
+}
+
+const (
+	aksClusterListHelpSyn  = `Lists the AKS clusters registered with the backend.`
+	aksClusterListHelpDesc = `The list will contain the names of the AKS cluster configurations.`
+	aksClusterHelpSyn      = `Registers an AKS cluster for workload identity logins.`
+	aksClusterHelpDesc     = `
+Roles with a login_type of "aks_workload_identity" accept Kubernetes service
+account tokens projected into pods of a registered AKS cluster. The tokens are
+verified against the keys published by the cluster's OIDC issuer, and roles
+may bind on the cluster's resource ID.
+`
+)
@@ -27,14 +27,19 @@ func TestConfig(t *testing.T) {
 				"tenant_id": "tid",
 			},
 			expected: map[string]interface{}{
-				"client_id":         "",
-				"environment":       "",
-				"max_retries":       defaultMaxRetries,
-				"max_retry_delay":   defaultMaxRetryDelay,
-				"resource":          "resource",
-				"retry_delay":       defaultRetryDelay,
-				"root_password_ttl": 15768000,
-				"tenant_id":         "tid",
+				"client_id":                "",
+				"environment":              "",
+				"max_retries":              defaultMaxRetries,
+				"max_retry_delay":          defaultMaxRetryDelay,
+				"resource":                 "resource",
+				"retry_delay":              defaultRetryDelay,
+				"root_password_ttl":        15768000,
+				"tenant_id":                "tid",
+				"alias_type":               defaultAliasType,
+				"alias_template":           "",
+				"alias_metadata":           aliasMetadataFields.Default,
+				"lookup_cache_ttl":         int64(defaultLookupCacheTTL.Seconds()),
+				"lookup_cache_max_entries": defaultLookupCacheMaxEntries,
 			},
 		},
 		{
@@ -45,14 +50,19 @@ func TestConfig(t *testing.T) {
 				"environment": "AzurePublicCloud",
 			},
 			expected: map[string]interface{}{
-				"client_id":         "",
-				"environment":       "AzurePublicCloud",
-				"max_retries":       defaultMaxRetries,
-				"max_retry_delay":   defaultMaxRetryDelay,
-				"resource":          "resource",
-				"retry_delay":       defaultRetryDelay,
-				"root_password_ttl": 15768000,
-				"tenant_id":         "tid",
+				"client_id":                "",
+				"environment":              "AzurePublicCloud",
+				"max_retries":              defaultMaxRetries,
+				"max_retry_delay":          defaultMaxRetryDelay,
+				"resource":                 "resource",
+				"retry_delay":              defaultRetryDelay,
+				"root_password_ttl":        15768000,
+				"tenant_id":                "tid",
+				"alias_type":               defaultAliasType,
+				"alias_template":           "",
+				"alias_metadata":           aliasMetadataFields.Default,
+				"lookup_cache_ttl":         int64(defaultLookupCacheTTL.Seconds()),
+				"lookup_cache_max_entries": defaultLookupCacheMaxEntries,
 			},
 		},
 		{
@@ -138,6 +148,35 @@ func TestConfigDelete(t *testing.T) {
 	}
 }
 
+func TestConfig_LookupCacheDefaults(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	// Configs written before the lookup cache settings were added keep the
+	// cache enabled with the defaults
+	entry, err := logical.StorageEntryJSON("config", map[string]interface{}{
+		"tenant_id": "tid",
+		"resource":  "resource",
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Put(ctx, entry))
+
+	config, err := b.config(ctx, s)
+	require.NoError(t, err)
+	assert.Equal(t, defaultLookupCacheTTL, config.LookupCacheTTL)
+	assert.Equal(t, defaultLookupCacheMaxEntries, config.LookupCacheMaxEntries)
+
+	// An explicitly disabled cache stays disabled
+	_, err = testConfigUpdate(t, b, s, map[string]interface{}{
+		"lookup_cache_ttl": 0,
+	})
+	require.NoError(t, err)
+
+	config, err = b.config(ctx, s)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), config.LookupCacheTTL)
+}
+
 func testConfigCreate(t *testing.T, b logical.Backend, s logical.Storage, d map[string]interface{}) (*logical.Response, error) {
 	t.Helper()
 	return testConfigCreateUpdate(t, b, logical.CreateOperation, s, d)
@@ -196,14 +235,19 @@ func TestConfig_RetryDefaults(t *testing.T) {
 	}
 
 	expected := map[string]interface{}{
-		"client_id":         "",
-		"environment":       "",
-		"max_retries":       defaultMaxRetries,
-		"max_retry_delay":   defaultMaxRetryDelay,
-		"resource":          "resource",
-		"retry_delay":       defaultRetryDelay,
-		"root_password_ttl": 15768000,
-		"tenant_id":         "tid",
+		"client_id":                "",
+		"environment":              "",
+		"max_retries":              defaultMaxRetries,
+		"max_retry_delay":          defaultMaxRetryDelay,
+		"resource":                 "resource",
+		"retry_delay":              defaultRetryDelay,
+		"root_password_ttl":        15768000,
+		"tenant_id":                "tid",
+		"alias_type":               defaultAliasType,
+		"alias_template":           "",
+		"alias_metadata":           aliasMetadataFields.Default,
+		"lookup_cache_ttl":         int64(defaultLookupCacheTTL.Seconds()),
+		"lookup_cache_max_entries": defaultLookupCacheMaxEntries,
 	}
 	testConfigRead(t, b, s, expected)
 
@@ -249,14 +293,19 @@ func TestConfig_RetryCustom(t *testing.T) {
 	}
 
 	expected := map[string]interface{}{
-		"client_id":         "",
-		"environment":       "",
-		"max_retries":       maxRetries,
-		"max_retry_delay":   maxRetryDelay,
-		"resource":          "resource",
-		"retry_delay":       retryDelay,
-		"root_password_ttl": 15768000,
-		"tenant_id":         "tid",
+		"client_id":                "",
+		"environment":              "",
+		"max_retries":              maxRetries,
+		"max_retry_delay":          maxRetryDelay,
+		"resource":                 "resource",
+		"retry_delay":              retryDelay,
+		"root_password_ttl":        15768000,
+		"tenant_id":                "tid",
+		"alias_type":               defaultAliasType,
+		"alias_template":           "",
+		"alias_metadata":           aliasMetadataFields.Default,
+		"lookup_cache_ttl":         int64(defaultLookupCacheTTL.Seconds()),
+		"lookup_cache_max_entries": defaultLookupCacheMaxEntries,
 	}
 	testConfigRead(t, b, s, expected)
 
@@ -130,6 +130,18 @@ func (b *azureAuthBackend) pathLogin(ctx context.Context, req *logical.Request,
 		}
 	}
 
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve backend configuration: %w", err)
+	}
+	if config == nil {
+		config = newAzureConfig()
+	}
+
+	if role.LoginType == loginTypeAKSWorkloadIdentity {
+		return b.pathLoginAKSWorkloadIdentity(ctx, req, signedJwt, roleName, role, config)
+	}
+
 	subscriptionID := data.Get("subscription_id").(string)
 	resourceGroupName := data.Get("resource_group_name").(string)
 	vmssName := data.Get("vmss_name").(string)
@@ -149,14 +161,6 @@ func (b *azureAuthBackend) pathLogin(ctx context.Context, req *logical.Request,
 		return logical.ErrorResponse(fmt.Sprintf("invalid vm name %q", vmName)), nil
 	}
 
-	config, err := b.config(ctx, req.Storage)
-	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve backend configuration: %w", err)
-	}
-	if config == nil {
-		config = new(azureConfig)
-	}
-
 	provider, err := b.getProvider(ctx, config)
 	if err != nil {
 		return nil, err
@@ -179,47 +183,73 @@ func (b *azureAuthBackend) pathLogin(ctx context.Context, req *logical.Request,
 		return nil, err
 	}
 
-	if err := b.verifyResource(ctx, subscriptionID, resourceGroupName, vmName, vmssName, resourceID, claims, role); err != nil {
+	if err := b.verifyResource(ctx, subscriptionID, resourceGroupName, vmName, vmssName, resourceID, claims, role, config.aliasFromResource()); err != nil {
 		return nil, err
 	}
 
-	auth := &logical.Auth{
-		DisplayName: claims.ObjectID,
-		Alias: &logical.Alias{
-			Name: claims.ObjectID,
-			Metadata: map[string]string{
-				"resource_group_name": resourceGroupName,
-				"subscription_id":     subscriptionID,
-			},
-		},
-		InternalData: map[string]interface{}{
-			"role": roleName,
-		},
-		Metadata: map[string]string{
-			"role":                roleName,
-			"resource_group_name": resourceGroupName,
-			"subscription_id":     subscriptionID,
-		},
+	input := &aliasInput{
+		RoleName:          roleName,
+		RoleID:            role.RoleID,
+		ObjectID:          claims.ObjectID,
+		AppID:             claims.AppID,
+		SubscriptionID:    subscriptionID,
+		ResourceGroupName: resourceGroupName,
+		VMName:            vmName,
+		VMSSName:          vmssName,
+		ResourceID:        resourceID,
 	}
+	return b.loginResponse(ctx, req, config, role, input, claims, idToken.Expiry, claims.ObjectID)
+}
 
-	if vmName != "" {
-		auth.Alias.Metadata["vm_name"] = vmName
-		auth.Metadata["vm_name"] = vmName
+// loginResponse builds the response of a successful login, or of an alias
+// lookahead, from the verified claims of the token.
+func (b *azureAuthBackend) loginResponse(ctx context.Context, req *logical.Request, config *azureConfig, role *azureRole, input *aliasInput, claims *additionalClaims, expiry time.Time, displayName string) (*logical.Response, error) {
+	alias, err := config.getAlias(input)
+	if err != nil {
+		return logical.ErrorResponse("unable to create alias: %s", err), nil
 	}
-	if vmssName != "" {
-		auth.Alias.Metadata["vmss_name"] = vmssName
-		auth.Metadata["vmss_name"] = vmssName
+
+	if req.Operation == logical.AliasLookaheadOperation {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: &logical.Alias{
+					Name: alias,
+				},
+			},
+		}, nil
 	}
-	if resourceID != "" {
-		auth.Alias.Metadata["resource_id"] = resourceID
-		auth.Metadata["resource_id"] = resourceID
+
+	if role.EnableReplayProtection {
+		if err := b.checkAndRecordToken(ctx, req.Storage, claims, expiry); err != nil {
+			return nil, err
+		}
 	}
-	if claims.AppID != "" {
-		auth.Alias.Metadata["app_id"] = claims.AppID
-		auth.Metadata["app_id"] = claims.AppID
+
+	auth := &logical.Auth{
+		DisplayName: displayName,
+		Alias: &logical.Alias{
+			Name: alias,
+		},
+		InternalData: map[string]interface{}{
+			"role": input.RoleName,
+
+			// The resource and identity are recorded so that they can be
+			// revalidated on renewal regardless of alias_metadata
+			"object_id":           input.ObjectID,
+			"app_id":              input.AppID,
+			"subscription_id":     input.SubscriptionID,
+			"resource_group_name": input.ResourceGroupName,
+			"vm_name":             input.VMName,
+			"vmss_name":           input.VMSSName,
+			"resource_id":         input.ResourceID,
+			"last_validated":      time.Now().UTC().Format(time.RFC3339),
+		},
 	}
 
 	role.PopulateTokenAuth(auth, req)
+	if err := config.AliasMetadata.PopulateDesiredMetadata(auth, authMetadata(input)); err != nil {
+		b.Logger().Warn("unable to populate alias metadata", "err", err.Error())
+	}
 
 	resp := &logical.Response{
 		Auth: auth,
@@ -244,6 +274,16 @@ func (b *azureAuthBackend) verifyClaims(claims *additionalClaims, role *azureRol
 		return fmt.Errorf("token is not yet valid (Token Not Before: %v)", notBefore)
 	}
 
+	if role.MaxTokenAge > 0 {
+		issuedAt := time.Time(claims.IssuedAt)
+		if issuedAt.IsZero() {
+			return errors.New("token does not contain an iat claim required by max_token_age")
+		}
+		if time.Since(issuedAt) > role.MaxTokenAge {
+			return fmt.Errorf("token is older than the max_token_age of the role (Token Issued At: %v)", issuedAt)
+		}
+	}
+
 	if (len(role.BoundServicePrincipalIDs) == 1 && role.BoundServicePrincipalIDs[0] == "*") &&
 		(len(role.BoundGroupIDs) == 1 && role.BoundGroupIDs[0] == "*") {
 		return fmt.Errorf("expected specific bound_group_ids or bound_service_principal_ids; both cannot be '*'")
@@ -276,9 +316,14 @@ func (b *azureAuthBackend) verifyClaims(claims *additionalClaims, role *azureRol
 	return nil
 }
 
-func (b *azureAuthBackend) verifyResource(ctx context.Context, subscriptionID, resourceGroupName, vmName, vmssName, resourceID string, claims *additionalClaims, role *azureRole) error {
+// verifyResource verifies that the resource given on login has the identity
+// of the token and satisfies the resource constraints of the role. If
+// aliasFromResource is true, the alias is built from the resource, which is
+// then verified even if the role has no resource constraints, and must have
+// the identity of the token itself.
+func (b *azureAuthBackend) verifyResource(ctx context.Context, subscriptionID, resourceGroupName, vmName, vmssName, resourceID string, claims *additionalClaims, role *azureRole, aliasFromResource bool) error {
 	// If not checking anything with the resource id, exit early
-	if len(role.BoundResourceGroups) == 0 && len(role.BoundSubscriptionsIDs) == 0 && len(role.BoundLocations) == 0 && len(role.BoundScaleSets) == 0 {
+	if !role.boundToResource() && !aliasFromResource {
 		return nil
 	}
 
@@ -424,6 +469,12 @@ func (b *azureAuthBackend) verifyResource(ctx context.Context, subscriptionID, r
 	// Ensure the token OID is the principal id of the system-assigned identity
 	// or one of the user-assigned identities
 	if _, ok := principalIDs[claims.ObjectID]; !ok {
+		// A WIF match only ties the token to the resource group, not to the
+		// resource that the alias is built from
+		if aliasFromResource {
+			return errors.New("token object id does not match the identities of the resource, which is required by the alias_type")
+		}
+
 		// if it isn't, check the appID and see if _that_ exists. In some cases, particularly WIF (workload identity
 		// federation), there is no principal that matches the incoming ObjectID. In this case, we can still validate
 		// by checking the appID against the list of managed identities. (The appID is valid for use with authorizing
@@ -507,6 +558,12 @@ func (b *azureAuthBackend) pathLoginRenew(ctx context.Context, req *logical.Requ
 		return nil, fmt.Errorf("role %s does not exist during renewal", roleName)
 	}
 
+	if role.loginType() == loginTypeManagedIdentity {
+		if err := b.revalidateResource(ctx, req, role); err != nil {
+			return nil, fmt.Errorf("failed to revalidate resource during renewal: %w", err)
+		}
+	}
+
 	resp := &logical.Response{Auth: req.Auth}
 	resp.Auth.TTL = role.TokenTTL
 	resp.Auth.MaxTTL = role.TokenMaxTTL
@@ -514,11 +571,97 @@ func (b *azureAuthBackend) pathLoginRenew(ctx context.Context, req *logical.Requ
 	return resp, nil
 }
 
+// revalidateResource checks that the resource the token was issued to still
+// satisfies the role. The check is skipped if the resource was validated
+// within the revalidation interval of the role.
+func (b *azureAuthBackend) revalidateResource(ctx context.Context, req *logical.Request, role *azureRole) error {
+	if !role.boundToResource() {
+		return nil
+	}
+
+	lastValidated, _ := time.Parse(time.RFC3339, authInternalString(req.Auth, "last_validated"))
+	if time.Since(lastValidated) < role.RevalidationInterval {
+		return nil
+	}
+
+	objectID := authInternalString(req.Auth, "object_id")
+	if _, ok := req.Auth.InternalData["object_id"]; !ok && req.Auth.Alias != nil {
+		// Tokens issued before the object ID was recorded always used it
+		// as the alias name
+		objectID = req.Auth.Alias.Name
+	}
+	claims := &additionalClaims{
+		ObjectID: objectID,
+		AppID:    authInternalString(req.Auth, "app_id"),
+	}
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve backend configuration: %w", err)
+	}
+	if config == nil {
+		config = newAzureConfig()
+	}
+	if _, err := b.getProvider(ctx, config); err != nil {
+		return err
+	}
+
+	err = b.verifyResource(ctx,
+		authInternalString(req.Auth, "subscription_id"),
+		authInternalString(req.Auth, "resource_group_name"),
+		authInternalString(req.Auth, "vm_name"),
+		authInternalString(req.Auth, "vmss_name"),
+		authInternalString(req.Auth, "resource_id"),
+		claims, role, false)
+	if err != nil {
+		return err
+	}
+
+	req.Auth.InternalData["last_validated"] = time.Now().UTC().Format(time.RFC3339)
+	return nil
+}
+
+// authInternalString returns the named value recorded in the internal data
+// of the token, falling back to the token metadata for tokens issued before
+// the value was recorded there.
+func authInternalString(auth *logical.Auth, key string) string {
+	if value, ok := auth.InternalData[key].(string); ok {
+		return value
+	}
+	return auth.Metadata[key]
+}
+
+// authMetadata returns the metadata that may be attached to the token and
+// alias, as selected by the alias_metadata config field.
+func authMetadata(input *aliasInput) map[string]string {
+	metadata := map[string]string{
+		"role":                input.RoleName,
+		"role_id":             input.RoleID,
+		"object_id":           input.ObjectID,
+		"app_id":              input.AppID,
+		"subscription_id":     input.SubscriptionID,
+		"resource_group_name": input.ResourceGroupName,
+		"vm_name":             input.VMName,
+		"vmss_name":           input.VMSSName,
+		"resource_id":         input.ResourceID,
+
+		"service_account_namespace": input.ServiceAccountNamespace,
+		"service_account_name":      input.ServiceAccountName,
+	}
+	if vmssID, err := getVMSSIDAlias(nil, input); err == nil {
+		metadata["vmss_id"] = vmssID
+	}
+	return metadata
+}
+
 type additionalClaims struct {
 	NotBefore jsonTime `json:"nbf"`
 	ObjectID  string   `json:"oid"`
 	AppID     string   `json:"appid"`
 	GroupIDs  []string `json:"groups"`
+	IssuedAt  jsonTime `json:"iat"`
+	UTI       string   `json:"uti"`
+	JTI       string   `json:"jti"`
 }
 
 const (
@@ -7,8 +7,10 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -703,6 +705,339 @@ func TestLogin_BoundScaleSet(t *testing.T) {
 	testLoginFailure(t, b, s, loginData, claims, roleData)
 }
 
+func TestLogin_AliasType(t *testing.T) {
+	principalID := "123e4567-e89b-12d3-a456-426655440000"
+	subscriptionID := "1234abcd-1234-abcd-1234-abcd1234ef90"
+	c, v, m := getTestBackendFunctions(false)
+
+	b, s := getTestBackendWithComputeClient(t, c, v, m, nil, nil)
+
+	configData := map[string]interface{}{
+		"tenant_id":      "tid",
+		"resource":       "resource",
+		"alias_type":     aliasTypeVMSSID,
+		"alias_metadata": []string{"role", "vmss_id"},
+	}
+	if _, err := testConfigCreate(t, b, s, configData); err != nil {
+		t.Fatal(err)
+	}
+	// writing the config resets the provider
+	b.provider = newMockProvider(c, v, m, nil, nil)
+
+	roleName := "testrole"
+	roleData := map[string]interface{}{
+		"name":             roleName,
+		"policies":         []string{"dev", "prod"},
+		"bound_scale_sets": []string{"goodvmss"},
+	}
+	testRoleCreate(t, b, s, roleData)
+
+	claims := map[string]interface{}{
+		"exp": time.Now().Add(60 * time.Second).Unix(),
+		"nbf": time.Now().Add(-60 * time.Second).Unix(),
+		"oid": principalID,
+	}
+	expectedAlias := fmt.Sprintf("/subscriptions/%s/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/goodvmss", subscriptionID)
+
+	for _, op := range []logical.Operation{logical.UpdateOperation, logical.AliasLookaheadOperation} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      "login",
+			Data: map[string]interface{}{
+				"role":                roleName,
+				"jwt":                 testJWT(t, claims),
+				"subscription_id":     subscriptionID,
+				"resource_group_name": "rg",
+				"vmss_name":           "goodvmss",
+			},
+			Storage: s,
+			Connection: &logical.Connection{
+				RemoteAddr: "127.0.0.1",
+			},
+		})
+		if err != nil || resp.IsError() {
+			t.Fatalf("op %s: err: %v resp: %#v", op, err, resp)
+		}
+		if resp.Auth.Alias.Name != expectedAlias {
+			t.Fatalf("op %s: expected alias %q, got %q", op, expectedAlias, resp.Auth.Alias.Name)
+		}
+		if op == logical.AliasLookaheadOperation {
+			continue
+		}
+
+		expectedMetadata := map[string]string{
+			"role":    roleName,
+			"vmss_id": expectedAlias,
+		}
+		if !reflect.DeepEqual(resp.Auth.Alias.Metadata, expectedMetadata) {
+			t.Fatalf("expected alias metadata %v, got %v", expectedMetadata, resp.Auth.Alias.Metadata)
+		}
+	}
+
+	// the alias must be derivable from the login, so a VM login fails
+	testLoginFailure(t, b, s, map[string]interface{}{
+		"role":                roleName,
+		"subscription_id":     subscriptionID,
+		"resource_group_name": "rg",
+		"vm_name":             "goodvm",
+	}, claims, roleData)
+}
+
+func TestLogin_AliasFromResource(t *testing.T) {
+	principalID := "123e4567-e89b-12d3-a456-426655440000"
+	otherPrincipalID := "223e4567-e89b-12d3-a456-426655440000"
+	subscriptionID := "1234abcd-1234-abcd-1234-abcd1234ef90"
+
+	c := func(vmName string) (armcompute.VirtualMachinesClientGetResponse, error) {
+		id := principalID
+		if vmName == "victim" {
+			id = otherPrincipalID
+		}
+		return armcompute.VirtualMachinesClientGetResponse{VirtualMachine: armcompute.VirtualMachine{
+			Identity: &armcompute.VirtualMachineIdentity{PrincipalID: &id},
+		}}, nil
+	}
+	_, v, m := getTestBackendFunctions(false)
+
+	// The role has no resource bindings, so the resource is only verified
+	// because the alias is built from it
+	roleData := map[string]interface{}{
+		"name":                        "testrole",
+		"policies":                    []string{"dev"},
+		"bound_service_principal_ids": []string{principalID},
+	}
+	claims := map[string]interface{}{
+		"exp":   time.Now().Add(60 * time.Second).Unix(),
+		"nbf":   time.Now().Add(-60 * time.Second).Unix(),
+		"oid":   principalID,
+		"appid": "app",
+	}
+
+	for name, configData := range map[string]map[string]interface{}{
+		"resource_id": {"alias_type": aliasTypeResourceID},
+		"template":    {"alias_type": aliasTypeTemplate, "alias_template": "{{ .VMName }}"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, s := getTestBackendWithComputeClient(t, c, v, m, nil, nil)
+			configData["tenant_id"] = "tid"
+			configData["resource"] = "resource"
+			if _, err := testConfigCreate(t, b, s, configData); err != nil {
+				t.Fatal(err)
+			}
+			// writing the config resets the provider
+			b.provider = newMockProvider(c, v, m, nil, nil)
+			testRoleCreate(t, b, s, roleData)
+
+			loginData := map[string]interface{}{
+				"role":                "testrole",
+				"subscription_id":     subscriptionID,
+				"resource_group_name": "rg",
+				"vm_name":             "mine",
+			}
+			testLoginSuccess(t, b, s, loginData, claims, roleData)
+
+			// A VM with another identity can't be claimed for its alias
+			loginData["vm_name"] = "victim"
+			testLoginFailure(t, b, s, loginData, claims, roleData)
+		})
+	}
+
+	// Aliases that are not built from the resource don't require it
+	b, s := getTestBackendWithComputeClient(t, c, v, m, nil, nil)
+	testRoleCreate(t, b, s, roleData)
+	testLoginSuccess(t, b, s, map[string]interface{}{
+		"role":                "testrole",
+		"subscription_id":     subscriptionID,
+		"resource_group_name": "rg",
+		"vm_name":             "victim",
+	}, claims, roleData)
+}
+
+func TestLogin_MaxTokenAge(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	roleName := "testrole"
+	roleData := map[string]interface{}{
+		"name":                        roleName,
+		"policies":                    []string{"dev", "prod"},
+		"bound_service_principal_ids": []string{"*"},
+		"max_token_age":               "5m",
+	}
+	testRoleCreate(t, b, s, roleData)
+
+	claims := map[string]interface{}{
+		"exp": time.Now().Add(60 * time.Second).Unix(),
+		"nbf": time.Now().Add(-60 * time.Second).Unix(),
+	}
+	loginData := map[string]interface{}{
+		"role": roleName,
+	}
+
+	// iat is required when max_token_age is set
+	testLoginFailure(t, b, s, loginData, claims, roleData)
+
+	claims["iat"] = time.Now().Add(-1 * time.Minute).Unix()
+	testLoginSuccess(t, b, s, loginData, claims, roleData)
+
+	claims["iat"] = time.Now().Add(-10 * time.Minute).Unix()
+	testLoginFailure(t, b, s, loginData, claims, roleData)
+}
+
+func TestLogin_ReplayProtection(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	roleName := "testrole"
+	roleData := map[string]interface{}{
+		"name":                        roleName,
+		"policies":                    []string{"dev", "prod"},
+		"bound_service_principal_ids": []string{"*"},
+		"enable_replay_protection":    true,
+	}
+	testRoleCreate(t, b, s, roleData)
+
+	claims := map[string]interface{}{
+		"exp": time.Now().Add(60 * time.Second).Unix(),
+		"nbf": time.Now().Add(-60 * time.Second).Unix(),
+	}
+	loginData := map[string]interface{}{
+		"role": roleName,
+	}
+
+	// a token identifier is required when replay protection is enabled
+	testLoginFailure(t, b, s, loginData, claims, roleData)
+
+	claims["uti"] = "first-token"
+	jwt := testJWT(t, claims)
+
+	// alias lookahead must not consume the token
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.AliasLookaheadOperation,
+		Path:      "login",
+		Data: map[string]interface{}{
+			"role": roleName,
+			"jwt":  jwt,
+		},
+		Storage: s,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	if err := testLoginWithJWT(t, b, s, jwt, loginData, roleData); err != nil {
+		t.Fatal(err)
+	}
+	if err := testLoginWithJWT(t, b, s, jwt, loginData, roleData); err == nil {
+		t.Fatal("expected replayed token to be rejected")
+	}
+
+	delete(claims, "uti")
+	claims["jti"] = "second-token"
+	testLoginSuccess(t, b, s, loginData, claims, roleData)
+	testLoginFailure(t, b, s, loginData, claims, roleData)
+}
+
+func TestLoginRenew_RevalidateResource(t *testing.T) {
+	principalID := "123e4567-e89b-12d3-a456-426655440000"
+	location := "loc"
+	var lookups int
+	c := func(_ string) (armcompute.VirtualMachinesClientGetResponse, error) {
+		lookups++
+		return armcompute.VirtualMachinesClientGetResponse{VirtualMachine: armcompute.VirtualMachine{
+			Identity: &armcompute.VirtualMachineIdentity{
+				PrincipalID: &principalID,
+			},
+			Location: &location,
+		}}, nil
+	}
+	b, s := getTestBackendWithComputeClient(t, c, nil, nil, nil, getTestMSGraphClient())
+
+	roleName := "testrole"
+	testRoleCreate(t, b, s, map[string]interface{}{
+		"name":            roleName,
+		"policies":        []string{"dev"},
+		"bound_locations": []string{"loc"},
+	})
+
+	claims := map[string]interface{}{
+		"exp": time.Now().Add(60 * time.Second).Unix(),
+		"nbf": time.Now().Add(-60 * time.Second).Unix(),
+		"oid": principalID,
+	}
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Data: map[string]interface{}{
+			"role":                roleName,
+			"jwt":                 testJWT(t, claims),
+			"subscription_id":     "1234abcd-1234-abcd-1234-abcd1234ef90",
+			"resource_group_name": "rg",
+			"vm_name":             "vm",
+		},
+		Storage: s,
+		Connection: &logical.Connection{
+			RemoteAddr: "127.0.0.1",
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	auth := resp.Auth
+
+	renew := func() error {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RenewOperation,
+			Path:      "login",
+			Auth:      auth,
+			Storage:   s,
+		})
+		if err == nil && resp.IsError() {
+			err = resp.Error()
+		}
+		return err
+	}
+
+	lookups = 0
+	if err := renew(); err != nil {
+		t.Fatal(err)
+	}
+	if lookups != 1 {
+		t.Fatalf("expected the virtual machine to be looked up on renewal, got %d lookups", lookups)
+	}
+
+	// the resource no longer satisfies the role
+	location = "elsewhere"
+	if err := renew(); err == nil {
+		t.Fatal("expected renewal to fail")
+	}
+
+	// within the revalidation interval the resource is not looked up
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/" + roleName,
+		Data: map[string]interface{}{
+			"revalidation_interval": "1h",
+		},
+		Storage: s,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	auth.InternalData["last_validated"] = time.Now().UTC().Format(time.RFC3339)
+	lookups = 0
+	if err := renew(); err != nil {
+		t.Fatal(err)
+	}
+	if lookups != 0 {
+		t.Fatalf("expected no lookups within the revalidation interval, got %d", lookups)
+	}
+
+	auth.InternalData["last_validated"] = time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	if err := renew(); err == nil {
+		t.Fatal("expected renewal to fail once the revalidation interval has passed")
+	}
+}
+
 func TestLogin_AppID(t *testing.T) {
 	appID := "123e4567-e89b-12d3-a456-426655440000"
 	badID := "aeoifkj"
@@ -899,10 +1234,10 @@ func TestVerifyClaims(t *testing.T) {
 			bgIds:  []string{"test-group-1"},
 			bspIds: []string{"*"},
 			claims: additionalClaims{
-				claims.NotBefore,
-				claims.ObjectID,
-				claims.AppID,
-				[]string{"test-group-2"},
+				NotBefore: claims.NotBefore,
+				ObjectID:  claims.ObjectID,
+				AppID:     claims.AppID,
+				GroupIDs:  []string{"test-group-2"},
 			},
 			error: "groups not authorized",
 		},
@@ -910,10 +1245,10 @@ func TestVerifyClaims(t *testing.T) {
 			bgIds:  []string{"test-group-1", "test-group2"},
 			bspIds: []string{"*"},
 			claims: additionalClaims{
-				claims.NotBefore,
-				claims.ObjectID,
-				claims.AppID,
-				[]string{"test-group-2"},
+				NotBefore: claims.NotBefore,
+				ObjectID:  claims.ObjectID,
+				AppID:     claims.AppID,
+				GroupIDs:  []string{"test-group-2"},
 			},
 			error: "",
 		},
@@ -921,10 +1256,10 @@ func TestVerifyClaims(t *testing.T) {
 			bgIds:  []string{"*"},
 			bspIds: []string{"spId1"},
 			claims: additionalClaims{
-				claims.NotBefore,
-				"test-oid",
-				claims.AppID,
-				claims.GroupIDs,
+				NotBefore: claims.NotBefore,
+				ObjectID:  "test-oid",
+				AppID:     claims.AppID,
+				GroupIDs:  claims.GroupIDs,
 			},
 			error: "service principal not authorized",
 		},
@@ -932,10 +1267,10 @@ func TestVerifyClaims(t *testing.T) {
 			bgIds:  []string{"*"},
 			bspIds: []string{"spId1", "test-oid"},
 			claims: additionalClaims{
-				claims.NotBefore,
-				"test-oid",
-				claims.AppID,
-				claims.GroupIDs,
+				NotBefore: claims.NotBefore,
+				ObjectID:  "test-oid",
+				AppID:     claims.AppID,
+				GroupIDs:  claims.GroupIDs,
 			},
 			error: "",
 		},
@@ -960,8 +1295,22 @@ func TestGetAPIVersionForResource(t *testing.T) {
 	subscriptionID := "eb936495-7356-4a35-af3e-ea68af201f0c"
 	resourceID := "/subscriptions/eb936495-7356-4a35-af3e-ea68af201f0c/resourceGroups/azure-func-rg/providers/Microsoft.Web/sites/my-azure-func"
 
+	var calls int
 	providersRespFunc := getProvidersResponse(t, resourceID)
-	b, _ := getTestBackendWithResourceClient(t, nil, providersRespFunc)
+	b, _ := getTestBackendWithResourceClient(t, nil, func(s string) (armresources.ProvidersClientGetResponse, error) {
+		calls++
+		if calls > 1 {
+			return armresources.ProvidersClientGetResponse{}, errors.New("providers lookup was not cached")
+		}
+		return providersRespFunc(s)
+	})
+	// The API version is cached even when the provider lookup cache is
+	// disabled
+	b.provider = &cachingMockProvider{
+		mockProvider: b.provider.(*mockProvider),
+		cache:        newLookupCache(0, 0),
+	}
+
 	apiVersion, err := b.getAPIVersionForResource(context.Background(), subscriptionID, resourceID)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
@@ -971,9 +1320,8 @@ func TestGetAPIVersionForResource(t *testing.T) {
 		t.Fatalf("unexpected apiVersion returned, got %s, want %s", apiVersion, expectedVer)
 	}
 
-	// reset the provider and call getAPIVersionForResource again to ensure
-	// we can get the API version from the cache
-	b.provider = &mockProvider{}
+	// call getAPIVersionForResource again to ensure we can get the API
+	// version from the cache
 	apiVersion, err = b.getAPIVersionForResource(context.Background(), subscriptionID, resourceID)
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
@@ -981,6 +1329,9 @@ func TestGetAPIVersionForResource(t *testing.T) {
 	if apiVersion != expectedVer {
 		t.Fatalf("unexpected apiVersion returned, got %s, want %s", apiVersion, expectedVer)
 	}
+	if calls != 1 {
+		t.Fatalf("expected a single providers lookup, got %d", calls)
+	}
 }
 
 // getResourceByIDResponses is a test helper to get the functions that return
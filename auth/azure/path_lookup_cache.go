@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func pathLookupCacheStats(b *azureAuthBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "lookup-cache/stats$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAzure,
+			OperationSuffix: "lookup-cache-stats",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathLookupCacheStatsRead,
+			},
+		},
+
+		HelpSynopsis:    pathLookupCacheStatsSyn,
+		HelpDescription: pathLookupCacheStatsDesc,
+	}
+}
+
+func (b *azureAuthBackend) pathLookupCacheStatsRead(_ context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	b.l.RLock()
+	provider := b.provider
+	b.l.RUnlock()
+
+	// The cache is created with the provider on the first login after the
+	// backend is configured
+	var stats lookupCacheStats
+	if provider != nil {
+		stats = provider.LookupCacheStats()
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"entries":     stats.Entries,
+			"max_entries": stats.MaxEntries,
+			"ttl":         int64(stats.TTL.Seconds()),
+			"hits":        stats.Hits,
+			"misses":      stats.Misses,
+			"evictions":   stats.Evictions,
+		},
+	}, nil
+}
+
+const pathLookupCacheStatsSyn = `
+Returns statistics of the cache of Azure Resource Manager lookups.
+`
+
+const pathLookupCacheStatsDesc = `
+The virtual machines, scale sets, identities and resource providers looked up
+on login are cached for lookup_cache_ttl to reduce the number of Azure Resource
+Manager requests. This endpoint returns the number of cached entries and the
+hits, misses and evictions since the backend was last configured.
+`
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/helper/tokenutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
@@ -93,6 +94,35 @@ func pathsRole(b *azureAuthBackend) []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: `Comma-separated list of scale sets that login is restricted to.`,
 				},
+				"login_type": {
+					Type:        framework.TypeString,
+					Default:     loginTypeManagedIdentity,
+					Description: `The type of token accepted on login. One of "managed_identity" or "aks_workload_identity".`,
+				},
+				"bound_aks_cluster_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: `Comma-separated list of AKS cluster resource IDs that login is restricted to. Only used by "aks_workload_identity" roles.`,
+				},
+				"bound_service_account_namespaces": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: `Comma-separated list of Kubernetes namespaces that login is restricted to, or "*". Only used by "aks_workload_identity" roles.`,
+				},
+				"bound_service_account_names": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: `Comma-separated list of Kubernetes service account names that login is restricted to, or "*". Only used by "aks_workload_identity" roles.`,
+				},
+				"max_token_age": {
+					Type:        framework.TypeDurationSecond,
+					Description: `If set, logins are rejected when the token was issued ('iat' claim) longer ago than this duration.`,
+				},
+				"enable_replay_protection": {
+					Type:        framework.TypeBool,
+					Description: `If set, each token ('uti' or 'jti' claim) may only be used to log in once.`,
+				},
+				"revalidation_interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: `The minimum time between checks that the resource of a managed identity still satisfies the role when a token is renewed. If not set, the resource is checked on every renewal.`,
+				},
 			},
 			ExistenceCheck: b.pathRoleExistenceCheck,
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -122,6 +152,9 @@ func pathsRole(b *azureAuthBackend) []*framework.Path {
 type azureRole struct {
 	tokenutil.TokenParams
 
+	// RoleID is a unique identifier for this role.
+	RoleID string `json:"role_id"`
+
 	// Policies that are to be required by the token to access this role
 	Policies []string `json:"policies"`
 
@@ -148,6 +181,39 @@ type azureRole struct {
 	BoundSubscriptionsIDs    []string `json:"bound_subscription_ids"`
 	BoundLocations           []string `json:"bound_locations"`
 	BoundScaleSets           []string `json:"bound_scale_sets"`
+
+	// LoginType is the type of token accepted on login
+	LoginType string `json:"login_type"`
+
+	// AKS workload identity binding properties
+	BoundAKSClusterIDs            []string `json:"bound_aks_cluster_ids"`
+	BoundServiceAccountNamespaces []string `json:"bound_service_account_namespaces"`
+	BoundServiceAccountNames      []string `json:"bound_service_account_names"`
+
+	// MaxTokenAge is the maximum time since the token was issued
+	MaxTokenAge time.Duration `json:"max_token_age"`
+
+	// EnableReplayProtection restricts each token to a single login
+	EnableReplayProtection bool `json:"enable_replay_protection"`
+
+	// RevalidationInterval is the minimum time between resource checks on
+	// renewal
+	RevalidationInterval time.Duration `json:"revalidation_interval"`
+}
+
+// boundToResource returns true if the role has constraints that require the
+// resource of a managed identity to be looked up.
+func (r *azureRole) boundToResource() bool {
+	return len(r.BoundResourceGroups) > 0 || len(r.BoundSubscriptionsIDs) > 0 || len(r.BoundLocations) > 0 || len(r.BoundScaleSets) > 0
+}
+
+// loginType returns the login type of the role, defaulting to managed
+// identity logins for roles created before login types were introduced.
+func (r *azureRole) loginType() string {
+	if r.LoginType == "" {
+		return loginTypeManagedIdentity
+	}
+	return r.LoginType
 }
 
 // role takes a storage backend and the name and returns the role's storage
@@ -219,12 +285,20 @@ func (b *azureAuthBackend) pathRoleRead(ctx context.Context, req *logical.Reques
 	}
 
 	d := map[string]interface{}{
-		"bound_service_principal_ids": role.BoundServicePrincipalIDs,
-		"bound_group_ids":             role.BoundGroupIDs,
-		"bound_subscription_ids":      role.BoundSubscriptionsIDs,
-		"bound_resource_groups":       role.BoundResourceGroups,
-		"bound_locations":             role.BoundLocations,
-		"bound_scale_sets":            role.BoundScaleSets,
+		"role_id":                          role.RoleID,
+		"bound_service_principal_ids":      role.BoundServicePrincipalIDs,
+		"bound_group_ids":                  role.BoundGroupIDs,
+		"bound_subscription_ids":           role.BoundSubscriptionsIDs,
+		"bound_resource_groups":            role.BoundResourceGroups,
+		"bound_locations":                  role.BoundLocations,
+		"bound_scale_sets":                 role.BoundScaleSets,
+		"login_type":                       role.loginType(),
+		"bound_aks_cluster_ids":            role.BoundAKSClusterIDs,
+		"bound_service_account_namespaces": role.BoundServiceAccountNamespaces,
+		"bound_service_account_names":      role.BoundServiceAccountNames,
+		"max_token_age":                    int64(role.MaxTokenAge.Seconds()),
+		"enable_replay_protection":         role.EnableReplayProtection,
+		"revalidation_interval":            int64(role.RevalidationInterval.Seconds()),
 	}
 
 	role.PopulateTokenData(d)
@@ -287,6 +361,14 @@ func (b *azureAuthBackend) pathRoleCreateUpdate(ctx context.Context, req *logica
 		role = new(azureRole)
 	}
 
+	if role.RoleID == "" {
+		roleID, err := uuid.NewRandom()
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate role_id: %w", err)
+		}
+		role.RoleID = roleID.String()
+	}
+
 	if err := role.ParseTokenFields(req, data); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
@@ -346,7 +428,65 @@ func (b *azureAuthBackend) pathRoleCreateUpdate(ctx context.Context, req *logica
 		role.BoundScaleSets = boundScaleSets.([]string)
 	}
 
-	if len(role.BoundServicePrincipalIDs) == 0 &&
+	if maxTokenAge, ok := data.GetOk("max_token_age"); ok {
+		role.MaxTokenAge = time.Duration(maxTokenAge.(int)) * time.Second
+	}
+	if role.MaxTokenAge < 0 {
+		return logical.ErrorResponse("max_token_age cannot be negative"), nil
+	}
+
+	if enableReplayProtection, ok := data.GetOk("enable_replay_protection"); ok {
+		role.EnableReplayProtection = enableReplayProtection.(bool)
+	}
+
+	if revalidationInterval, ok := data.GetOk("revalidation_interval"); ok {
+		role.RevalidationInterval = time.Duration(revalidationInterval.(int)) * time.Second
+	}
+	if role.RevalidationInterval < 0 {
+		return logical.ErrorResponse("revalidation_interval cannot be negative"), nil
+	}
+
+	if loginType, ok := data.GetOk("login_type"); ok {
+		role.LoginType = loginType.(string)
+	}
+
+	if boundAKSClusterIDs, ok := data.GetOk("bound_aks_cluster_ids"); ok {
+		role.BoundAKSClusterIDs = boundAKSClusterIDs.([]string)
+	}
+
+	if boundNamespaces, ok := data.GetOk("bound_service_account_namespaces"); ok {
+		role.BoundServiceAccountNamespaces = boundNamespaces.([]string)
+	}
+
+	if boundNames, ok := data.GetOk("bound_service_account_names"); ok {
+		role.BoundServiceAccountNames = boundNames.([]string)
+	}
+
+	switch role.loginType() {
+	case loginTypeManagedIdentity:
+		if len(role.BoundAKSClusterIDs) > 0 ||
+			len(role.BoundServiceAccountNamespaces) > 0 ||
+			len(role.BoundServiceAccountNames) > 0 {
+			return logical.ErrorResponse("AKS bindings are only supported for the %q login type", loginTypeAKSWorkloadIdentity), nil
+		}
+	case loginTypeAKSWorkloadIdentity:
+		if len(role.BoundServicePrincipalIDs) > 0 ||
+			len(role.BoundGroupIDs) > 0 ||
+			len(role.BoundSubscriptionsIDs) > 0 ||
+			len(role.BoundResourceGroups) > 0 ||
+			len(role.BoundLocations) > 0 ||
+			len(role.BoundScaleSets) > 0 {
+			return logical.ErrorResponse("managed identity bindings are not supported for the %q login type", loginTypeAKSWorkloadIdentity), nil
+		}
+		if len(role.BoundServiceAccountNamespaces) == 0 || len(role.BoundServiceAccountNames) == 0 {
+			return logical.ErrorResponse("bound_service_account_namespaces and bound_service_account_names are required for the %q login type", loginTypeAKSWorkloadIdentity), nil
+		}
+	default:
+		return logical.ErrorResponse("invalid login_type %q: must be one of %q or %q", role.LoginType, loginTypeManagedIdentity, loginTypeAKSWorkloadIdentity), nil
+	}
+
+	if role.loginType() == loginTypeManagedIdentity &&
+		len(role.BoundServicePrincipalIDs) == 0 &&
 		len(role.BoundGroupIDs) == 0 &&
 		len(role.BoundSubscriptionsIDs) == 0 &&
 		len(role.BoundResourceGroups) == 0 &&
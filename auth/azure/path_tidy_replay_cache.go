@@ -0,0 +1,101 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// defaultReplayCacheSafetyBuffer is the time an expired replay cache entry is
+// kept before it is removed by tidy. Expired tokens are rejected by the token
+// verifier regardless, so no buffer is needed by default.
+const defaultReplayCacheSafetyBuffer = 0
+
+func pathTidyReplayCache(b *azureAuthBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy/replay-cache$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAzure,
+			OperationSuffix: "replay-cache",
+			OperationVerb:   "tidy",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"safety_buffer": {
+				Type:    framework.TypeDurationSecond,
+				Default: defaultReplayCacheSafetyBuffer,
+				Description: `The amount of extra time that must have passed beyond the token's
+expiration, before its replay cache entry is removed from the backend storage.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathTidyReplayCacheUpdate,
+			},
+		},
+
+		HelpSynopsis:    pathTidyReplayCacheSyn,
+		HelpDescription: pathTidyReplayCacheDesc,
+	}
+}
+
+// tidyReplayCacheAsync starts a tidy of the replay cache in the background.
+func (b *azureAuthBackend) tidyReplayCacheAsync(req *logical.Request, safetyBuffer time.Duration) (*logical.Response, error) {
+	// If we are a performance standby forward the request to the active node
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+		return nil, logical.ErrReadOnly
+	}
+
+	if !atomic.CompareAndSwapUint32(b.tidyReplayCacheCASGuard, 0, 1) {
+		resp := &logical.Response{}
+		resp.AddWarning("Tidy operation already in progress.")
+		return resp, nil
+	}
+
+	s := req.Storage
+
+	go func() {
+		defer atomic.StoreUint32(b.tidyReplayCacheCASGuard, 0)
+
+		// Don't cancel when the original client request goes away
+		ctx := context.Background()
+
+		if err := b.tidyReplayCache(ctx, s, safetyBuffer); err != nil {
+			b.Logger().Named("replaytidy").Error("error running replay cache tidy", "error", err)
+		}
+	}()
+
+	resp := &logical.Response{}
+	resp.AddWarning("Tidy operation successfully started. Any information from the operation will be printed to OpenBao's server logs.")
+	return logical.RespondWithStatusCode(resp, req, http.StatusAccepted)
+}
+
+func (b *azureAuthBackend) pathTidyReplayCacheUpdate(_ context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	safetyBuffer := time.Duration(data.Get("safety_buffer").(int)) * time.Second
+	return b.tidyReplayCacheAsync(req, safetyBuffer)
+}
+
+const pathTidyReplayCacheSyn = `
+Clean-up the replay cache of tokens used to log in.
+`
+
+const pathTidyReplayCacheDesc = `
+When replay protection is enabled on a role, the identifier of every token
+used to log in is recorded until the token expires, so that it cannot be
+used again.
+
+When this endpoint is invoked, all the entries that are expired will be deleted.
+A 'safety_buffer' (duration in seconds) can be provided, to ensure deletion of
+only those entries that are expired before 'safety_buffer' seconds. Expired
+entries are also removed periodically.
+`
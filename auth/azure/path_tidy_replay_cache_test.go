@@ -0,0 +1,84 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestTidyReplayCache(t *testing.T) {
+	b, s := getTestBackend(t)
+	ctx := context.Background()
+
+	expired := &additionalClaims{UTI: "expired"}
+	live := &additionalClaims{JTI: "live"}
+	if err := b.checkAndRecordToken(ctx, s, expired, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.checkAndRecordToken(ctx, s, live, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// an expired entry doesn't prevent reuse, since the token itself is rejected
+	if err := b.checkAndRecordToken(ctx, s, expired, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.checkAndRecordToken(ctx, s, live, time.Now().Add(time.Hour)); err == nil {
+		t.Fatal("expected error for reused token")
+	}
+
+	// a safety buffer keeps recently expired entries
+	if err := b.tidyReplayCache(ctx, s, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	assertReplayCacheKeys(t, s, 2)
+
+	if err := b.tidyReplayCache(ctx, s, 0); err != nil {
+		t.Fatal(err)
+	}
+	assertReplayCacheKeys(t, s, 1)
+
+	entry, err := s.Get(ctx, replayCacheKey("live"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		t.Fatal("expected live replay cache entry to remain")
+	}
+}
+
+func TestTidyReplayCache_Endpoint(t *testing.T) {
+	b, s := getTestBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy/replay-cache",
+		Data: map[string]interface{}{
+			"safety_buffer": "1h",
+		},
+		Storage: s,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data[logical.HTTPStatusCode] != http.StatusAccepted {
+		t.Fatalf("expected status code %d, got %v", http.StatusAccepted, resp.Data[logical.HTTPStatusCode])
+	}
+}
+
+func assertReplayCacheKeys(t *testing.T, s logical.Storage, expected int) {
+	t.Helper()
+	keys, err := s.List(context.Background(), replayCacheStoragePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != expected {
+		t.Fatalf("expected %d replay cache entries, got %d", expected, len(keys))
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// replayCacheStoragePrefix is the local storage prefix for the identifiers of
+// tokens which have already been used to log in.
+const replayCacheStoragePrefix = "replay/"
+
+// replayCacheEntry records a used token until it expires.
+type replayCacheEntry struct {
+	ExpirationTime time.Time `json:"expiration_time"`
+}
+
+// tokenIdentifier returns the unique identifier of the token, preferring the
+// Entra 'uti' claim over the standard 'jti' claim.
+func tokenIdentifier(claims *additionalClaims) string {
+	if claims.UTI != "" {
+		return claims.UTI
+	}
+	return claims.JTI
+}
+
+// replayCacheKey returns the storage key for a token identifier. The
+// identifier is hashed so that the raw claim is not persisted.
+func replayCacheKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return replayCacheStoragePrefix + hex.EncodeToString(sum[:])
+}
+
+// checkAndRecordToken returns an error if the token was previously used to
+// log in. Otherwise it records the token until its expiration time.
+func (b *azureAuthBackend) checkAndRecordToken(ctx context.Context, s logical.Storage, claims *additionalClaims, expiry time.Time) error {
+	id := tokenIdentifier(claims)
+	if id == "" {
+		return errors.New("token does not contain a uti or jti claim required for replay protection")
+	}
+	key := replayCacheKey(id)
+
+	b.replayLock.Lock()
+	defer b.replayLock.Unlock()
+
+	raw, err := s.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("unable to read replay cache: %w", err)
+	}
+	if raw != nil {
+		var entry replayCacheEntry
+		if err := raw.DecodeJSON(&entry); err != nil {
+			return fmt.Errorf("unable to decode replay cache entry: %w", err)
+		}
+		if time.Now().Before(entry.ExpirationTime) {
+			return errors.New("token has already been used to log in")
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(key, &replayCacheEntry{
+		ExpirationTime: expiry,
+	})
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// tidyReplayCache deletes replay cache entries which expired more than
+// safetyBuffer ago.
+func (b *azureAuthBackend) tidyReplayCache(ctx context.Context, s logical.Storage, safetyBuffer time.Duration) error {
+	keys, err := s.List(ctx, replayCacheStoragePrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		raw, err := s.Get(ctx, replayCacheStoragePrefix+key)
+		if err != nil {
+			return fmt.Errorf("error fetching replay cache entry %q: %w", key, err)
+		}
+		if raw == nil {
+			continue
+		}
+
+		var entry replayCacheEntry
+		if err := raw.DecodeJSON(&entry); err != nil {
+			return err
+		}
+
+		if time.Now().After(entry.ExpirationTime.Add(safetyBuffer)) {
+			if err := s.Delete(ctx, replayCacheStoragePrefix+key); err != nil {
+				return fmt.Errorf("error deleting replay cache entry %q: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -6,15 +6,26 @@ package gcp
 import (
 	"context"
 	"fmt"
+	"regexp"
 
 	log "github.com/hashicorp/go-hclog"
-	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/iam/v1"
 )
 
 var _ client = (*gcpClient)(nil)
 
+var (
+	// diskSelfLinkRegex matches the self-links of zonal and regional disks.
+	diskSelfLinkRegex = regexp.MustCompile(`projects/([^/]+)/(zones|regions)/([^/]+)/disks/([^/]+)$`)
+
+	// imageSelfLinkRegex matches the self-links of images.
+	imageSelfLinkRegex = regexp.MustCompile(`projects/([^/]+)/global/images/([^/]+)$`)
+
+	// imageFamilyRegex matches the image families returned by ImageFamily.
+	imageFamilyRegex = regexp.MustCompile(`^projects/[a-z0-9][a-z0-9.:-]*/global/images/family/[a-z]([a-z0-9-]*[a-z0-9])?$`)
+)
+
 // gcpClient implements client and communicates with the GCP API. It is
 // abstracted as an interface for stubbing during testing. See stubbedClient for
 // more details.
@@ -24,7 +35,7 @@ type gcpClient struct {
 	iamSvc     *iam.Service
 }
 
-func (c *gcpClient) InstanceGroups(ctx context.Context, project string, boundInstanceGroups []string) (map[string][]string, map[string][]string, error) {
+func (c *gcpClient) InstanceGroups(ctx context.Context, project string, boundInstanceGroups []string, globs bool) (map[string][]string, map[string][]string, error) {
 	// maps of zone/region names to a slice of instance group names in that
 	// location.
 	igz := make(map[string][]string)
@@ -43,7 +54,7 @@ func (c *gcpClient) InstanceGroups(ctx context.Context, project string, boundIns
 				}
 
 				for _, g := range v.InstanceGroups {
-					if strutil.StrListContains(boundInstanceGroups, g.Name) {
+					if boundListContains(boundInstanceGroups, g.Name, globs) {
 						if zone != "" {
 							igz[zone] = append(igz[zone], g.Name)
 						}
@@ -115,3 +126,58 @@ func (c *gcpClient) ServiceAccount(ctx context.Context, name string) (string, st
 
 	return account.UniqueId, account.Email, nil
 }
+
+// ImageFamily returns the image family, formatted as
+// "projects/$PROJECT/global/images/family/$FAMILY", of the image the given disk
+// was created from. It returns an empty string if the disk was not created
+// from an image, or the image has no family.
+func (c *gcpClient) ImageFamily(ctx context.Context, disk string) (string, error) {
+	m := diskSelfLinkRegex.FindStringSubmatch(disk)
+	if m == nil {
+		return "", fmt.Errorf("failed to parse disk %q", disk)
+	}
+	project, location, name := m[1], m[3], m[4]
+
+	var sourceImage string
+	if m[2] == "zones" {
+		d, err := c.computeSvc.Disks.Get(project, location, name).
+			Fields("sourceImage").
+			Context(ctx).
+			Do()
+		if err != nil {
+			return "", err
+		}
+		sourceImage = d.SourceImage
+	} else {
+		d, err := c.computeSvc.RegionDisks.Get(project, location, name).
+			Fields("sourceImage").
+			Context(ctx).
+			Do()
+		if err != nil {
+			return "", err
+		}
+		sourceImage = d.SourceImage
+	}
+	if sourceImage == "" {
+		return "", nil
+	}
+
+	m = imageSelfLinkRegex.FindStringSubmatch(sourceImage)
+	if m == nil {
+		return "", fmt.Errorf("failed to parse image %q", sourceImage)
+	}
+	imageProject, imageName := m[1], m[2]
+
+	image, err := c.computeSvc.Images.Get(imageProject, imageName).
+		Fields("family").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", err
+	}
+	if image.Family == "" {
+		return "", nil
+	}
+
+	return fmt.Sprintf("projects/%s/global/images/family/%s", imageProject, image.Family), nil
+}
@@ -14,9 +14,10 @@ type stubbedClient struct {
 	instanceGroupsByRegion        map[string][]string
 	instanceGroupContainsInstance bool
 	saId, saEmail                 string
+	imageFamily                   string
 }
 
-func (c *stubbedClient) InstanceGroups(_ context.Context, _ string, _ []string) (map[string][]string, map[string][]string, error) {
+func (c *stubbedClient) InstanceGroups(_ context.Context, _ string, _ []string, _ bool) (map[string][]string, map[string][]string, error) {
 	return c.instanceGroupsByZone, c.instanceGroupsByRegion, nil
 }
 
@@ -27,3 +28,7 @@ func (c *stubbedClient) InstanceGroupContainsInstance(_ context.Context, _, _, _
 func (c *stubbedClient) ServiceAccount(_ context.Context, _ string) (string, string, error) {
 	return c.saId, c.saEmail, nil
 }
+
+func (c *stubbedClient) ImageFamily(_ context.Context, _ string) (string, error) {
+	return c.imageFamily, nil
+}
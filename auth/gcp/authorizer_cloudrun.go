@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+)
+
+type AuthorizeCloudRunInput struct {
+	// kind is either "service" or "function".
+	kind   string
+	name   string
+	region string
+
+	// workloadServiceAccount is the service account the service or function
+	// runs as, according to the admin API.
+	workloadServiceAccount string
+	workloadLabels         map[string]string
+
+	// serviceAccountId and serviceAccountEmail identify the service account
+	// of the ID token.
+	serviceAccountId    string
+	serviceAccountEmail string
+
+	boundServices        []string
+	boundRegions         []string
+	boundLabels          map[string]string
+	boundServiceAccounts []string
+
+	// globs is whether bound label values and service accounts are glob
+	// patterns rather than exact values.
+	globs bool
+}
+
+func AuthorizeCloudRun(i *AuthorizeCloudRunInput) error {
+	// The ID token only proves the identity of the service account, so the
+	// service or function must run as that service account.
+	if !strings.EqualFold(i.workloadServiceAccount, i.serviceAccountEmail) {
+		return fmt.Errorf("%s %q does not run as service account %q", i.kind, i.name, i.serviceAccountEmail)
+	}
+
+	if len(i.boundServices) > 0 && !strutil.StrListContains(i.boundServices, i.name) {
+		return fmt.Errorf("%s %q not in bound services %q", i.kind, i.name, i.boundServices)
+	}
+
+	if len(i.boundRegions) > 0 && !strutil.StrListContains(i.boundRegions, i.region) {
+		return fmt.Errorf("%s %q not in bound regions %q", i.kind, i.name, i.boundRegions)
+	}
+
+	for k, v := range i.boundLabels {
+		if act, ok := i.workloadLabels[k]; !ok || !boundValueMatches(v, act, i.globs) {
+			return fmt.Errorf("%s missing bound label \"%s:%s\"", i.kind, k, v)
+		}
+	}
+
+	// Check if role has the wildcard as the only service account.
+	if len(i.boundServiceAccounts) == 0 ||
+		(len(i.boundServiceAccounts) == 1 && i.boundServiceAccounts[0] == serviceAccountsWildcard) {
+		return nil
+	}
+
+	if boundListContains(i.boundServiceAccounts, i.serviceAccountEmail, i.globs) ||
+		boundListContains(i.boundServiceAccounts, i.serviceAccountId, i.globs) {
+		return nil
+	}
+
+	return fmt.Errorf("service account %q (%q) is not in bound service accounts %q",
+		i.serviceAccountId, i.serviceAccountEmail, i.boundServiceAccounts)
+}
@@ -0,0 +1,177 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuthorizeCloudRun(t *testing.T) {
+	t.Parallel()
+
+	workload := func(i *AuthorizeCloudRunInput) *AuthorizeCloudRunInput {
+		i.kind = "service"
+		i.name = "api"
+		i.region = "us-central1"
+		i.workloadServiceAccount = "api@my-project.iam.gserviceaccount.com"
+		i.workloadLabels = map[string]string{"env": "prod"}
+		i.serviceAccountId = "1234"
+		i.serviceAccountEmail = "api@my-project.iam.gserviceaccount.com"
+		return i
+	}
+
+	cases := []struct {
+		name    string
+		i       *AuthorizeCloudRunInput
+		err     bool
+		errText string
+	}{
+		{
+			"unbound",
+			workload(&AuthorizeCloudRunInput{}),
+			false,
+			"",
+		},
+		{
+			"other_service_account",
+			func() *AuthorizeCloudRunInput {
+				i := workload(&AuthorizeCloudRunInput{})
+				i.workloadServiceAccount = "other@my-project.iam.gserviceaccount.com"
+				return i
+			}(),
+			true,
+			`service "api" does not run as service account "api@my-project.iam.gserviceaccount.com"`,
+		},
+
+		// services
+		{
+			"services_match",
+			workload(&AuthorizeCloudRunInput{
+				boundServices: []string{"worker", "api"},
+			}),
+			false,
+			"",
+		},
+		{
+			"services_no_match",
+			workload(&AuthorizeCloudRunInput{
+				boundServices: []string{"worker"},
+			}),
+			true,
+			`service "api" not in bound services`,
+		},
+
+		// regions
+		{
+			"regions_match",
+			workload(&AuthorizeCloudRunInput{
+				boundRegions: []string{"us-central1"},
+			}),
+			false,
+			"",
+		},
+		{
+			"regions_no_match",
+			workload(&AuthorizeCloudRunInput{
+				boundRegions: []string{"europe-west1"},
+			}),
+			true,
+			`service "api" not in bound regions`,
+		},
+
+		// labels
+		{
+			"labels_match",
+			workload(&AuthorizeCloudRunInput{
+				boundLabels: map[string]string{"env": "prod"},
+			}),
+			false,
+			"",
+		},
+		{
+			"labels_no_match_value",
+			workload(&AuthorizeCloudRunInput{
+				boundLabels: map[string]string{"env": "dev"},
+			}),
+			true,
+			`service missing bound label "env:dev"`,
+		},
+		{
+			"labels_glob_match",
+			workload(&AuthorizeCloudRunInput{
+				boundLabels: map[string]string{"env": "pro*"},
+				globs:       true,
+			}),
+			false,
+			"",
+		},
+
+		// service accounts
+		{
+			"service_accounts_wildcard",
+			workload(&AuthorizeCloudRunInput{
+				boundServiceAccounts: []string{"*"},
+			}),
+			false,
+			"",
+		},
+		{
+			"service_accounts_id",
+			workload(&AuthorizeCloudRunInput{
+				boundServiceAccounts: []string{"1234"},
+			}),
+			false,
+			"",
+		},
+		{
+			"service_accounts_glob_match",
+			workload(&AuthorizeCloudRunInput{
+				boundServiceAccounts: []string{"*@my-project.iam.gserviceaccount.com"},
+				globs:                true,
+			}),
+			false,
+			"",
+		},
+		{
+			"service_accounts_glob_not_allowed",
+			workload(&AuthorizeCloudRunInput{
+				boundServiceAccounts: []string{"*@my-project.iam.gserviceaccount.com"},
+			}),
+			true,
+			"is not in bound service accounts",
+		},
+		{
+			"service_accounts_no_match",
+			workload(&AuthorizeCloudRunInput{
+				boundServiceAccounts: []string{"other@my-project.iam.gserviceaccount.com"},
+			}),
+			true,
+			"is not in bound service accounts",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := AuthorizeCloudRun(tc.i)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+
+				if !strings.Contains(err.Error(), tc.errText) {
+					t.Errorf("expected %q to contain %q", err.Error(), tc.errText)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ package gcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -12,9 +13,10 @@ import (
 )
 
 type client interface {
-	InstanceGroups(context.Context, string, []string) (map[string][]string, map[string][]string, error)
+	InstanceGroups(context.Context, string, []string, bool) (map[string][]string, map[string][]string, error)
 	InstanceGroupContainsInstance(context.Context, string, string, string, string, string) (bool, error)
 	ServiceAccount(context.Context, string) (string, string, error)
+	ImageFamily(context.Context, string) (string, error)
 }
 
 type AuthorizeGCEInput struct {
@@ -27,22 +29,67 @@ type AuthorizeGCEInput struct {
 	instanceSelfLink string
 	instanceZone     string
 
+	// instanceSecureBoot, instanceIntegrityMonitoring and instanceConfidential
+	// are the Shielded VM and Confidential VM features enabled on the instance.
+	instanceSecureBoot          bool
+	instanceIntegrityMonitoring bool
+	instanceConfidential        bool
+	instanceNetworkTags         []string
+
+	// instanceBootDisk is the self-link of the boot disk of the instance.
+	instanceBootDisk string
+
 	boundLabels  map[string]string
 	boundRegions []string
 	boundZones   []string
 
 	boundInstanceGroups  []string
 	boundServiceAccounts []string
+
+	requireSecureBoot          bool
+	requireIntegrityMonitoring bool
+	requireConfidentialVM      bool
+	boundNetworkTags           []string
+	boundImageFamilies         []string
+
+	// globs is whether bound label values, instance groups and service
+	// accounts are glob patterns rather than exact values.
+	globs bool
+}
+
+// instanceGroupLocation is an instance group which matched a bound instance
+// group, along with the zone or region it is in.
+type instanceGroupLocation struct {
+	group, zone, region string
 }
 
 func AuthorizeGCE(ctx context.Context, i *AuthorizeGCEInput) error {
 	// Verify instance has role labels if labels were set on role.
 	for k, v := range i.boundLabels {
-		if act, ok := i.instanceLabels[k]; !ok || act != v {
+		if act, ok := i.instanceLabels[k]; !ok || !boundValueMatches(v, act, i.globs) {
 			return fmt.Errorf("instance missing bound label \"%s:%s\"", k, v)
 		}
 	}
 
+	// Verify the instance has the Shielded VM and Confidential VM features
+	// required by the role.
+	if i.requireSecureBoot && !i.instanceSecureBoot {
+		return errors.New("instance does not have Shielded VM secure boot enabled")
+	}
+	if i.requireIntegrityMonitoring && !i.instanceIntegrityMonitoring {
+		return errors.New("instance does not have Shielded VM integrity monitoring enabled")
+	}
+	if i.requireConfidentialVM && !i.instanceConfidential {
+		return errors.New("instance is not a Confidential VM")
+	}
+
+	// Verify instance has all role network tags if tags were set on role.
+	for _, tag := range i.boundNetworkTags {
+		if !strutil.StrListContains(i.instanceNetworkTags, tag) {
+			return fmt.Errorf("instance missing bound network tag %q", tag)
+		}
+	}
+
 	// Parse the zone name from the self-link URI if given; compute
 	// instances are always zonal.
 	zone, _, err := zoneOrRegionFromSelfLink(i.instanceZone)
@@ -71,7 +118,7 @@ func AuthorizeGCE(ctx context.Context, i *AuthorizeGCEInput) error {
 	// For each bound instance group, verify the group exists and that the
 	// instance is a member of that group.
 	if len(i.boundInstanceGroups) > 0 {
-		igz, igr, err := i.client.InstanceGroups(ctx, i.project, i.boundInstanceGroups)
+		igz, igr, err := i.client.InstanceGroups(ctx, i.project, i.boundInstanceGroups, i.globs)
 		if err != nil {
 			return fmt.Errorf("failed to list instance groups for project %q: %s", i.project, err)
 		}
@@ -85,21 +132,20 @@ func AuthorizeGCE(ctx context.Context, i *AuthorizeGCEInput) error {
 				break
 			}
 
-			var group, zone, region string
+			// A bound instance group may match several instance groups when
+			// it is a glob pattern, so check each of them.
+			var candidates []instanceGroupLocation
 
 			switch {
 			case len(i.boundZones) > 0:
 				for _, z := range i.boundZones {
-					if groups, ok := igz[z]; ok && len(groups) > 0 {
-						for _, grp := range groups {
-							if grp == g {
-								group = g
-								zone = z
-							}
+					for _, grp := range igz[z] {
+						if boundValueMatches(g, grp, i.globs) {
+							candidates = append(candidates, instanceGroupLocation{group: grp, zone: z})
 						}
 					}
 				}
-				if group == "" {
+				if len(candidates) == 0 {
 					return fmt.Errorf("instance group %q does not exist in zones %q for project %q",
 						g, i.boundZones, i.project)
 				}
@@ -108,23 +154,21 @@ func AuthorizeGCE(ctx context.Context, i *AuthorizeGCEInput) error {
 					for z, groups := range igz {
 						if strings.HasPrefix(z, r) { // zone is prefixed with region
 							for _, grp := range groups {
-								if grp == g {
-									group = g
-									zone = z
+								if boundValueMatches(g, grp, i.globs) {
+									candidates = append(candidates, instanceGroupLocation{group: grp, zone: z})
 								}
 							}
 						}
 					}
 					for r, groups := range igr {
 						for _, grp := range groups {
-							if grp == g {
-								group = g
-								region = r
+							if boundValueMatches(g, grp, i.globs) {
+								candidates = append(candidates, instanceGroupLocation{group: grp, region: r})
 							}
 						}
 					}
 				}
-				if group == "" {
+				if len(candidates) == 0 {
 					return fmt.Errorf("instance group %q does not exist in regions %q for project %q",
 						g, i.boundRegions, i.project)
 				}
@@ -132,14 +176,17 @@ func AuthorizeGCE(ctx context.Context, i *AuthorizeGCEInput) error {
 				return fmt.Errorf("instance group %q is not bound to any zones or regions", g)
 			}
 
-			ok, err := i.client.InstanceGroupContainsInstance(ctx, i.project, zone, region, group, i.instanceSelfLink)
-			if err != nil {
-				return fmt.Errorf("failed to list instances in instance group %q for project %q: %s",
-					group, i.project, err)
-			}
+			for _, c := range candidates {
+				ok, err := i.client.InstanceGroupContainsInstance(ctx, i.project, c.zone, c.region, c.group, i.instanceSelfLink)
+				if err != nil {
+					return fmt.Errorf("failed to list instances in instance group %q for project %q: %s",
+						c.group, i.project, err)
+				}
 
-			if ok {
-				found = true
+				if ok {
+					found = true
+					break
+				}
 			}
 		}
 
@@ -149,6 +196,24 @@ func AuthorizeGCE(ctx context.Context, i *AuthorizeGCEInput) error {
 		}
 	}
 
+	// Verify the boot disk of the instance was created from one of the
+	// allowed image families.
+	if len(i.boundImageFamilies) > 0 {
+		if i.instanceBootDisk == "" {
+			return errors.New("instance has no boot disk")
+		}
+
+		family, err := i.client.ImageFamily(ctx, i.instanceBootDisk)
+		if err != nil {
+			return fmt.Errorf("failed to get image family of boot disk %q: %s", i.instanceBootDisk, err)
+		}
+
+		if !strutil.StrListContains(i.boundImageFamilies, family) {
+			return fmt.Errorf("boot disk image family %q not in bound image families %q",
+				family, i.boundImageFamilies)
+		}
+	}
+
 	// Verify instance is running under one of the allowed service accounts.
 	if len(i.boundServiceAccounts) > 0 {
 		// ServiceAccount wraps a call to the GCP IAM API to get a service account.
@@ -159,8 +224,8 @@ func AuthorizeGCE(ctx context.Context, i *AuthorizeGCEInput) error {
 			return fmt.Errorf("could not find service account %q: %w", i.serviceAccount, err)
 		}
 
-		if !(strutil.StrListContains(i.boundServiceAccounts, saEmail) ||
-			strutil.StrListContains(i.boundServiceAccounts, saId)) {
+		if !(boundListContains(i.boundServiceAccounts, saEmail, i.globs) ||
+			boundListContains(i.boundServiceAccounts, saId, i.globs)) {
 			return fmt.Errorf("service account %q (%q) is not in bound service accounts %q",
 				saId, saEmail, i.boundServiceAccounts)
 		}
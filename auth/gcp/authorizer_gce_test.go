@@ -48,6 +48,95 @@ func TestAuthorizeGCE(t *testing.T) {
 			true,
 			`instance missing bound label "foo:zip"`,
 		},
+		{
+			"labels_glob_match",
+			&AuthorizeGCEInput{
+				client: &stubbedClient{},
+				instanceLabels: map[string]string{
+					"env": "prod-eu",
+				},
+				boundLabels: map[string]string{
+					"env": "prod-*",
+				},
+				instanceZone: "us-east1-a",
+				globs:        true,
+			},
+			false,
+			"",
+		},
+		{
+			"labels_glob_not_allowed",
+			&AuthorizeGCEInput{
+				client: &stubbedClient{},
+				instanceLabels: map[string]string{
+					"env": "prod-eu",
+				},
+				boundLabels: map[string]string{
+					"env": "prod-*",
+				},
+			},
+			true,
+			`instance missing bound label "env:prod-*"`,
+		},
+
+		// Shielded VM, Confidential VM and network tags
+		{
+			"secure_boot_not_enabled",
+			&AuthorizeGCEInput{
+				client:                      &stubbedClient{},
+				instanceIntegrityMonitoring: true,
+				requireSecureBoot:           true,
+			},
+			true,
+			"instance does not have Shielded VM secure boot enabled",
+		},
+		{
+			"integrity_monitoring_not_enabled",
+			&AuthorizeGCEInput{
+				client:                     &stubbedClient{},
+				instanceSecureBoot:         true,
+				requireSecureBoot:          true,
+				requireIntegrityMonitoring: true,
+			},
+			true,
+			"instance does not have Shielded VM integrity monitoring enabled",
+		},
+		{
+			"confidential_vm_not_enabled",
+			&AuthorizeGCEInput{
+				client:                &stubbedClient{},
+				requireConfidentialVM: true,
+			},
+			true,
+			"instance is not a Confidential VM",
+		},
+		{
+			"network_tags_no_match",
+			&AuthorizeGCEInput{
+				client:              &stubbedClient{},
+				instanceNetworkTags: []string{"web"},
+				boundNetworkTags:    []string{"web", "prod"},
+			},
+			true,
+			`instance missing bound network tag "prod"`,
+		},
+		{
+			"shielded_confidential_and_network_tags",
+			&AuthorizeGCEInput{
+				client:                      &stubbedClient{},
+				instanceZone:                "us-east1-a",
+				instanceSecureBoot:          true,
+				instanceIntegrityMonitoring: true,
+				instanceConfidential:        true,
+				instanceNetworkTags:         []string{"web", "prod"},
+				requireSecureBoot:           true,
+				requireIntegrityMonitoring:  true,
+				requireConfidentialVM:       true,
+				boundNetworkTags:            []string{"prod"},
+			},
+			false,
+			"",
+		},
 
 		// instance zone
 		{
@@ -275,6 +364,77 @@ func TestAuthorizeGCE(t *testing.T) {
 			true,
 			`instance is not part of instance groups ["my-instance-group"]`,
 		},
+		{
+			"bound_instance_groups_glob",
+			&AuthorizeGCEInput{
+				client: &stubbedClient{
+					instanceGroupsByZone: map[string][]string{
+						"us-east1-a": []string{"web-blue", "web-green"},
+					},
+					instanceGroupContainsInstance: true,
+				},
+				instanceZone:        "us-east1-a",
+				boundInstanceGroups: []string{"web-*"},
+				boundZones:          []string{"us-east1-a"},
+				globs:               true,
+			},
+			false,
+			"",
+		},
+		{
+			"bound_instance_groups_glob_not_allowed",
+			&AuthorizeGCEInput{
+				client: &stubbedClient{
+					instanceGroupsByZone: map[string][]string{
+						"us-east1-a": []string{"web-blue", "web-green"},
+					},
+					instanceGroupContainsInstance: true,
+				},
+				instanceZone:        "us-east1-a",
+				boundInstanceGroups: []string{"web-*"},
+				boundZones:          []string{"us-east1-a"},
+			},
+			true,
+			`instance group "web-*" does not exist in zones ["us-east1-a"]`,
+		},
+
+		// image families
+		{
+			"bound_image_families_no_boot_disk",
+			&AuthorizeGCEInput{
+				client:             &stubbedClient{},
+				instanceZone:       "us-east1-a",
+				boundImageFamilies: []string{"projects/debian-cloud/global/images/family/debian-12"},
+			},
+			true,
+			"instance has no boot disk",
+		},
+		{
+			"bound_image_families_no_match",
+			&AuthorizeGCEInput{
+				client: &stubbedClient{
+					imageFamily: "projects/my-project/global/images/family/debian-12",
+				},
+				instanceZone:       "us-east1-a",
+				instanceBootDisk:   "https://www.googleapis.com/compute/v1/projects/my-project/zones/us-east1-a/disks/my-instance",
+				boundImageFamilies: []string{"projects/debian-cloud/global/images/family/debian-12"},
+			},
+			true,
+			`boot disk image family "projects/my-project/global/images/family/debian-12" not in bound image families`,
+		},
+		{
+			"bound_image_families_match",
+			&AuthorizeGCEInput{
+				client: &stubbedClient{
+					imageFamily: "projects/debian-cloud/global/images/family/debian-12",
+				},
+				instanceZone:       "us-east1-a",
+				instanceBootDisk:   "https://www.googleapis.com/compute/v1/projects/my-project/zones/us-east1-a/disks/my-instance",
+				boundImageFamilies: []string{"projects/debian-cloud/global/images/family/debian-12"},
+			},
+			false,
+			"",
+		},
 
 		// service account
 		{
@@ -317,6 +477,20 @@ func TestAuthorizeGCE(t *testing.T) {
 			false,
 			"",
 		},
+		{
+			"bound_service_account_glob",
+			&AuthorizeGCEInput{
+				client: &stubbedClient{
+					saId:    "foo",
+					saEmail: "foo@bar.iam.gserviceaccount.com",
+				},
+				instanceZone:         "us-east1-a",
+				boundServiceAccounts: []string{"*@bar.iam.gserviceaccount.com"},
+				globs:                true,
+			},
+			false,
+			"",
+		},
 
 		// full success examples
 		{
@@ -0,0 +1,41 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcp
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+)
+
+type AuthorizeGKEInput struct {
+	// cluster is the resource name of the GKE cluster that issued the
+	// Kubernetes service account token.
+	cluster            string
+	namespace          string
+	serviceAccountName string
+
+	boundClusters                  []string
+	boundNamespaces                []string
+	boundKubernetesServiceAccounts []string
+}
+
+func AuthorizeGKE(i *AuthorizeGKEInput) error {
+	if !strutil.StrListContains(i.boundClusters, i.cluster) {
+		return fmt.Errorf("cluster %q not in bound clusters %q", i.cluster, i.boundClusters)
+	}
+
+	if !strutil.StrListContains(i.boundNamespaces, serviceAccountsWildcard) &&
+		!strutil.StrListContains(i.boundNamespaces, i.namespace) {
+		return fmt.Errorf("namespace %q not in bound namespaces %q", i.namespace, i.boundNamespaces)
+	}
+
+	if !strutil.StrListContains(i.boundKubernetesServiceAccounts, serviceAccountsWildcard) &&
+		!strutil.StrListContains(i.boundKubernetesServiceAccounts, i.serviceAccountName) {
+		return fmt.Errorf("Kubernetes service account %q not in bound Kubernetes service accounts %q",
+			i.serviceAccountName, i.boundKubernetesServiceAccounts)
+	}
+
+	return nil
+}
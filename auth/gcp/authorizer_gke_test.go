@@ -0,0 +1,103 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuthorizeGKE(t *testing.T) {
+	t.Parallel()
+
+	const cluster = "projects/my-project/locations/us-central1/clusters/prod"
+
+	workload := func(i *AuthorizeGKEInput) *AuthorizeGKEInput {
+		i.cluster = cluster
+		i.namespace = "payments"
+		i.serviceAccountName = "api"
+		return i
+	}
+
+	cases := []struct {
+		name    string
+		i       *AuthorizeGKEInput
+		err     bool
+		errText string
+	}{
+		{
+			"match",
+			workload(&AuthorizeGKEInput{
+				boundClusters:                  []string{cluster},
+				boundNamespaces:                []string{"default", "payments"},
+				boundKubernetesServiceAccounts: []string{"api"},
+			}),
+			false,
+			"",
+		},
+		{
+			"wildcards",
+			workload(&AuthorizeGKEInput{
+				boundClusters:                  []string{cluster},
+				boundNamespaces:                []string{"*"},
+				boundKubernetesServiceAccounts: []string{"*"},
+			}),
+			false,
+			"",
+		},
+		{
+			"cluster_no_match",
+			workload(&AuthorizeGKEInput{
+				boundClusters:                  []string{"projects/my-project/locations/us-central1/clusters/dev"},
+				boundNamespaces:                []string{"*"},
+				boundKubernetesServiceAccounts: []string{"*"},
+			}),
+			true,
+			"not in bound clusters",
+		},
+		{
+			"namespace_no_match",
+			workload(&AuthorizeGKEInput{
+				boundClusters:                  []string{cluster},
+				boundNamespaces:                []string{"default"},
+				boundKubernetesServiceAccounts: []string{"*"},
+			}),
+			true,
+			`namespace "payments" not in bound namespaces`,
+		},
+		{
+			"service_account_no_match",
+			workload(&AuthorizeGKEInput{
+				boundClusters:                  []string{cluster},
+				boundNamespaces:                []string{"payments"},
+				boundKubernetesServiceAccounts: []string{"worker"},
+			}),
+			true,
+			`Kubernetes service account "api" not in bound Kubernetes service accounts`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := AuthorizeGKE(tc.i)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+
+				if !strings.Contains(err.Error(), tc.errText) {
+					t.Errorf("expected %q to contain %q", err.Error(), tc.errText)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcp
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+)
+
+type AuthorizeUserInput struct {
+	email        string
+	hostedDomain string
+
+	// groups are the emails of the Google Groups that the user is a direct
+	// or transitive member of.
+	groups []string
+
+	boundHostedDomains []string
+	boundEmails        []string
+	boundGroups        []string
+}
+
+func AuthorizeUser(i *AuthorizeUserInput) error {
+	// Consumer Google accounts have no hosted domain.
+	if len(i.boundHostedDomains) > 0 && !strutil.StrListContainsCaseInsensitive(i.boundHostedDomains, i.hostedDomain) {
+		return fmt.Errorf("user %q of hosted domain %q not in bound hosted domains %q", i.email, i.hostedDomain, i.boundHostedDomains)
+	}
+
+	if len(i.boundEmails) == 0 && len(i.boundGroups) == 0 {
+		return nil
+	}
+
+	if strutil.StrListContainsCaseInsensitive(i.boundEmails, i.email) {
+		return nil
+	}
+
+	for _, group := range i.groups {
+		if strutil.StrListContainsCaseInsensitive(i.boundGroups, group) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("user %q is not in bound emails or a member of bound groups", i.email)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuthorizeUser(t *testing.T) {
+	t.Parallel()
+
+	user := func(i *AuthorizeUserInput) *AuthorizeUserInput {
+		i.email = "Alice@example.com"
+		i.hostedDomain = "example.com"
+		i.groups = []string{"devs@example.com", "everyone@example.com"}
+		return i
+	}
+
+	cases := []struct {
+		name    string
+		i       *AuthorizeUserInput
+		err     bool
+		errText string
+	}{
+		{
+			"hosted_domain_match",
+			user(&AuthorizeUserInput{
+				boundHostedDomains: []string{"example.com"},
+			}),
+			false,
+			"",
+		},
+		{
+			"hosted_domain_no_match",
+			user(&AuthorizeUserInput{
+				boundHostedDomains: []string{"example.org"},
+			}),
+			true,
+			`user "Alice@example.com" of hosted domain "example.com" not in bound hosted domains`,
+		},
+		{
+			"consumer_account",
+			func() *AuthorizeUserInput {
+				i := user(&AuthorizeUserInput{
+					boundHostedDomains: []string{"example.com"},
+				})
+				i.hostedDomain = ""
+				return i
+			}(),
+			true,
+			"not in bound hosted domains",
+		},
+		{
+			"email_match",
+			user(&AuthorizeUserInput{
+				boundEmails: []string{"alice@example.com"},
+			}),
+			false,
+			"",
+		},
+		{
+			"group_match",
+			user(&AuthorizeUserInput{
+				boundEmails: []string{"bob@example.com"},
+				boundGroups: []string{"devs@example.com"},
+			}),
+			false,
+			"",
+		},
+		{
+			"email_and_group_no_match",
+			user(&AuthorizeUserInput{
+				boundEmails: []string{"bob@example.com"},
+				boundGroups: []string{"admins@example.com"},
+			}),
+			true,
+			"is not in bound emails or a member of bound groups",
+		},
+		{
+			"hosted_domain_and_group",
+			user(&AuthorizeUserInput{
+				boundHostedDomains: []string{"example.org"},
+				boundGroups:        []string{"devs@example.com"},
+			}),
+			true,
+			"not in bound hosted domains",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := AuthorizeUser(tc.i)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+
+				if !strings.Contains(err.Error(), tc.errText) {
+					t.Errorf("expected %q to contain %q", err.Error(), tc.errText)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
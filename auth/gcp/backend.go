@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-cleanhttp"
@@ -15,10 +16,13 @@ import (
 	"github.com/openbao/openbao/sdk/v2/logical"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/cloudfunctions/v2"
+	"google.golang.org/api/cloudidentity/v1"
 	"google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/iam/v1"
 	"google.golang.org/api/option"
+	"google.golang.org/api/run/v2"
 
 	"github.com/openbao/openbao-plugins/auth/gcp/cache"
 )
@@ -34,6 +38,26 @@ const (
 // must be less than 60 minutes.
 var cacheTime = 30 * time.Minute
 
+// ancestryCacheTime is the duration for which to cache the resource hierarchy
+// ancestry of projects.
+var ancestryCacheTime = 5 * time.Minute
+
+// defaultGoogleGroupsCacheTTL is the default duration for which to cache the
+// Google Groups of service accounts for group aliases.
+const defaultGoogleGroupsCacheTTL = 5 * time.Minute
+
+// gkeJWKSCacheTime is the duration for which to cache the OIDC signing keys of
+// GKE clusters.
+var gkeJWKSCacheTime = 5 * time.Minute
+
+// lookupCacheMaxEntries is the maximum number of per-resource lookups, such
+// as project ancestries, GKE signing keys and Google Groups, to cache.
+const lookupCacheMaxEntries = 10000
+
+// replayCacheTidyInterval is the interval at which expired JWTs are deleted
+// from the replay cache.
+var replayCacheTidyInterval = time.Hour
+
 type GcpAuthBackend struct {
 	*framework.Backend
 
@@ -41,8 +65,23 @@ type GcpAuthBackend struct {
 	// cache directly.
 	cache *cache.Cache
 
+	// lookupCache caches the results of per-resource lookups made on login.
+	// These are slow network calls, so they don't share the lock of cache.
+	lookupCache *cache.KeyedCache
+
 	// pluginEnv contains Vault version information. It is used in user-agent headers.
 	pluginEnv *logical.PluginEnvironment
+
+	// replayCacheLock serializes reads and writes of the replay cache so that
+	// a JWT cannot be used by concurrent logins.
+	replayCacheLock sync.Mutex
+
+	// tidyReplayCacheCASGuard guards the replay cache tidy function.
+	tidyReplayCacheCASGuard *uint32
+
+	// nextReplayCacheTidyTime is the next time at which periodicFunc tidies
+	// the replay cache.
+	nextReplayCacheTidyTime time.Time
 }
 
 // Factory returns a new backend as logical.Backend.
@@ -56,7 +95,9 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 
 func Backend() *GcpAuthBackend {
 	b := &GcpAuthBackend{
-		cache: cache.New(),
+		cache:                   cache.New(),
+		lookupCache:             cache.NewKeyed(lookupCacheMaxEntries),
+		tidyReplayCacheCASGuard: new(uint32),
 	}
 
 	b.Backend = &framework.Backend{
@@ -70,17 +111,22 @@ func Backend() *GcpAuthBackend {
 			SealWrapStorage: []string{
 				"config",
 			},
+			LocalStorage: []string{
+				replayCacheStoragePrefix,
+			},
 		},
 		Paths: framework.PathAppend(
 			[]*framework.Path{
 				pathConfig(b),
 				pathLogin(b),
 				pathConfigRotateRoot(b),
+				pathTidyReplayCache(b),
 			},
 			pathsRole(b),
 		),
 		InitializeFunc: b.initialize,
 		Invalidate:     b.invalidate,
+		PeriodicFunc:   b.periodicFunc,
 	}
 	return b
 }
@@ -95,6 +141,21 @@ func (b *GcpAuthBackend) initialize(ctx context.Context, _ *logical.Initializati
 	return nil
 }
 
+// periodicFunc deletes expired JWTs from the replay cache once every
+// replayCacheTidyInterval.
+func (b *GcpAuthBackend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	if !b.nextReplayCacheTidyTime.IsZero() && time.Now().Before(b.nextReplayCacheTidyTime) {
+		return nil
+	}
+
+	if _, err := b.tidyReplayCache(req); err != nil {
+		return err
+	}
+	b.nextReplayCacheTidyTime = time.Now().Add(replayCacheTidyInterval)
+
+	return nil
+}
+
 // IAMClient returns a new IAM client. This client talks to the IAM endpoint,
 // for all things that are not signing JWTs. The SignJWT method in the IAM
 // client has been deprecated, but other methods are still valid and supported.
@@ -186,6 +247,92 @@ func (b *GcpAuthBackend) CRMClient(ctx context.Context, s logical.Storage) (*clo
 	return client.(*cloudresourcemanager.Service), nil
 }
 
+// CloudRunClient returns a new Cloud Run Admin client. The client is cached.
+func (b *GcpAuthBackend) CloudRunClient(ctx context.Context, s logical.Storage) (*run.Service, error) {
+	cfg, err := b.config(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config while creating Cloud Run client: %w", err)
+	}
+
+	opts, err := b.clientOptions(ctx, s, cfg.RunCustomEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Run client options: %w", err)
+	}
+
+	client, err := b.cache.Fetch("run", cacheTime, func() (interface{}, error) {
+		client, err := run.NewService(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Cloud Run client: %w", err)
+		}
+		client.UserAgent = useragent.PluginString(b.pluginEnv, userAgentPluginName)
+
+		return client, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return client.(*run.Service), nil
+}
+
+// CloudFunctionsClient returns a new Cloud Functions client. The client is
+// cached.
+func (b *GcpAuthBackend) CloudFunctionsClient(ctx context.Context, s logical.Storage) (*cloudfunctions.Service, error) {
+	cfg, err := b.config(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config while creating Cloud Functions client: %w", err)
+	}
+
+	opts, err := b.clientOptions(ctx, s, cfg.FunctionsCustomEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Functions client options: %w", err)
+	}
+
+	client, err := b.cache.Fetch("functions", cacheTime, func() (interface{}, error) {
+		client, err := cloudfunctions.NewService(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Cloud Functions client: %w", err)
+		}
+		client.UserAgent = useragent.PluginString(b.pluginEnv, userAgentPluginName)
+
+		return client, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return client.(*cloudfunctions.Service), nil
+}
+
+// CloudIdentityClient returns a new Cloud Identity client. The client is
+// cached.
+func (b *GcpAuthBackend) CloudIdentityClient(ctx context.Context, s logical.Storage) (*cloudidentity.Service, error) {
+	cfg, err := b.config(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config while creating Cloud Identity client: %w", err)
+	}
+
+	opts, err := b.clientOptions(ctx, s, cfg.CloudIdentityCustomEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Identity client options: %w", err)
+	}
+
+	client, err := b.cache.Fetch("cloudidentity", cacheTime, func() (interface{}, error) {
+		client, err := cloudidentity.NewService(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Cloud Identity client: %w", err)
+		}
+		client.UserAgent = useragent.PluginString(b.pluginEnv, userAgentPluginName)
+
+		return client, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return client.(*cloudidentity.Service), nil
+}
+
 // clientOptions returns a new set of client options containing an http.Client and optional
 // custom endpoint. The http.Client is authenticated using the provided credentials. The
 // underlying http.Client is cached among all clients.
@@ -255,9 +402,10 @@ func (b *GcpAuthBackend) credentials(ctx context.Context, s logical.Storage) (*g
 	return creds.(*google.Credentials), nil
 }
 
-// ClearCaches deletes all cached clients and credentials.
+// ClearCaches deletes all cached clients, credentials and lookups.
 func (b *GcpAuthBackend) ClearCaches() {
 	b.cache.Clear()
+	b.lookupCache.Clear()
 }
 
 // invalidate resets the plugin. This is called when a key is updated via
@@ -271,11 +419,20 @@ func (b *GcpAuthBackend) invalidate(_ context.Context, key string) {
 
 const backendHelp = `
 The GCP auth method allows machines to authenticate Google Cloud Platform
-entities. It supports two modes of authentication:
+entities. It supports five modes of authentication:
 
 - IAM service accounts: provides a signed JSON Web Token for a given
   service account key
 
 - GCE VM metadata: provides a signed JSON Web Token using instance metadata
   obtained from the GCE instance metadata server
+
+- Cloud Run: provides a Google-signed ID token of a Cloud Run service or
+  Cloud Function obtained from its metadata server
+
+- GKE Workload Identity: provides a Kubernetes service account token of a
+  pod, signed by the OIDC issuer of its GKE cluster
+
+- Google users: provides a Google-signed ID token of a Google Workspace or
+  Cloud Identity user
 `
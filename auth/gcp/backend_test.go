@@ -7,6 +7,8 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -16,10 +18,16 @@ import (
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/openbao/openbao/sdk/v2/helper/authmetadata"
 	"github.com/openbao/openbao/sdk/v2/logical"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 )
 
 const (
 	googleCredentialsEnv = "GOOGLE_TEST_CREDENTIALS"
+
+	// testAudience is the audience of ID tokens configured by
+	// testBackendWithServer.
+	testAudience = "https://vault.example.com"
 )
 
 func skipIfAccTestNotEnabled(t *testing.T) {
@@ -73,6 +81,60 @@ func testBackendWithCreds(tb testing.TB) (*GcpAuthBackend, logical.Storage, *gcp
 	return b, storage, creds
 }
 
+// testBackendWithServer returns a new backend whose Google API clients send
+// their requests to a local server with the given handler.
+func testBackendWithServer(tb testing.TB, handler http.Handler) (*GcpAuthBackend, logical.Storage) {
+	tb.Helper()
+
+	srv := httptest.NewServer(handler)
+	tb.Cleanup(srv.Close)
+
+	b, storage := testBackend(tb)
+	ctx := context.Background()
+
+	entry, err := logical.StorageEntryJSON("config", &gcpConfig{
+		GCEAuthMetadata:             authmetadata.NewHandler(gceAuthMetadataFields),
+		IAMAuthMetadata:             authmetadata.NewHandler(iamAuthMetadataFields),
+		CloudRunAuthMetadata:        authmetadata.NewHandler(cloudRunAuthMetadataFields),
+		GKEAuthMetadata:             authmetadata.NewHandler(gkeAuthMetadataFields),
+		UserAuthMetadata:            authmetadata.NewHandler(userAuthMetadataFields),
+		Audience:                    testAudience,
+		APICustomEndpoint:           srv.URL + "/",
+		IAMCustomEndpoint:           srv.URL + "/",
+		CRMCustomEndpoint:           srv.URL + "/",
+		ComputeCustomEndpoint:       srv.URL + "/",
+		RunCustomEndpoint:           srv.URL + "/",
+		FunctionsCustomEndpoint:     srv.URL + "/",
+		ContainerCustomEndpoint:     srv.URL + "/",
+		CloudIdentityCustomEndpoint: srv.URL + "/",
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := storage.Put(ctx, entry); err != nil {
+		tb.Fatal(err)
+	}
+
+	testSeedCredentials(tb, b)
+
+	return b, storage
+}
+
+// testSeedCredentials caches static credentials on the backend, so that
+// clients don't look for default credentials. Writing the config clears the
+// cache, so tests that update it need to seed the credentials again.
+func testSeedCredentials(tb testing.TB, b *GcpAuthBackend) {
+	tb.Helper()
+
+	if _, err := b.cache.Fetch("credentials", cacheTime, func() (interface{}, error) {
+		return &google.Credentials{
+			TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test"}),
+		}, nil
+	}); err != nil {
+		tb.Fatal(err)
+	}
+}
+
 func testCredentials(tb testing.TB) *gcputil.GcpCredentials {
 	tb.Helper()
 
@@ -0,0 +1,126 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is the minimum interval between sweeps of expired entries.
+const sweepInterval = time.Minute
+
+// KeyedCache caches the results of lookups by key, such as per-resource API
+// calls. Unlike Cache, no lock is held while a lookup runs, so lookups of
+// different keys don't block each other. Concurrent lookups of the same key
+// share a single call of the cache function. Expired entries are evicted, and
+// the oldest entry is evicted once the cache holds maxEntries.
+type KeyedCache struct {
+	lock       sync.Mutex
+	maxEntries int
+	data       map[string]*cacheEntry
+	calls      map[string]*call
+	lastSweep  time.Time
+}
+
+// call is a lookup in progress.
+type call struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+// NewKeyed creates a keyed cache holding at most maxEntries entries.
+func NewKeyed(maxEntries int) *KeyedCache {
+	return &KeyedCache{
+		maxEntries: maxEntries,
+		data:       map[string]*cacheEntry{},
+		calls:      map[string]*call{},
+	}
+}
+
+// Fetch retrieves an item from the cache. If the item exists in the cache and
+// is within its lifetime, it is returned. Otherwise, the function f is invoked,
+// or the result of a concurrent invocation for the same name is awaited, and
+// the result is stored in the cache.
+func (c *KeyedCache) Fetch(name string, t time.Duration, f Func) (interface{}, error) {
+	c.lock.Lock()
+	if e, ok := c.data[name]; ok && time.Since(e.created) < e.lifetime {
+		c.lock.Unlock()
+		return e.result, nil
+	}
+	if cl, ok := c.calls[name]; ok {
+		c.lock.Unlock()
+		<-cl.done
+		return cl.result, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.calls[name] = cl
+	c.lock.Unlock()
+
+	// The call is always released, so that a panic in f doesn't leave the
+	// callers waiting for it blocked.
+	finished := false
+	defer func() {
+		c.lock.Lock()
+		delete(c.calls, name)
+		if finished && cl.err == nil {
+			c.store(name, cl.result, t)
+		}
+		c.lock.Unlock()
+		close(cl.done)
+	}()
+
+	cl.result, cl.err = f()
+	finished = true
+	return cl.result, cl.err
+}
+
+// store adds an entry to the cache, evicting expired entries and, if the
+// cache is full, the oldest entry. The caller must hold the lock.
+func (c *KeyedCache) store(name string, result interface{}, t time.Duration) {
+	delete(c.data, name)
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) >= sweepInterval || len(c.data) >= c.maxEntries {
+		for k, e := range c.data {
+			if now.Sub(e.created) >= e.lifetime {
+				delete(c.data, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	if len(c.data) >= c.maxEntries {
+		var oldest string
+		var oldestCreated time.Time
+		for k, e := range c.data {
+			if oldest == "" || e.created.Before(oldestCreated) {
+				oldest, oldestCreated = k, e.created
+			}
+		}
+		delete(c.data, oldest)
+	}
+
+	c.data[name] = &cacheEntry{
+		result:   result,
+		created:  now,
+		lifetime: t,
+	}
+}
+
+// Len returns the number of entries in the cache, including expired entries
+// that have not been evicted yet.
+func (c *KeyedCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.data)
+}
+
+// Clear empties the cache for all values.
+func (c *KeyedCache) Clear() {
+	c.lock.Lock()
+	c.data = map[string]*cacheEntry{}
+	c.lock.Unlock()
+}
@@ -0,0 +1,154 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeyedCache_Fetch(t *testing.T) {
+	t.Parallel()
+
+	c := NewKeyed(10)
+
+	var calls int32
+	f := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "value", nil
+	}
+
+	for i := 0; i < 2; i++ {
+		v, err := c.Fetch("key", time.Minute, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != "value" {
+			t.Errorf("expected %q to be %q", v, "value")
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected 1 call, got %d", n)
+	}
+
+	// Errors are not cached
+	if _, err := c.Fetch("error", time.Minute, func() (interface{}, error) {
+		return nil, errors.New("lookup failed")
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := c.Fetch("error", time.Minute, f); err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired entries are looked up again
+	if _, err := c.Fetch("expired", 0, f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Fetch("expired", 0, f); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Errorf("expected 4 calls, got %d", n)
+	}
+}
+
+func TestKeyedCache_ConcurrentKeys(t *testing.T) {
+	t.Parallel()
+
+	c := NewKeyed(10)
+
+	// A slow lookup of one key doesn't block lookups of other keys
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.Fetch("slow", time.Minute, func() (interface{}, error) {
+			close(started)
+			<-release
+			return "slow", nil
+		})
+	}()
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Fetch("fast", time.Minute, func() (interface{}, error) {
+			return "fast", nil
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lookup of another key was blocked")
+	}
+
+	// Concurrent lookups of the same key share the call in progress
+	var calls int32
+	results := make(chan interface{}, 1)
+	go func() {
+		v, _ := c.Fetch("slow", time.Minute, func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return "other", nil
+		})
+		results <- v
+	}()
+
+	close(release)
+	wg.Wait()
+	if v := <-results; v != "slow" {
+		t.Errorf("expected %q to be %q", v, "slow")
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("expected no calls, got %d", n)
+	}
+}
+
+func TestKeyedCache_Eviction(t *testing.T) {
+	t.Parallel()
+
+	c := NewKeyed(2)
+	f := func() (interface{}, error) {
+		return "value", nil
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := c.Fetch(key, time.Hour, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+
+	// Expired entries are evicted before the oldest live entry
+	c = NewKeyed(2)
+	if _, err := c.Fetch("expired", time.Nanosecond, f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Fetch("live", time.Hour, f); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := c.Fetch("new", time.Hour, f); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int32
+	if _, err := c.Fetch("live", time.Hour, func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "value", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("expected live entry to be kept, got %d calls", n)
+	}
+}
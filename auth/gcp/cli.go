@@ -8,7 +8,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,79 +21,181 @@ import (
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/openbao/openbao/api/v2"
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/iamcredentials/v1"
 	"google.golang.org/api/option"
 )
 
-type CLIHandler struct{}
+type CLIHandler struct {
+	// MetadataEndpoint overrides the base URL of the GCE metadata server,
+	// e.g. "http://127.0.0.1:8080". When empty, the metadata server of the
+	// environment is used if it is detected.
+	MetadataEndpoint string
+}
 
-func getSignedJwt(role string, m map[string]string) (string, error) {
-	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, cleanhttp.DefaultClient())
+// errMetadataUnavailable is returned when no metadata server is detected.
+var errMetadataUnavailable = errors.New("metadata server is not available")
 
-	credentials, tokenSource, err := gcputil.FindCredentials(m["credentials"], ctx, iamcredentials.CloudPlatformScope)
-	if err != nil {
-		return "", fmt.Errorf("could not obtain credentials: %v", err)
-	}
+// getSignedJwt returns a JWT to login against 'iam' and 'gce' roles. It is
+// signed with the IAM Credentials signJwt API for the given service account,
+// or the service account of the credentials. Without a service account, e.g.
+// with the credentials of the metadata server, the ID token of the default
+// service account is requested from the metadata server instead.
+//
+// The metadata server is not used when a JWT expiration is given, since its
+// ID tokens expire after an hour, which exceeds the max_jwt_exp of 'iam'
+// roles that don't set bound_audiences.
+func (h *CLIHandler) getSignedJwt(role string, m map[string]string) (string, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, cleanhttp.DefaultClient())
 
-	httpClient := oauth2.NewClient(ctx, tokenSource)
+	credentials, tokenSource, credsErr := gcputil.FindCredentials(m["credentials"], ctx, iamcredentials.CloudPlatformScope)
 
 	serviceAccount, ok := m["service_account"]
 	if !ok && credentials != nil {
 		serviceAccount = credentials.ClientEmail
 	}
-	if serviceAccount == "" {
-		// Check if the metadata server is available.
-		if !metadata.OnGCE() {
-			return "", errors.New("could not obtain service account from credentials (are you using Application Default Credentials?). You must provide a service account to authenticate as")
+
+	if serviceAccount == "" && useMetadataIdentityToken(m) {
+		identityJwt, err := h.getMetadataIdentityToken(role, "default")
+		if err == nil {
+			return identityJwt, nil
 		}
-		metadataClient := metadata.NewClient(cleanhttp.DefaultClient())
-		v := url.Values{}
-		v.Set("audience", fmt.Sprintf("http://vault/%s", role))
-		v.Set("format", "full")
-		path := "instance/service-accounts/default/identity?" + v.Encode()
-		instanceJwt, err := metadataClient.Get(path)
-		if err != nil {
-			return "", fmt.Errorf("unable to read the identity token: %w", err)
+		if !errors.Is(err, errMetadataUnavailable) {
+			return "", err
 		}
-		return instanceJwt, nil
+	}
 
-	} else {
-		ttl := time.Duration(defaultIamMaxJwtExpMinutes) * time.Minute
-		jwtExpStr, ok := m["jwt_exp"]
-		if ok {
-			ttl, err = parseutil.ParseDurationSecond(jwtExpStr)
-			if err != nil {
-				return "", fmt.Errorf("could not parse jwt_exp '%s' into integer value", jwtExpStr)
-			}
+	if credsErr != nil {
+		return "", fmt.Errorf("could not obtain credentials: %v", credsErr)
+	}
+	if serviceAccount == "" {
+		return "", errors.New("could not obtain service account from credentials (are you using Application Default Credentials?). You must provide a service account to authenticate as")
+	}
+	return getIamSignedJwt(ctx, tokenSource, role, serviceAccount, m)
+}
+
+// useMetadataIdentityToken returns whether the login options leave the choice
+// of the token to the environment, so that the metadata server may be used.
+func useMetadataIdentityToken(m map[string]string) bool {
+	for _, k := range []string{"credentials", "service_account", "jwt_exp"} {
+		if _, ok := m[k]; ok {
+			return false
 		}
+	}
+	return true
+}
+
+// getMetadataIdentityToken requests a Google-signed ID token of the given
+// service account attached to the instance from the metadata server.
+func (h *CLIHandler) getMetadataIdentityToken(role, serviceAccount string) (string, error) {
+	v := url.Values{}
+	v.Set("audience", fmt.Sprintf("http://vault/%s", role))
+	v.Set("format", "full")
+	path := fmt.Sprintf("instance/service-accounts/%s/identity?%s", url.PathEscape(serviceAccount), v.Encode())
 
-		jwtPayload := map[string]interface{}{
-			"aud": fmt.Sprintf("http://vault/%s", role),
-			"sub": serviceAccount,
-			"exp": time.Now().Add(ttl).Unix(),
+	if h.MetadataEndpoint == "" {
+		if !metadata.OnGCE() {
+			return "", errMetadataUnavailable
 		}
-		payloadBytes, err := json.Marshal(jwtPayload)
+		identityJwt, err := metadata.NewClient(cleanhttp.DefaultClient()).Get(path)
 		if err != nil {
-			return "", fmt.Errorf("could not convert JWT payload to JSON string: %v", err)
+			return "", fmt.Errorf("unable to read the identity token: %w", err)
 		}
+		return identityJwt, nil
+	}
 
-		jwtReq := &iamcredentials.SignJwtRequest{
-			Payload: string(payloadBytes),
-		}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(h.MetadataEndpoint, "/")+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errMetadataUnavailable, err)
+	}
+	defer resp.Body.Close()
 
-		iamClient, err := iamcredentials.NewService(ctx, option.WithHTTPClient(httpClient))
-		if err != nil {
-			return "", fmt.Errorf("could not create IAM client: %v", err)
-		}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the identity token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to read the identity token: metadata server returned status %d", resp.StatusCode)
+	}
+	return string(body), nil
+}
+
+// getIamSignedJwt signs a JWT for the given service account with the IAM
+// Credentials signJwt API.
+func getIamSignedJwt(ctx context.Context, tokenSource oauth2.TokenSource, role, serviceAccount string, m map[string]string) (string, error) {
+	httpClient := oauth2.NewClient(ctx, tokenSource)
 
-		resourceName := fmt.Sprintf(gcputil.ServiceAccountCredentialsTemplate, serviceAccount)
-		resp, err := iamClient.Projects.ServiceAccounts.SignJwt(resourceName, jwtReq).Do()
+	ttl := time.Duration(defaultIamMaxJwtExpMinutes) * time.Minute
+	jwtExpStr, ok := m["jwt_exp"]
+	if ok {
+		var err error
+		ttl, err = parseutil.ParseDurationSecond(jwtExpStr)
 		if err != nil {
-			return "", fmt.Errorf("unable to sign JWT for %s using given Vault credentials: %v", resourceName, err)
+			return "", fmt.Errorf("could not parse jwt_exp '%s' into integer value", jwtExpStr)
 		}
+	}
+
+	jwtPayload := map[string]interface{}{
+		"aud": fmt.Sprintf("http://vault/%s", role),
+		"sub": serviceAccount,
+		"exp": time.Now().Add(ttl).Unix(),
+	}
+	payloadBytes, err := json.Marshal(jwtPayload)
+	if err != nil {
+		return "", fmt.Errorf("could not convert JWT payload to JSON string: %v", err)
+	}
 
-		return resp.SignedJwt, nil
+	jwtReq := &iamcredentials.SignJwtRequest{
+		Payload: string(payloadBytes),
 	}
+
+	iamClient, err := iamcredentials.NewService(ctx, option.WithHTTPClient(httpClient))
+	if err != nil {
+		return "", fmt.Errorf("could not create IAM client: %v", err)
+	}
+
+	resourceName := fmt.Sprintf(gcputil.ServiceAccountCredentialsTemplate, serviceAccount)
+	resp, err := iamClient.Projects.ServiceAccounts.SignJwt(resourceName, jwtReq).Do()
+	if err != nil {
+		return "", fmt.Errorf("unable to sign JWT for %s using given Vault credentials: %v", resourceName, err)
+	}
+
+	return resp.SignedJwt, nil
+}
+
+// getUserIDToken returns the Google-signed ID token of the user of the given
+// credentials or of the Application Default Credentials, to login against
+// 'user' roles.
+func getUserIDToken(m map[string]string) (string, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, cleanhttp.DefaultClient())
+	scopes := []string{"openid", "https://www.googleapis.com/auth/userinfo.email"}
+
+	var credentials *google.Credentials
+	var err error
+	if v, ok := m["credentials"]; ok {
+		credentials, err = google.CredentialsFromJSON(ctx, []byte(v), scopes...)
+	} else {
+		credentials, err = google.FindDefaultCredentials(ctx, scopes...)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not obtain credentials: %v", err)
+	}
+
+	token, err := credentials.TokenSource.Token()
+	if err != nil {
+		return "", fmt.Errorf("could not obtain token from credentials: %v", err)
+	}
+
+	idToken, ok := token.Extra("id_token").(string)
+	if !ok || idToken == "" {
+		return "", errors.New(`credentials did not return an ID token. Use user credentials, e.g. from "gcloud auth application-default login"`)
+	}
+	return idToken, nil
 }
 
 func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, error) {
@@ -106,10 +211,23 @@ func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, erro
 
 	var loginToken string
 	var err error
+
+	isUser := false
+	if v, ok := m["user"]; ok {
+		if isUser, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("could not parse user '%s' into boolean value", v)
+		}
+	}
+
 	if v, ok := m["jwt"]; ok {
 		loginToken = v
+	} else if isUser {
+		loginToken, err = getUserIDToken(m)
+		if err != nil {
+			return nil, err
+		}
 	} else {
-		loginToken, err = getSignedJwt(role, m)
+		loginToken, err = h.getSignedJwt(role, m)
 		if err != nil {
 			return nil, err
 		}
@@ -150,7 +268,17 @@ Authenticate using explicitly passed-in credentials:
 	Example:
 	vault login -method=gcp role=my-iam-role -credentials=@path/to/creds role=my-iam-role
 
-This tool generates a signed JWT signed using the given credentials.
+Authenticate as a Google user against a 'user' role, using the user
+Application Default Credentials of "gcloud auth application-default login":
+
+  Example: vault login -method=gcp role=my-user-role user=true
+
+This tool generates a JWT signed using the given credentials, or the
+Application Default Credentials. On GCE, Cloud Run and GKE, if the
+credentials have no service account email, e.g. the credentials of the
+metadata server, and no service account or JWT expiration is passed in, it
+requests an ID token of the attached service account from the metadata server
+instead.
 
 Configuration:
 
@@ -171,13 +299,19 @@ Configuration:
 	time in minutes that all valid authentication JWTs
 	must expire within (from time of authentication).
 	Defaults to 15 minutes, the default max_jwt_exp for a role.
-	Must be less than an hour. 
+	Must be less than an hour. If set, the metadata server is not used.
 
   service_account=<string>	
 	Service account to generate a JWT for. Defaults to credentials 
 	"client_email" if "credentials" specified and this value is not. 
 	The actual credential must have the "iam.serviceAccounts.signJWT" 
-	permissions on this service account. 
+	permissions on this service account. If set, the metadata server is
+	not used.
+
+  user=<bool>
+	Login as a Google user with the ID token of the user credentials,
+	for 'user' roles. The role must bind the OAuth client ID of the
+	credentials in "bound_audiences". Defaults to false.
 `
 
 	return strings.TrimSpace(help)
@@ -0,0 +1,175 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCLIHandler_MetadataIdentityToken(t *testing.T) {
+	// Without a credentials file, the credentials have no service account
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/identity" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("audience") != "http://vault/my-role" || q.Get("format") != "full" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("identity-jwt"))
+	}))
+	t.Cleanup(srv.Close)
+
+	h := &CLIHandler{MetadataEndpoint: srv.URL + "/"}
+
+	t.Run("default_service_account", func(t *testing.T) {
+		t.Parallel()
+
+		token, err := h.getSignedJwt("my-role", map[string]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "identity-jwt" {
+			t.Errorf("expected %q to be %q", token, "identity-jwt")
+		}
+	})
+
+	t.Run("unattached_service_account", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := h.getMetadataIdentityToken("my-role", "other@my-project.iam.gserviceaccount.com")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if errors.Is(err, errMetadataUnavailable) {
+			t.Errorf("expected metadata server to be available: %v", err)
+		}
+		if exp, act := "status 404", err.Error(); !strings.Contains(act, exp) {
+			t.Errorf("expected %q to contain %q", act, exp)
+		}
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+
+		h := &CLIHandler{MetadataEndpoint: down.URL}
+		_, err := h.getMetadataIdentityToken("my-role", "default")
+		if !errors.Is(err, errMetadataUnavailable) {
+			t.Errorf("expected metadata server to be unavailable: %v", err)
+		}
+	})
+}
+
+func TestCLIHandler_ExplicitOptionsSkipMetadata(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte("identity-jwt"))
+	}))
+	t.Cleanup(srv.Close)
+
+	// Fail the signJwt fallback fast, without looking up real credentials.
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+
+	h := &CLIHandler{MetadataEndpoint: srv.URL}
+
+	cases := map[string]map[string]string{
+		// The ID tokens of the metadata server are GCE tokens on GCE, which
+		// 'iam' roles with allow_gce_inference=false reject.
+		"service_account": {"service_account": "ci@my-project.iam.gserviceaccount.com"},
+		// The ID tokens of the metadata server expire after an hour,
+		// regardless of jwt_exp.
+		"jwt_exp": {"jwt_exp": "10m"},
+	}
+	for name, m := range cases {
+		t.Run(name, func(t *testing.T) {
+			token, err := h.getSignedJwt("my-role", m)
+			if err == nil {
+				t.Fatalf("expected signJwt error, got token %q", token)
+			}
+			if exp, act := "could not obtain credentials", err.Error(); !strings.Contains(act, exp) {
+				t.Errorf("expected %q to contain %q", act, exp)
+			}
+		})
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("expected no metadata server requests, got %d", n)
+	}
+}
+
+func TestCLIHandler_CredentialsFileSkipsMetadata(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte("identity-jwt"))
+	}))
+	t.Cleanup(srv.Close)
+
+	// Fail the token exchange of the key file fast, without reaching Google
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(tokenSrv.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyJSON, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "my-project",
+		"private_key_id": "1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"client_email":   "ci@my-project.iam.gserviceaccount.com",
+		"client_id":      "1",
+		"token_uri":      tokenSrv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(path, keyJSON, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+	// The key file's service account signs the JWT, rather than the
+	// attached service account of the metadata server
+	h := &CLIHandler{MetadataEndpoint: srv.URL}
+	token, err := h.getSignedJwt("my-role", map[string]string{})
+	if err == nil {
+		t.Fatalf("expected signJwt error, got token %q", token)
+	}
+	if exp, act := "unable to sign JWT for projects/-/serviceAccounts/ci@my-project.iam.gserviceaccount.com", err.Error(); !strings.Contains(act, exp) {
+		t.Errorf("expected %q to contain %q", act, exp)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("expected no metadata server requests, got %d", n)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-gcp-common/gcputil"
 	"github.com/openbao/openbao/sdk/v2/framework"
@@ -24,6 +25,28 @@ type gcpConfig struct {
 	GCEAliasType    string                  `json:"gce_alias"`
 	GCEAuthMetadata *authmetadata.Handler   `json:"gce_auth_metadata_handler"`
 
+	// CloudRunAuthMetadata selects the metadata of 'cloudrun' logins.
+	CloudRunAuthMetadata *authmetadata.Handler `json:"cloudrun_auth_metadata_handler"`
+
+	// GKEAuthMetadata selects the metadata of 'gke' logins.
+	GKEAuthMetadata *authmetadata.Handler `json:"gke_auth_metadata_handler"`
+
+	// UserAuthMetadata selects the metadata of 'user' logins.
+	UserAuthMetadata *authmetadata.Handler `json:"user_auth_metadata_handler"`
+
+	// Audience is the audience that Google-signed ID tokens of 'cloudrun'
+	// logins and Kubernetes service account tokens of 'gke' logins must be
+	// issued for.
+	Audience string `json:"audience"`
+
+	// GoogleSignedTokensOnly rejects JWTs that are not signed with the Google
+	// OAuth2 certificates, such as JWTs signed with service account keys.
+	GoogleSignedTokensOnly bool `json:"google_signed_tokens_only"`
+
+	// GoogleGroupsCacheTTL is the duration for which the Google Groups of service
+	// accounts are cached for group aliases. Zero uses defaultGoogleGroupsCacheTTL.
+	GoogleGroupsCacheTTL time.Duration `json:"google_groups_cache_ttl"`
+
 	// APICustomEndpoint overrides the service endpoint for www.googleapis.com
 	APICustomEndpoint string `json:"api_custom_endpoint"`
 	// IAMCustomEndpoint overrides the service endpoint for api.googleapis.com
@@ -32,6 +55,15 @@ type gcpConfig struct {
 	CRMCustomEndpoint string `json:"crm_custom_endpoint"`
 	// ComputeCustomEndpoint overrides the service endpoint for compute.googleapis.com
 	ComputeCustomEndpoint string `json:"compute_custom_endpoint"`
+	// RunCustomEndpoint overrides the service endpoint for run.googleapis.com
+	RunCustomEndpoint string `json:"run_custom_endpoint"`
+	// FunctionsCustomEndpoint overrides the service endpoint for cloudfunctions.googleapis.com
+	FunctionsCustomEndpoint string `json:"functions_custom_endpoint"`
+	// ContainerCustomEndpoint overrides the service endpoint for container.googleapis.com,
+	// which serves the OIDC JWKS of GKE clusters
+	ContainerCustomEndpoint string `json:"container_custom_endpoint"`
+	// CloudIdentityCustomEndpoint overrides the service endpoint for cloudidentity.googleapis.com
+	CloudIdentityCustomEndpoint string `json:"cloudidentity_custom_endpoint"`
 
 	ServiceAccountEmail string `json:"service_account_email"`
 }
@@ -104,6 +136,34 @@ func (c *gcpConfig) Update(d *framework.FieldData) error {
 		return fmt.Errorf("failed to parse gce metadata: %w", err)
 	}
 
+	if err := c.CloudRunAuthMetadata.ParseAuthMetadata(d); err != nil {
+		return fmt.Errorf("failed to parse cloudrun metadata: %w", err)
+	}
+
+	if err := c.GKEAuthMetadata.ParseAuthMetadata(d); err != nil {
+		return fmt.Errorf("failed to parse gke metadata: %w", err)
+	}
+
+	if err := c.UserAuthMetadata.ParseAuthMetadata(d); err != nil {
+		return fmt.Errorf("failed to parse user metadata: %w", err)
+	}
+
+	if audience, ok := d.GetOk("audience"); ok {
+		c.Audience = audience.(string)
+	}
+
+	if googleSignedTokensOnly, ok := d.GetOk("google_signed_tokens_only"); ok {
+		c.GoogleSignedTokensOnly = googleSignedTokensOnly.(bool)
+	}
+
+	if googleGroupsCacheTTL, ok := d.GetOk("google_groups_cache_ttl"); ok {
+		ttl := time.Duration(googleGroupsCacheTTL.(int)) * time.Second
+		if ttl < 0 {
+			return errors.New("google_groups_cache_ttl cannot be negative")
+		}
+		c.GoogleGroupsCacheTTL = ttl
+	}
+
 	rawEndpoint, exists := d.GetOk("custom_endpoint")
 	if exists {
 		for k, v := range rawEndpoint.(map[string]string) {
@@ -116,8 +176,16 @@ func (c *gcpConfig) Update(d *framework.FieldData) error {
 				c.CRMCustomEndpoint = v
 			case "compute":
 				c.ComputeCustomEndpoint = v
+			case "run":
+				c.RunCustomEndpoint = v
+			case "functions":
+				c.FunctionsCustomEndpoint = v
+			case "container":
+				c.ContainerCustomEndpoint = v
+			case "cloudidentity":
+				c.CloudIdentityCustomEndpoint = v
 			default:
-				return fmt.Errorf("invalid custom endpoint type %q. Available types are: 'api', 'iam', 'crm', 'compute'", k)
+				return fmt.Errorf("invalid custom endpoint type %q. Available types are: 'api', 'iam', 'crm', 'compute', 'run', 'functions', 'container', 'cloudidentity'", k)
 			}
 		}
 	}
@@ -131,6 +199,15 @@ func (c *gcpConfig) Update(d *framework.FieldData) error {
 	return nil
 }
 
+// googleGroupsCacheTTL returns the duration for which the Google Groups of
+// service accounts are cached.
+func (c *gcpConfig) googleGroupsCacheTTL() time.Duration {
+	if c.GoogleGroupsCacheTTL == 0 {
+		return defaultGoogleGroupsCacheTTL
+	}
+	return c.GoogleGroupsCacheTTL
+}
+
 func (c *gcpConfig) getIAMAlias(role *gcpRole, svcAccount *iam.ServiceAccount) (alias string, err error) {
 	aliaser, exists := allowedIAMAliases[c.IAMAliasType]
 	if !exists {
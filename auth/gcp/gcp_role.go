@@ -6,9 +6,12 @@ package gcp
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
-	"github.com/hashicorp/go-gcp-common/gcputil"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/helper/tokenutil"
@@ -41,12 +44,35 @@ type gcpRole struct {
 	// Projects that entities must belong to
 	BoundProjects []string `json:"bound_projects,omitempty"`
 
+	// Folders and organizations that the projects of entities must be
+	// descendants of. An entity's project only needs to match one of
+	// BoundProjects, BoundFolders or BoundOrganizations.
+	BoundFolders       []string `json:"bound_folders,omitempty"`
+	BoundOrganizations []string `json:"bound_organizations,omitempty"`
+
 	// Service accounts allowed to login under this role.
 	BoundServiceAccounts []string `json:"bound_service_accounts,omitempty"`
 
+	// BoundAudiences are the audiences that tokens must be issued for. Roles of type
+	// 'iam' and 'gce' with bound audiences only accept Google-signed ID tokens, and
+	// for 'user' roles these are the OAuth client IDs of ID tokens.
+	BoundAudiences []string `json:"bound_audiences,omitempty"`
+
 	// AddGroupAliases adds Vault group aliases to the response.
 	AddGroupAliases bool `json:"add_group_aliases,omitempty"`
 
+	// AddGoogleGroupAliases adds the Google Groups that the authenticating service
+	// account is a direct or transitive member of as Vault group aliases.
+	AddGoogleGroupAliases bool `json:"add_google_group_aliases,omitempty"`
+
+	// AllowGlobPatterns makes bound service accounts, label values and
+	// instance groups glob patterns instead of exact values.
+	AllowGlobPatterns bool `json:"allow_glob_patterns,omitempty"`
+
+	// ReplayProtection rejects logins with JWTs that were already used to login
+	// under this role.
+	ReplayProtection bool `json:"replay_protection,omitempty"`
+
 	// --| IAM-only attributes |--
 	// MaxJwtExp is the duration from time of authentication that a JWT used to authenticate to role must expire within.
 	// TODO(emilymye): Allow this to be updated for GCE roles once 'exp' parameter has been allowed for GCE metadata.
@@ -69,6 +95,47 @@ type gcpRole struct {
 	// BoundLabels that instances must currently have set in order to login under this role.
 	BoundLabels map[string]string `json:"bound_labels,omitempty"`
 
+	// RequireSecureBoot, RequireIntegrityMonitoring and RequireConfidentialVM require
+	// instances to have these Shielded VM or Confidential VM features enabled.
+	RequireSecureBoot          bool `json:"require_secure_boot,omitempty"`
+	RequireIntegrityMonitoring bool `json:"require_integrity_monitoring,omitempty"`
+	RequireConfidentialVM      bool `json:"require_confidential_vm,omitempty"`
+
+	// BoundNetworkTags are the network tags that instances must all have.
+	BoundNetworkTags []string `json:"bound_network_tags,omitempty"`
+
+	// BoundImageFamilies are the image families that the boot disks of instances
+	// must be created from, formatted as "projects/$PROJECT/global/images/family/$FAMILY".
+	BoundImageFamilies []string `json:"bound_image_families,omitempty"`
+
+	// --| Cloud Run-only attributes |--
+	// BoundServices are the names of the Cloud Run services or Cloud Functions allowed to
+	// login under this role. BoundRegions and BoundLabels also apply to Cloud Run roles.
+	BoundServices []string `json:"bound_services,omitempty"`
+
+	// --| GKE-only attributes |--
+	// BoundClusters are the resource names of the GKE clusters whose Kubernetes service
+	// accounts are allowed to login under this role.
+	BoundClusters []string `json:"bound_clusters,omitempty"`
+
+	// BoundNamespaces are the Kubernetes namespaces of service accounts allowed to login
+	// under this role.
+	BoundNamespaces []string `json:"bound_namespaces,omitempty"`
+
+	// BoundKubernetesServiceAccounts are the names of Kubernetes service accounts allowed
+	// to login under this role.
+	BoundKubernetesServiceAccounts []string `json:"bound_kubernetes_service_accounts,omitempty"`
+
+	// --| User-only attributes |--
+	// BoundHostedDomains are the Google Workspace or Cloud Identity domains that users
+	// must belong to.
+	BoundHostedDomains []string `json:"bound_hosted_domains,omitempty"`
+
+	// BoundEmails and BoundGroups are the emails of users, and of Google Groups that users
+	// are members of, allowed to login under this role. Users only need to match one.
+	BoundEmails []string `json:"bound_emails,omitempty"`
+	BoundGroups []string `json:"bound_groups,omitempty"`
+
 	// Deprecated fields
 	// TODO: Remove in 0.5.0+
 	ProjectId          string `json:"project_id,omitempty"`
@@ -172,18 +239,55 @@ func (role *gcpRole) updateRole(sys logical.SystemView, req *logical.Request, da
 		role.BoundProjects = strutil.RemoveDuplicates(role.BoundProjects, false)
 	}
 
+	// Update bound GCP folders and organizations.
+	if folders, ok := data.GetOk("bound_folders"); ok {
+		if role.BoundFolders, err = parseResourceIDs(folders.([]string), "folders/"); err != nil {
+			return warnings, fmt.Errorf("invalid bound_folders: %w", err)
+		}
+	}
+	if organizations, ok := data.GetOk("bound_organizations"); ok {
+		if role.BoundOrganizations, err = parseResourceIDs(organizations.([]string), "organizations/"); err != nil {
+			return warnings, fmt.Errorf("invalid bound_organizations: %w", err)
+		}
+	}
+
 	// Update bound GCP projects.
 	addGroupAliases, ok := data.GetOk("add_group_aliases")
 	if ok {
 		role.AddGroupAliases = addGroupAliases.(bool)
 	}
 
+	if addGoogleGroupAliases, ok := data.GetOk("add_google_group_aliases"); ok {
+		role.AddGoogleGroupAliases = addGoogleGroupAliases.(bool)
+	}
+
+	if audiences, ok := data.GetOk("bound_audiences"); ok {
+		role.BoundAudiences = strutil.RemoveDuplicates(strutil.TrimStrings(audiences.([]string)), false)
+	}
+
+	if allowGlobPatterns, ok := data.GetOk("allow_glob_patterns"); ok {
+		role.AllowGlobPatterns = allowGlobPatterns.(bool)
+	}
+
+	if replayProtection, ok := data.GetOk("replay_protection"); ok {
+		role.ReplayProtection = replayProtection.(bool)
+	}
+
 	// Update fields specific to this type
 	switch role.RoleType {
 	case iamRoleType:
 		if err = checkInvalidRoleTypeArgs(data, gceOnlyFieldSchema); err != nil {
 			return warnings, err
 		}
+		if err = checkInvalidRoleTypeArgs(data, cloudRunOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, gkeOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, userOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
 		if warnings, err = role.updateIamFields(data, req.Operation); err != nil {
 			return warnings, err
 		}
@@ -191,9 +295,72 @@ func (role *gcpRole) updateRole(sys logical.SystemView, req *logical.Request, da
 		if err = checkInvalidRoleTypeArgs(data, iamOnlyFieldSchema); err != nil {
 			return warnings, err
 		}
+		if err = checkInvalidRoleTypeArgs(data, cloudRunOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, gkeOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, userOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
 		if warnings, err = role.updateGceFields(data, req.Operation); err != nil {
 			return warnings, err
 		}
+	case cloudRunRoleType:
+		if err = checkInvalidRoleTypeArgs(data, iamOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		gceOnlyFields := make(map[string]*framework.FieldSchema, len(gceOnlyFieldSchema))
+		for k, v := range gceOnlyFieldSchema {
+			if !strutil.StrListContains(cloudRunSharedGceFields, k) {
+				gceOnlyFields[k] = v
+			}
+		}
+		if err = checkInvalidRoleTypeArgs(data, gceOnlyFields); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, gkeOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, userOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if warnings, err = role.updateCloudRunFields(data); err != nil {
+			return warnings, err
+		}
+	case gkeRoleType:
+		if err = checkInvalidRoleTypeArgs(data, iamOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, gceOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, cloudRunOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, userOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if warnings, err = role.updateGKEFields(data); err != nil {
+			return warnings, err
+		}
+	case userRoleType:
+		if err = checkInvalidRoleTypeArgs(data, iamOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, gceOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, cloudRunOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if err = checkInvalidRoleTypeArgs(data, gkeOnlyFieldSchema); err != nil {
+			return warnings, err
+		}
+		if warnings, err = role.updateUserFields(data); err != nil {
+			return warnings, err
+		}
 	}
 
 	return warnings, nil
@@ -211,12 +378,36 @@ func (role *gcpRole) validate(sys logical.SystemView) (warnings []string, err er
 		if warnings, err = role.validateForGCE(); err != nil {
 			return warnings, err
 		}
+	case cloudRunRoleType:
+		if warnings, err = role.validateForCloudRun(); err != nil {
+			return warnings, err
+		}
+	case gkeRoleType:
+		if warnings, err = role.validateForGKE(); err != nil {
+			return warnings, err
+		}
+	case userRoleType:
+		if warnings, err = role.validateForUser(); err != nil {
+			return warnings, err
+		}
 	case "":
 		return warnings, errors.New(errEmptyRoleType)
 	default:
 		return warnings, fmt.Errorf("role type '%s' is invalid", role.RoleType)
 	}
 
+	// Kubernetes service accounts and users are not Google service accounts.
+	// Users get the aliases of their Google Groups with add_group_aliases.
+	if role.AddGoogleGroupAliases && (role.RoleType == gkeRoleType || role.RoleType == userRoleType) {
+		return warnings, fmt.Errorf("add_google_group_aliases cannot be set for '%s' roles", role.RoleType)
+	}
+
+	globWarnings, err := role.validateGlobPatterns()
+	warnings = append(warnings, globWarnings...)
+	if err != nil {
+		return warnings, err
+	}
+
 	defaultLeaseTTL := sys.DefaultLeaseTTL()
 	if role.TokenTTL > defaultLeaseTTL {
 		warnings = append(warnings, fmt.Sprintf(
@@ -244,6 +435,50 @@ func (role *gcpRole) validate(sys logical.SystemView) (warnings []string, err er
 	return warnings, nil
 }
 
+// validateGlobPatterns checks the bound values of a role that may be glob
+// patterns. Patterns made up only of '*' are rejected since they match
+// everything. If the role does not allow glob patterns, values containing '*'
+// are matched exactly, so a warning is returned for them.
+func (role *gcpRole) validateGlobPatterns() (warnings []string, err error) {
+	labelValues := make([]string, 0, len(role.BoundLabels))
+	for _, v := range role.BoundLabels {
+		labelValues = append(labelValues, v)
+	}
+	sort.Strings(labelValues)
+
+	for _, field := range []struct {
+		name   string
+		values []string
+	}{
+		{"bound_service_accounts", role.BoundServiceAccounts},
+		{"bound_labels", labelValues},
+		{"bound_instance_groups", role.BoundInstanceGroups},
+	} {
+		for _, v := range field.values {
+			if !strings.Contains(v, "*") {
+				continue
+			}
+			// The wildcard of service accounts is handled separately.
+			if field.name == "bound_service_accounts" && v == serviceAccountsWildcard {
+				continue
+			}
+			if !role.AllowGlobPatterns {
+				warnings = append(warnings, fmt.Sprintf(
+					"%s value %q contains '*' but allow_glob_patterns is not set; it will be matched exactly",
+					field.name, v))
+				continue
+			}
+			// Patterns without any letter or digit, such as "*@*", match
+			// every value in practice.
+			if !strings.ContainsFunc(v, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+				return warnings, fmt.Errorf("invalid %s: glob pattern %q matches everything", field.name, v)
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
 // updateIamFields updates IAM-only fields for a role.
 func (role *gcpRole) updateIamFields(data *framework.FieldData, op logical.Operation) (warnings []string, err error) {
 	if allowGCEInference, ok := data.GetOk("allow_gce_inference"); ok {
@@ -321,13 +556,36 @@ func (role *gcpRole) updateGceFields(data *framework.FieldData, op logical.Opera
 	}
 
 	if labelsRaw, ok := data.GetOk("bound_labels"); ok {
-		labels, invalidLabels := gcputil.ParseGcpLabels(labelsRaw.([]string))
+		labels, invalidLabels := parseBoundLabels(labelsRaw.([]string), role.AllowGlobPatterns)
 		if len(invalidLabels) > 0 {
 			return warnings, fmt.Errorf("invalid labels given: %q", invalidLabels)
 		}
 		role.BoundLabels = labels
 	}
 
+	if requireSecureBoot, ok := data.GetOk("require_secure_boot"); ok {
+		role.RequireSecureBoot = requireSecureBoot.(bool)
+	}
+	if requireIntegrityMonitoring, ok := data.GetOk("require_integrity_monitoring"); ok {
+		role.RequireIntegrityMonitoring = requireIntegrityMonitoring.(bool)
+	}
+	if requireConfidentialVM, ok := data.GetOk("require_confidential_vm"); ok {
+		role.RequireConfidentialVM = requireConfidentialVM.(bool)
+	}
+
+	if tags, ok := data.GetOk("bound_network_tags"); ok {
+		role.BoundNetworkTags = strutil.RemoveDuplicates(strutil.TrimStrings(tags.([]string)), false)
+	}
+
+	if families, ok := data.GetOk("bound_image_families"); ok {
+		role.BoundImageFamilies = strutil.RemoveDuplicates(strutil.TrimStrings(families.([]string)), false)
+		for _, f := range role.BoundImageFamilies {
+			if !imageFamilyRegex.MatchString(f) {
+				return warnings, fmt.Errorf("invalid bound_image_families: %q is not formatted as projects/$PROJECT/global/images/family/$FAMILY", f)
+			}
+		}
+	}
+
 	if len(role.Policies) > 0 {
 		role.Policies = strutil.TrimStrings(role.Policies)
 		role.Policies = strutil.RemoveDuplicates(role.Policies, false)
@@ -351,6 +609,67 @@ func (role *gcpRole) updateGceFields(data *framework.FieldData, op logical.Opera
 	return warnings, nil
 }
 
+// updateCloudRunFields updates Cloud Run-only fields for a role.
+func (role *gcpRole) updateCloudRunFields(data *framework.FieldData) (warnings []string, err error) {
+	if services, ok := data.GetOk("bound_services"); ok {
+		role.BoundServices = strutil.RemoveDuplicates(strutil.TrimStrings(services.([]string)), false)
+	}
+
+	if regions, ok := data.GetOk("bound_regions"); ok {
+		role.BoundRegions = strutil.RemoveDuplicates(strutil.TrimStrings(regions.([]string)), false)
+	}
+
+	if labelsRaw, ok := data.GetOk("bound_labels"); ok {
+		labels, invalidLabels := parseBoundLabels(labelsRaw.([]string), role.AllowGlobPatterns)
+		if len(invalidLabels) > 0 {
+			return warnings, fmt.Errorf("invalid labels given: %q", invalidLabels)
+		}
+		role.BoundLabels = labels
+	}
+
+	return warnings, nil
+}
+
+// updateGKEFields updates GKE-only fields for a role.
+func (role *gcpRole) updateGKEFields(data *framework.FieldData) (warnings []string, err error) {
+	if clusters, ok := data.GetOk("bound_clusters"); ok {
+		role.BoundClusters = strutil.RemoveDuplicates(strutil.TrimStrings(clusters.([]string)), false)
+		for _, cluster := range role.BoundClusters {
+			if !gkeClusterRegex.MatchString(cluster) {
+				return warnings, fmt.Errorf("invalid bound_clusters: %q is not formatted as projects/$PROJECT/locations/$LOCATION/clusters/$CLUSTER", cluster)
+			}
+		}
+	}
+
+	if namespaces, ok := data.GetOk("bound_namespaces"); ok {
+		role.BoundNamespaces = strutil.RemoveDuplicates(strutil.TrimStrings(namespaces.([]string)), false)
+	}
+
+	if serviceAccounts, ok := data.GetOk("bound_kubernetes_service_accounts"); ok {
+		role.BoundKubernetesServiceAccounts = strutil.RemoveDuplicates(strutil.TrimStrings(serviceAccounts.([]string)), false)
+	}
+
+	return warnings, nil
+}
+
+// updateUserFields updates user-only fields for a role. Domains and emails
+// are compared case-insensitively, so they are stored in lowercase.
+func (role *gcpRole) updateUserFields(data *framework.FieldData) (warnings []string, err error) {
+	if domains, ok := data.GetOk("bound_hosted_domains"); ok {
+		role.BoundHostedDomains = strutil.RemoveDuplicates(strutil.TrimStrings(domains.([]string)), true)
+	}
+
+	if emails, ok := data.GetOk("bound_emails"); ok {
+		role.BoundEmails = strutil.RemoveDuplicates(strutil.TrimStrings(emails.([]string)), true)
+	}
+
+	if groups, ok := data.GetOk("bound_groups"); ok {
+		role.BoundGroups = strutil.RemoveDuplicates(strutil.TrimStrings(groups.([]string)), true)
+	}
+
+	return warnings, nil
+}
+
 // validateIamFields validates the IAM-only fields for a role.
 func (role *gcpRole) validateForIAM() (warnings []string, err error) {
 	if len(role.BoundServiceAccounts) == 0 {
@@ -392,3 +711,103 @@ func (role *gcpRole) validateForGCE() (warnings []string, err error) {
 
 	return warnings, nil
 }
+
+// validateForGKE validates the GKE-only fields for a role.
+func (role *gcpRole) validateForGKE() (warnings []string, err error) {
+	warnings = []string{}
+
+	if len(role.BoundClusters) == 0 {
+		return warnings, errors.New("GKE role type must have at least one bound cluster")
+	}
+	if len(role.BoundNamespaces) == 0 {
+		return warnings, errors.New(`GKE role type must have at least one bound namespace, or the wildcard "*"`)
+	}
+	if len(role.BoundKubernetesServiceAccounts) == 0 {
+		return warnings, errors.New(`GKE role type must have at least one bound Kubernetes service account, or the wildcard "*"`)
+	}
+
+	// Kubernetes service account tokens do not identify the Google service
+	// account that the Kubernetes service account impersonates.
+	if len(role.BoundServiceAccounts) > 0 {
+		return warnings, errors.New("bound_service_accounts cannot be set for GKE role type, use bound_kubernetes_service_accounts")
+	}
+
+	return warnings, nil
+}
+
+// validateForCloudRun validates the Cloud Run-only fields for a role. All
+// bindings of 'cloudrun' roles are optional.
+func (role *gcpRole) validateForCloudRun() (warnings []string, err error) {
+	warnings = []string{}
+
+	// The ID token only proves the service account. The service or function
+	// and its region are given by the caller, so any workload that can get
+	// an ID token can claim any of the service account's workloads.
+	if len(role.BoundServiceAccounts) == 0 {
+		warnings = append(warnings, "bound_service_accounts is empty: the ID token of a Cloud Run login only proves the service account, "+
+			"so any workload with an ID token can log in by naming a service or function that runs as its service account")
+	}
+
+	return warnings, nil
+}
+
+// validateForUser validates the user-only fields for a role.
+func (role *gcpRole) validateForUser() (warnings []string, err error) {
+	warnings = []string{}
+
+	if len(role.BoundAudiences) == 0 {
+		return warnings, errors.New("user role type must have at least one bound audience")
+	}
+
+	// Any Google account can obtain an ID token, so users must be bound.
+	if len(role.BoundHostedDomains) == 0 && len(role.BoundEmails) == 0 && len(role.BoundGroups) == 0 {
+		return warnings, errors.New("user role type must have at least one of bound_hosted_domains, bound_emails or bound_groups")
+	}
+
+	if len(role.BoundServiceAccounts) > 0 {
+		return warnings, errors.New("bound_service_accounts cannot be set for user role type")
+	}
+	if len(role.BoundProjects) > 0 || role.hasResourceHierarchyBindings() {
+		return warnings, errors.New("bound_projects, bound_folders and bound_organizations cannot be set for user role type")
+	}
+
+	return warnings, nil
+}
+
+// hasResourceHierarchyBindings returns whether the role binds entities on the
+// folders or organizations of their project.
+func (role *gcpRole) hasResourceHierarchyBindings() bool {
+	return len(role.BoundFolders) > 0 || len(role.BoundOrganizations) > 0
+}
+
+// parseResourceIDs parses the numeric IDs of folders or organizations, given
+// either as IDs or as resource names with the given prefix, e.g. "folders/".
+func parseResourceIDs(values []string, prefix string) ([]string, error) {
+	ids := make([]string, 0, len(values))
+	for _, v := range strutil.TrimStrings(values) {
+		id := strings.TrimPrefix(v, prefix)
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a numeric ID or %s<ID> resource name", v, prefix)
+		}
+		ids = append(ids, id)
+	}
+	return strutil.RemoveDuplicates(ids, false), nil
+}
+
+// audiences returns the audiences that tokens must be issued for to login under
+// the role: its bound audiences, or otherwise the audience of the config.
+func (role *gcpRole) audiences(conf *gcpConfig) []string {
+	if len(role.BoundAudiences) > 0 {
+		return role.BoundAudiences
+	}
+	if conf.Audience != "" {
+		return []string{conf.Audience}
+	}
+	return nil
+}
+
+// googleSignedTokensOnly returns whether only JWTs signed with the Google OAuth2
+// certificates are accepted to login under the role.
+func (role *gcpRole) googleSignedTokensOnly(conf *gcpConfig) bool {
+	return conf.GoogleSignedTokensOnly || len(role.BoundAudiences) > 0
+}
@@ -5,9 +5,12 @@ package gcp
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
+	"github.com/hashicorp/go-gcp-common/gcputil"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/logical"
 )
@@ -75,3 +78,78 @@ func zoneOrRegionFromSelfLink(selfLink string) (string, string, error) {
 
 	return zone, region, nil
 }
+
+// boundListContains returns whether value is in the bound values of a role. If
+// globs is true, the bound values are glob patterns in which '*' matches any
+// sequence of characters.
+func boundListContains(bound []string, value string, globs bool) bool {
+	if !globs {
+		return strutil.StrListContains(bound, value)
+	}
+	for _, pattern := range bound {
+		if globMatch(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch returns whether value matches pattern, in which '*' matches any
+// sequence of characters anywhere in the pattern and all other characters
+// match themselves.
+func globMatch(pattern, value string) bool {
+	// p and v are the current positions in pattern and value. star and match
+	// are the positions after the last '*' and the value position it was
+	// matched from, to backtrack to.
+	p, v, star, match := 0, 0, -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, match = p+1, v
+			p++
+		case p < len(pattern) && pattern[p] == value[v]:
+			p++
+			v++
+		case star != -1:
+			match++
+			p, v = star, match
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// boundValueMatches returns whether value matches a single bound value of a
+// role, which is a glob pattern if globs is true.
+func boundValueMatches(bound, value string, globs bool) bool {
+	return boundListContains([]string{bound}, value, globs)
+}
+
+// boundLabelGlobRegex is the format of bound labels of roles which allow glob
+// patterns, where label values may also contain '*'.
+var boundLabelGlobRegex = regexp.MustCompile(`^([a-z][\w-]*):([\w*-]*)$`)
+
+// parseBoundLabels parses "key:value" bound labels like
+// gcputil.ParseGcpLabels, additionally allowing '*' in values if globs is
+// true.
+func parseBoundLabels(labels []string, globs bool) (parsed map[string]string, invalid []string) {
+	if !globs {
+		return gcputil.ParseGcpLabels(labels)
+	}
+
+	parsed = map[string]string{}
+	invalid = []string{}
+	for _, l := range labels {
+		m := boundLabelGlobRegex.FindStringSubmatch(l)
+		if m == nil {
+			invalid = append(invalid, l)
+			continue
+		}
+		parsed[m[1]] = m[2]
+	}
+	return parsed, invalid
+}
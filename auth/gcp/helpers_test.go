@@ -5,6 +5,7 @@ package gcp
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -120,3 +121,86 @@ func TestZoneOrRegionFromSelfLink(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBoundLabels(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		labels  []string
+		globs   bool
+		parsed  map[string]string
+		invalid []string
+	}{
+		{
+			[]string{"env:prod", "team:web"},
+			false,
+			map[string]string{"env": "prod", "team": "web"},
+			[]string{},
+		},
+		{
+			[]string{"env:prod-*"},
+			false,
+			map[string]string{},
+			[]string{"env:prod-*"},
+		},
+		{
+			[]string{"env:prod-*", "team:web"},
+			true,
+			map[string]string{"env": "prod-*", "team": "web"},
+			[]string{},
+		},
+		{
+			[]string{"env*:prod", "Env:prod"},
+			true,
+			map[string]string{},
+			[]string{"env*:prod", "Env:prod"},
+		},
+	}
+
+	for i, tc := range cases {
+		tc := tc
+		name := fmt.Sprintf("%d", i)
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			parsed, invalid := parseBoundLabels(tc.labels, tc.globs)
+			if !reflect.DeepEqual(parsed, tc.parsed) {
+				t.Errorf("expected %q to parse to %v, got %v", tc.labels, tc.parsed, parsed)
+			}
+			if !reflect.DeepEqual(invalid, tc.invalid) {
+				t.Errorf("expected %q to be invalid, got %q", tc.invalid, invalid)
+			}
+		})
+	}
+}
+
+func TestBoundListContains(t *testing.T) {
+	cases := []struct {
+		bound    []string
+		value    string
+		globs    bool
+		expected bool
+	}{
+		{[]string{"ci@prj.iam.gserviceaccount.com"}, "ci@prj.iam.gserviceaccount.com", false, true},
+		{[]string{"ci-*@prj.iam.gserviceaccount.com"}, "ci-web@prj.iam.gserviceaccount.com", false, false},
+		{[]string{"ci-*@prj.iam.gserviceaccount.com"}, "ci-*@prj.iam.gserviceaccount.com", false, true},
+		{[]string{"*-ci@prj.iam.gserviceaccount.com"}, "web-ci@prj.iam.gserviceaccount.com", true, true},
+		{[]string{"prod-*"}, "prod-web", true, true},
+		{[]string{"ci-*@prj-*.iam.gserviceaccount.com"}, "ci-web@prj-dev.iam.gserviceaccount.com", true, true},
+		{[]string{"ci-*@prj-*.iam.gserviceaccount.com"}, "ci-@prj-.iam.gserviceaccount.com", true, true},
+		{[]string{"ci-*@prj-*.iam.gserviceaccount.com"}, "ci-web@other.iam.gserviceaccount.com", true, false},
+		{[]string{"ci-*@prj-*.iam.gserviceaccount.com"}, "ci-web@prj-dev.iam.gserviceaccount.com.evil", true, false},
+		{[]string{"web-*-a"}, "web-x-a-b-a", true, true},
+		{[]string{"web-*-a"}, "web-x-a-b", true, false},
+		{[]string{"a*b*c"}, "abc", true, true},
+		{[]string{"a*b*c"}, "acb", true, false},
+		{[]string{"other", "web-*"}, "web-1", true, true},
+	}
+
+	for _, tc := range cases {
+		if actual := boundListContains(tc.bound, tc.value, tc.globs); actual != tc.expected {
+			t.Errorf("expected boundListContains(%q, %q, %t) to be %t", tc.bound, tc.value, tc.globs, tc.expected)
+		}
+	}
+}
@@ -51,6 +51,55 @@ var (
 		},
 		AvailableToAdd: []string{},
 	}
+
+	// 'cloudrun' logins are aliased by service account unique ID. The
+	// default fields below identify the service or function that logged in.
+	cloudRunAuthMetadataFields = &authmetadata.Fields{
+		FieldName: "cloudrun_metadata",
+		Default: []string{
+			"function_name",
+			"project_id",
+			"region",
+			"revision_name",
+			"role",
+			"service_account_id",
+			"service_account_email",
+			"service_name",
+		},
+		AvailableToAdd: []string{},
+	}
+
+	// 'gke' logins are aliased by Kubernetes service account UID. The
+	// default fields below identify the cluster and service account that
+	// logged in.
+	gkeAuthMetadataFields = &authmetadata.Fields{
+		FieldName: "gke_metadata",
+		Default: []string{
+			"cluster_name",
+			"location",
+			"namespace",
+			"project_id",
+			"role",
+			"service_account_name",
+			"service_account_uid",
+		},
+		AvailableToAdd: []string{
+			"pod_name",
+			"pod_uid",
+		},
+	}
+
+	// 'user' logins are aliased by the unique ID of the Google account.
+	userAuthMetadataFields = &authmetadata.Fields{
+		FieldName: "user_metadata",
+		Default: []string{
+			"email",
+			"hosted_domain",
+			"role",
+			"user_id",
+		},
+		AvailableToAdd: []string{},
+	}
 )
 
 func pathConfig(b *GcpAuthBackend) *framework.Path {
@@ -82,7 +131,26 @@ If not specified, will use application default credentials`,
 				Default:     defaultGCEAlias,
 				Description: "Indicates what value to use when generating an alias for GCE authentications.",
 			},
-			gceAuthMetadataFields.FieldName: authmetadata.FieldSchema(gceAuthMetadataFields),
+			gceAuthMetadataFields.FieldName:      authmetadata.FieldSchema(gceAuthMetadataFields),
+			cloudRunAuthMetadataFields.FieldName: authmetadata.FieldSchema(cloudRunAuthMetadataFields),
+			gkeAuthMetadataFields.FieldName:      authmetadata.FieldSchema(gkeAuthMetadataFields),
+			userAuthMetadataFields.FieldName:     authmetadata.FieldSchema(userAuthMetadataFields),
+			"audience": {
+				Type: framework.TypeString,
+				Description: "Audience that Google-signed ID tokens ('cloudrun' roles) and Kubernetes " +
+					"service account tokens ('gke' roles) must be issued for.",
+			},
+			"google_signed_tokens_only": {
+				Type: framework.TypeBool,
+				Description: "If true, only JWTs signed with the Google OAuth2 certificates, such as " +
+					"ID tokens, are accepted. JWTs signed with service account keys, including " +
+					"those of the signJwt method, are rejected.",
+			},
+			"google_groups_cache_ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: "Duration for which the Google Groups of service accounts are cached " +
+					"for roles with add_google_group_aliases. Defaults to 5 minutes.",
+			},
 			"custom_endpoint": {
 				Type:        framework.TypeKVPairs,
 				Description: `Specifies overrides for various Google API Service Endpoints used in requests.`,
@@ -125,6 +193,13 @@ signed JWT requests passed in on login. The credentials should have the followin
 
 iam AUTH:
 * iam.serviceAccountKeys.get
+
+cloudrun AUTH:
+* run.services.get, run.revisions.get
+* cloudfunctions.functions.get
+
+user AUTH (with bound_groups or add_group_aliases), and roles with add_google_group_aliases:
+* Groups Reader admin role in Google Workspace or Cloud Identity
 `,
 	}
 
@@ -173,8 +248,11 @@ func (b *GcpAuthBackend) pathConfigRead(ctx context.Context, req *logical.Reques
 	}
 
 	resp := map[string]interface{}{
-		gceAuthMetadataFields.FieldName: config.GCEAuthMetadata.AuthMetadata(),
-		iamAuthMetadataFields.FieldName: config.IAMAuthMetadata.AuthMetadata(),
+		gceAuthMetadataFields.FieldName:      config.GCEAuthMetadata.AuthMetadata(),
+		iamAuthMetadataFields.FieldName:      config.IAMAuthMetadata.AuthMetadata(),
+		cloudRunAuthMetadataFields.FieldName: config.CloudRunAuthMetadata.AuthMetadata(),
+		gkeAuthMetadataFields.FieldName:      config.GKEAuthMetadata.AuthMetadata(),
+		userAuthMetadataFields.FieldName:     config.UserAuthMetadata.AuthMetadata(),
 	}
 
 	if config.Credentials != nil {
@@ -198,6 +276,15 @@ func (b *GcpAuthBackend) pathConfigRead(ctx context.Context, req *logical.Reques
 	if v := config.GCEAliasType; v != "" {
 		resp["gce_alias"] = v
 	}
+	if v := config.Audience; v != "" {
+		resp["audience"] = v
+	}
+	if config.GoogleSignedTokensOnly {
+		resp["google_signed_tokens_only"] = true
+	}
+	if v := config.GoogleGroupsCacheTTL; v != 0 {
+		resp["google_groups_cache_ttl"] = int64(v.Seconds())
+	}
 
 	endpoints := make(map[string]string)
 	if v := config.APICustomEndpoint; v != "" {
@@ -212,6 +299,18 @@ func (b *GcpAuthBackend) pathConfigRead(ctx context.Context, req *logical.Reques
 	if v := config.ComputeCustomEndpoint; v != "" {
 		endpoints["compute"] = v
 	}
+	if v := config.RunCustomEndpoint; v != "" {
+		endpoints["run"] = v
+	}
+	if v := config.FunctionsCustomEndpoint; v != "" {
+		endpoints["functions"] = v
+	}
+	if v := config.ContainerCustomEndpoint; v != "" {
+		endpoints["container"] = v
+	}
+	if v := config.CloudIdentityCustomEndpoint; v != "" {
+		endpoints["cloudidentity"] = v
+	}
 	if len(endpoints) > 0 {
 		resp["custom_endpoint"] = endpoints
 	}
@@ -229,8 +328,11 @@ func (b *GcpAuthBackend) pathConfigRead(ctx context.Context, req *logical.Reques
 // This assumes the caller has already obtained the backend's config lock.
 func (b *GcpAuthBackend) config(ctx context.Context, s logical.Storage) (*gcpConfig, error) {
 	config := &gcpConfig{
-		GCEAuthMetadata: authmetadata.NewHandler(gceAuthMetadataFields),
-		IAMAuthMetadata: authmetadata.NewHandler(iamAuthMetadataFields),
+		GCEAuthMetadata:      authmetadata.NewHandler(gceAuthMetadataFields),
+		IAMAuthMetadata:      authmetadata.NewHandler(iamAuthMetadataFields),
+		CloudRunAuthMetadata: authmetadata.NewHandler(cloudRunAuthMetadataFields),
+		GKEAuthMetadata:      authmetadata.NewHandler(gkeAuthMetadataFields),
+		UserAuthMetadata:     authmetadata.NewHandler(userAuthMetadataFields),
 	}
 	entry, err := s.Get(ctx, "config")
 	if err != nil {
@@ -242,5 +344,14 @@ func (b *GcpAuthBackend) config(ctx context.Context, s logical.Storage) (*gcpCon
 	if err := entry.DecodeJSON(config); err != nil {
 		return nil, err
 	}
+	if config.CloudRunAuthMetadata == nil {
+		config.CloudRunAuthMetadata = authmetadata.NewHandler(cloudRunAuthMetadataFields)
+	}
+	if config.GKEAuthMetadata == nil {
+		config.GKEAuthMetadata = authmetadata.NewHandler(gkeAuthMetadataFields)
+	}
+	if config.UserAuthMetadata == nil {
+		config.UserAuthMetadata = authmetadata.NewHandler(userAuthMetadataFields)
+	}
 	return config, nil
 }
@@ -7,6 +7,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-gcp-common/gcputil"
 	"github.com/openbao/openbao/sdk/v2/framework"
@@ -37,9 +38,9 @@ func TestBackend_PathConfigRead(t *testing.T) {
 			t.Fatal("expected non-nil response")
 		}
 		// These fields are always returned on read
-		// 2 Metadata response fields
-		if len(resp.Data) != 2 {
-			t.Fatal("expected 2 fields")
+		// 5 Metadata response fields
+		if len(resp.Data) != 5 {
+			t.Fatal("expected 5 fields")
 		}
 		expectedResp := &logical.Response{
 			Data: map[string]interface{}{
@@ -60,6 +61,31 @@ func TestBackend_PathConfigRead(t *testing.T) {
 					"service_account_email",
 					"zone",
 				},
+				"cloudrun_metadata": []string{
+					"function_name",
+					"project_id",
+					"region",
+					"revision_name",
+					"role",
+					"service_account_id",
+					"service_account_email",
+					"service_name",
+				},
+				"gke_metadata": []string{
+					"cluster_name",
+					"location",
+					"namespace",
+					"project_id",
+					"role",
+					"service_account_name",
+					"service_account_uid",
+				},
+				"user_metadata": []string{
+					"email",
+					"hosted_domain",
+					"role",
+					"user_id",
+				},
 			},
 		}
 		if !reflect.DeepEqual(resp, expectedResp) {
@@ -81,14 +107,21 @@ func TestBackend_PathConfigRead(t *testing.T) {
 				PrivateKey:   "key",
 				ProjectId:    "project",
 			},
-			IAMAliasType:          defaultIAMAlias,
-			IAMAuthMetadata:       authmetadata.NewHandler(iamAuthMetadataFields),
-			GCEAliasType:          defaultGCEAlias,
-			GCEAuthMetadata:       authmetadata.NewHandler(gceAuthMetadataFields),
-			APICustomEndpoint:     "https://www.example.com",
-			IAMCustomEndpoint:     "https://iam.example.com",
-			CRMCustomEndpoint:     "https://cloudresourcemanager.example.com",
-			ComputeCustomEndpoint: "https://compute.example.com",
+			IAMAliasType:                defaultIAMAlias,
+			IAMAuthMetadata:             authmetadata.NewHandler(iamAuthMetadataFields),
+			GCEAliasType:                defaultGCEAlias,
+			GCEAuthMetadata:             authmetadata.NewHandler(gceAuthMetadataFields),
+			Audience:                    "https://vault.example.com",
+			GoogleSignedTokensOnly:      true,
+			GoogleGroupsCacheTTL:        10 * time.Minute,
+			APICustomEndpoint:           "https://www.example.com",
+			IAMCustomEndpoint:           "https://iam.example.com",
+			CRMCustomEndpoint:           "https://cloudresourcemanager.example.com",
+			ComputeCustomEndpoint:       "https://compute.example.com",
+			RunCustomEndpoint:           "https://run.example.com",
+			FunctionsCustomEndpoint:     "https://cloudfunctions.example.com",
+			ContainerCustomEndpoint:     "https://container.example.com",
+			CloudIdentityCustomEndpoint: "https://cloudidentity.example.com",
 		})
 		if err != nil {
 			t.Fatal(err)
@@ -130,11 +163,43 @@ func TestBackend_PathConfigRead(t *testing.T) {
 				"service_account_email",
 				"zone",
 			},
+			"cloudrun_metadata": []string{
+				"function_name",
+				"project_id",
+				"region",
+				"revision_name",
+				"role",
+				"service_account_id",
+				"service_account_email",
+				"service_name",
+			},
+			"gke_metadata": []string{
+				"cluster_name",
+				"location",
+				"namespace",
+				"project_id",
+				"role",
+				"service_account_name",
+				"service_account_uid",
+			},
+			"user_metadata": []string{
+				"email",
+				"hosted_domain",
+				"role",
+				"user_id",
+			},
+			"audience":                  "https://vault.example.com",
+			"google_signed_tokens_only": true,
+			"google_groups_cache_ttl":   int64(600),
 			"custom_endpoint": map[string]string{
-				"api":     "https://www.example.com",
-				"iam":     "https://iam.example.com",
-				"crm":     "https://cloudresourcemanager.example.com",
-				"compute": "https://compute.example.com",
+				"api":           "https://www.example.com",
+				"iam":           "https://iam.example.com",
+				"crm":           "https://cloudresourcemanager.example.com",
+				"compute":       "https://compute.example.com",
+				"run":           "https://run.example.com",
+				"functions":     "https://cloudfunctions.example.com",
+				"container":     "https://container.example.com",
+				"cloudidentity": "https://cloudidentity.example.com",
 			},
 		}
 
@@ -430,6 +495,9 @@ func TestConfig_Update(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.original.GCEAuthMetadata = authmetadata.NewHandler(gceAuthMetadataFields)
 			tc.original.IAMAuthMetadata = authmetadata.NewHandler(gceAuthMetadataFields)
+			tc.original.CloudRunAuthMetadata = authmetadata.NewHandler(cloudRunAuthMetadataFields)
+			tc.original.GKEAuthMetadata = authmetadata.NewHandler(gkeAuthMetadataFields)
+			tc.original.UserAuthMetadata = authmetadata.NewHandler(userAuthMetadataFields)
 
 			if tc.fieldData != nil {
 				var b GcpAuthBackend
@@ -5,21 +5,25 @@ package gcp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
 	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-gcp-common/gcputil"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/helper/cidrutil"
 	"github.com/openbao/openbao/sdk/v2/helper/policyutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
+	"google.golang.org/api/cloudidentity/v1"
 	"google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/iam/v1"
@@ -28,6 +32,12 @@ import (
 const (
 	expectedJwtAudTemplate string = "vault/%s"
 	jwtExpToleranceSec            = 60
+
+	// gkeIssuerPrefix is the prefix of the OIDC issuers of GKE clusters,
+	// followed by the resource name of the cluster.
+	gkeIssuerPrefix = "https://container.googleapis.com/v1/"
+
+	defaultContainerEndpoint = "https://container.googleapis.com/"
 )
 
 var (
@@ -36,6 +46,9 @@ var (
 		jose.ES256,
 		jose.HS256,
 	}
+
+	// googleIDTokenIssuers are the issuers of ID tokens signed by Google.
+	googleIDTokenIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
 )
 
 func pathLogin(b *GcpAuthBackend) *framework.Path {
@@ -55,8 +68,31 @@ func pathLogin(b *GcpAuthBackend) *framework.Path {
 			"jwt": {
 				Type: framework.TypeString,
 				Description: `
-A signed JWT. This is either a self-signed service account JWT ('iam' roles only) or a
-GCE identity metadata token ('iam', 'gce' roles).`,
+A signed JWT. This is either a self-signed service account JWT ('iam' roles only), a
+GCE identity metadata token ('iam', 'gce' roles), a Google-signed ID token of a service
+account ('cloudrun' roles) or user ('user' roles), or a Kubernetes service account token
+of a GKE pod ('gke' roles).`,
+			},
+			"service": {
+				Type:        framework.TypeString,
+				Description: `Name of the Cloud Run service that is authenticating ('cloudrun' roles only).`,
+			},
+			"revision": {
+				Type:        framework.TypeString,
+				Description: `Optional revision of the Cloud Run service that is authenticating ('cloudrun' roles only).`,
+			},
+			"function": {
+				Type:        framework.TypeString,
+				Description: `Name of the Cloud Function that is authenticating ('cloudrun' roles only).`,
+			},
+			"region": {
+				Type:        framework.TypeString,
+				Description: `Region of the Cloud Run service or Cloud Function ('cloudrun' roles only).`,
+			},
+			"project": {
+				Type: framework.TypeString,
+				Description: `Project of the Cloud Run service or Cloud Function ('cloudrun' roles only). ` +
+					`Defaults to the project of the service account.`,
 			},
 		},
 
@@ -118,15 +154,37 @@ func (b *GcpAuthBackend) pathLogin(ctx context.Context, req *logical.Request, da
 		}
 	}
 
+	var resp *logical.Response
 	roleType := loginInfo.Role.RoleType
 	switch roleType {
 	case iamRoleType:
-		return b.pathIamLogin(ctx, req, loginInfo)
+		resp, err = b.pathIamLogin(ctx, req, loginInfo)
 	case gceRoleType:
-		return b.pathGceLogin(ctx, req, loginInfo)
+		resp, err = b.pathGceLogin(ctx, req, loginInfo)
+	case cloudRunRoleType:
+		resp, err = b.pathCloudRunLogin(ctx, req, loginInfo)
+	case gkeRoleType:
+		resp, err = b.pathGKELogin(ctx, req, loginInfo)
+	case userRoleType:
+		resp, err = b.pathUserLogin(ctx, req, loginInfo)
 	default:
 		return logical.ErrorResponse("login against role type %q is unsupported", roleType), nil
 	}
+	if err != nil || resp.IsError() {
+		return resp, err
+	}
+
+	// Only record JWTs of actual logins, not of alias lookaheads.
+	if loginInfo.Role.ReplayProtection && req.Operation == logical.UpdateOperation {
+		if err := b.recordJWT(ctx, req.Storage, loginInfo, data.Get("jwt").(string)); err != nil {
+			if errors.Is(err, errJWTReplayed) {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+			return nil, err
+		}
+	}
+
+	return resp, nil
 }
 
 func (b *GcpAuthBackend) pathLoginRenew(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
@@ -153,6 +211,18 @@ func (b *GcpAuthBackend) pathLoginRenew(ctx context.Context, req *logical.Reques
 		if err := b.pathGceRenew(ctx, req, roleName, role); err != nil {
 			return logical.ErrorResponse(err.Error()), nil
 		}
+	case cloudRunRoleType:
+		if err := b.pathCloudRunRenew(ctx, req, roleName, role); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	case gkeRoleType:
+		if err := b.pathGKERenew(ctx, req, roleName, role); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	case userRoleType:
+		if err := b.pathUserRenew(ctx, req, roleName, role); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
 	default:
 		return nil, fmt.Errorf("unexpected role type %q for login renewal", role.RoleType)
 	}
@@ -180,6 +250,26 @@ type gcpLoginInfo struct {
 
 	// Metadata from a GCE instance identity token.
 	GceMetadata *gcputil.GCEIdentityMetadata
+
+	// Email of the service account or user of a Google-signed ID token.
+	Email string
+
+	// Hosted domain of the user of a Google-signed ID token.
+	HostedDomain string
+
+	// Cloud Run service or Cloud Function of a 'cloudrun' login.
+	CloudRun *cloudRunWorkload
+
+	// Kubernetes service account of a 'gke' login.
+	GKE *gkeWorkload
+}
+
+// idTokenClaims are the claims of Google-signed ID tokens identifying the
+// service account or user.
+type idTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	HostedDomain  string `json:"hd"`
 }
 
 func (b *GcpAuthBackend) parseAndValidateJwt(ctx context.Context, s logical.Storage, data *framework.FieldData) (*gcpLoginInfo, error) {
@@ -219,7 +309,12 @@ func (b *GcpAuthBackend) parseAndValidateJwt(ctx context.Context, s logical.Stor
 		return nil, fmt.Errorf("unable to parse signed JWT: %w", err)
 	}
 
-	key, err := b.getSigningKey(ctx, jwtVal, signedJwt.(string), loginInfo.Role, conf.APICustomEndpoint)
+	// Kubernetes service account tokens are signed by the cluster, not Google.
+	if loginInfo.Role.RoleType == gkeRoleType {
+		return b.parseAndValidateGKEToken(ctx, conf, loginInfo, jwtVal)
+	}
+
+	key, err := b.getSigningKey(ctx, jwtVal, signedJwt.(string), loginInfo.Role, conf.APICustomEndpoint, loginInfo.Role.googleSignedTokensOnly(conf))
 	if err != nil {
 		return nil, fmt.Errorf("unable to get public key for signed JWT: %w", err)
 	}
@@ -227,12 +322,23 @@ func (b *GcpAuthBackend) parseAndValidateJwt(ctx context.Context, s logical.Stor
 	// Parse claims and verify signature.
 	baseClaims := &jwt.Claims{}
 	customClaims := &gcputil.CustomJWTClaims{}
+	idClaims := &idTokenClaims{}
 
-	if err = jwtVal.Claims(key, baseClaims, customClaims); err != nil {
+	if err = jwtVal.Claims(key, baseClaims, customClaims, idClaims); err != nil {
 		return nil, err
 	}
 
-	if err = validateBaseJWTClaims(baseClaims, loginInfo.RoleName); err != nil {
+	switch {
+	case loginInfo.Role.RoleType == cloudRunRoleType || loginInfo.Role.RoleType == userRoleType:
+		err = validateIDTokenClaims(baseClaims, idClaims, loginInfo.Role.audiences(conf))
+	case len(loginInfo.Role.BoundAudiences) > 0:
+		// 'iam' and 'gce' roles with bound audiences accept ID tokens instead
+		// of JWTs with an audience of the role.
+		err = validateIDTokenClaims(baseClaims, idClaims, loginInfo.Role.BoundAudiences)
+	default:
+		err = validateBaseJWTClaims(baseClaims, loginInfo.RoleName)
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -247,10 +353,22 @@ func (b *GcpAuthBackend) parseAndValidateJwt(ctx context.Context, s logical.Stor
 			return nil, errors.New("expected JWT to have claims with GCE metadata")
 		}
 	}
+
+	if loginInfo.Role.RoleType == cloudRunRoleType {
+		loginInfo.Email = idClaims.Email
+		if loginInfo.CloudRun, err = cloudRunWorkloadFromLogin(data, idClaims.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	if loginInfo.Role.RoleType == userRoleType {
+		loginInfo.Email = idClaims.Email
+		loginInfo.HostedDomain = idClaims.HostedDomain
+	}
 	return loginInfo, nil
 }
 
-func (b *GcpAuthBackend) getSigningKey(ctx context.Context, token *jwt.JSONWebToken, rawToken string, role *gcpRole, endpoint string) (interface{}, error) {
+func (b *GcpAuthBackend) getSigningKey(ctx context.Context, token *jwt.JSONWebToken, rawToken string, role *gcpRole, endpoint string, googleSignedOnly bool) (interface{}, error) {
 	b.Logger().Debug("Getting signing Key for JWT")
 
 	if len(token.Headers) != 1 {
@@ -271,6 +389,11 @@ func (b *GcpAuthBackend) getSigningKey(ctx context.Context, token *jwt.JSONWebTo
 		return nil, err
 	}
 
+	if googleSignedOnly {
+		return nil, fmt.Errorf("key %q of JWT subject %q is not a Google OAuth2 certificate: only "+
+			"Google-signed ID tokens are accepted, JWTs signed with service account keys are rejected: %w", kid, saId, gErr)
+	}
+
 	if role.RoleType == iamRoleType {
 		// If that failed, and the authentication type is IAM, try to get account-specific key
 		b.Logger().Debug("Unable to get Google-wide OAuth2 Key, trying service-account public key")
@@ -280,7 +403,7 @@ func (b *GcpAuthBackend) getSigningKey(ctx context.Context, token *jwt.JSONWebTo
 		}
 		return nil, fmt.Errorf("unable to get public key %q for JWT subject %q: %w", kid, saId, saErr)
 	}
-	return nil, fmt.Errorf("unable to get public key %q for JWT subject %q: no Google OAuth2 provider key found for GCE role", kid, saId)
+	return nil, fmt.Errorf("unable to get public key %q for JWT subject %q: no Google OAuth2 provider key found for %s role", kid, saId, role.RoleType)
 }
 
 // getJWTSubject grabs 'sub' claim given an unverified signed JWT.
@@ -301,6 +424,61 @@ func getJWTSubject(signedJwt string) (string, error) {
 }
 
 func validateBaseJWTClaims(c *jwt.Claims, roleName string) error {
+	if err := validateJWTExpiry(c); err != nil {
+		return err
+	}
+
+	if len(c.Subject) == 0 {
+		return errors.New("expected JWT to have 'sub' claim with service account id or email")
+	}
+
+	expectedAudSuffix := fmt.Sprintf(expectedJwtAudTemplate, roleName)
+	for _, aud := range c.Audience {
+		if !strings.HasSuffix(aud, expectedAudSuffix) {
+			return fmt.Errorf("at least one of the JWT claim 'aud' must end in %q", expectedAudSuffix)
+		}
+	}
+
+	return nil
+}
+
+// validateIDTokenClaims validates the claims of a Google-signed ID token,
+// which must be issued for one of the given audiences.
+func validateIDTokenClaims(c *jwt.Claims, idClaims *idTokenClaims, audiences []string) error {
+	if len(audiences) == 0 {
+		return errors.New("no audience is configured for ID tokens")
+	}
+
+	if err := validateJWTExpiry(c); err != nil {
+		return err
+	}
+
+	if !strutil.StrListContains(googleIDTokenIssuers, c.Issuer) {
+		return fmt.Errorf("ID token must be issued by Google, got issuer %q", c.Issuer)
+	}
+
+	if len(c.Audience) == 0 || !strutil.StrListSubset(audiences, c.Audience) {
+		quoted := make([]string, len(audiences))
+		for i, aud := range audiences {
+			quoted[i] = strconv.Quote(aud)
+		}
+		return fmt.Errorf("ID token claim 'aud' must be %s", strings.Join(quoted, " or "))
+	}
+
+	if len(c.Subject) == 0 {
+		return errors.New("expected ID token to have 'sub' claim with unique account id")
+	}
+
+	if idClaims.Email == "" || !idClaims.EmailVerified {
+		return errors.New("expected ID token to have verified 'email' claim")
+	}
+
+	return nil
+}
+
+// validateJWTExpiry validates that the JWT is not expired and expires within
+// the maximum allowed expiration.
+func validateJWTExpiry(c *jwt.Claims) error {
 	exp := c.Expiry.Time()
 	tolDelt := time.Second * jwtExpToleranceSec
 	// Compare expiration to current time with tolerance
@@ -315,17 +493,6 @@ func validateBaseJWTClaims(c *jwt.Claims, roleName string) error {
 		return fmt.Errorf("JWT must expire in %d minutes, expires in %v", maxJwtExpMaxMinutes, expIn)
 	}
 
-	if len(c.Subject) == 0 {
-		return errors.New("expected JWT to have 'sub' claim with service account id or email")
-	}
-
-	expectedAudSuffix := fmt.Sprintf(expectedJwtAudTemplate, roleName)
-	for _, aud := range c.Audience {
-		if !strings.HasSuffix(aud, expectedAudSuffix) {
-			return fmt.Errorf("at least one of the JWT claim 'aud' must end in %q", expectedAudSuffix)
-		}
-	}
-
 	return nil
 }
 
@@ -346,6 +513,8 @@ func (b *GcpAuthBackend) pathIamLogin(ctx context.Context, req *logical.Request,
 	// TODO(emilymye): move to general JWT validation once custom expiry is supported for other JWT types.
 	if loginInfo.GceMetadata != nil {
 		b.Logger().Info("GCE Metadata found in JWT, skipping custom expiry check")
+	} else if len(role.BoundAudiences) > 0 {
+		b.Logger().Debug("ID token expiry is set by Google, skipping custom expiry check")
 	} else if loginInfo.JWTClaims.Expiry.Time().After(time.Now().Add(role.MaxJwtExp)) {
 		return logical.ErrorResponse("role requires that service account JWTs expire within %d seconds", int(role.MaxJwtExp/time.Second)), nil
 	}
@@ -385,7 +554,7 @@ func (b *GcpAuthBackend) pathIamLogin(ctx context.Context, req *logical.Request,
 	}
 
 	// Validate service account can login against role.
-	if err := b.authorizeIAMServiceAccount(serviceAccount, role); err != nil {
+	if err := b.authorizeIAMServiceAccount(ctx, req.Storage, serviceAccount, role); err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
@@ -417,6 +586,14 @@ func (b *GcpAuthBackend) pathIamLogin(ctx context.Context, req *logical.Request,
 		resp.Auth.GroupAliases = aliases
 	}
 
+	if role.AddGoogleGroupAliases {
+		aliases, err := b.serviceAccountGroupAliases(ctx, req.Storage, conf, serviceAccount.Email)
+		if err != nil {
+			return nil, err
+		}
+		resp.Auth.GroupAliases = append(resp.Auth.GroupAliases, aliases...)
+	}
+
 	return resp, nil
 }
 
@@ -452,7 +629,7 @@ func (b *GcpAuthBackend) pathIamRenew(ctx context.Context, req *logical.Request,
 		return fmt.Errorf("GCE inferrence is no longer allowed for role %s", roleName)
 	}
 
-	if err := b.authorizeIAMServiceAccount(serviceAccount, role); err != nil {
+	if err := b.authorizeIAMServiceAccount(ctx, req.Storage, serviceAccount, role); err != nil {
 		return fmt.Errorf("service account is no longer authorized for role %s", roleName)
 	}
 
@@ -460,9 +637,9 @@ func (b *GcpAuthBackend) pathIamRenew(ctx context.Context, req *logical.Request,
 }
 
 // validateAgainstIAMRole returns an error if the given IAM service account is not authorized for the role.
-func (b *GcpAuthBackend) authorizeIAMServiceAccount(serviceAccount *iam.ServiceAccount, role *gcpRole) error {
-	if len(role.BoundProjects) > 0 && !strutil.StrListContains(role.BoundProjects, serviceAccount.ProjectId) {
-		return fmt.Errorf("service account %q not in bound projects %+v", serviceAccount.Email, role.BoundProjects)
+func (b *GcpAuthBackend) authorizeIAMServiceAccount(ctx context.Context, s logical.Storage, serviceAccount *iam.ServiceAccount, role *gcpRole) error {
+	if err := b.authorizeProject(ctx, s, role, serviceAccount.ProjectId); err != nil {
+		return fmt.Errorf("service account %q %w", serviceAccount.Email, err)
 	}
 
 	// Check if role has the wildcard as the only service account.
@@ -471,8 +648,8 @@ func (b *GcpAuthBackend) authorizeIAMServiceAccount(serviceAccount *iam.ServiceA
 	}
 
 	// Check for service account id/email.
-	if strutil.StrListContains(role.BoundServiceAccounts, serviceAccount.Email) ||
-		strutil.StrListContains(role.BoundServiceAccounts, serviceAccount.UniqueId) {
+	if boundListContains(role.BoundServiceAccounts, serviceAccount.Email, role.AllowGlobPatterns) ||
+		boundListContains(role.BoundServiceAccounts, serviceAccount.UniqueId, role.AllowGlobPatterns) {
 		return nil
 	}
 
@@ -489,8 +666,8 @@ func (b *GcpAuthBackend) pathGceLogin(ctx context.Context, req *logical.Request,
 		return logical.ErrorResponse("could not get GCE metadata from given JWT"), nil
 	}
 
-	if len(role.BoundProjects) > 0 && !strutil.StrListContains(role.BoundProjects, metadata.ProjectId) {
-		return logical.ErrorResponse("instance %q (project %q) not in bound projects %+v", metadata.InstanceId, metadata.ProjectId, role.BoundProjects), nil
+	if err := b.authorizeProject(ctx, req.Storage, role, metadata.ProjectId); err != nil {
+		return logical.ErrorResponse("instance %q (project %q) %s", metadata.InstanceId, metadata.ProjectId, err), nil
 	}
 
 	// Verify instance exists.
@@ -570,6 +747,14 @@ func (b *GcpAuthBackend) pathGceLogin(ctx context.Context, req *logical.Request,
 		}
 		resp.Auth.GroupAliases = aliases
 	}
+
+	if role.AddGoogleGroupAliases {
+		aliases, err := b.serviceAccountGroupAliases(ctx, req.Storage, conf, serviceAccount.Email)
+		if err != nil {
+			return nil, err
+		}
+		resp.Auth.GroupAliases = append(resp.Auth.GroupAliases, aliases...)
+	}
 	return resp, nil
 }
 
@@ -589,16 +774,13 @@ func (b *GcpAuthBackend) pathGceLogin(ctx context.Context, req *logical.Request,
 //
 // ]
 func (b *GcpAuthBackend) groupAliases(crmClient *cloudresourcemanager.Service, ctx context.Context, projectId string) ([]*logical.Alias, error) {
-	ancestry, err := crmClient.Projects.
-		GetAncestry(projectId, &cloudresourcemanager.GetAncestryRequest{}).
-		Context(ctx).
-		Do()
+	ancestors, err := b.getAncestry(ctx, crmClient, projectId)
 	if err != nil {
 		return nil, err
 	}
 
-	aliases := make([]*logical.Alias, len(ancestry.Ancestor))
-	for i, parent := range ancestry.Ancestor {
+	aliases := make([]*logical.Alias, len(ancestors))
+	for i, parent := range ancestors {
 		aliases[i] = &logical.Alias{
 			Name: fmt.Sprintf("%s-%s", parent.ResourceId.Type, parent.ResourceId.Id),
 		}
@@ -606,6 +788,74 @@ func (b *GcpAuthBackend) groupAliases(crmClient *cloudresourcemanager.Service, c
 	return aliases, nil
 }
 
+// getAncestry returns the ancestors of the project in the Cloud Resource
+// Manager hierarchy, starting with the project itself. The ancestry is cached
+// for ancestryCacheTime, so projects moved between folders are picked up
+// eventually.
+func (b *GcpAuthBackend) getAncestry(ctx context.Context, crmClient *cloudresourcemanager.Service, projectId string) ([]*cloudresourcemanager.Ancestor, error) {
+	ancestors, err := b.lookupCache.Fetch("ancestry-"+projectId, ancestryCacheTime, func() (interface{}, error) {
+		ancestry, err := crmClient.Projects.
+			GetAncestry(projectId, &cloudresourcemanager.GetAncestryRequest{}).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return nil, err
+		}
+		return ancestry.Ancestor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ancestors.([]*cloudresourcemanager.Ancestor), nil
+}
+
+// authorizeProject returns an error if the project is not authorized for the
+// role. The project is authorized if the role has no project, folder or
+// organization bindings, or if it matches any of them.
+func (b *GcpAuthBackend) authorizeProject(ctx context.Context, s logical.Storage, role *gcpRole, projectId string) error {
+	if len(role.BoundProjects) == 0 && !role.hasResourceHierarchyBindings() {
+		return nil
+	}
+	if strutil.StrListContains(role.BoundProjects, projectId) {
+		return nil
+	}
+
+	if role.hasResourceHierarchyBindings() {
+		crmClient, err := b.CRMClient(ctx, s)
+		if err != nil {
+			return err
+		}
+
+		ancestors, err := b.getAncestry(ctx, crmClient, projectId)
+		if err != nil {
+			return fmt.Errorf("unable to resolve ancestry of project %q: %w", projectId, err)
+		}
+
+		for _, ancestor := range ancestors {
+			if ancestor.ResourceId == nil {
+				continue
+			}
+			switch ancestor.ResourceId.Type {
+			case "folder":
+				if strutil.StrListContains(role.BoundFolders, ancestor.ResourceId.Id) {
+					return nil
+				}
+			case "organization":
+				if strutil.StrListContains(role.BoundOrganizations, ancestor.ResourceId.Id) {
+					return nil
+				}
+			}
+		}
+	}
+
+	if !role.hasResourceHierarchyBindings() {
+		return fmt.Errorf("not in bound projects %+v", role.BoundProjects)
+	}
+	return fmt.Errorf("not in bound projects %+v, folders %+v or organizations %+v",
+		role.BoundProjects, role.BoundFolders, role.BoundOrganizations)
+}
+
 func authMetadata(loginInfo *gcpLoginInfo, serviceAccount *iam.ServiceAccount) map[string]string {
 	metadata := map[string]string{
 		"role":                  loginInfo.RoleName,
@@ -648,6 +898,9 @@ func (b *GcpAuthBackend) pathGceRenew(ctx context.Context, req *logical.Request,
 	if !ok {
 		return errors.New("invalid auth metadata: service_account_id not found")
 	}
+	if err := b.authorizeProject(ctx, req.Storage, role, meta.ProjectId); err != nil {
+		return fmt.Errorf("could not renew token for role %s: project %q %v", roleName, meta.ProjectId, err)
+	}
 	if err := b.authorizeGCEInstance(ctx, meta.ProjectId, instance, req.Storage, role, serviceAccountId); err != nil {
 		return fmt.Errorf("could not renew token for role %s: %v", roleName, err)
 	}
@@ -727,13 +980,831 @@ func (b *GcpAuthBackend) authorizeGCEInstance(ctx context.Context, project strin
 		instanceSelfLink: instance.SelfLink,
 		instanceZone:     instance.Zone,
 
+		instanceSecureBoot:          instance.ShieldedInstanceConfig != nil && instance.ShieldedInstanceConfig.EnableSecureBoot,
+		instanceIntegrityMonitoring: instance.ShieldedInstanceConfig != nil && instance.ShieldedInstanceConfig.EnableIntegrityMonitoring,
+		instanceConfidential:        instance.ConfidentialInstanceConfig != nil && instance.ConfidentialInstanceConfig.EnableConfidentialCompute,
+		instanceNetworkTags:         instanceNetworkTags(instance),
+		instanceBootDisk:            instanceBootDisk(instance),
+
 		boundLabels:  role.BoundLabels,
 		boundRegions: role.BoundRegions,
 		boundZones:   role.BoundZones,
 
 		boundInstanceGroups:  role.BoundInstanceGroups,
 		boundServiceAccounts: role.BoundServiceAccounts,
+
+		requireSecureBoot:          role.RequireSecureBoot,
+		requireIntegrityMonitoring: role.RequireIntegrityMonitoring,
+		requireConfidentialVM:      role.RequireConfidentialVM,
+		boundNetworkTags:           role.BoundNetworkTags,
+		boundImageFamilies:         role.BoundImageFamilies,
+
+		globs: role.AllowGlobPatterns,
+	})
+}
+
+// instanceNetworkTags returns the network tags of the given instance.
+func instanceNetworkTags(instance *compute.Instance) []string {
+	if instance.Tags == nil {
+		return nil
+	}
+	return instance.Tags.Items
+}
+
+// instanceBootDisk returns the self-link of the boot disk of the given
+// instance, or an empty string if it has none.
+func instanceBootDisk(instance *compute.Instance) string {
+	for _, d := range instance.Disks {
+		if d.Boot {
+			return d.Source
+		}
+	}
+	return ""
+}
+
+// ---- Cloud Run login domain ----
+// cloudRunResourceIDRegex matches the project, region and names given with a
+// 'cloudrun' login, so that they cannot address other resources.
+var cloudRunResourceIDRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+
+// cloudRunWorkload is the Cloud Run service or Cloud Function of a 'cloudrun'
+// login. The service account and labels are looked up with the admin API.
+type cloudRunWorkload struct {
+	Project  string
+	Region   string
+	Service  string
+	Revision string
+	Function string
+
+	ServiceAccount string
+	Labels         map[string]string
+}
+
+// cloudRunWorkloadFromLogin parses the Cloud Run service or Cloud Function
+// given with a 'cloudrun' login. The project defaults to the project of the
+// service account email. These are claims of the caller: the ID token only
+// proves the service account, and the workload is only checked to run as it.
+func cloudRunWorkloadFromLogin(data *framework.FieldData, email string) (*cloudRunWorkload, error) {
+	w := &cloudRunWorkload{
+		Project:  data.Get("project").(string),
+		Region:   data.Get("region").(string),
+		Service:  data.Get("service").(string),
+		Revision: data.Get("revision").(string),
+		Function: data.Get("function").(string),
+	}
+
+	switch {
+	case w.Service == "" && w.Function == "":
+		return nil, errors.New("one of service or function is required")
+	case w.Service != "" && w.Function != "":
+		return nil, errors.New("only one of service or function can be given")
+	case w.Function != "" && w.Revision != "":
+		return nil, errors.New("revision can only be given with service")
+	case w.Region == "":
+		return nil, errors.New("region is required")
+	}
+
+	if w.Project == "" {
+		_, domain, _ := strings.Cut(email, "@")
+		project, ok := strings.CutSuffix(domain, ".iam.gserviceaccount.com")
+		if !ok || project == "" {
+			return nil, fmt.Errorf("project is required for service account %q", email)
+		}
+		w.Project = project
+	}
+
+	for _, v := range []string{w.Project, w.Region, w.Service, w.Revision, w.Function} {
+		if v != "" && !cloudRunResourceIDRegex.MatchString(v) {
+			return nil, fmt.Errorf("invalid resource ID %q", v)
+		}
+	}
+
+	return w, nil
+}
+
+// cloudRunWorkloadFromAuth returns the Cloud Run service or Cloud Function,
+// and the ID and email of its service account, stored with a token on login.
+func cloudRunWorkloadFromAuth(internalData map[string]interface{}) (*cloudRunWorkload, string, string, error) {
+	values := make(map[string]string)
+	for _, k := range []string{"project", "region", "service", "revision", "function", "service_account_id", "service_account_email"} {
+		v, _ := internalData[k].(string)
+		values[k] = v
+	}
+
+	w := &cloudRunWorkload{
+		Project:  values["project"],
+		Region:   values["region"],
+		Service:  values["service"],
+		Revision: values["revision"],
+		Function: values["function"],
+	}
+	if w.Project == "" || w.Region == "" || (w.Service == "" && w.Function == "") {
+		return nil, "", "", errors.New("expected 'project', 'region' and 'service' or 'function' fields")
+	}
+	if values["service_account_id"] == "" || values["service_account_email"] == "" {
+		return nil, "", "", errors.New("expected 'service_account_id' and 'service_account_email' fields")
+	}
+
+	return w, values["service_account_id"], values["service_account_email"], nil
+}
+
+func (w *cloudRunWorkload) kind() string {
+	if w.Function != "" {
+		return "function"
+	}
+	return "service"
+}
+
+func (w *cloudRunWorkload) name() string {
+	if w.Function != "" {
+		return w.Function
+	}
+	return w.Service
+}
+
+func (w *cloudRunWorkload) resourceName() string {
+	return fmt.Sprintf("projects/%s/locations/%s/%ss/%s", w.Project, w.Region, w.kind(), w.name())
+}
+
+// lookupCloudRunWorkload looks up the service account and labels of the
+// Cloud Run service or Cloud Function with the admin API. If a revision is
+// given, its service account is used instead of that of the service template.
+func (b *GcpAuthBackend) lookupCloudRunWorkload(ctx context.Context, s logical.Storage, w *cloudRunWorkload) error {
+	if w.Function != "" {
+		client, err := b.CloudFunctionsClient(ctx, s)
+		if err != nil {
+			return err
+		}
+
+		function, err := client.Projects.Locations.Functions.Get(w.resourceName()).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error looking up function %q: %w", w.resourceName(), err)
+		}
+		w.Labels = function.Labels
+		if function.ServiceConfig != nil {
+			w.ServiceAccount = function.ServiceConfig.ServiceAccountEmail
+		}
+		return nil
+	}
+
+	client, err := b.CloudRunClient(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	service, err := client.Projects.Locations.Services.Get(w.resourceName()).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error looking up service %q: %w", w.resourceName(), err)
+	}
+	w.Labels = service.Labels
+	if service.Template != nil {
+		w.ServiceAccount = service.Template.ServiceAccount
+	}
+
+	if w.Revision != "" {
+		revisionName := w.resourceName() + "/revisions/" + w.Revision
+		revision, err := client.Projects.Locations.Services.Revisions.Get(revisionName).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error looking up revision %q: %w", revisionName, err)
+		}
+		w.ServiceAccount = revision.ServiceAccount
+	}
+	return nil
+}
+
+// authorizeCloudRunWorkload returns an error if the Cloud Run service or Cloud
+// Function, running as the given service account, is not authorized for the
+// role.
+func (b *GcpAuthBackend) authorizeCloudRunWorkload(ctx context.Context, s logical.Storage, role *gcpRole, w *cloudRunWorkload, serviceAccountId, serviceAccountEmail string) error {
+	if err := b.authorizeProject(ctx, s, role, w.Project); err != nil {
+		return fmt.Errorf("%s %q (project %q) %w", w.kind(), w.name(), w.Project, err)
+	}
+
+	if err := b.lookupCloudRunWorkload(ctx, s, w); err != nil {
+		return err
+	}
+
+	return AuthorizeCloudRun(&AuthorizeCloudRunInput{
+		kind:   w.kind(),
+		name:   w.name(),
+		region: w.Region,
+
+		workloadServiceAccount: w.ServiceAccount,
+		workloadLabels:         w.Labels,
+
+		serviceAccountId:    serviceAccountId,
+		serviceAccountEmail: serviceAccountEmail,
+
+		boundServices:        role.BoundServices,
+		boundRegions:         role.BoundRegions,
+		boundLabels:          role.BoundLabels,
+		boundServiceAccounts: role.BoundServiceAccounts,
+
+		globs: role.AllowGlobPatterns,
+	})
+}
+
+// pathCloudRunLogin attempts a login operation using the parsed login info.
+func (b *GcpAuthBackend) pathCloudRunLogin(ctx context.Context, req *logical.Request, loginInfo *gcpLoginInfo) (*logical.Response, error) {
+	role := loginInfo.Role
+	workload := loginInfo.CloudRun
+	if workload == nil {
+		return logical.ErrorResponse("could not get Cloud Run service or function from login"), nil
+	}
+
+	// Service accounts are aliased by their unique ID, which is the subject
+	// of Google-signed ID tokens.
+	alias := loginInfo.EmailOrId
+
+	if req.Operation == logical.AliasLookaheadOperation {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: &logical.Alias{
+					Name: alias,
+				},
+			},
+		}, nil
+	}
+
+	if err := b.authorizeCloudRunWorkload(ctx, req.Storage, role, workload, loginInfo.EmailOrId, loginInfo.Email); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	conf, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("unable to retrieve GCP configuration"), nil
+	}
+
+	auth := &logical.Auth{
+		InternalData: map[string]interface{}{
+			"project":               workload.Project,
+			"region":                workload.Region,
+			"service":               workload.Service,
+			"revision":              workload.Revision,
+			"function":              workload.Function,
+			"service_account_id":    loginInfo.EmailOrId,
+			"service_account_email": loginInfo.Email,
+		},
+		Alias: &logical.Alias{
+			Name: alias,
+		},
+		DisplayName: workload.name(),
+	}
+	role.PopulateTokenAuth(auth, req)
+	if err := conf.CloudRunAuthMetadata.PopulateDesiredMetadata(auth, cloudRunAuthMetadata(loginInfo)); err != nil {
+		b.Logger().Warn("unable to populate cloudrun metadata", "err", err.Error())
+	}
+
+	resp := &logical.Response{
+		Auth: auth,
+	}
+
+	if role.AddGroupAliases {
+		crmClient, err := b.CRMClient(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		aliases, err := b.groupAliases(crmClient, ctx, workload.Project)
+		if err != nil {
+			return nil, err
+		}
+		resp.Auth.GroupAliases = aliases
+	}
+
+	if role.AddGoogleGroupAliases {
+		aliases, err := b.serviceAccountGroupAliases(ctx, req.Storage, conf, loginInfo.Email)
+		if err != nil {
+			return nil, err
+		}
+		resp.Auth.GroupAliases = append(resp.Auth.GroupAliases, aliases...)
+	}
+	return resp, nil
+}
+
+func cloudRunAuthMetadata(loginInfo *gcpLoginInfo) map[string]string {
+	workload := loginInfo.CloudRun
+	return map[string]string{
+		"role":                  loginInfo.RoleName,
+		"service_account_id":    loginInfo.EmailOrId,
+		"service_account_email": loginInfo.Email,
+		"project_id":            workload.Project,
+		"region":                workload.Region,
+		"service_name":          workload.Service,
+		"revision_name":         workload.Revision,
+		"function_name":         workload.Function,
+	}
+}
+
+// pathCloudRunRenew returns an error if the Cloud Run service or Cloud
+// Function referenced in the auth token cannot renew the auth token for the
+// given role.
+func (b *GcpAuthBackend) pathCloudRunRenew(ctx context.Context, req *logical.Request, roleName string, role *gcpRole) error {
+	workload, serviceAccountId, serviceAccountEmail, err := cloudRunWorkloadFromAuth(req.Auth.InternalData)
+	if err != nil {
+		return fmt.Errorf("invalid auth internal data: %v", err)
+	}
+
+	if err := b.authorizeCloudRunWorkload(ctx, req.Storage, role, workload, serviceAccountId, serviceAccountEmail); err != nil {
+		return fmt.Errorf("could not renew token for role %s: %v", roleName, err)
+	}
+
+	return nil
+}
+
+// ---- GKE login domain ----
+// gkeClusterRegex matches the resource names of GKE clusters, which are also
+// the paths of their OIDC issuers.
+var gkeClusterRegex = regexp.MustCompile(`^projects/([A-Za-z0-9][A-Za-z0-9._:-]*)/locations/([A-Za-z0-9][A-Za-z0-9_-]*)/clusters/([A-Za-z0-9][A-Za-z0-9_-]*)$`)
+
+// gkeWorkload is the Kubernetes service account of a 'gke' login.
+type gkeWorkload struct {
+	Project     string
+	Location    string
+	ClusterName string
+
+	Namespace          string
+	ServiceAccountName string
+	ServiceAccountUID  string
+
+	PodName string
+	PodUID  string
+}
+
+// kubernetesTokenClaims are the private claims of Kubernetes service account
+// tokens.
+type kubernetesTokenClaims struct {
+	Kubernetes *struct {
+		Namespace      string `json:"namespace"`
+		ServiceAccount struct {
+			Name string `json:"name"`
+			UID  string `json:"uid"`
+		} `json:"serviceaccount"`
+		Pod *struct {
+			Name string `json:"name"`
+			UID  string `json:"uid"`
+		} `json:"pod"`
+	} `json:"kubernetes.io"`
+}
+
+// gkeWorkloadFromCluster returns the workload with the project, location and
+// name of the given cluster resource name, or nil if it is invalid.
+func gkeWorkloadFromCluster(cluster string) *gkeWorkload {
+	m := gkeClusterRegex.FindStringSubmatch(cluster)
+	if m == nil {
+		return nil
+	}
+	return &gkeWorkload{
+		Project:     m[1],
+		Location:    m[2],
+		ClusterName: m[3],
+	}
+}
+
+// gkeWorkloadFromAuth returns the Kubernetes service account stored with a
+// token on login.
+func gkeWorkloadFromAuth(internalData map[string]interface{}) (*gkeWorkload, error) {
+	cluster, _ := internalData["cluster"].(string)
+	w := gkeWorkloadFromCluster(cluster)
+	if w == nil {
+		return nil, fmt.Errorf("invalid 'cluster' field %q", cluster)
+	}
+
+	w.Namespace, _ = internalData["namespace"].(string)
+	w.ServiceAccountName, _ = internalData["service_account_name"].(string)
+	w.ServiceAccountUID, _ = internalData["service_account_uid"].(string)
+	if w.Namespace == "" || w.ServiceAccountName == "" || w.ServiceAccountUID == "" {
+		return nil, errors.New("expected 'namespace', 'service_account_name' and 'service_account_uid' fields")
+	}
+	return w, nil
+}
+
+func (w *gkeWorkload) cluster() string {
+	return fmt.Sprintf("projects/%s/locations/%s/clusters/%s", w.Project, w.Location, w.ClusterName)
+}
+
+// parseAndValidateGKEToken verifies a Kubernetes service account token against
+// the OIDC signing keys of the GKE cluster that issued it.
+func (b *GcpAuthBackend) parseAndValidateGKEToken(ctx context.Context, conf *gcpConfig, loginInfo *gcpLoginInfo, jwtVal *jwt.JSONWebToken) (*gcpLoginInfo, error) {
+	audiences := loginInfo.Role.audiences(conf)
+	if len(audiences) == 0 {
+		return nil, errors.New("no audience is configured for Kubernetes service account tokens")
+	}
+
+	// The issuer selects the cluster whose keys verify the token, so it is
+	// checked against the role before fetching any keys.
+	var unverified jwt.Claims
+	if err := jwtVal.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, fmt.Errorf("could not parse claims from JWT: %w", err)
+	}
+
+	cluster, ok := strings.CutPrefix(unverified.Issuer, gkeIssuerPrefix)
+	workload := gkeWorkloadFromCluster(cluster)
+	if !ok || workload == nil {
+		return nil, fmt.Errorf("JWT issuer %q is not a GKE cluster", unverified.Issuer)
+	}
+	if !strutil.StrListContains(loginInfo.Role.BoundClusters, cluster) {
+		return nil, fmt.Errorf("cluster %q not in bound clusters %q", cluster, loginInfo.Role.BoundClusters)
+	}
+
+	if len(jwtVal.Headers) != 1 {
+		return nil, errors.New("expected token to have exactly one header")
+	}
+	key, err := b.getGKESigningKey(ctx, conf, cluster, jwtVal.Headers[0].KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get public key for signed JWT: %w", err)
+	}
+
+	baseClaims := &jwt.Claims{}
+	k8sClaims := &kubernetesTokenClaims{}
+	if err := jwtVal.Claims(key, baseClaims, k8sClaims); err != nil {
+		return nil, err
+	}
+
+	if err := validateKubernetesTokenClaims(baseClaims, k8sClaims, unverified.Issuer, audiences); err != nil {
+		return nil, err
+	}
+
+	workload.Namespace = k8sClaims.Kubernetes.Namespace
+	workload.ServiceAccountName = k8sClaims.Kubernetes.ServiceAccount.Name
+	workload.ServiceAccountUID = k8sClaims.Kubernetes.ServiceAccount.UID
+	if pod := k8sClaims.Kubernetes.Pod; pod != nil {
+		workload.PodName = pod.Name
+		workload.PodUID = pod.UID
+	}
+
+	loginInfo.JWTClaims = baseClaims
+	loginInfo.EmailOrId = baseClaims.Subject
+	loginInfo.GKE = workload
+	return loginInfo, nil
+}
+
+// validateKubernetesTokenClaims validates the claims of a Kubernetes service
+// account token, which must be issued for the configured audience. The
+// lifetime of the token is controlled by the cluster, so it is not limited.
+func validateKubernetesTokenClaims(c *jwt.Claims, k *kubernetesTokenClaims, issuer string, audiences []string) error {
+	if c.Expiry == nil {
+		return errors.New("JWT is expired or does not have proper 'exp' claim")
+	}
+
+	expected := jwt.Expected{
+		Issuer:      issuer,
+		AnyAudience: jwt.Audience(audiences),
+		Time:        time.Now(),
+	}
+	if err := c.ValidateWithLeeway(expected, jwtExpToleranceSec*time.Second); err != nil {
+		return fmt.Errorf("invalid Kubernetes service account token: %w", err)
+	}
+
+	if k.Kubernetes == nil || k.Kubernetes.Namespace == "" ||
+		k.Kubernetes.ServiceAccount.Name == "" || k.Kubernetes.ServiceAccount.UID == "" {
+		return errors.New("expected JWT to have 'kubernetes.io' claim with namespace and service account")
+	}
+
+	expectedSubject := fmt.Sprintf("system:serviceaccount:%s:%s", k.Kubernetes.Namespace, k.Kubernetes.ServiceAccount.Name)
+	if c.Subject != expectedSubject {
+		return fmt.Errorf("JWT claim 'sub' must be %q", expectedSubject)
+	}
+
+	return nil
+}
+
+// getGKESigningKey returns the public key with the given ID from the JWKS of
+// the OIDC issuer of the GKE cluster. The JWKS is cached.
+func (b *GcpAuthBackend) getGKESigningKey(ctx context.Context, conf *gcpConfig, cluster, kid string) (interface{}, error) {
+	endpoint := conf.ContainerCustomEndpoint
+	if endpoint == "" {
+		endpoint = defaultContainerEndpoint
+	}
+	jwksURL := strings.TrimSuffix(endpoint, "/") + "/v1/" + cluster + "/jwks"
+
+	keySet, err := b.lookupCache.Fetch("gke-jwks-"+cluster, gkeJWKSCacheTime, func() (interface{}, error) {
+		b.Logger().Debug("fetching GKE cluster JWKS", "cluster", cluster)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := cleanhttp.DefaultClient().Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching JWKS of cluster %q: %w", cluster, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("error fetching JWKS of cluster %q: unexpected status %q", cluster, resp.Status)
+		}
+
+		keySet := &jose.JSONWebKeySet{}
+		if err := json.NewDecoder(resp.Body).Decode(keySet); err != nil {
+			return nil, fmt.Errorf("error decoding JWKS of cluster %q: %w", cluster, err)
+		}
+		return keySet, nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range keySet.(*jose.JSONWebKeySet).Key(kid) {
+		if k.Valid() && k.IsPublic() {
+			return k.Key, nil
+		}
+	}
+	return nil, fmt.Errorf("no public key %q in JWKS of cluster %q", kid, cluster)
+}
+
+// authorizeGKEWorkload returns an error if the Kubernetes service account is
+// not authorized for the role.
+func (b *GcpAuthBackend) authorizeGKEWorkload(ctx context.Context, s logical.Storage, role *gcpRole, w *gkeWorkload) error {
+	if err := b.authorizeProject(ctx, s, role, w.Project); err != nil {
+		return fmt.Errorf("cluster %q (project %q) %w", w.cluster(), w.Project, err)
+	}
+
+	return AuthorizeGKE(&AuthorizeGKEInput{
+		cluster:            w.cluster(),
+		namespace:          w.Namespace,
+		serviceAccountName: w.ServiceAccountName,
+
+		boundClusters:                  role.BoundClusters,
+		boundNamespaces:                role.BoundNamespaces,
+		boundKubernetesServiceAccounts: role.BoundKubernetesServiceAccounts,
+	})
+}
+
+// pathGKELogin attempts a login operation using the parsed login info.
+func (b *GcpAuthBackend) pathGKELogin(ctx context.Context, req *logical.Request, loginInfo *gcpLoginInfo) (*logical.Response, error) {
+	role := loginInfo.Role
+	workload := loginInfo.GKE
+	if workload == nil {
+		return logical.ErrorResponse("could not get Kubernetes service account from login"), nil
+	}
+
+	// Kubernetes service accounts are aliased by their UID, as names can be
+	// reused across clusters and after deletion.
+	alias := workload.ServiceAccountUID
+
+	if req.Operation == logical.AliasLookaheadOperation {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: &logical.Alias{
+					Name: alias,
+				},
+			},
+		}, nil
+	}
+
+	if err := b.authorizeGKEWorkload(ctx, req.Storage, role, workload); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	conf, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("unable to retrieve GCP configuration"), nil
+	}
+
+	auth := &logical.Auth{
+		InternalData: map[string]interface{}{
+			"cluster":              workload.cluster(),
+			"namespace":            workload.Namespace,
+			"service_account_name": workload.ServiceAccountName,
+			"service_account_uid":  workload.ServiceAccountUID,
+		},
+		Alias: &logical.Alias{
+			Name: alias,
+		},
+		DisplayName: fmt.Sprintf("%s-%s", workload.Namespace, workload.ServiceAccountName),
+	}
+	role.PopulateTokenAuth(auth, req)
+	if err := conf.GKEAuthMetadata.PopulateDesiredMetadata(auth, gkeAuthMetadata(loginInfo)); err != nil {
+		b.Logger().Warn("unable to populate gke metadata", "err", err.Error())
+	}
+
+	resp := &logical.Response{
+		Auth: auth,
+	}
+
+	if role.AddGroupAliases {
+		crmClient, err := b.CRMClient(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		aliases, err := b.groupAliases(crmClient, ctx, workload.Project)
+		if err != nil {
+			return nil, err
+		}
+		resp.Auth.GroupAliases = aliases
+	}
+	return resp, nil
+}
+
+func gkeAuthMetadata(loginInfo *gcpLoginInfo) map[string]string {
+	workload := loginInfo.GKE
+	return map[string]string{
+		"role":                 loginInfo.RoleName,
+		"project_id":           workload.Project,
+		"location":             workload.Location,
+		"cluster_name":         workload.ClusterName,
+		"namespace":            workload.Namespace,
+		"service_account_name": workload.ServiceAccountName,
+		"service_account_uid":  workload.ServiceAccountUID,
+		"pod_name":             workload.PodName,
+		"pod_uid":              workload.PodUID,
+	}
+}
+
+// pathGKERenew returns an error if the Kubernetes service account referenced
+// in the auth token cannot renew the auth token for the given role.
+func (b *GcpAuthBackend) pathGKERenew(ctx context.Context, req *logical.Request, roleName string, role *gcpRole) error {
+	workload, err := gkeWorkloadFromAuth(req.Auth.InternalData)
+	if err != nil {
+		return fmt.Errorf("invalid auth internal data: %v", err)
+	}
+
+	if err := b.authorizeGKEWorkload(ctx, req.Storage, role, workload); err != nil {
+		return fmt.Errorf("could not renew token for role %s: %v", roleName, err)
+	}
+
+	return nil
+}
+
+// ---- User login domain ----
+// groupMemberships returns the emails of the Google Groups that the given
+// member is a direct or transitive member of, using the Cloud Identity Groups
+// API.
+func (b *GcpAuthBackend) groupMemberships(ctx context.Context, client *cloudidentity.Service, memberEmail string) ([]string, error) {
+	member := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(memberEmail)
+	query := fmt.Sprintf("member_key_id == '%s' && 'cloudidentity.googleapis.com/groups.discussion_forum' in labels", member)
+
+	var groups []string
+	err := client.Groups.Memberships.SearchTransitiveGroups("groups/-").
+		Query(query).
+		Pages(ctx, func(resp *cloudidentity.SearchTransitiveGroupsResponse) error {
+			for _, m := range resp.Memberships {
+				if m.GroupKey != nil && m.GroupKey.Id != "" {
+					groups = append(groups, strings.ToLower(m.GroupKey.Id))
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error looking up groups of %q: %w", memberEmail, err)
+	}
+	return groups, nil
+}
+
+// googleGroupAliases returns the group aliases of the given Google Groups.
+func googleGroupAliases(groups []string) []*logical.Alias {
+	aliases := make([]*logical.Alias, len(groups))
+	for i, group := range groups {
+		aliases[i] = &logical.Alias{
+			Name: "group-" + group,
+		}
+	}
+	return aliases
+}
+
+// serviceAccountGroupAliases returns the group aliases of the Google Groups
+// that the given service account is a direct or transitive member of. The
+// groups are cached per service account for the configured cache TTL, so
+// membership changes are picked up eventually.
+func (b *GcpAuthBackend) serviceAccountGroupAliases(ctx context.Context, s logical.Storage, conf *gcpConfig, email string) ([]*logical.Alias, error) {
+	if email == "" {
+		return nil, errors.New("service account email is required to look up its Google Groups")
+	}
+
+	client, err := b.CloudIdentityClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := b.lookupCache.Fetch("groups-"+strings.ToLower(email), conf.googleGroupsCacheTTL(), func() (interface{}, error) {
+		return b.groupMemberships(ctx, client, email)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return googleGroupAliases(groups.([]string)), nil
+}
+
+// userFromAuth returns the user ID, email and hosted domain stored with a
+// token on login.
+func userFromAuth(internalData map[string]interface{}) (string, string, string, error) {
+	userId, _ := internalData["user_id"].(string)
+	email, _ := internalData["email"].(string)
+	hostedDomain, _ := internalData["hosted_domain"].(string)
+	if userId == "" || email == "" {
+		return "", "", "", errors.New("expected 'user_id' and 'email' fields")
+	}
+	return userId, email, hostedDomain, nil
+}
+
+// authorizeUser returns an error if the user is not authorized for the role.
+// The Google Groups of the user are looked up and returned if the role binds
+// groups or adds group aliases.
+func (b *GcpAuthBackend) authorizeUser(ctx context.Context, s logical.Storage, role *gcpRole, email, hostedDomain string) ([]string, error) {
+	var groups []string
+	if len(role.BoundGroups) > 0 || role.AddGroupAliases {
+		client, err := b.CloudIdentityClient(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		if groups, err = b.groupMemberships(ctx, client, email); err != nil {
+			return nil, err
+		}
+	}
+
+	err := AuthorizeUser(&AuthorizeUserInput{
+		email:        email,
+		hostedDomain: hostedDomain,
+		groups:       groups,
+
+		boundHostedDomains: role.BoundHostedDomains,
+		boundEmails:        role.BoundEmails,
+		boundGroups:        role.BoundGroups,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// pathUserLogin attempts a login operation using the parsed login info.
+func (b *GcpAuthBackend) pathUserLogin(ctx context.Context, req *logical.Request, loginInfo *gcpLoginInfo) (*logical.Response, error) {
+	role := loginInfo.Role
+
+	// Users are aliased by the unique ID of their Google account, which is
+	// the subject of Google-signed ID tokens. Emails can be reassigned.
+	alias := loginInfo.EmailOrId
+
+	if req.Operation == logical.AliasLookaheadOperation {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: &logical.Alias{
+					Name: alias,
+				},
+			},
+		}, nil
+	}
+
+	groups, err := b.authorizeUser(ctx, req.Storage, role, loginInfo.Email, loginInfo.HostedDomain)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	conf, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("unable to retrieve GCP configuration"), nil
+	}
+
+	auth := &logical.Auth{
+		InternalData: map[string]interface{}{
+			"user_id":       loginInfo.EmailOrId,
+			"email":         loginInfo.Email,
+			"hosted_domain": loginInfo.HostedDomain,
+		},
+		Alias: &logical.Alias{
+			Name: alias,
+		},
+		DisplayName: loginInfo.Email,
+	}
+	role.PopulateTokenAuth(auth, req)
+	if err := conf.UserAuthMetadata.PopulateDesiredMetadata(auth, userAuthMetadata(loginInfo)); err != nil {
+		b.Logger().Warn("unable to populate user metadata", "err", err.Error())
+	}
+
+	if role.AddGroupAliases {
+		auth.GroupAliases = googleGroupAliases(groups)
+	}
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+func userAuthMetadata(loginInfo *gcpLoginInfo) map[string]string {
+	return map[string]string{
+		"role":          loginInfo.RoleName,
+		"user_id":       loginInfo.EmailOrId,
+		"email":         loginInfo.Email,
+		"hosted_domain": loginInfo.HostedDomain,
+	}
+}
+
+// pathUserRenew returns an error if the user referenced in the auth token
+// cannot renew the auth token for the given role. Group memberships are
+// looked up again, so users removed from bound groups cannot renew.
+func (b *GcpAuthBackend) pathUserRenew(ctx context.Context, req *logical.Request, roleName string, role *gcpRole) error {
+	_, email, hostedDomain, err := userFromAuth(req.Auth.InternalData)
+	if err != nil {
+		return fmt.Errorf("invalid auth internal data: %v", err)
+	}
+
+	if _, err := b.authorizeUser(ctx, req.Storage, role, email, hostedDomain); err != nil {
+		return fmt.Errorf("could not renew token for role %s: %v", roleName, err)
+	}
+
+	return nil
 }
 
 const (
@@ -750,6 +1821,26 @@ This JWT should contain the id (expected field 'client_id') or email
 (expected field 'client_email') of the authenticating service account in its claims.
 Vault verifies the signed JWT and parses the identity of the account.
 
+Cloud Run services and Cloud Functions
+======================================
+Serverless workloads request a Google-signed ID token for the configured
+audience from the metadata server, and login with the name and region of
+their service or function. Vault verifies the ID token and that the service
+or function runs as the service account of the token.
+
+GKE Workload Identity
+=====================
+Pods on GKE clusters login with their projected Kubernetes service account
+token, issued for the configured audience. Vault verifies the token against
+the OIDC signing keys of the cluster and binds on the cluster, namespace and
+Kubernetes service account.
+
+Google users
+============
+Users of Google Workspace or Cloud Identity login with a Google-signed ID
+token issued for one of the role's bound audiences. Vault checks the verified
+email and binds on the hosted domain, email and Google Groups of the user.
+
 Renewal is rejected if the role, service account, or original signing key no longer exists.
 `
 )
@@ -5,11 +5,17 @@ package gcp
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"math/big"
+	"net/http"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -613,6 +619,645 @@ func Test_Renew(t *testing.T) {
 
 // testIAMCredentialsClient returns a new IAM Service Account Credentials client.
 // This client can be used to sign JWTs using the IAM Service Credentials endpoint.
+func TestAuthorizeProject(t *testing.T) {
+	t.Parallel()
+
+	var ancestryRequests atomic.Int32
+	b, storage := testBackendWithServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/projects/my-project:getAncestry" {
+			http.NotFound(w, r)
+			return
+		}
+		ancestryRequests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ancestor": [
+			{"resourceId": {"type": "project", "id": "my-project"}},
+			{"resourceId": {"type": "folder", "id": "123"}},
+			{"resourceId": {"type": "organization", "id": "456"}}
+		]}`)
+	}))
+
+	cases := []struct {
+		name    string
+		role    *gcpRole
+		project string
+		err     string
+	}{
+		{
+			name:    "unbound",
+			role:    &gcpRole{},
+			project: "my-project",
+		},
+		{
+			name:    "bound_project",
+			role:    &gcpRole{BoundProjects: []string{"my-project"}},
+			project: "my-project",
+		},
+		{
+			name:    "not_bound_project",
+			role:    &gcpRole{BoundProjects: []string{"other-project"}},
+			project: "my-project",
+			err:     "not in bound projects [other-project]",
+		},
+		{
+			name:    "bound_folder",
+			role:    &gcpRole{BoundProjects: []string{"other-project"}, BoundFolders: []string{"789", "123"}},
+			project: "my-project",
+		},
+		{
+			name:    "bound_organization",
+			role:    &gcpRole{BoundOrganizations: []string{"456"}},
+			project: "my-project",
+		},
+		{
+			name:    "not_bound_folder",
+			role:    &gcpRole{BoundFolders: []string{"456"}, BoundOrganizations: []string{"123"}},
+			project: "my-project",
+			err:     "not in bound projects [], folders [456] or organizations [123]",
+		},
+		{
+			name:    "unknown_project",
+			role:    &gcpRole{BoundFolders: []string{"123"}},
+			project: "other-project",
+			err:     "unable to resolve ancestry",
+		},
+	}
+
+	for _, tc := range cases {
+		err := b.authorizeProject(context.Background(), storage, tc.role, tc.project)
+		if tc.err == "" {
+			assert.NoError(t, err, tc.name)
+		} else if assert.Error(t, err, tc.name) {
+			assert.Contains(t, err.Error(), tc.err, tc.name)
+		}
+	}
+
+	// The ancestry of the project is cached.
+	assert.Equal(t, int32(1), ancestryRequests.Load())
+}
+
+func TestLogin_CloudRun(t *testing.T) {
+	t.Parallel()
+
+	const (
+		serviceAccountId    = "1234"
+		serviceAccountEmail = "api@my-project.iam.gserviceaccount.com"
+	)
+
+	signer := newTestGoogleSigner(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /oauth2/v1/certs", signer.serveCerts)
+	mux.HandleFunc("GET /v2/projects/my-project/locations/us-central1/services/api", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name": "api", "labels": {"env": "prod"}, "template": {"serviceAccount": %q}}`, serviceAccountEmail)
+	})
+	mux.HandleFunc("GET /v2/projects/my-project/locations/us-central1/services/api/revisions/api-00001", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "api-00001", "serviceAccount": "old@my-project.iam.gserviceaccount.com"}`)
+	})
+	mux.HandleFunc("GET /v2/projects/my-project/locations/us-central1/functions/fn", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name": "fn", "labels": {"env": "dev"}, "serviceConfig": {"serviceAccountEmail": %q}}`, serviceAccountEmail)
+	})
+	b, storage := testBackendWithServer(t, mux)
+	ctx := context.Background()
+
+	testRoleCreate(t, b, storage, map[string]interface{}{
+		"name":           "api",
+		"type":           cloudRunRoleType,
+		"bound_services": "api,fn",
+		"bound_regions":  "us-central1",
+		"policies":       "dev",
+	})
+
+	idToken := func(aud, email string) string {
+		return signer.idToken(t, &jwt.Claims{
+			Issuer:   "https://accounts.google.com",
+			Subject:  serviceAccountId,
+			Audience: []string{aud},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(30 * time.Minute)),
+		}, map[string]interface{}{
+			"email":          email,
+			"email_verified": true,
+		})
+	}
+	login := func(data map[string]interface{}) (*logical.Response, error) {
+		data["role"] = "api"
+		return b.HandleRequest(ctx, &logical.Request{
+			Storage:    storage,
+			Operation:  logical.UpdateOperation,
+			Path:       "login",
+			Data:       data,
+			Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+		})
+	}
+
+	t.Run("service", func(t *testing.T) {
+		resp, err := login(map[string]interface{}{
+			"jwt":     idToken(testAudience, serviceAccountEmail),
+			"service": "api",
+			"region":  "us-central1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+		assert.Equal(t, serviceAccountId, resp.Auth.Alias.Name)
+		assert.Equal(t, "api", resp.Auth.DisplayName)
+		assert.Equal(t, map[string]string{
+			"role":                  "api",
+			"project_id":            "my-project",
+			"region":                "us-central1",
+			"service_name":          "api",
+			"service_account_id":    serviceAccountId,
+			"service_account_email": serviceAccountEmail,
+		}, resp.Auth.Metadata)
+
+		// Renewal looks up the service again.
+		resp.Auth.TokenPolicies = resp.Auth.Policies
+		req := &logical.Request{Storage: storage, Auth: resp.Auth}
+		resp, err = b.pathLoginRenew(ctx, req, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+	})
+
+	t.Run("function", func(t *testing.T) {
+		resp, err := login(map[string]interface{}{
+			"jwt":      idToken(testAudience, serviceAccountEmail),
+			"function": "fn",
+			"region":   "us-central1",
+			"project":  "my-project",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+		assert.Equal(t, "fn", resp.Auth.Metadata["function_name"])
+	})
+
+	errCases := []struct {
+		name string
+		data map[string]interface{}
+		err  string
+	}{
+		{
+			name: "wrong_audience",
+			data: map[string]interface{}{
+				"jwt":     idToken("https://other.example.com", serviceAccountEmail),
+				"service": "api",
+				"region":  "us-central1",
+			},
+			err: fmt.Sprintf("ID token claim 'aud' must be %q", testAudience),
+		},
+		{
+			name: "other_service_account",
+			data: map[string]interface{}{
+				"jwt":     idToken(testAudience, "other@my-project.iam.gserviceaccount.com"),
+				"service": "api",
+				"region":  "us-central1",
+			},
+			err: `service "api" does not run as service account "other@my-project.iam.gserviceaccount.com"`,
+		},
+		{
+			name: "revision_service_account",
+			data: map[string]interface{}{
+				"jwt":      idToken(testAudience, serviceAccountEmail),
+				"service":  "api",
+				"revision": "api-00001",
+				"region":   "us-central1",
+			},
+			err: "does not run as service account",
+		},
+		{
+			name: "unknown_service",
+			data: map[string]interface{}{
+				"jwt":     idToken(testAudience, serviceAccountEmail),
+				"service": "api",
+				"region":  "europe-west1",
+			},
+			err: `error looking up service "projects/my-project/locations/europe-west1/services/api"`,
+		},
+		{
+			name: "missing_service",
+			data: map[string]interface{}{
+				"jwt":    idToken(testAudience, serviceAccountEmail),
+				"region": "us-central1",
+			},
+			err: "one of service or function is required",
+		},
+		{
+			name: "invalid_service",
+			data: map[string]interface{}{
+				"jwt":     idToken(testAudience, serviceAccountEmail),
+				"service": "api/revisions/api-00001",
+				"region":  "us-central1",
+			},
+			err: "invalid resource ID",
+		},
+	}
+	for _, tc := range errCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := login(tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.IsError() {
+				t.Fatal("expected error response")
+			}
+			assert.Contains(t, resp.Error().Error(), tc.err)
+		})
+	}
+}
+
+func TestLogin_GKE(t *testing.T) {
+	t.Parallel()
+
+	const (
+		cluster = "projects/my-project/locations/us-central1/clusters/prod"
+		issuer  = gkeIssuerPrefix + cluster
+	)
+
+	// The signer stands in for the OIDC issuer of the cluster.
+	signer := newTestGoogleSigner(t)
+	var jwksRequests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/"+cluster+"/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwksRequests.Add(1)
+		signer.serveJWKS(w, r)
+	})
+	b, storage := testBackendWithServer(t, mux)
+	ctx := context.Background()
+
+	testRoleCreate(t, b, storage, map[string]interface{}{
+		"name":                              "payments",
+		"type":                              gkeRoleType,
+		"bound_clusters":                    cluster + ",projects/my-project/locations/us-central1/clusters/dev",
+		"bound_namespaces":                  "payments",
+		"bound_kubernetes_service_accounts": "api",
+		"policies":                          "dev",
+	})
+
+	ksaToken := func(iss, aud, namespace, name string) string {
+		return signer.idToken(t, &jwt.Claims{
+			Issuer:   iss,
+			Subject:  fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name),
+			Audience: []string{aud},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(2 * time.Hour)),
+		}, map[string]interface{}{
+			"kubernetes.io": map[string]interface{}{
+				"namespace": namespace,
+				"serviceaccount": map[string]string{
+					"name": name,
+					"uid":  "ksa-uid",
+				},
+				"pod": map[string]string{
+					"name": "api-7d4b9",
+					"uid":  "pod-uid",
+				},
+			},
+		})
+	}
+	login := func(token string) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Data: map[string]interface{}{
+				"role": "payments",
+				"jwt":  token,
+			},
+			Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+		})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		resp, err := login(ksaToken(issuer, testAudience, "payments", "api"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+		assert.Equal(t, "ksa-uid", resp.Auth.Alias.Name)
+		assert.Equal(t, "payments-api", resp.Auth.DisplayName)
+		assert.Equal(t, map[string]string{
+			"role":                 "payments",
+			"project_id":           "my-project",
+			"location":             "us-central1",
+			"cluster_name":         "prod",
+			"namespace":            "payments",
+			"service_account_name": "api",
+			"service_account_uid":  "ksa-uid",
+		}, resp.Auth.Metadata)
+
+		resp.Auth.TokenPolicies = resp.Auth.Policies
+		req := &logical.Request{Storage: storage, Auth: resp.Auth}
+		resp, err = b.pathLoginRenew(ctx, req, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+
+		// The JWKS of the cluster is cached.
+		if _, err := login(ksaToken(issuer, testAudience, "payments", "api")); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, int32(1), jwksRequests.Load())
+	})
+
+	otherSigner := newTestGoogleSigner(t)
+	otherSigner.kid = "other-key"
+
+	errCases := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{
+			name:  "wrong_audience",
+			token: ksaToken(issuer, "https://other.example.com", "payments", "api"),
+			err:   "invalid Kubernetes service account token",
+		},
+		{
+			name:  "unbound_cluster",
+			token: ksaToken(gkeIssuerPrefix+"projects/my-project/locations/us-central1/clusters/other", testAudience, "payments", "api"),
+			err:   "not in bound clusters",
+		},
+		{
+			name:  "not_gke_issuer",
+			token: ksaToken("https://kubernetes.default.svc", testAudience, "payments", "api"),
+			err:   "is not a GKE cluster",
+		},
+		{
+			name:  "unbound_namespace",
+			token: ksaToken(issuer, testAudience, "default", "api"),
+			err:   `namespace "default" not in bound namespaces`,
+		},
+		{
+			name:  "unbound_service_account",
+			token: ksaToken(issuer, testAudience, "payments", "worker"),
+			err:   `Kubernetes service account "worker" not in bound Kubernetes service accounts`,
+		},
+		{
+			name: "unknown_key",
+			token: otherSigner.idToken(t, &jwt.Claims{
+				Issuer:   issuer,
+				Subject:  "system:serviceaccount:payments:api",
+				Audience: []string{testAudience},
+				Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			}),
+			err: `no public key "other-key"`,
+		},
+		{
+			name: "cluster_down",
+			token: signer.idToken(t, &jwt.Claims{
+				Issuer:   gkeIssuerPrefix + "projects/my-project/locations/us-central1/clusters/dev",
+				Subject:  "system:serviceaccount:payments:api",
+				Audience: []string{testAudience},
+				Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			}),
+			err: "error fetching JWKS of cluster",
+		},
+		{
+			name: "subject_mismatch",
+			token: signer.idToken(t, &jwt.Claims{
+				Issuer:   issuer,
+				Subject:  "system:serviceaccount:payments:worker",
+				Audience: []string{testAudience},
+				Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			}, map[string]interface{}{
+				"kubernetes.io": map[string]interface{}{
+					"namespace":      "payments",
+					"serviceaccount": map[string]string{"name": "api", "uid": "ksa-uid"},
+				},
+			}),
+			err: `JWT claim 'sub' must be "system:serviceaccount:payments:api"`,
+		},
+	}
+	for _, tc := range errCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := login(tc.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.IsError() {
+				t.Fatal("expected error response")
+			}
+			assert.Contains(t, resp.Error().Error(), tc.err)
+		})
+	}
+}
+
+func TestLogin_User(t *testing.T) {
+	t.Parallel()
+
+	const clientId = "1234.apps.googleusercontent.com"
+
+	signer := newTestGoogleSigner(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /oauth2/v1/certs", signer.serveCerts)
+	mux.HandleFunc("GET /v1/groups/-/memberships:searchTransitiveGroups", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Query().Get("query"), "member_key_id == 'alice@example.com'") {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		fmt.Fprint(w, `{"memberships": [{"groupKey": {"id": "Devs@example.com"}}, {"groupKey": {"id": "everyone@example.com"}}]}`)
+	})
+	b, storage := testBackendWithServer(t, mux)
+	ctx := context.Background()
+
+	testRoleCreate(t, b, storage, map[string]interface{}{
+		"name":                 "devs",
+		"type":                 userRoleType,
+		"bound_audiences":      clientId,
+		"bound_hosted_domains": "example.com",
+		"bound_groups":         "devs@example.com",
+		"add_group_aliases":    true,
+		"policies":             "dev",
+	})
+
+	userToken := func(aud, email, hd string, verified bool) string {
+		return signer.idToken(t, &jwt.Claims{
+			Issuer:   "https://accounts.google.com",
+			Subject:  "1078",
+			Audience: []string{aud},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(30 * time.Minute)),
+		}, map[string]interface{}{
+			"email":          email,
+			"email_verified": verified,
+			"hd":             hd,
+		})
+	}
+	login := func(token string) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Data: map[string]interface{}{
+				"role": "devs",
+				"jwt":  token,
+			},
+			Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+		})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		resp, err := login(userToken(clientId, "alice@example.com", "example.com", true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+		assert.Equal(t, "1078", resp.Auth.Alias.Name)
+		assert.Equal(t, "alice@example.com", resp.Auth.DisplayName)
+		assert.Equal(t, map[string]string{
+			"role":          "devs",
+			"user_id":       "1078",
+			"email":         "alice@example.com",
+			"hosted_domain": "example.com",
+		}, resp.Auth.Metadata)
+		assert.Equal(t, []*logical.Alias{
+			{Name: "group-devs@example.com"},
+			{Name: "group-everyone@example.com"},
+		}, resp.Auth.GroupAliases)
+
+		resp.Auth.TokenPolicies = resp.Auth.Policies
+		req := &logical.Request{Storage: storage, Auth: resp.Auth}
+		resp, err = b.pathLoginRenew(ctx, req, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+	})
+
+	errCases := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{
+			name:  "wrong_audience",
+			token: userToken("https://vault.example.com", "alice@example.com", "example.com", true),
+			err:   fmt.Sprintf("ID token claim 'aud' must be %q", clientId),
+		},
+		{
+			name:  "unverified_email",
+			token: userToken(clientId, "alice@example.com", "example.com", false),
+			err:   "expected ID token to have verified 'email' claim",
+		},
+		{
+			name:  "other_hosted_domain",
+			token: userToken(clientId, "alice@example.org", "example.org", true),
+			err:   "not in bound hosted domains",
+		},
+		{
+			name:  "not_in_group",
+			token: userToken(clientId, "bob@example.com", "example.com", true),
+			err:   `user "bob@example.com" is not in bound emails or a member of bound groups`,
+		},
+	}
+	for _, tc := range errCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := login(tc.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.IsError() {
+				t.Fatal("expected error response")
+			}
+			assert.Contains(t, resp.Error().Error(), tc.err)
+		})
+	}
+}
+
+// testGoogleSigner is a stand-in for the keys that Google signs ID tokens
+// with.
+type testGoogleSigner struct {
+	kid     string
+	key     *rsa.PrivateKey
+	certPEM string
+}
+
+func newTestGoogleSigner(tb testing.TB) *testGoogleSigner {
+	tb.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "accounts.google.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return &testGoogleSigner{
+		kid:     "test-key",
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})),
+	}
+}
+
+// serveCerts serves the certificates of the signer like the Google OAuth2
+// certificates endpoint.
+func (s *testGoogleSigner) serveCerts(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{s.kid: s.certPEM})
+}
+
+// serveJWKS serves the public key of the signer like the JWKS endpoint of
+// the OIDC issuer of a GKE cluster.
+func (s *testGoogleSigner) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{{
+			Key:       &s.key.PublicKey,
+			KeyID:     s.kid,
+			Algorithm: string(jose.RS256),
+			Use:       "sig",
+		}},
+	})
+}
+
+// idToken returns an ID token with the given claims, signed by the signer.
+func (s *testGoogleSigner) idToken(tb testing.TB, claims ...interface{}) string {
+	tb.Helper()
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.RS256,
+		Key:       s.key,
+	}, (&jose.SignerOptions{}).WithHeader("kid", s.kid))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	builder := jwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+	token, err := builder.Serialize()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return token
+}
+
 func testIAMCredentialsClient(t *testing.T, creds *gcputil.GcpCredentials) *iamcredentials.Service {
 	t.Helper()
 
@@ -681,3 +1326,275 @@ func testCreateExpiredJwtToken(tb testing.TB, roleName string, creds *gcputil.Gc
 
 	return jwt
 }
+
+func TestLogin_ReplayProtection(t *testing.T) {
+	t.Parallel()
+
+	const clientId = "1234.apps.googleusercontent.com"
+
+	signer := newTestGoogleSigner(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /oauth2/v1/certs", signer.serveCerts)
+	b, storage := testBackendWithServer(t, mux)
+	ctx := context.Background()
+
+	for _, name := range []string{"once", "reusable"} {
+		testRoleCreate(t, b, storage, map[string]interface{}{
+			"name":              name,
+			"type":              userRoleType,
+			"bound_audiences":   clientId,
+			"bound_emails":      "alice@example.com",
+			"replay_protection": name == "once",
+		})
+	}
+
+	var n int
+	userToken := func() string {
+		n++
+		return signer.idToken(t, &jwt.Claims{
+			Issuer:   "https://accounts.google.com",
+			Subject:  "1078",
+			Audience: []string{clientId},
+			IssuedAt: jwt.NewNumericDate(time.Now().Add(time.Duration(n) * time.Second)),
+			Expiry:   jwt.NewNumericDate(time.Now().Add(30 * time.Minute)),
+		}, map[string]interface{}{
+			"email":          "alice@example.com",
+			"email_verified": true,
+		})
+	}
+	login := func(op logical.Operation, role, token string) *logical.Response {
+		t.Helper()
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      "login",
+			Data: map[string]interface{}{
+				"role": role,
+				"jwt":  token,
+			},
+			Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	token := userToken()
+	if resp := login(logical.UpdateOperation, "once", token); resp.IsError() {
+		t.Fatal(resp.Error())
+	}
+	resp := login(logical.UpdateOperation, "once", token)
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), errJWTReplayed.Error()) {
+		t.Fatalf("expected replayed JWT to be rejected, got %#v", resp)
+	}
+
+	// Roles without replay protection and alias lookaheads do not record JWTs.
+	for i := 0; i < 2; i++ {
+		if resp := login(logical.UpdateOperation, "reusable", token); resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+	}
+	token = userToken()
+	if resp := login(logical.AliasLookaheadOperation, "once", token); resp.IsError() {
+		t.Fatal(resp.Error())
+	}
+	if resp := login(logical.UpdateOperation, "once", token); resp.IsError() {
+		t.Fatal(resp.Error())
+	}
+
+	// Tidy deletes expired JWTs only.
+	entry, err := logical.StorageEntryJSON(replayCacheStoragePrefix+"expired", &replayCacheEntry{
+		ExpirationTime: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.doTidyReplayCache(ctx, storage); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := storage.List(ctx, replayCacheStoragePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, keys, 2)
+	assert.NotContains(t, keys, "expired")
+}
+
+func TestLogin_GoogleSignedTokensOnly(t *testing.T) {
+	t.Parallel()
+
+	const (
+		serviceAccountId    = "1234"
+		serviceAccountEmail = "dev@my-project.iam.gserviceaccount.com"
+		audience            = "https://vault.example.com/iam"
+	)
+
+	google := newTestGoogleSigner(t)
+	serviceAccountKey := newTestGoogleSigner(t)
+	serviceAccountKey.kid = "service-account-key"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /oauth2/v1/certs", google.serveCerts)
+	mux.HandleFunc("GET /service_accounts/v1/metadata/x509/{account}", serviceAccountKey.serveCerts)
+	mux.HandleFunc("GET /v1/projects/-/serviceAccounts/{account}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"uniqueId": %q, "email": %q, "projectId": "my-project"}`, serviceAccountId, serviceAccountEmail)
+	})
+	b, storage := testBackendWithServer(t, mux)
+	ctx := context.Background()
+
+	testRoleCreate(t, b, storage, map[string]interface{}{
+		"name":                   "jwt",
+		"type":                   iamRoleType,
+		"bound_service_accounts": serviceAccountEmail,
+	})
+	testRoleCreate(t, b, storage, map[string]interface{}{
+		"name":                   "id-token",
+		"type":                   iamRoleType,
+		"bound_service_accounts": serviceAccountEmail,
+		"bound_audiences":        audience,
+	})
+
+	selfSignedJWT := func(role string) string {
+		return serviceAccountKey.idToken(t, &jwt.Claims{
+			Subject:  serviceAccountEmail,
+			Audience: []string{fmt.Sprintf(expectedJwtAudTemplate, role)},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(10 * time.Minute)),
+		})
+	}
+	idToken := google.idToken(t, &jwt.Claims{
+		Issuer:   "https://accounts.google.com",
+		Subject:  serviceAccountId,
+		Audience: []string{audience},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}, map[string]interface{}{
+		"email":          serviceAccountEmail,
+		"email_verified": true,
+	})
+	login := func(role, token string) *logical.Response {
+		t.Helper()
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Data: map[string]interface{}{
+				"role": role,
+				"jwt":  token,
+			},
+			Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	const errGoogleSignedOnly = "only Google-signed ID tokens are accepted"
+
+	if resp := login("jwt", selfSignedJWT("jwt")); resp.IsError() {
+		t.Fatal(resp.Error())
+	}
+
+	// Roles with bound audiences only accept Google-signed ID tokens, which
+	// may expire after max_jwt_exp.
+	if resp := login("id-token", idToken); resp.IsError() {
+		t.Fatal(resp.Error())
+	}
+	resp := login("id-token", selfSignedJWT("id-token"))
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), errGoogleSignedOnly) {
+		t.Fatalf("expected self-signed JWT to be rejected, got %#v", resp)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data: map[string]interface{}{
+			"google_signed_tokens_only": true,
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("unable to update config: %v, %#v", err, resp)
+	}
+	testSeedCredentials(t, b)
+
+	resp = login("jwt", selfSignedJWT("jwt"))
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), errGoogleSignedOnly) {
+		t.Fatalf("expected self-signed JWT to be rejected, got %#v", resp)
+	}
+	if resp := login("id-token", idToken); resp.IsError() {
+		t.Fatal(resp.Error())
+	}
+}
+
+func TestLogin_GoogleGroupAliases(t *testing.T) {
+	t.Parallel()
+
+	const serviceAccountEmail = "api@my-project.iam.gserviceaccount.com"
+
+	var lookups int32
+	signer := newTestGoogleSigner(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /oauth2/v1/certs", signer.serveCerts)
+	mux.HandleFunc("GET /v2/projects/my-project/locations/us-central1/services/api", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name": "api", "template": {"serviceAccount": %q}}`, serviceAccountEmail)
+	})
+	mux.HandleFunc("GET /v1/groups/-/memberships:searchTransitiveGroups", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		if !strings.Contains(r.URL.Query().Get("query"), fmt.Sprintf("member_key_id == '%s'", serviceAccountEmail)) {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		fmt.Fprint(w, `{"memberships": [{"groupKey": {"id": "Deployers@example.com"}}, {"groupKey": {"id": "services@example.com"}}]}`)
+	})
+	b, storage := testBackendWithServer(t, mux)
+	ctx := context.Background()
+
+	testRoleCreate(t, b, storage, map[string]interface{}{
+		"name":                     "api",
+		"type":                     cloudRunRoleType,
+		"add_google_group_aliases": true,
+	})
+
+	idToken := signer.idToken(t, &jwt.Claims{
+		Issuer:   "https://accounts.google.com",
+		Subject:  "1234",
+		Audience: []string{testAudience},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(30 * time.Minute)),
+	}, map[string]interface{}{
+		"email":          serviceAccountEmail,
+		"email_verified": true,
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Data: map[string]interface{}{
+				"role":    "api",
+				"jwt":     idToken,
+				"service": "api",
+				"region":  "us-central1",
+			},
+			Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+		assert.Equal(t, []*logical.Alias{
+			{Name: "group-deployers@example.com"},
+			{Name: "group-services@example.com"},
+		}, resp.Auth.GroupAliases)
+	}
+
+	// The groups of the service account are cached across logins.
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lookups))
+}
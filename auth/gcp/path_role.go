@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/hashicorp/go-gcp-common/gcputil"
 	"github.com/hashicorp/go-uuid"
 	"github.com/openbao/openbao/sdk/v2/framework"
 	vaultconsts "github.com/openbao/openbao/sdk/v2/helper/consts"
@@ -19,8 +18,11 @@ import (
 
 const (
 	// Role types
-	iamRoleType = "iam"
-	gceRoleType = "gce"
+	iamRoleType      = "iam"
+	gceRoleType      = "gce"
+	cloudRunRoleType = "cloudrun"
+	gkeRoleType      = "gke"
+	userRoleType     = "user"
 
 	// Errors
 	errEmptyRoleName           = "role name is required"
@@ -48,7 +50,7 @@ func baseRoleFieldSchema() map[string]*framework.FieldSchema {
 		},
 		"type": {
 			Type:        framework.TypeString,
-			Description: "Type of the role. Currently supported: iam, gce",
+			Description: "Type of the role. Currently supported: iam, gce, cloudrun, gke, user",
 		},
 		// Token Limits
 		"policies": {
@@ -76,6 +78,18 @@ func baseRoleFieldSchema() map[string]*framework.FieldSchema {
 			Type:        framework.TypeCommaStringSlice,
 			Description: `GCP Projects that authenticating entities must belong to.`,
 		},
+		"bound_folders": {
+			Type: framework.TypeCommaStringSlice,
+			Description: "Comma-separated list of GCP folder IDs. Entities are authorized if their project " +
+				"is a descendant of one of these folders. Requires IAM permission " +
+				"`resourcemanager.projects.get` on the projects of entities.",
+		},
+		"bound_organizations": {
+			Type: framework.TypeCommaStringSlice,
+			Description: "Comma-separated list of GCP organization IDs. Entities are authorized if their " +
+				"project is a descendant of one of these organizations. Requires IAM permission " +
+				"`resourcemanager.projects.get` on the projects of entities.",
+		},
 		"bound_service_accounts": {
 			Type: framework.TypeCommaStringSlice,
 			Description: `
@@ -91,6 +105,35 @@ func baseRoleFieldSchema() map[string]*framework.FieldSchema {
 				"for the given entity's project. Requires IAM permission `resourcemanager.projects.get` " +
 				"on this project.",
 		},
+		"add_google_group_aliases": {
+			Type:    framework.TypeBool,
+			Default: false,
+			Description: "If true, will add group aliases to auth tokens generated under this role " +
+				"for the Google Groups that the authenticating service account is a direct or " +
+				"transitive member of, named 'group-<group email>'. Looked up with the Cloud " +
+				"Identity Groups API and cached for the configured google_groups_cache_ttl. " +
+				"Not supported by 'gke' and 'user' roles.",
+		},
+		"bound_audiences": {
+			Type: framework.TypeCommaStringSlice,
+			Description: "Comma-separated list of audiences that tokens must be issued for. If set " +
+				"on \"iam\" or \"gce\" roles, only Google-signed ID tokens are accepted. For " +
+				"\"cloudrun\" and \"gke\" roles, this overrides the audience of the config. For " +
+				"\"user\" roles, these are the OAuth client IDs of ID tokens and are required.",
+		},
+		"allow_glob_patterns": {
+			Type:    framework.TypeBool,
+			Default: false,
+			Description: "If true, values of bound_service_accounts, bound_labels and " +
+				"bound_instance_groups are glob patterns in which '*' matches any sequence " +
+				"of characters. Otherwise, they must match exactly.",
+		},
+		"replay_protection": {
+			Type:    framework.TypeBool,
+			Default: false,
+			Description: "If true, each JWT can only be used once to login under this role. " +
+				"Used JWTs are recorded in the local storage of the mount until they expire.",
+		},
 	}
 	tokenutil.AddTokenFields(d)
 	return d
@@ -123,7 +166,8 @@ var gceOnlyFieldSchema = map[string]*framework.FieldSchema{
 		Description: "Comma-separated list of permitted regions to which the GCE " +
 			"instance must belong. If a group is provided, it is assumed to be a " +
 			"regional group. If \"zone\" is provided, this option is ignored. This " +
-			"can be a self-link or region name. This option only applies to \"gce\" roles.",
+			"can be a self-link or region name. For \"cloudrun\" roles, this is the " +
+			"list of permitted regions of the service or function.",
 	},
 
 	"bound_instance_groups": {
@@ -136,7 +180,104 @@ var gceOnlyFieldSchema = map[string]*framework.FieldSchema{
 		Type: framework.TypeCommaStringSlice,
 		Description: "Comma-separated list of GCP labels formatted as" +
 			"\"key:value\" strings that must be present on the GCE instance " +
-			"in order to authenticate. This option only applies to \"gce\" roles.",
+			"in order to authenticate. For \"cloudrun\" roles, the labels must be " +
+			"present on the service or function.",
+	},
+
+	"require_secure_boot": {
+		Type:    framework.TypeBool,
+		Default: false,
+		Description: "If true, the GCE instance must have Shielded VM secure boot " +
+			"enabled. This option only applies to \"gce\" roles.",
+	},
+
+	"require_integrity_monitoring": {
+		Type:    framework.TypeBool,
+		Default: false,
+		Description: "If true, the GCE instance must have Shielded VM integrity " +
+			"monitoring enabled. This option only applies to \"gce\" roles.",
+	},
+
+	"require_confidential_vm": {
+		Type:    framework.TypeBool,
+		Default: false,
+		Description: "If true, the GCE instance must be a Confidential VM. This " +
+			"option only applies to \"gce\" roles.",
+	},
+
+	"bound_network_tags": {
+		Type: framework.TypeCommaStringSlice,
+		Description: "Comma-separated list of network tags that must all be present " +
+			"on the GCE instance. This option only applies to \"gce\" roles.",
+	},
+
+	"bound_image_families": {
+		Type: framework.TypeCommaStringSlice,
+		Description: "Comma-separated list of permitted image families, formatted as " +
+			"\"projects/$PROJECT/global/images/family/$FAMILY\", that the boot disk " +
+			"of the GCE instance must be created from. This option only applies to " +
+			"\"gce\" roles.",
+	},
+}
+
+var cloudRunOnlyFieldSchema = map[string]*framework.FieldSchema{
+	"bound_services": {
+		Type: framework.TypeCommaStringSlice,
+		Description: "Comma-separated list of permitted names of the Cloud Run service " +
+			"or Cloud Function that is authenticating. This option only applies to " +
+			"\"cloudrun\" roles.",
+	},
+}
+
+// cloudRunSharedGceFields are the 'gce' role attributes that also apply to
+// 'cloudrun' roles.
+var cloudRunSharedGceFields = []string{"bound_regions", "bound_labels"}
+
+var gkeOnlyFieldSchema = map[string]*framework.FieldSchema{
+	"bound_clusters": {
+		Type: framework.TypeCommaStringSlice,
+		Description: "Comma-separated list of GKE clusters, formatted as " +
+			"\"projects/$PROJECT/locations/$LOCATION/clusters/$CLUSTER\", whose " +
+			"Kubernetes service accounts are permitted to authenticate. This option " +
+			"only applies to \"gke\" roles and is required for them.",
+	},
+
+	"bound_namespaces": {
+		Type: framework.TypeCommaStringSlice,
+		Description: "Comma-separated list of permitted Kubernetes namespaces of " +
+			"the authenticating service account. If the single value \"*\" is given, " +
+			"all namespaces are permitted. This option only applies to \"gke\" roles " +
+			"and is required for them.",
+	},
+
+	"bound_kubernetes_service_accounts": {
+		Type: framework.TypeCommaStringSlice,
+		Description: "Comma-separated list of permitted names of the authenticating " +
+			"Kubernetes service account. If the single value \"*\" is given, all " +
+			"service accounts are permitted. This option only applies to \"gke\" " +
+			"roles and is required for them.",
+	},
+}
+
+var userOnlyFieldSchema = map[string]*framework.FieldSchema{
+	"bound_hosted_domains": {
+		Type: framework.TypeCommaStringSlice,
+		Description: "Comma-separated list of Google Workspace or Cloud Identity domains " +
+			"that users must belong to. This option only applies to \"user\" roles.",
+	},
+
+	"bound_emails": {
+		Type: framework.TypeCommaStringSlice,
+		Description: "Comma-separated list of verified emails of users permitted to " +
+			"authenticate. This option only applies to \"user\" roles.",
+	},
+
+	"bound_groups": {
+		Type: framework.TypeCommaStringSlice,
+		Description: "Comma-separated list of emails of Google Groups that users must be " +
+			"a direct or transitive member of. Users only need to match one of " +
+			"bound_emails or bound_groups. Requires the Groups Reader admin role. This " +
+			"option only applies to \"user\" roles.",
 	},
 }
 
@@ -152,6 +293,15 @@ func pathsRole(b *GcpAuthBackend) []*framework.Path {
 	for k, v := range gceOnlyFieldSchema {
 		roleFieldSchema[k] = v
 	}
+	for k, v := range cloudRunOnlyFieldSchema {
+		roleFieldSchema[k] = v
+	}
+	for k, v := range gkeOnlyFieldSchema {
+		roleFieldSchema[k] = v
+	}
+	for k, v := range userOnlyFieldSchema {
+		roleFieldSchema[k] = v
+	}
 	for k, v := range deprecatedFieldSchema {
 		roleFieldSchema[k] = v
 	}
@@ -258,8 +408,8 @@ func pathsRole(b *GcpAuthBackend) []*framework.Path {
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: b.pathRoleEditGceLabels,
 			},
-			HelpSynopsis: "Add or remove labels for an existing 'gce' role",
-			HelpDescription: `Add or remove labels for an existing 'gce' role. 'add' labels should be
+			HelpSynopsis: "Add or remove labels for an existing 'gce' or 'cloudrun' role",
+			HelpDescription: `Add or remove labels for an existing 'gce' or 'cloudrun' role. 'add' labels should be
 			of format '$key:$value' and 'remove' labels should be a list of keys to remove.`,
 		},
 	}
@@ -314,7 +464,19 @@ func (b *GcpAuthBackend) pathRoleRead(ctx context.Context, req *logical.Request,
 	if len(role.BoundProjects) > 0 {
 		respData["bound_projects"] = role.BoundProjects
 	}
+	if len(role.BoundFolders) > 0 {
+		respData["bound_folders"] = role.BoundFolders
+	}
+	if len(role.BoundOrganizations) > 0 {
+		respData["bound_organizations"] = role.BoundOrganizations
+	}
+	if len(role.BoundAudiences) > 0 {
+		respData["bound_audiences"] = role.BoundAudiences
+	}
 	respData["add_group_aliases"] = role.AddGroupAliases
+	respData["add_google_group_aliases"] = role.AddGoogleGroupAliases
+	respData["allow_glob_patterns"] = role.AllowGlobPatterns
+	respData["replay_protection"] = role.ReplayProtection
 
 	switch role.RoleType {
 	case iamRoleType:
@@ -335,6 +497,39 @@ func (b *GcpAuthBackend) pathRoleRead(ctx context.Context, req *logical.Request,
 		if len(role.BoundLabels) > 0 {
 			respData["bound_labels"] = role.BoundLabels
 		}
+		respData["require_secure_boot"] = role.RequireSecureBoot
+		respData["require_integrity_monitoring"] = role.RequireIntegrityMonitoring
+		respData["require_confidential_vm"] = role.RequireConfidentialVM
+		if len(role.BoundNetworkTags) > 0 {
+			respData["bound_network_tags"] = role.BoundNetworkTags
+		}
+		if len(role.BoundImageFamilies) > 0 {
+			respData["bound_image_families"] = role.BoundImageFamilies
+		}
+	case cloudRunRoleType:
+		if len(role.BoundServices) > 0 {
+			respData["bound_services"] = role.BoundServices
+		}
+		if len(role.BoundRegions) > 0 {
+			respData["bound_regions"] = role.BoundRegions
+		}
+		if len(role.BoundLabels) > 0 {
+			respData["bound_labels"] = role.BoundLabels
+		}
+	case gkeRoleType:
+		respData["bound_clusters"] = role.BoundClusters
+		respData["bound_namespaces"] = role.BoundNamespaces
+		respData["bound_kubernetes_service_accounts"] = role.BoundKubernetesServiceAccounts
+	case userRoleType:
+		if len(role.BoundHostedDomains) > 0 {
+			respData["bound_hosted_domains"] = role.BoundHostedDomains
+		}
+		if len(role.BoundEmails) > 0 {
+			respData["bound_emails"] = role.BoundEmails
+		}
+		if len(role.BoundGroups) > 0 {
+			respData["bound_groups"] = role.BoundGroups
+		}
 	}
 
 	// Upgrade vals
@@ -499,11 +694,11 @@ func (b *GcpAuthBackend) pathRoleEditGceLabels(ctx context.Context, req *logical
 		return nil, err
 	}
 
-	if role.RoleType != gceRoleType {
-		return logical.ErrorResponse(fmt.Sprintf(errTemplateEditListWrongType, role.RoleType, "labels", gceRoleType)), nil
+	if role.RoleType != gceRoleType && role.RoleType != cloudRunRoleType {
+		return logical.ErrorResponse(fmt.Sprintf(errTemplateEditListWrongType, role.RoleType, "labels", gceRoleType+" or "+cloudRunRoleType)), nil
 	}
 
-	labelsToAdd, invalidLabels := gcputil.ParseGcpLabels(toAdd)
+	labelsToAdd, invalidLabels := parseBoundLabels(toAdd, role.AllowGlobPatterns)
 	if len(invalidLabels) > 0 {
 		return logical.ErrorResponse(fmt.Sprintf("given invalid labels to add: %q", invalidLabels)), nil
 	}
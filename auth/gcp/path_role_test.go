@@ -23,31 +23,52 @@ import (
 // Defaults for verifying response data. If a value is not included here, it must be included in the
 // 'expected' map param for a test.
 var expectedDefaults = map[string]interface{}{
-	"token_policies":          []string{},
-	"policies":                []string{},
-	"token_ttl":               int64(0),
-	"ttl":                     int64(0),
-	"token_max_ttl":           int64(0),
-	"max_ttl":                 int64(0),
-	"token_period":            int64(0),
-	"period":                  int64(0),
-	"token_explicit_max_ttl":  int64(0),
-	"token_no_default_policy": false,
-	"token_bound_cidrs":       []string{},
-	"token_num_uses":          int(0),
-	"token_type":              logical.TokenTypeDefault.String(),
-	"bound_projects":          []string{},
-	"bound_service_accounts":  []string{},
-	"add_group_aliases":       false,
+	"token_policies":           []string{},
+	"policies":                 []string{},
+	"token_ttl":                int64(0),
+	"ttl":                      int64(0),
+	"token_max_ttl":            int64(0),
+	"max_ttl":                  int64(0),
+	"token_period":             int64(0),
+	"period":                   int64(0),
+	"token_explicit_max_ttl":   int64(0),
+	"token_no_default_policy":  false,
+	"token_bound_cidrs":        []string{},
+	"token_num_uses":           int(0),
+	"token_type":               logical.TokenTypeDefault.String(),
+	"bound_projects":           []string{},
+	"bound_folders":            []string{},
+	"bound_organizations":      []string{},
+	"bound_service_accounts":   []string{},
+	"add_group_aliases":        false,
+	"add_google_group_aliases": false,
+	"allow_glob_patterns":      false,
+	"replay_protection":        false,
 	// IAM
 	"max_jwt_exp":         int64(iamOnlyFieldSchema["max_jwt_exp"].Default.(int)),
 	"allow_gce_inference": iamOnlyFieldSchema["allow_gce_inference"].Default.(bool),
 	// GCE
-	"bound_zones":            []string{},
-	"bound_regions":          []string{},
-	"bound_instance_groups":  []string{},
-	"bound_labels":           map[string]string{},
-	"token_strictly_bind_ip": false,
+	"bound_zones":                  []string{},
+	"bound_regions":                []string{},
+	"bound_instance_groups":        []string{},
+	"bound_labels":                 map[string]string{},
+	"require_secure_boot":          false,
+	"require_integrity_monitoring": false,
+	"require_confidential_vm":      false,
+	"bound_network_tags":           []string{},
+	"bound_image_families":         []string{},
+	"token_strictly_bind_ip":       false,
+	// Cloud Run
+	"bound_services": []string{},
+	// GKE
+	"bound_clusters":                    []string{},
+	"bound_namespaces":                  []string{},
+	"bound_kubernetes_service_accounts": []string{},
+	// User
+	"bound_audiences":      []string{},
+	"bound_hosted_domains": []string{},
+	"bound_emails":         []string{},
+	"bound_groups":         []string{},
 }
 
 // -- IAM ROLE TESTS --
@@ -132,6 +153,89 @@ func TestRoleIam_Wildcard(t *testing.T) {
 	})
 }
 
+func TestRole_GlobPatterns(t *testing.T) {
+	t.Parallel()
+
+	b, reqStorage := testBackend(t)
+
+	roleName, projectId := testRoleAndProject(t)
+
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                   roleName,
+		"type":                   gceRoleType,
+		"bound_projects":         projectId,
+		"bound_labels":           "env:**",
+		"allow_glob_patterns":    true,
+		"bound_service_accounts": "*-ci@my-project.iam.gserviceaccount.com",
+	}, []string{
+		`invalid bound_labels: glob pattern "**" matches everything`,
+	})
+
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                   roleName,
+		"type":                   iamRoleType,
+		"bound_projects":         projectId,
+		"allow_glob_patterns":    true,
+		"bound_service_accounts": "*@*",
+	}, []string{
+		`invalid bound_service_accounts: glob pattern "*@*" matches everything`,
+	})
+
+	testRoleCreate(t, b, reqStorage, map[string]interface{}{
+		"name":                   roleName,
+		"type":                   gceRoleType,
+		"bound_projects":         projectId,
+		"bound_labels":           "env:prod-*",
+		"bound_instance_groups":  "web-*",
+		"bound_zones":            "us-central1-a",
+		"allow_glob_patterns":    true,
+		"bound_service_accounts": "ci-*@prj-*.iam.gserviceaccount.com",
+	})
+
+	testRoleRead(t, b, reqStorage, roleName, map[string]interface{}{
+		"type":                   gceRoleType,
+		"bound_projects":         []string{projectId},
+		"bound_labels":           map[string]string{"env": "prod-*"},
+		"bound_instance_groups":  []string{"web-*"},
+		"bound_zones":            []string{"us-central1-a"},
+		"allow_glob_patterns":    true,
+		"bound_service_accounts": []string{"ci-*@prj-*.iam.gserviceaccount.com"},
+	})
+
+	// Without the opt-in, patterns are stored but matched exactly.
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      fmt.Sprintf("role/%s", roleName),
+		Data: map[string]interface{}{
+			"allow_glob_patterns": false,
+		},
+		Storage: reqStorage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad response: %#v", resp)
+	}
+
+	expected := []string{
+		`bound_service_accounts value "ci-*@prj-*.iam.gserviceaccount.com" contains '*'`,
+		`bound_labels value "prod-*" contains '*'`,
+		`bound_instance_groups value "web-*" contains '*'`,
+	}
+	for _, e := range expected {
+		found := false
+		for _, w := range resp.Warnings {
+			if strings.Contains(w, e) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected warning %q in %q", e, resp.Warnings)
+		}
+	}
+}
+
 func TestRoleIam_EditServiceAccounts(t *testing.T) {
 	t.Parallel()
 
@@ -279,6 +383,48 @@ func TestRoleGce(t *testing.T) {
 	})
 }
 
+func TestRoleGce_InstanceSecurity(t *testing.T) {
+	t.Parallel()
+
+	b, reqStorage := testBackend(t)
+
+	roleName, projectId := testRoleAndProject(t)
+
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                 roleName,
+		"type":                 gceRoleType,
+		"bound_image_families": "debian-12",
+	}, []string{
+		`invalid bound_image_families: "debian-12" is not formatted as projects/$PROJECT/global/images/family/$FAMILY`,
+	})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                roleName + "-cloudrun",
+		"type":                cloudRunRoleType,
+		"require_secure_boot": true,
+	}, []string{fmt.Sprintf(errTemplateInvalidRoleTypeArgs, cloudRunRoleType, "require_secure_boot")})
+
+	testRoleCreate(t, b, reqStorage, map[string]interface{}{
+		"name":                         roleName,
+		"type":                         gceRoleType,
+		"bound_projects":               projectId,
+		"require_secure_boot":          true,
+		"require_integrity_monitoring": true,
+		"require_confidential_vm":      true,
+		"bound_network_tags":           "vault-client,prod",
+		"bound_image_families":         "projects/debian-cloud/global/images/family/debian-12",
+	})
+
+	testRoleRead(t, b, reqStorage, roleName, map[string]interface{}{
+		"type":                         gceRoleType,
+		"bound_projects":               []string{projectId},
+		"require_secure_boot":          true,
+		"require_integrity_monitoring": true,
+		"require_confidential_vm":      true,
+		"bound_network_tags":           []string{"vault-client", "prod"},
+		"bound_image_families":         []string{"projects/debian-cloud/global/images/family/debian-12"},
+	})
+}
+
 func TestRoleGce_EditLabels(t *testing.T) {
 	t.Parallel()
 
@@ -422,6 +568,293 @@ func TestRoleGce_DeprecatedFields(t *testing.T) {
 }
 
 // -- BASE ROLE TESTS --
+// -- Cloud Run ROLE TESTS --
+func TestRoleCloudRun(t *testing.T) {
+	t.Parallel()
+
+	b, reqStorage := testBackend(t)
+
+	roleName, _ := testRoleAndProject(t)
+
+	testRoleCreate(t, b, reqStorage, map[string]interface{}{
+		"name":           roleName,
+		"type":           cloudRunRoleType,
+		"bound_services": "api, worker,api",
+		"bound_regions":  "us-central1",
+		"bound_labels":   "env:prod",
+		"policies":       "dev",
+	})
+	testRoleRead(t, b, reqStorage, roleName, map[string]interface{}{
+		"name":           roleName,
+		"type":           cloudRunRoleType,
+		"bound_services": []string{"api", "worker"},
+		"bound_regions":  []string{"us-central1"},
+		"bound_labels":   map[string]string{"env": "prod"},
+		"policies":       []string{"dev"},
+		"token_policies": []string{"dev"},
+	})
+
+	testRoleEditLabels(t, b, reqStorage, map[string]interface{}{
+		"name":   roleName,
+		"add":    "team:payments",
+		"remove": "env",
+	})
+	testRoleRead(t, b, reqStorage, roleName, map[string]interface{}{
+		"name":           roleName,
+		"type":           cloudRunRoleType,
+		"bound_services": []string{"api", "worker"},
+		"bound_regions":  []string{"us-central1"},
+		"bound_labels":   map[string]string{"team": "payments"},
+		"policies":       []string{"dev"},
+		"token_policies": []string{"dev"},
+	})
+
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":        roleName + "-zones",
+		"type":        cloudRunRoleType,
+		"bound_zones": "us-central1-a",
+	}, []string{fmt.Sprintf(errTemplateInvalidRoleTypeArgs, cloudRunRoleType, "bound_zones")})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":        roleName + "-jwt",
+		"type":        cloudRunRoleType,
+		"max_jwt_exp": 60,
+	}, []string{fmt.Sprintf(errTemplateInvalidRoleTypeArgs, cloudRunRoleType, "max_jwt_exp")})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":           roleName + "-gce",
+		"type":           gceRoleType,
+		"bound_services": "api",
+	}, []string{fmt.Sprintf(errTemplateInvalidRoleTypeArgs, gceRoleType, "bound_services")})
+
+	// The service or function is given by the caller, so roles without bound
+	// service accounts are allowed with a warning.
+	for _, tc := range []struct {
+		sa      string
+		warning bool
+	}{
+		{"", true},
+		{"api@my-project.iam.gserviceaccount.com", false},
+	} {
+		data := map[string]interface{}{
+			"type":           cloudRunRoleType,
+			"bound_services": "api",
+		}
+		if tc.sa != "" {
+			data["bound_service_accounts"] = tc.sa
+		}
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      fmt.Sprintf("role/%s-sa", roleName),
+			Data:      data,
+			Storage:   reqStorage,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || resp.IsError() {
+			t.Fatalf("bad response: %#v", resp)
+		}
+		var found bool
+		for _, w := range resp.Warnings {
+			if strings.Contains(w, "bound_service_accounts is empty") {
+				found = true
+			}
+		}
+		if found != tc.warning {
+			t.Errorf("bound_service_accounts %q: expected warning %t, got %q", tc.sa, tc.warning, resp.Warnings)
+		}
+	}
+}
+
+// -- GKE ROLE TESTS --
+func TestRoleGKE(t *testing.T) {
+	t.Parallel()
+
+	b, reqStorage := testBackend(t)
+
+	roleName, _ := testRoleAndProject(t)
+	cluster := "projects/my-project/locations/us-central1/clusters/prod"
+
+	testRoleCreate(t, b, reqStorage, map[string]interface{}{
+		"name":                              roleName,
+		"type":                              gkeRoleType,
+		"bound_clusters":                    cluster,
+		"bound_namespaces":                  "payments, default,payments",
+		"bound_kubernetes_service_accounts": "*",
+		"policies":                          "dev",
+	})
+	testRoleRead(t, b, reqStorage, roleName, map[string]interface{}{
+		"name":                              roleName,
+		"type":                              gkeRoleType,
+		"bound_clusters":                    []string{cluster},
+		"bound_namespaces":                  []string{"payments", "default"},
+		"bound_kubernetes_service_accounts": []string{"*"},
+		"policies":                          []string{"dev"},
+		"token_policies":                    []string{"dev"},
+	})
+
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                              roleName + "-cluster",
+		"type":                              gkeRoleType,
+		"bound_clusters":                    "prod",
+		"bound_namespaces":                  "*",
+		"bound_kubernetes_service_accounts": "*",
+	}, []string{"invalid bound_clusters"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                              roleName + "-no-cluster",
+		"type":                              gkeRoleType,
+		"bound_namespaces":                  "*",
+		"bound_kubernetes_service_accounts": "*",
+	}, []string{"at least one bound cluster"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                              roleName + "-no-namespace",
+		"type":                              gkeRoleType,
+		"bound_clusters":                    cluster,
+		"bound_kubernetes_service_accounts": "*",
+	}, []string{"at least one bound namespace"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":             roleName + "-no-ksa",
+		"type":             gkeRoleType,
+		"bound_clusters":   cluster,
+		"bound_namespaces": "*",
+	}, []string{"at least one bound Kubernetes service account"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                              roleName + "-gsa",
+		"type":                              gkeRoleType,
+		"bound_clusters":                    cluster,
+		"bound_namespaces":                  "*",
+		"bound_kubernetes_service_accounts": "*",
+		"bound_service_accounts":            "*",
+	}, []string{"bound_service_accounts cannot be set"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                              roleName + "-regions",
+		"type":                              gkeRoleType,
+		"bound_clusters":                    cluster,
+		"bound_namespaces":                  "*",
+		"bound_kubernetes_service_accounts": "*",
+		"bound_regions":                     "us-central1",
+	}, []string{fmt.Sprintf(errTemplateInvalidRoleTypeArgs, gkeRoleType, "bound_regions")})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                              roleName + "-google-groups",
+		"type":                              gkeRoleType,
+		"bound_clusters":                    cluster,
+		"bound_namespaces":                  "*",
+		"bound_kubernetes_service_accounts": "*",
+		"add_google_group_aliases":          true,
+	}, []string{"add_google_group_aliases cannot be set for 'gke' roles"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":           roleName + "-gce",
+		"type":           gceRoleType,
+		"bound_clusters": cluster,
+	}, []string{fmt.Sprintf(errTemplateInvalidRoleTypeArgs, gceRoleType, "bound_clusters")})
+}
+
+// -- User ROLE TESTS --
+func TestRoleUser(t *testing.T) {
+	t.Parallel()
+
+	b, reqStorage := testBackend(t)
+
+	roleName, _ := testRoleAndProject(t)
+	clientId := "1234.apps.googleusercontent.com"
+
+	testRoleCreate(t, b, reqStorage, map[string]interface{}{
+		"name":                 roleName,
+		"type":                 userRoleType,
+		"bound_audiences":      clientId,
+		"bound_hosted_domains": "Example.com",
+		"bound_emails":         "alice@example.com, Alice@example.com",
+		"bound_groups":         "devs@example.com",
+		"add_group_aliases":    true,
+		"policies":             "dev",
+	})
+	testRoleRead(t, b, reqStorage, roleName, map[string]interface{}{
+		"name":                 roleName,
+		"type":                 userRoleType,
+		"bound_audiences":      []string{clientId},
+		"bound_hosted_domains": []string{"example.com"},
+		"bound_emails":         []string{"alice@example.com"},
+		"bound_groups":         []string{"devs@example.com"},
+		"add_group_aliases":    true,
+		"policies":             []string{"dev"},
+		"token_policies":       []string{"dev"},
+	})
+
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                 roleName + "-no-audience",
+		"type":                 userRoleType,
+		"bound_hosted_domains": "example.com",
+	}, []string{"at least one bound audience"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":            roleName + "-unbound",
+		"type":            userRoleType,
+		"bound_audiences": clientId,
+	}, []string{"at least one of bound_hosted_domains, bound_emails or bound_groups"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                 roleName + "-project",
+		"type":                 userRoleType,
+		"bound_audiences":      clientId,
+		"bound_hosted_domains": "example.com",
+		"bound_projects":       "my-project",
+	}, []string{"cannot be set for user role type"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                 roleName + "-zones",
+		"type":                 userRoleType,
+		"bound_audiences":      clientId,
+		"bound_hosted_domains": "example.com",
+		"bound_zones":          "us-central1-a",
+	}, []string{fmt.Sprintf(errTemplateInvalidRoleTypeArgs, userRoleType, "bound_zones")})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":         roleName + "-iam",
+		"type":         iamRoleType,
+		"bound_emails": "alice@example.com",
+	}, []string{fmt.Sprintf(errTemplateInvalidRoleTypeArgs, iamRoleType, "bound_emails")})
+}
+
+func TestRole_ResourceHierarchy(t *testing.T) {
+	t.Parallel()
+
+	b, reqStorage := testBackend(t)
+
+	roleName, _ := testRoleAndProject(t)
+
+	testRoleCreate(t, b, reqStorage, map[string]interface{}{
+		"name":                   "iam-" + roleName,
+		"type":                   iamRoleType,
+		"bound_service_accounts": "*",
+		"bound_folders":          "123, folders/456,123",
+		"bound_organizations":    "organizations/789",
+	})
+	testRoleRead(t, b, reqStorage, "iam-"+roleName, map[string]interface{}{
+		"name":                   "iam-" + roleName,
+		"type":                   iamRoleType,
+		"bound_service_accounts": []string{"*"},
+		"bound_folders":          []string{"123", "456"},
+		"bound_organizations":    []string{"789"},
+	})
+
+	testRoleCreate(t, b, reqStorage, map[string]interface{}{
+		"name":          "gce-" + roleName,
+		"type":          gceRoleType,
+		"bound_folders": "123",
+	})
+	testRoleRead(t, b, reqStorage, "gce-"+roleName, map[string]interface{}{
+		"name":          "gce-" + roleName,
+		"type":          gceRoleType,
+		"bound_folders": []string{"123"},
+	})
+
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":          roleName,
+		"type":          gceRoleType,
+		"bound_folders": "my-folder",
+	}, []string{"invalid bound_folders", "my-folder"})
+	testRoleCreateError(t, b, reqStorage, map[string]interface{}{
+		"name":                roleName,
+		"type":                gceRoleType,
+		"bound_organizations": "folders/123",
+	}, []string{"invalid bound_organizations"})
+}
+
 func TestRole_MissingRequiredArgs(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,125 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func pathTidyReplayCache(b *GcpAuthBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy/replay-cache$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixGoogleCloud,
+			OperationSuffix: "replay-cache",
+			OperationVerb:   "tidy",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathTidyReplayCacheUpdate,
+			},
+		},
+
+		HelpSynopsis:    pathTidyReplayCacheHelpSyn,
+		HelpDescription: pathTidyReplayCacheHelpDesc,
+	}
+}
+
+func (b *GcpAuthBackend) pathTidyReplayCacheUpdate(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	return b.tidyReplayCache(req)
+}
+
+// tidyReplayCache starts deleting the expired JWTs of the replay cache in the
+// background.
+func (b *GcpAuthBackend) tidyReplayCache(req *logical.Request) (*logical.Response, error) {
+	// If we are a performance standby forward the request to the active node
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+		return nil, logical.ErrReadOnly
+	}
+
+	if !atomic.CompareAndSwapUint32(b.tidyReplayCacheCASGuard, 0, 1) {
+		resp := &logical.Response{}
+		resp.AddWarning("Tidy operation already in progress.")
+		return resp, nil
+	}
+
+	s := req.Storage
+
+	go func() {
+		defer atomic.StoreUint32(b.tidyReplayCacheCASGuard, 0)
+
+		// Don't cancel when the original client request goes away
+		ctx := context.Background()
+
+		if err := b.doTidyReplayCache(ctx, s); err != nil {
+			b.Logger().Error("error running replay cache tidy", "error", err)
+		}
+	}()
+
+	resp := &logical.Response{}
+	resp.AddWarning("Tidy operation successfully started. Any information from the operation will be printed to OpenBao's server logs.")
+	return logical.RespondWithStatusCode(resp, req, http.StatusAccepted)
+}
+
+// doTidyReplayCache deletes the expired JWTs of the replay cache.
+func (b *GcpAuthBackend) doTidyReplayCache(ctx context.Context, s logical.Storage) error {
+	keys, err := s.List(ctx, replayCacheStoragePrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := b.tidyReplayCacheEntry(ctx, s, replayCacheStoragePrefix+key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *GcpAuthBackend) tidyReplayCacheEntry(ctx context.Context, s logical.Storage, key string) error {
+	b.replayCacheLock.Lock()
+	defer b.replayCacheLock.Unlock()
+
+	raw, err := s.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("error fetching replay cache entry %q: %w", key, err)
+	}
+	if raw == nil {
+		return nil
+	}
+
+	var entry replayCacheEntry
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return fmt.Errorf("error decoding replay cache entry %q: %w", key, err)
+	}
+
+	if time.Now().After(entry.ExpirationTime) {
+		if err := s.Delete(ctx, key); err != nil {
+			return fmt.Errorf("error deleting replay cache entry %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+const pathTidyReplayCacheHelpSyn = `
+Clean-up the expired JWTs of the replay cache.
+`
+
+const pathTidyReplayCacheHelpDesc = `
+Roles with replay protection record each JWT used to log in until the JWT
+expires, to reject logins that reuse a JWT. When this endpoint is invoked, the
+recorded JWTs that have expired are deleted. This also runs periodically.
+`
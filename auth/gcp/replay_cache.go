@@ -0,0 +1,87 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// replayCacheStoragePrefix is the prefix of the local storage entries of JWTs
+// used to log in under roles with replay protection.
+const replayCacheStoragePrefix = "replay/"
+
+var errJWTReplayed = errors.New("JWT has already been used to log in under this role")
+
+// replayCacheEntry records a JWT used to log in under a role with replay
+// protection until the JWT expires.
+type replayCacheEntry struct {
+	ExpirationTime time.Time `json:"expiration_time"`
+}
+
+// replayCacheKey returns the storage key of a JWT used to log in under the role
+// with the given ID. The key is a hash of the role ID and the payload of the
+// JWT, which is covered by the signature of the JWT, unlike the signature
+// itself.
+func replayCacheKey(roleID, signedJwt string) string {
+	payload := signedJwt
+	if parts := strings.Split(signedJwt, "."); len(parts) == 3 {
+		payload = parts[1]
+	}
+
+	sum := sha256.Sum256([]byte(roleID + "." + payload))
+	return replayCacheStoragePrefix + hex.EncodeToString(sum[:])
+}
+
+// recordJWT records the JWT of a successful login under the role of the login
+// until the JWT expires. It returns errJWTReplayed if the JWT was already used
+// to log in under the role.
+func (b *GcpAuthBackend) recordJWT(ctx context.Context, s logical.Storage, loginInfo *gcpLoginInfo, signedJwt string) error {
+	if loginInfo.JWTClaims == nil || loginInfo.JWTClaims.Expiry == nil {
+		return errors.New("JWT must have an 'exp' claim for replay protection")
+	}
+	// JWTs are accepted until their expiry plus the allowed clock skew.
+	expiration := loginInfo.JWTClaims.Expiry.Time().Add(jwtExpToleranceSec * time.Second)
+
+	key := replayCacheKey(loginInfo.Role.RoleID, signedJwt)
+
+	b.replayCacheLock.Lock()
+	defer b.replayCacheLock.Unlock()
+
+	raw, err := s.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to read replay cache: %w", err)
+	}
+	if raw != nil {
+		var entry replayCacheEntry
+		if err := raw.DecodeJSON(&entry); err != nil {
+			return fmt.Errorf("failed to decode replay cache entry: %w", err)
+		}
+		if time.Now().Before(entry.ExpirationTime) {
+			metrics.IncrCounterWithLabels([]string{"gcp", "login", "replay_rejected"}, 1,
+				[]metrics.Label{{Name: "role", Value: loginInfo.RoleName}})
+			return errJWTReplayed
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(key, &replayCacheEntry{
+		ExpirationTime: expiration,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to write replay cache: %w", err)
+	}
+
+	return nil
+}
@@ -23,6 +23,10 @@ type ApplicationsClient interface {
 	ListApplications(ctx context.Context, filter string) ([]Application, error)
 	AddApplicationPassword(ctx context.Context, applicationObjectID string, displayName string, endDateTime time.Time) (PasswordCredential, error)
 	RemoveApplicationPassword(ctx context.Context, applicationObjectID string, keyID string) error
+	AddApplicationKeyCredential(ctx context.Context, applicationObjectID string, displayName string, certificate []byte, startDateTime, endDateTime time.Time) (KeyCredential, error)
+	RemoveApplicationKeyCredential(ctx context.Context, applicationObjectID string, keyID string) error
+	AddApplicationFederatedIdentityCredential(ctx context.Context, applicationObjectID string, credential FederatedIdentityCredential) (FederatedIdentityCredential, error)
+	RemoveApplicationFederatedIdentityCredential(ctx context.Context, applicationObjectID string, credentialID string) error
 }
 
 var _ ApplicationsClient = (*MSGraphClient)(nil)
@@ -36,7 +40,10 @@ type MSGraphClient struct {
 type Application struct {
 	AppID               string
 	AppObjectID         string
+	DisplayName         string
+	CreatedDateTime     time.Time
 	PasswordCredentials []PasswordCredential
+	KeyCredentials      []KeyCredential
 }
 
 type PasswordCredential struct {
@@ -45,6 +52,21 @@ type PasswordCredential struct {
 	SecretText string
 }
 
+type FederatedIdentityCredential struct {
+	ID          string
+	Name        string
+	Description string
+	Issuer      string
+	Subject     string
+	Audiences   []string
+}
+
+type KeyCredential struct {
+	EndDate     time.Time
+	KeyID       string
+	DisplayName string
+}
+
 // NewMSGraphClient returns a new MSGraphClient configured to interact with
 // the Microsoft Graph API. It can be configured to target alternative national cloud
 // deployments via graphURI. For details on the client configuration see
@@ -87,6 +109,7 @@ func (c *MSGraphClient) GetApplication(ctx context.Context, applicationObjectID
 		AppID:               *app.GetAppId(),
 		AppObjectID:         *app.GetId(),
 		PasswordCredentials: getPasswordCredentialsForApplication(app),
+		KeyCredentials:      getKeyCredentialsForApplication(app),
 	}, nil
 }
 
@@ -175,6 +198,156 @@ func (c *MSGraphClient) RemoveApplicationPassword(ctx context.Context, applicati
 	return c.client.Applications().ByApplicationId(applicationObjectID).RemovePassword().Post(ctx, requestBody, nil)
 }
 
+// AddApplicationKeyCredential registers the DER encoded X.509 certificate as
+// a key credential of the application. Applications without an existing valid
+// certificate cannot use addKey, so the key credentials of the application
+// are updated instead. Existing key credentials are sent back without their
+// key, which leaves them unchanged. Callers must serialize updates to the key
+// credentials of an application.
+func (c *MSGraphClient) AddApplicationKeyCredential(ctx context.Context, applicationObjectID string, displayName string, certificate []byte, startDateTime, endDateTime time.Time) (KeyCredential, error) {
+	existing, err := c.getApplicationKeyCredentials(ctx, applicationObjectID)
+	if err != nil {
+		return KeyCredential{}, err
+	}
+
+	keyID := uuid.New()
+	keyType := "AsymmetricX509Cert"
+	usage := "Verify"
+	keyCredential := models.NewKeyCredential()
+	keyCredential.SetKeyId(&keyID)
+	keyCredential.SetDisplayName(&displayName)
+	keyCredential.SetTypeEscaped(&keyType)
+	keyCredential.SetUsage(&usage)
+	keyCredential.SetKey(certificate)
+	keyCredential.SetStartDateTime(&startDateTime)
+	keyCredential.SetEndDateTime(&endDateTime)
+
+	if err := c.updateApplicationKeyCredentials(ctx, applicationObjectID, append(existing, keyCredential)); err != nil {
+		return KeyCredential{}, err
+	}
+
+	return KeyCredential{
+		EndDate:     endDateTime,
+		KeyID:       keyID.String(),
+		DisplayName: displayName,
+	}, nil
+}
+
+// RemoveApplicationKeyCredential removes the key credential with the given
+// key ID from the application. Callers must serialize updates to the key
+// credentials of an application.
+func (c *MSGraphClient) RemoveApplicationKeyCredential(ctx context.Context, applicationObjectID string, keyID string) error {
+	existing, err := c.getApplicationKeyCredentials(ctx, applicationObjectID)
+	if err != nil {
+		return err
+	}
+
+	var remaining []models.KeyCredentialable
+	found := false
+	for _, cred := range existing {
+		if cred.GetKeyId() != nil && cred.GetKeyId().String() == keyID {
+			found = true
+			continue
+		}
+		remaining = append(remaining, cred)
+	}
+	if !found {
+		return fmt.Errorf("No key credential found with keyId %s", keyID)
+	}
+
+	return c.updateApplicationKeyCredentials(ctx, applicationObjectID, remaining)
+}
+
+// AddApplicationFederatedIdentityCredential adds a federated identity
+// credential to the application. The name of the credential must be unique
+// within the application.
+func (c *MSGraphClient) AddApplicationFederatedIdentityCredential(ctx context.Context, applicationObjectID string, credential FederatedIdentityCredential) (FederatedIdentityCredential, error) {
+	requestBody := models.NewFederatedIdentityCredential()
+	requestBody.SetName(&credential.Name)
+	requestBody.SetIssuer(&credential.Issuer)
+	requestBody.SetSubject(&credential.Subject)
+	requestBody.SetAudiences(credential.Audiences)
+	if credential.Description != "" {
+		requestBody.SetDescription(&credential.Description)
+	}
+
+	resp, err := c.client.Applications().ByApplicationId(applicationObjectID).FederatedIdentityCredentials().Post(ctx, requestBody, nil)
+	if err != nil {
+		return FederatedIdentityCredential{}, err
+	}
+
+	return getFederatedIdentityCredentialResponse(resp), nil
+}
+
+// RemoveApplicationFederatedIdentityCredential removes the federated identity
+// credential from the application.
+func (c *MSGraphClient) RemoveApplicationFederatedIdentityCredential(ctx context.Context, applicationObjectID string, credentialID string) error {
+	return c.client.Applications().ByApplicationId(applicationObjectID).FederatedIdentityCredentials().ByFederatedIdentityCredentialId(credentialID).Delete(ctx, nil)
+}
+
+func (c *MSGraphClient) getApplicationKeyCredentials(ctx context.Context, applicationObjectID string) ([]models.KeyCredentialable, error) {
+	configuration := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "keyCredentials"},
+		},
+	}
+	app, err := c.client.Applications().ByApplicationId(applicationObjectID).Get(ctx, configuration)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, fmt.Errorf("no application found")
+	}
+
+	return app.GetKeyCredentials(), nil
+}
+
+func (c *MSGraphClient) updateApplicationKeyCredentials(ctx context.Context, applicationObjectID string, keyCredentials []models.KeyCredentialable) error {
+	if keyCredentials == nil {
+		keyCredentials = []models.KeyCredentialable{}
+	}
+	requestBody := models.NewApplication()
+	requestBody.SetKeyCredentials(keyCredentials)
+
+	_, err := c.client.Applications().ByApplicationId(applicationObjectID).Patch(ctx, requestBody, nil)
+	return err
+}
+
+func getKeyCredentialsForApplication(app models.Applicationable) []KeyCredential {
+	var keyCredentials []KeyCredential
+	for _, cred := range app.GetKeyCredentials() {
+		if cred == nil {
+			continue
+		}
+		kc := KeyCredential{
+			DisplayName: ptrToString(cred.GetDisplayName()),
+		}
+		if cred.GetKeyId() != nil {
+			kc.KeyID = cred.GetKeyId().String()
+		}
+		if cred.GetEndDateTime() != nil {
+			kc.EndDate = *cred.GetEndDateTime()
+		}
+		keyCredentials = append(keyCredentials, kc)
+	}
+
+	return keyCredentials
+}
+
+func getFederatedIdentityCredentialResponse(cred models.FederatedIdentityCredentialable) FederatedIdentityCredential {
+	if cred == nil {
+		return FederatedIdentityCredential{}
+	}
+	return FederatedIdentityCredential{
+		ID:          ptrToString(cred.GetId()),
+		Name:        ptrToString(cred.GetName()),
+		Description: ptrToString(cred.GetDescription()),
+		Issuer:      ptrToString(cred.GetIssuer()),
+		Subject:     ptrToString(cred.GetSubject()),
+		Audiences:   cred.GetAudiences(),
+	}
+}
+
 func getPasswordCredentialsForApplication(app models.Applicationable) []PasswordCredential {
 	var appCredentials []PasswordCredential
 	creds := app.GetPasswordCredentials()
@@ -194,12 +367,22 @@ func ptrToString(s *string) string {
 	return ""
 }
 
+func ptrToTime(t *time.Time) time.Time {
+	if t != nil {
+		return *t
+	}
+	return time.Time{}
+}
+
 func getApplicationResponse(app models.Applicationable) Application {
 	if app != nil {
 		return Application{
 			AppID:               ptrToString(app.GetAppId()),
 			AppObjectID:         ptrToString(app.GetId()),
+			DisplayName:         ptrToString(app.GetDisplayName()),
+			CreatedDateTime:     ptrToTime(app.GetCreatedDateTime()),
 			PasswordCredentials: getPasswordCredentialsForApplication(app),
+			KeyCredentials:      getKeyCredentialsForApplication(app),
 		}
 
 	}
@@ -0,0 +1,70 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+import (
+	"context"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/rolemanagement"
+)
+
+// DirectoryRolesClient manages Microsoft Entra directory role assignments
+// through Graph role management.
+type DirectoryRolesClient interface {
+	ListDirectoryRoleDefinitions(ctx context.Context, filter string) ([]DirectoryRoleDefinition, error)
+	// CreateDirectoryRoleAssignment assigns a directory role to a principal. The
+	// directory scope is "/" for the whole tenant or
+	// "/administrativeUnits/{id}" for an administrative unit.
+	CreateDirectoryRoleAssignment(ctx context.Context, principalID, roleDefinitionID, directoryScopeID string) (string, error)
+	DeleteDirectoryRoleAssignment(ctx context.Context, assignmentID string) error
+}
+
+type DirectoryRoleDefinition struct {
+	ID          string
+	TemplateID  string
+	DisplayName string
+}
+
+func (c *MSGraphClient) ListDirectoryRoleDefinitions(ctx context.Context, filter string) ([]DirectoryRoleDefinition, error) {
+	req := &rolemanagement.DirectoryRoleDefinitionsRequestBuilderGetQueryParameters{
+		Filter: &filter,
+	}
+	configuration := &rolemanagement.DirectoryRoleDefinitionsRequestBuilderGetRequestConfiguration{
+		QueryParameters: req,
+	}
+
+	resp, err := c.client.RoleManagement().Directory().RoleDefinitions().Get(ctx, configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []DirectoryRoleDefinition
+	for _, def := range resp.GetValue() {
+		result = append(result, DirectoryRoleDefinition{
+			ID:          ptrToString(def.GetId()),
+			TemplateID:  ptrToString(def.GetTemplateId()),
+			DisplayName: ptrToString(def.GetDisplayName()),
+		})
+	}
+	return result, nil
+}
+
+func (c *MSGraphClient) CreateDirectoryRoleAssignment(ctx context.Context, principalID, roleDefinitionID, directoryScopeID string) (string, error) {
+	req := models.NewUnifiedRoleAssignment()
+	req.SetPrincipalId(&principalID)
+	req.SetRoleDefinitionId(&roleDefinitionID)
+	req.SetDirectoryScopeId(&directoryScopeID)
+
+	resp, err := c.client.RoleManagement().Directory().RoleAssignments().Post(ctx, req, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return ptrToString(resp.GetId()), nil
+}
+
+func (c *MSGraphClient) DeleteDirectoryRoleAssignment(ctx context.Context, assignmentID string) error {
+	return c.client.RoleManagement().Directory().RoleAssignments().ByUnifiedRoleAssignmentId(assignmentID).Delete(ctx, nil)
+}
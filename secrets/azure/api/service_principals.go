@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/hashicorp/go-multierror"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
 	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
@@ -16,24 +17,46 @@ import (
 type ServicePrincipalClient interface {
 	// CreateServicePrincipal in Azure. The password returned is the actual password that the appID was created with
 	CreateServicePrincipal(ctx context.Context, appID string, startDate time.Time, endDate time.Time) (id string, password string, err error)
+	// CreateServicePrincipalWithoutPassword in Azure. The service principal is created without any credentials
+	CreateServicePrincipalWithoutPassword(ctx context.Context, appID string) (id string, err error)
 	DeleteServicePrincipal(ctx context.Context, spObjectID string, permanentlyDelete bool) error
+	// ListServicePrincipals returns the service principals of the application with the given appID
+	ListServicePrincipals(ctx context.Context, appID string) ([]ServicePrincipal, error)
+
+	// AddAppRoleAssignment grants the app role of a resource service principal to a service principal
+	AddAppRoleAssignment(ctx context.Context, spObjectID, resourceObjectID, appRoleID string) (AppRoleAssignment, error)
+	RemoveAppRoleAssignment(ctx context.Context, spObjectID, assignmentID string) error
+	ListAppRoleAssignments(ctx context.Context, spObjectID string) ([]AppRoleAssignment, error)
 }
 
 type ServicePrincipal struct {
-	ID    string
-	AppID string
+	ID       string
+	AppID    string
+	AppRoles []AppRole
 }
 
-func (c *MSGraphClient) CreateServicePrincipal(ctx context.Context, appID string, startDate time.Time, endDate time.Time) (string, string, error) {
-	spReq := models.NewServicePrincipal()
-	spReq.SetAppId(&appID)
+// AppRole is a role defined by an application, which can be assigned to
+// users, groups or service principals.
+type AppRole struct {
+	ID                 string
+	Value              string
+	AllowedMemberTypes []string
+	IsEnabled          bool
+}
 
-	sp, err := c.client.ServicePrincipals().Post(ctx, spReq, nil)
+type AppRoleAssignment struct {
+	ID         string
+	AppRoleID  string
+	ResourceID string
+}
+
+func (c *MSGraphClient) CreateServicePrincipal(ctx context.Context, appID string, startDate time.Time, endDate time.Time) (string, string, error) {
+	id, err := c.CreateServicePrincipalWithoutPassword(ctx, appID)
 	if err != nil {
 		return "", "", err
 	}
 
-	spID := sp.GetId()
+	spID := &id
 
 	passwordReq := serviceprincipals.NewItemAddPasswordPostRequestBody()
 	passwordCredential := models.NewPasswordCredential()
@@ -52,6 +75,18 @@ func (c *MSGraphClient) CreateServicePrincipal(ctx context.Context, appID string
 	return *spID, *password.GetSecretText(), nil
 }
 
+func (c *MSGraphClient) CreateServicePrincipalWithoutPassword(ctx context.Context, appID string) (string, error) {
+	spReq := models.NewServicePrincipal()
+	spReq.SetAppId(&appID)
+
+	sp, err := c.client.ServicePrincipals().Post(ctx, spReq, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return ptrToString(sp.GetId()), nil
+}
+
 func (c *MSGraphClient) DeleteServicePrincipal(ctx context.Context, spObjectID string, permanentlyDelete bool) error {
 	err := c.client.ServicePrincipals().ByServicePrincipalId(spObjectID).Delete(ctx, nil)
 
@@ -64,8 +99,8 @@ func (c *MSGraphClient) DeleteServicePrincipal(ctx context.Context, spObjectID s
 	return err
 }
 
-func (c *MSGraphClient) ListServicePrincipals(ctx context.Context, spObjectID string) ([]ServicePrincipal, error) {
-	filter := fmt.Sprintf("appId eq '%s'", spObjectID)
+func (c *MSGraphClient) ListServicePrincipals(ctx context.Context, appID string) ([]ServicePrincipal, error) {
+	filter := fmt.Sprintf("appId eq '%s'", appID)
 	requestParameters := &serviceprincipals.ServicePrincipalsRequestBuilderGetQueryParameters{
 		Filter: &filter,
 	}
@@ -95,11 +130,85 @@ func (c *MSGraphClient) GetServicePrincipalByID(ctx context.Context, spObjectID
 	return getServicePrincipalResponse(sp), nil
 }
 
+func (c *MSGraphClient) AddAppRoleAssignment(ctx context.Context, spObjectID, resourceObjectID, appRoleID string) (AppRoleAssignment, error) {
+	principalID, err := uuid.Parse(spObjectID)
+	if err != nil {
+		return AppRoleAssignment{}, fmt.Errorf("invalid service principal object ID: %w", err)
+	}
+	resourceID, err := uuid.Parse(resourceObjectID)
+	if err != nil {
+		return AppRoleAssignment{}, fmt.Errorf("invalid resource object ID: %w", err)
+	}
+	roleID, err := uuid.Parse(appRoleID)
+	if err != nil {
+		return AppRoleAssignment{}, fmt.Errorf("invalid app role ID: %w", err)
+	}
+
+	req := models.NewAppRoleAssignment()
+	req.SetPrincipalId(&principalID)
+	req.SetResourceId(&resourceID)
+	req.SetAppRoleId(&roleID)
+
+	resp, err := c.client.ServicePrincipals().ByServicePrincipalId(spObjectID).AppRoleAssignments().Post(ctx, req, nil)
+	if err != nil {
+		return AppRoleAssignment{}, err
+	}
+
+	return getAppRoleAssignmentResponse(resp), nil
+}
+
+func (c *MSGraphClient) RemoveAppRoleAssignment(ctx context.Context, spObjectID, assignmentID string) error {
+	return c.client.ServicePrincipals().ByServicePrincipalId(spObjectID).AppRoleAssignments().ByAppRoleAssignmentId(assignmentID).Delete(ctx, nil)
+}
+
+func (c *MSGraphClient) ListAppRoleAssignments(ctx context.Context, spObjectID string) ([]AppRoleAssignment, error) {
+	resp, err := c.client.ServicePrincipals().ByServicePrincipalId(spObjectID).AppRoleAssignments().Get(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []AppRoleAssignment
+	for _, assignment := range resp.GetValue() {
+		result = append(result, getAppRoleAssignmentResponse(assignment))
+	}
+	return result, nil
+}
+
+func getAppRoleAssignmentResponse(assignment models.AppRoleAssignmentable) AppRoleAssignment {
+	if assignment == nil {
+		return AppRoleAssignment{}
+	}
+
+	return AppRoleAssignment{
+		ID:         ptrToString(assignment.GetId()),
+		AppRoleID:  uuidToString(assignment.GetAppRoleId()),
+		ResourceID: uuidToString(assignment.GetResourceId()),
+	}
+}
+
+func uuidToString(u *uuid.UUID) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
 func getServicePrincipalResponse(sp models.ServicePrincipalable) ServicePrincipal {
 	if sp != nil {
+		var appRoles []AppRole
+		for _, role := range sp.GetAppRoles() {
+			appRoles = append(appRoles, AppRole{
+				ID:                 uuidToString(role.GetId()),
+				Value:              ptrToString(role.GetValue()),
+				AllowedMemberTypes: role.GetAllowedMemberTypes(),
+				IsEnabled:          role.GetIsEnabled() != nil && *role.GetIsEnabled(),
+			})
+		}
+
 		return ServicePrincipal{
-			ID:    ptrToString(sp.GetId()),
-			AppID: ptrToString(sp.GetAppId()),
+			ID:       ptrToString(sp.GetId()),
+			AppID:    ptrToString(sp.GetAppId()),
+			AppRoles: appRoles,
 		}
 	}
 	return ServicePrincipal{
@@ -0,0 +1,169 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+const (
+	storageModuleName    = "azure-secrets-storage"
+	storageModuleVersion = "v1.0.0"
+
+	// storageAccountsAPIVersion is the Azure Resource Manager API version of
+	// the storage accounts requests.
+	storageAccountsAPIVersion = "2023-01-01"
+
+	// StorageServiceVersion is the version of the Blob service requests and
+	// of the shared access signatures.
+	StorageServiceVersion = "2022-11-02"
+
+	storageScope = "https://storage.azure.com/.default"
+)
+
+// StorageClient retrieves the keys used to sign shared access signatures of
+// Azure Storage accounts.
+type StorageClient interface {
+	// ListStorageAccountKeys lists the access keys of a storage account.
+	ListStorageAccountKeys(ctx context.Context, resourceGroup, account string) ([]StorageAccountKey, error)
+	// GetUserDelegationKey requests a user delegation key for the Blob
+	// service of a storage account. The key is valid between start and
+	// expiry, for at most 7 days.
+	GetUserDelegationKey(ctx context.Context, account string, start, expiry time.Time) (UserDelegationKey, error)
+}
+
+type StorageAccountKey struct {
+	KeyName     string `json:"keyName"`
+	Value       string `json:"value"`
+	Permissions string `json:"permissions"`
+}
+
+// UserDelegationKey is a key of the Blob service used to sign user
+// delegation shared access signatures.
+type UserDelegationKey struct {
+	SignedOID     string `xml:"SignedOid"`
+	SignedTID     string `xml:"SignedTid"`
+	SignedStart   string `xml:"SignedStart"`
+	SignedExpiry  string `xml:"SignedExpiry"`
+	SignedService string `xml:"SignedService"`
+	SignedVersion string `xml:"SignedVersion"`
+	Value         string `xml:"Value"`
+}
+
+var _ StorageClient = (*StorageRESTClient)(nil)
+
+// StorageRESTClient is a StorageClient using the REST APIs of Azure Resource
+// Manager and the Blob service.
+type StorageRESTClient struct {
+	subscriptionID string
+	armEndpoint    string
+	blobSuffix     string
+	armPipeline    runtime.Pipeline
+	blobPipeline   runtime.Pipeline
+}
+
+// NewStorageRESTClient returns a new StorageRESTClient. Storage accounts are
+// managed through the Azure Resource Manager endpoint of the cloud of the
+// options, and their Blob service is at https://<account>.blob.<blobSuffix>.
+func NewStorageRESTClient(subscriptionID, blobSuffix string, cred azcore.TokenCredential, options policy.ClientOptions) (*StorageRESTClient, error) {
+	rm, ok := options.Cloud.Services[cloud.ResourceManager]
+	if !ok {
+		return nil, fmt.Errorf("no resource manager endpoint in cloud configuration")
+	}
+
+	armPipeline := runtime.NewPipeline(storageModuleName, storageModuleVersion, runtime.PipelineOptions{
+		PerRetry: []policy.Policy{runtime.NewBearerTokenPolicy(cred, []string{strings.TrimSuffix(rm.Audience, "/") + "/.default"}, nil)},
+	}, &options)
+	blobPipeline := runtime.NewPipeline(storageModuleName, storageModuleVersion, runtime.PipelineOptions{
+		PerRetry: []policy.Policy{runtime.NewBearerTokenPolicy(cred, []string{storageScope}, nil)},
+	}, &options)
+
+	return &StorageRESTClient{
+		subscriptionID: subscriptionID,
+		armEndpoint:    strings.TrimSuffix(rm.Endpoint, "/"),
+		blobSuffix:     blobSuffix,
+		armPipeline:    armPipeline,
+		blobPipeline:   blobPipeline,
+	}, nil
+}
+
+func (c *StorageRESTClient) ListStorageAccountKeys(ctx context.Context, resourceGroup, account string) ([]StorageAccountKey, error) {
+	endpoint := fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Storage/storageAccounts/%s/listKeys",
+		c.armEndpoint, url.PathEscape(c.subscriptionID), url.PathEscape(resourceGroup), url.PathEscape(account))
+
+	req, err := runtime.NewRequest(ctx, http.MethodPost, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	query := req.Raw().URL.Query()
+	query.Set("api-version", storageAccountsAPIVersion)
+	req.Raw().URL.RawQuery = query.Encode()
+	req.Raw().Header.Set("Accept", "application/json")
+
+	resp, err := c.armPipeline.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return nil, runtime.NewResponseError(resp)
+	}
+
+	var result struct {
+		Keys []StorageAccountKey `json:"keys"`
+	}
+	if err := runtime.UnmarshalAsJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Keys, nil
+}
+
+func (c *StorageRESTClient) GetUserDelegationKey(ctx context.Context, account string, start, expiry time.Time) (UserDelegationKey, error) {
+	endpoint := fmt.Sprintf("https://%s.blob.%s/", account, c.blobSuffix)
+
+	req, err := runtime.NewRequest(ctx, http.MethodPost, endpoint)
+	if err != nil {
+		return UserDelegationKey{}, err
+	}
+	req.Raw().URL.RawQuery = "restype=service&comp=userdelegationkey"
+	req.Raw().Header.Set("x-ms-version", StorageServiceVersion)
+
+	keyInfo := struct {
+		XMLName xml.Name `xml:"KeyInfo"`
+		Start   string   `xml:"Start"`
+		Expiry  string   `xml:"Expiry"`
+	}{
+		Start:  start.UTC().Format(time.RFC3339),
+		Expiry: expiry.UTC().Format(time.RFC3339),
+	}
+	if err := runtime.MarshalAsXML(req, keyInfo); err != nil {
+		return UserDelegationKey{}, err
+	}
+
+	resp, err := c.blobPipeline.Do(req)
+	if err != nil {
+		return UserDelegationKey{}, err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return UserDelegationKey{}, runtime.NewResponseError(resp)
+	}
+
+	var key UserDelegationKey
+	if err := runtime.UnmarshalAsXML(resp, &key); err != nil {
+		return UserDelegationKey{}, err
+	}
+
+	return key, nil
+}
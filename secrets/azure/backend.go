@@ -10,11 +10,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/hashicorp/go-hclog"
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/helper/consts"
 	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/sdk/v2/queue"
 )
 
 const (
@@ -28,6 +30,7 @@ type azureSecretBackend struct {
 	*framework.Backend
 
 	getProvider func(context.Context, hclog.Logger, logical.SystemView, *clientSettings) (AzureProvider, error)
+	getAppToken func(ctx context.Context, settings *clientSettings, clientID, clientSecret, scope string) (azcore.AccessToken, error)
 	client      *client
 	settings    *clientSettings
 	lock        sync.RWMutex
@@ -36,6 +39,23 @@ type azureSecretBackend struct {
 	// operation that must be locked per Application Object ID.
 	appLocks       []*locksutil.LockEntry
 	updatePassword bool
+
+	// groupMembershipLocks guard the lease counts of group memberships of
+	// existing principals, per principal and group.
+	groupMembershipLocks []*locksutil.LockEntry
+
+	// staticRotationQueue orders static roles by their next rotation or
+	// previous password removal. staticRolesLock guards static roles, their
+	// credentials and the queue.
+	staticRotationQueue *queue.PriorityQueue
+	staticRolesLock     sync.RWMutex
+
+	// mountID identifies the applications created by this mount, see
+	// getMountTag. lastOrphanTidy is the time of the last periodic tidy of
+	// orphaned applications.
+	mountID        string
+	mountIDLock    sync.Mutex
+	lastOrphanTidy time.Time
 }
 
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
@@ -59,19 +79,30 @@ func backend() *azureSecretBackend {
 			},
 			SealWrapStorage: []string{
 				"config",
+				staticCredsStoragePath + "/",
+				tokenCredsStoragePath + "/",
 			},
 		},
 		Paths: framework.PathAppend(
 			pathsRole(&b),
+			pathsStaticRole(&b),
+			pathsSASRole(&b),
 			[]*framework.Path{
 				pathConfig(&b),
 				pathServicePrincipal(&b),
 				pathRotateRoot(&b),
+				pathStaticCreds(&b),
+				pathRotateStaticRole(&b),
+				pathToken(&b),
+				pathGroupMembership(&b),
+				pathTidyOrphans(&b),
+				pathSAS(&b),
 			},
 		),
 		Secrets: []*framework.Secret{
 			secretServicePrincipal(&b),
 			secretStaticServicePrincipal(&b),
+			secretGroupMembership(&b),
 		},
 		BackendType: logical.TypeLogical,
 		Invalidate:  b.invalidate,
@@ -80,11 +111,15 @@ func backend() *azureSecretBackend {
 		// to roll back during creation.
 		WALRollbackMinAge: 10 * time.Minute,
 
-		WALRollback:  b.walRollback,
-		PeriodicFunc: b.periodicFunc,
+		WALRollback:    b.walRollback,
+		PeriodicFunc:   b.periodicFunc,
+		InitializeFunc: b.initStaticRotationQueue,
 	}
 	b.getProvider = newAzureProvider
+	b.getAppToken = getAppAccessToken
 	b.appLocks = locksutil.CreateLocks()
+	b.groupMembershipLocks = locksutil.CreateLocks()
+	b.staticRotationQueue = queue.New()
 
 	return &b
 }
@@ -99,6 +134,13 @@ func (b *azureSecretBackend) periodicFunc(ctx context.Context, sys *logical.Requ
 		!replicationState.HasState(consts.ReplicationPerformanceStandby) {
 
 		b.Logger().Debug("starting periodic func")
+		if err := b.rotateExpiredStaticRoles(ctx, sys); err != nil {
+			b.Logger().Error("failed to rotate static roles", "error", err)
+		}
+		if err := b.tidyOrphansPeriodic(ctx, sys.Storage); err != nil {
+			b.Logger().Error("failed to tidy orphaned applications", "error", err)
+		}
+
 		if !b.updatePassword {
 			b.Logger().Debug("periodic func", "rotate-root", "no rotate-root update")
 			return nil
@@ -0,0 +1,158 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCertificateKeyBits = 2048
+
+	// certificateBackdate is subtracted from the start of the validity period
+	// of issued certificates to allow for clock skew.
+	certificateBackdate = 30 * time.Second
+)
+
+var validCertificateKeyBits = []int{2048, 3072, 4096}
+
+// issuedCertificate is a certificate issued for use as the key credential of
+// an application.
+type issuedCertificate struct {
+	der        []byte
+	certPEM    string
+	keyPEM     string // empty if the certificate was issued for a CSR
+	issuerPEM  string // the one-time issuer of a certificate issued for a CSR
+	thumbprint string
+	notBefore  time.Time
+	notAfter   time.Time
+}
+
+// issueCertificate creates a certificate valid for the given duration. If
+// csr is nil, a new RSA key pair is generated and the certificate is
+// self-signed. Otherwise, the certificate is issued for the public key of the
+// CSR, and the caller keeps the private key.
+//
+// There is no CA to issue certificates for CSRs. Microsoft Entra ID only uses
+// the public key of a key credential, so the certificate is signed by a
+// one-time issuer. The issuer certificate is returned so that the certificate
+// can be verified, and its key is discarded.
+func issueCertificate(commonName string, keyBits int, csr *x509.CertificateRequest, duration time.Duration) (*issuedCertificate, error) {
+	now := time.Now()
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-certificateBackdate),
+		NotAfter:              now.Add(duration),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("error generating serial number: %w", err)
+	}
+	template.SerialNumber = serial
+
+	result := &issuedCertificate{}
+	var publicKey *rsa.PublicKey
+	var signer *rsa.PrivateKey
+	parent := template
+	if csr == nil {
+		key, err := rsa.GenerateKey(rand.Reader, keyBits)
+		if err != nil {
+			return nil, fmt.Errorf("error generating private key: %w", err)
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding private key: %w", err)
+		}
+		result.keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+		publicKey, signer = &key.PublicKey, key
+	} else {
+		publicKey = csr.PublicKey.(*rsa.PublicKey)
+		if csr.Subject.CommonName != "" {
+			template.Subject = csr.Subject
+		}
+
+		signer, err = rsa.GenerateKey(rand.Reader, defaultCertificateKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("error generating issuer key: %w", err)
+		}
+		issuerSerial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+		if err != nil {
+			return nil, fmt.Errorf("error generating serial number: %w", err)
+		}
+		issuerTemplate := &x509.Certificate{
+			SerialNumber:          issuerSerial,
+			Subject:               pkix.Name{CommonName: commonName + " one-time issuer"},
+			NotBefore:             template.NotBefore,
+			NotAfter:              template.NotAfter,
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			MaxPathLenZero:        true,
+		}
+		issuerDER, err := x509.CreateCertificate(rand.Reader, issuerTemplate, issuerTemplate, &signer.PublicKey, signer)
+		if err != nil {
+			return nil, fmt.Errorf("error creating issuer certificate: %w", err)
+		}
+		if parent, err = x509.ParseCertificate(issuerDER); err != nil {
+			return nil, fmt.Errorf("error parsing issuer certificate: %w", err)
+		}
+		result.issuerPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuerDER}))
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, signer)
+	if err != nil {
+		return nil, fmt.Errorf("error creating certificate: %w", err)
+	}
+
+	thumbprint := sha1.Sum(der)
+	result.der = der
+	result.certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	result.thumbprint = strings.ToUpper(hex.EncodeToString(thumbprint[:]))
+	result.notBefore = template.NotBefore
+	result.notAfter = template.NotAfter
+
+	return result, nil
+}
+
+// parseCSR parses and verifies a PEM encoded certificate signing request.
+// Microsoft Entra ID only supports RSA keys for certificate credentials.
+func parseCSR(csrPEM string, minKeyBits int) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("csr must be a PEM encoded certificate request")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing csr: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid csr signature: %w", err)
+	}
+
+	publicKey, ok := csr.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("csr must contain an RSA public key")
+	}
+	if publicKey.N.BitLen() < minKeyBits {
+		return nil, fmt.Errorf("csr key must be at least %d bits", minKeyBits)
+	}
+
+	return csr, nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-multierror"
+	"github.com/openbao/openbao/sdk/v2/helper/strutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
 
 	"github.com/openbao/openbao-plugins/secrets/azure/api"
@@ -35,6 +36,10 @@ const (
 	azureChinaCloudEnvName  = "AZURECHINACLOUD"
 	azureUSGovCloudEnvName  = "AZUREUSGOVERNMENTCLOUD"
 
+	azurePublicCloudStorageSuffix = "core.windows.net"
+	azureChinaCloudStorageSuffix  = "core.chinacloudapi.cn"
+	azureUSGovCloudStorageSuffix  = "core.usgovcloudapi.net"
+
 	errInvalidApplicationObject = "does not reference a valid application object"
 )
 
@@ -115,6 +120,27 @@ func (c *client) createSP(
 	return result.ID, result.Password, result.EndDate, nil
 }
 
+// createSPWithoutPassword creates a new service principal without any
+// credentials.
+func (c *client) createSPWithoutPassword(ctx context.Context, app api.Application) (spID string, err error) {
+	resultRaw, err := retry(ctx, func() (interface{}, bool, error) {
+		spID, err := c.provider.CreateServicePrincipalWithoutPassword(ctx, app.AppID)
+
+		// Propagation delays within Azure can cause this error occasionally, so don't quit on it.
+		if err != nil && (strings.Contains(err.Error(), errInvalidApplicationObject)) {
+			return nil, false, nil
+		}
+
+		return spID, true, err
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("error creating service principal: %w", err)
+	}
+
+	return resultRaw.(string), nil
+}
+
 // addAppPassword adds a new password to an App's credentials list.
 func (c *client) addAppPassword(ctx context.Context, appObjID string, expiresIn time.Duration) (string, string, time.Time, error) {
 	exp := time.Now().Add(expiresIn)
@@ -142,6 +168,70 @@ func (c *client) deleteAppPassword(ctx context.Context, appObjID string, keyID s
 	return nil
 }
 
+// addAppKeyCredential registers a certificate with an App's key credentials.
+func (c *client) addAppKeyCredential(ctx context.Context, appObjID string, cert *issuedCertificate) (string, error) {
+	resp, err := c.provider.AddApplicationKeyCredential(ctx, appObjID, "vault-plugin-secrets-azure", cert.der, cert.notBefore, cert.notAfter)
+	if err != nil {
+		if strings.Contains(err.Error(), "size of the object has exceeded its limit") {
+			err = errors.New("maximum number of Application key credentials reached")
+		}
+		return "", fmt.Errorf("error updating key credentials: %w", err)
+	}
+
+	return resp.KeyID, nil
+}
+
+// deleteAppKeyCredential removes a key credential, if present, from an App's key credentials.
+func (c *client) deleteAppKeyCredential(ctx context.Context, appObjID string, keyID string) error {
+	err := c.provider.RemoveApplicationKeyCredential(ctx, appObjID, keyID)
+	if err != nil {
+		if strings.Contains(err.Error(), "No key credential found with keyId") {
+			return nil
+		}
+		return fmt.Errorf("error removing key credentials: %w", err)
+	}
+
+	return nil
+}
+
+// addAppFederatedIdentityCredential adds a federated identity credential to an
+// App. The credential is given a unique name so that it can be tracked by the
+// lease.
+func (c *client) addAppFederatedIdentityCredential(ctx context.Context, appObjID string, issuer, subject string, audiences []string) (api.FederatedIdentityCredential, error) {
+	resp, err := c.provider.AddApplicationFederatedIdentityCredential(ctx, appObjID, api.FederatedIdentityCredential{
+		Name:        appNamePrefix + uuid.New().String(),
+		Description: "Managed by vault-plugin-secrets-azure",
+		Issuer:      issuer,
+		Subject:     subject,
+		Audiences:   audiences,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "FederatedIdentityCredential with same issuer and subject") {
+			err = errors.New("a federated identity credential with the same issuer and subject already exists on the Application")
+		} else if strings.Contains(err.Error(), "size of the object has exceeded its limit") ||
+			strings.Contains(err.Error(), "Maximum number of FederatedIdentityCredentials") {
+			err = errors.New("maximum number of Application federated identity credentials reached")
+		}
+		return api.FederatedIdentityCredential{}, fmt.Errorf("error adding federated identity credential: %w", err)
+	}
+
+	return resp, nil
+}
+
+// deleteAppFederatedIdentityCredential removes a federated identity
+// credential, if present, from an App.
+func (c *client) deleteAppFederatedIdentityCredential(ctx context.Context, appObjID string, credentialID string) error {
+	err := c.provider.RemoveApplicationFederatedIdentityCredential(ctx, appObjID, credentialID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Request_ResourceNotFound") {
+			return nil
+		}
+		return fmt.Errorf("error removing federated identity credential: %w", err)
+	}
+
+	return nil
+}
+
 // deleteApp deletes an Azure application.
 func (c *client) deleteApp(ctx context.Context, appObjectID string, permanentlyDelete bool) error {
 	return c.provider.DeleteApplication(ctx, appObjectID, permanentlyDelete)
@@ -273,6 +363,160 @@ func (c *client) removeGroupMemberships(ctx context.Context, servicePrincipalObj
 	return merr.ErrorOrNil()
 }
 
+// addMemberToGroup adds an existing principal to the passed group. It returns
+// false if the principal was already a member of the group.
+func (c *client) addMemberToGroup(ctx context.Context, memberID string, group *AzureGroup) (bool, error) {
+	added, err := retry(ctx, func() (interface{}, bool, error) {
+		err := c.provider.AddGroupMember(ctx, group.ObjectID, memberID)
+		if err != nil && strings.Contains(err.Error(), "added object references already exist") {
+			return false, true, nil
+		}
+
+		// Propagation delays within Azure can cause this error occasionally, so don't quit on it.
+		if err != nil && strings.Contains(err.Error(), "Request_ResourceNotFound") {
+			return nil, false, err
+		}
+
+		return true, true, err
+	})
+	if err != nil {
+		return false, fmt.Errorf("error while adding group membership of '%s': %w", group.GroupName, err)
+	}
+
+	return added.(bool), nil
+}
+
+// resolveAPIPermission looks up the service principal of the API and the app
+// role of the permission, which may be given by value or ID.
+func (c *client) resolveAPIPermission(ctx context.Context, p *APIPermission) error {
+	if p.ResourceAppID == "" {
+		return errors.New("resource_app_id is required for API permissions")
+	}
+	if p.Permission == "" && p.AppRoleID == "" {
+		return fmt.Errorf("either permission or app_role_id is required for API permissions of '%s'", p.ResourceAppID)
+	}
+
+	sps, err := c.provider.ListServicePrincipals(ctx, p.ResourceAppID)
+	if err != nil {
+		return fmt.Errorf("unable to lookup service principal of API '%s': %w", p.ResourceAppID, err)
+	}
+	if len(sps) != 1 {
+		return fmt.Errorf("no service principal found for resource_app_id: '%s'", p.ResourceAppID)
+	}
+	sp := sps[0]
+
+	for _, appRole := range sp.AppRoles {
+		if (p.AppRoleID != "" && !strings.EqualFold(appRole.ID, p.AppRoleID)) ||
+			(p.Permission != "" && appRole.Value != p.Permission) {
+			continue
+		}
+		if !appRole.IsEnabled || !strutil.StrListContains(appRole.AllowedMemberTypes, "Application") {
+			return fmt.Errorf("permission '%s' of API '%s' cannot be granted to applications", appRole.Value, p.ResourceAppID)
+		}
+
+		p.Permission = appRole.Value
+		p.AppRoleID = appRole.ID
+		p.ResourceObjectID = sp.ID
+		return nil
+	}
+
+	return fmt.Errorf("no permission found for API '%s': '%s%s'", p.ResourceAppID, p.Permission, p.AppRoleID)
+}
+
+// assignAPIPermissions grants API application permissions to a service
+// principal. Assigning app roles as the plugin's identity is equivalent to
+// admin consent.
+func (c *client) assignAPIPermissions(ctx context.Context, spID string, permissions []*APIPermission) ([]string, error) {
+	var ids []string
+
+	for _, p := range permissions {
+		resultRaw, err := retry(ctx, func() (interface{}, bool, error) {
+			assignment, err := c.provider.AddAppRoleAssignment(ctx, spID, p.ResourceObjectID, p.AppRoleID)
+
+			// Propagation delays within Azure can cause this error occasionally, so don't quit on it.
+			if err != nil && strings.Contains(err.Error(), "Request_ResourceNotFound") {
+				return nil, false, err
+			}
+
+			return assignment.ID, true, err
+		})
+		if err != nil {
+			return ids, fmt.Errorf("error while granting API permission '%s': %w", p.Permission, err)
+		}
+
+		ids = append(ids, resultRaw.(string))
+	}
+
+	return ids, nil
+}
+
+// unassignAPIPermissions removes API permission assignments from a service
+// principal. This is a clean-up operation that isn't essential to revocation.
+// As such, an attempt is made to remove all assignments, and not return
+// immediately if there is an error.
+func (c *client) unassignAPIPermissions(ctx context.Context, spID string, assignmentIDs []string) error {
+	var merr *multierror.Error
+
+	for _, id := range assignmentIDs {
+		if err := c.provider.RemoveAppRoleAssignment(ctx, spID, id); err != nil {
+			// The assignment or service principal was deleted out-of-band
+			if strings.Contains(err.Error(), "Request_ResourceNotFound") {
+				continue
+			}
+			merr = multierror.Append(merr, fmt.Errorf("error removing API permission: %w", err))
+		}
+	}
+
+	return merr.ErrorOrNil()
+}
+
+// assignEntraRoles assigns Microsoft Entra directory roles to a service
+// principal. The role is assigned by template ID, which is stable across
+// tenants.
+func (c *client) assignEntraRoles(ctx context.Context, spID string, roles []*EntraRole) ([]string, error) {
+	var ids []string
+
+	for _, r := range roles {
+		resultRaw, err := retry(ctx, func() (interface{}, bool, error) {
+			id, err := c.provider.CreateDirectoryRoleAssignment(ctx, spID, r.TemplateID, r.directoryScopeID())
+
+			// Propagation delays within Azure can cause this error occasionally, so don't quit on it.
+			if err != nil && (strings.Contains(err.Error(), "Request_ResourceNotFound") || strings.Contains(err.Error(), "does not exist")) {
+				return nil, false, err
+			}
+
+			return id, true, err
+		})
+		if err != nil {
+			return ids, fmt.Errorf("error while assigning Entra role '%s': %w", r.RoleName, err)
+		}
+
+		ids = append(ids, resultRaw.(string))
+	}
+
+	return ids, nil
+}
+
+// unassignEntraRoles removes Microsoft Entra directory role assignments. This
+// is a clean-up operation that isn't essential to revocation. As such, an
+// attempt is made to remove all assignments, and not return immediately if
+// there is an error.
+func (c *client) unassignEntraRoles(ctx context.Context, assignmentIDs []string) error {
+	var merr *multierror.Error
+
+	for _, id := range assignmentIDs {
+		if err := c.provider.DeleteDirectoryRoleAssignment(ctx, id); err != nil {
+			// The assignment or service principal was deleted out-of-band
+			if strings.Contains(err.Error(), "Request_ResourceNotFound") {
+				continue
+			}
+			merr = multierror.Append(merr, fmt.Errorf("error removing Entra role: %w", err))
+		}
+	}
+
+	return merr.ErrorOrNil()
+}
+
 // groupObjectIDs is a helper for converting a list of AzureGroup
 // objects to a list of their object IDs.
 func groupObjectIDs(groups []*AzureGroup) []string {
@@ -303,6 +547,7 @@ type clientSettings struct {
 	ClientID       string
 	ClientSecret   string
 	GraphURI       string
+	StorageSuffix  string
 	CloudConfig    cloud.Configuration
 	PluginEnv      *logical.PluginEnvironment
 }
@@ -339,6 +584,7 @@ func (b *azureSecretBackend) getClientSettings(ctx context.Context, config *azur
 		// Default to Azure public cloud
 		settings.CloudConfig = cloud.AzurePublic
 		settings.GraphURI = azurePublicCloudBaseURI
+		settings.StorageSuffix = azurePublicCloudStorageSuffix
 	} else {
 		var err error
 		settings.CloudConfig, err = cloudConfigFromName(envName)
@@ -350,6 +596,11 @@ func (b *azureSecretBackend) getClientSettings(ctx context.Context, config *azur
 		if err != nil {
 			return nil, err
 		}
+
+		settings.StorageSuffix, err = storageSuffixFromName(envName)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	pluginEnv, err := b.System().PluginEnv(ctx)
@@ -393,6 +644,22 @@ func graphURIFromName(name string) (string, error) {
 	return c, nil
 }
 
+func storageSuffixFromName(name string) (string, error) {
+	configs := map[string]string{
+		azureChinaCloudEnvName:  azureChinaCloudStorageSuffix,
+		azurePublicCloudEnvName: azurePublicCloudStorageSuffix,
+		azureUSGovCloudEnvName:  azureUSGovCloudStorageSuffix,
+	}
+
+	name = strings.ToUpper(name)
+	c, ok := configs[name]
+	if !ok {
+		return c, fmt.Errorf("err: no storage endpoint suffix matching the name %q", name)
+	}
+
+	return c, nil
+}
+
 // retry will repeatedly call f until one of:
 //
 //   - f returns true
@@ -37,6 +37,7 @@ type azureConfig struct {
 	Environment                   string        `json:"environment"`
 	RootPasswordTTL               time.Duration `json:"root_password_ttl"`
 	RootPasswordExpirationDate    time.Time     `json:"root_password_expiration_date"`
+	OrphanTidyInterval            time.Duration `json:"orphan_tidy_interval"`
 }
 
 func pathConfig(b *azureSecretBackend) *framework.Path {
@@ -77,6 +78,10 @@ func pathConfig(b *azureSecretBackend) *framework.Path {
 				Description: "The TTL of the root password in Azure. This can be either a number of seconds or a time formatted duration (ex: 24h, 48ds)",
 				Required:    false,
 			},
+			"orphan_tidy_interval": {
+				Type:        framework.TypeDurationSecond,
+				Description: "The interval at which orphaned applications created by the mount are deleted. Disabled if 0. This can be either a number of seconds or a time formatted duration (ex: 24h, 48ds)",
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ReadOperation: &framework.PathOperation{
@@ -158,6 +163,10 @@ func (b *azureSecretBackend) pathConfigWrite(ctx context.Context, req *logical.R
 		config.RootPasswordTTL = defaultRootPasswordTTL
 	}
 
+	if orphanTidyIntervalRaw, ok := data.GetOk("orphan_tidy_interval"); ok {
+		config.OrphanTidyInterval = time.Second * time.Duration(orphanTidyIntervalRaw.(int))
+	}
+
 	if merr.ErrorOrNil() != nil {
 		return logical.ErrorResponse(merr.Error()), nil
 	}
@@ -182,11 +191,12 @@ func (b *azureSecretBackend) pathConfigRead(ctx context.Context, req *logical.Re
 
 	resp := &logical.Response{
 		Data: map[string]interface{}{
-			"subscription_id":   config.SubscriptionID,
-			"tenant_id":         config.TenantID,
-			"environment":       config.Environment,
-			"client_id":         config.ClientID,
-			"root_password_ttl": int(config.RootPasswordTTL.Seconds()),
+			"subscription_id":      config.SubscriptionID,
+			"tenant_id":            config.TenantID,
+			"environment":          config.Environment,
+			"client_id":            config.ClientID,
+			"root_password_ttl":    int(config.RootPasswordTTL.Seconds()),
+			"orphan_tidy_interval": int(config.OrphanTidyInterval.Seconds()),
 		},
 	}
 
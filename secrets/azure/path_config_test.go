@@ -28,11 +28,12 @@ func TestConfig(t *testing.T) {
 				"client_secret":   "testClientSecret",
 			},
 			expected: map[string]interface{}{
-				"subscription_id":   "a228ceec-bf1a-4411-9f95-39678d8cdb34",
-				"tenant_id":         "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
-				"client_id":         "testClientId",
-				"environment":       "",
-				"root_password_ttl": 15768000,
+				"subscription_id":      "a228ceec-bf1a-4411-9f95-39678d8cdb34",
+				"tenant_id":            "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
+				"client_id":            "testClientId",
+				"environment":          "",
+				"root_password_ttl":    15768000,
+				"orphan_tidy_interval": 0,
 			},
 		},
 		{
@@ -45,11 +46,12 @@ func TestConfig(t *testing.T) {
 				"root_password_ttl": "1m",
 			},
 			expected: map[string]interface{}{
-				"subscription_id":   "a228ceec-bf1a-4411-9f95-39678d8cdb34",
-				"tenant_id":         "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
-				"client_id":         "testClientId",
-				"environment":       "",
-				"root_password_ttl": 60,
+				"subscription_id":      "a228ceec-bf1a-4411-9f95-39678d8cdb34",
+				"tenant_id":            "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
+				"client_id":            "testClientId",
+				"environment":          "",
+				"root_password_ttl":    60,
+				"orphan_tidy_interval": 0,
 			},
 		},
 		{
@@ -62,11 +64,30 @@ func TestConfig(t *testing.T) {
 				"environment":     "AZURECHINACLOUD",
 			},
 			expected: map[string]interface{}{
-				"subscription_id":   "a228ceec-bf1a-4411-9f95-39678d8cdb34",
-				"tenant_id":         "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
-				"client_id":         "testClientId",
-				"root_password_ttl": 15768000,
-				"environment":       "AZURECHINACLOUD",
+				"subscription_id":      "a228ceec-bf1a-4411-9f95-39678d8cdb34",
+				"tenant_id":            "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
+				"client_id":            "testClientId",
+				"root_password_ttl":    15768000,
+				"environment":          "AZURECHINACLOUD",
+				"orphan_tidy_interval": 0,
+			},
+		},
+		{
+			name: "orphan_tidy_interval set if provided",
+			config: map[string]interface{}{
+				"subscription_id":      "a228ceec-bf1a-4411-9f95-39678d8cdb34",
+				"tenant_id":            "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
+				"client_id":            "testClientId",
+				"client_secret":        "testClientSecret",
+				"orphan_tidy_interval": "12h",
+			},
+			expected: map[string]interface{}{
+				"subscription_id":      "a228ceec-bf1a-4411-9f95-39678d8cdb34",
+				"tenant_id":            "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
+				"client_id":            "testClientId",
+				"environment":          "",
+				"root_password_ttl":    15768000,
+				"orphan_tidy_interval": 43200,
 			},
 		},
 	}
@@ -159,12 +180,13 @@ func TestConfigDelete(t *testing.T) {
 
 	// Test valid config
 	config := map[string]interface{}{
-		"subscription_id":   "a228ceec-bf1a-4411-9f95-39678d8cdb34",
-		"tenant_id":         "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
-		"client_id":         "testClientId",
-		"client_secret":     "testClientSecret",
-		"environment":       "AZURECHINACLOUD",
-		"root_password_ttl": int((24 * time.Hour).Seconds()),
+		"subscription_id":      "a228ceec-bf1a-4411-9f95-39678d8cdb34",
+		"tenant_id":            "7ac36e27-80fc-4209-a453-e8ad83dc18c2",
+		"client_id":            "testClientId",
+		"client_secret":        "testClientSecret",
+		"environment":          "AZURECHINACLOUD",
+		"root_password_ttl":    int((24 * time.Hour).Seconds()),
+		"orphan_tidy_interval": 0,
 	}
 
 	testConfigCreate(t, b, s, config, false)
@@ -185,11 +207,12 @@ func TestConfigDelete(t *testing.T) {
 	}
 
 	config = map[string]interface{}{
-		"subscription_id":   "",
-		"tenant_id":         "",
-		"client_id":         "",
-		"environment":       "",
-		"root_password_ttl": 0,
+		"subscription_id":      "",
+		"tenant_id":            "",
+		"client_id":            "",
+		"environment":          "",
+		"root_password_ttl":    0,
+		"orphan_tidy_interval": 0,
 	}
 	testConfigRead(t, b, s, config)
 }
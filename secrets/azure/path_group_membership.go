@@ -0,0 +1,367 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
+	"github.com/openbao/openbao/sdk/v2/helper/strutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	SecretTypeGroupMembership = "group_membership"
+
+	groupMembershipStoragePath = "group-memberships"
+)
+
+func secretGroupMembership(b *azureSecretBackend) *framework.Secret {
+	return &framework.Secret{
+		Type:   SecretTypeGroupMembership,
+		Renew:  b.groupMembershipRenew,
+		Revoke: b.groupMembershipRevoke,
+	}
+}
+
+func pathGroupMembership(b *azureSecretBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "group-membership/" + framework.GenericNameRegex("role"),
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAzure,
+			OperationVerb:   "request",
+			OperationSuffix: "group-membership",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the role.",
+			},
+			"member_id": {
+				Type:        framework.TypeString,
+				Description: "Microsoft Entra object ID of the user or service principal to add to the groups of the role. Must be one of the allowed_member_ids of the role, or the object ID of the caller. Defaults to the object ID of the caller.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback:                    b.pathGroupMembershipRead,
+				ForwardPerformanceSecondary: true,
+				ForwardPerformanceStandby:   true,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                    b.pathGroupMembershipRead,
+				ForwardPerformanceSecondary: true,
+				ForwardPerformanceStandby:   true,
+			},
+		},
+		HelpSynopsis:    groupMembershipHelpSyn,
+		HelpDescription: groupMembershipHelpDesc,
+	}
+}
+
+// pathGroupMembershipRead adds an existing principal to the groups of the role
+// for the duration of the lease.
+func (b *azureSecretBackend) pathGroupMembershipRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+
+	role, err := getRole(ctx, roleName, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role '%s' does not exist", roleName), nil
+	}
+
+	if !role.allowsGroupMembership() {
+		return logical.ErrorResponse("role '%s' does not allow group memberships, member_alias_mount_accessor and allowed_member_ids are empty", roleName), nil
+	}
+
+	memberID, err := b.groupMemberFromRequest(req, role, d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	c, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	groupIDs, err := b.acquireGroupMemberships(ctx, req.Storage, c, memberID, role.AzureGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"member_id": memberID,
+		"group_ids": groupObjectIDs(role.AzureGroups),
+	}
+	internalData := map[string]interface{}{
+		"member_id":            memberID,
+		"group_membership_ids": groupIDs,
+		"role":                 roleName,
+	}
+
+	resp := b.Secret(SecretTypeGroupMembership).Response(data, internalData)
+	resp.Secret.TTL = role.TTL
+	resp.Secret.MaxTTL = role.MaxTTL
+	if role.ExplicitMaxTTL != 0 && (role.ExplicitMaxTTL < role.MaxTTL || role.MaxTTL == 0) {
+		resp.Secret.MaxTTL = role.ExplicitMaxTTL
+	}
+	return resp, nil
+}
+
+// groupMemberFromRequest returns the object ID of the principal to add to
+// the groups of the role. The principal is either the caller, identified by
+// their entity alias on the member_alias_mount_accessor of the role, or one
+// of the allowed_member_ids of the role.
+func (b *azureSecretBackend) groupMemberFromRequest(req *logical.Request, role *roleEntry, d *framework.FieldData) (string, error) {
+	callerID, err := b.callerMemberID(req, role)
+	if err != nil {
+		return "", err
+	}
+
+	memberID, ok := d.GetOk("member_id")
+	if !ok {
+		if callerID == "" {
+			return "", errors.New("member_id is required, the caller has no entity alias on the member_alias_mount_accessor of the role")
+		}
+		return callerID, nil
+	}
+
+	if (callerID == "" || !strings.EqualFold(memberID.(string), callerID)) &&
+		!strutil.StrListContains(role.AllowedMemberIDs, strings.ToLower(memberID.(string))) {
+		return "", fmt.Errorf("member_id %q is not allowed by the role", memberID)
+	}
+
+	return memberID.(string), nil
+}
+
+// callerMemberID returns the name of the entity alias of the caller on the
+// member_alias_mount_accessor of the role, if any.
+func (b *azureSecretBackend) callerMemberID(req *logical.Request, role *roleEntry) (string, error) {
+	if role.MemberAliasMountAccessor == "" || req.EntityID == "" {
+		return "", nil
+	}
+
+	entity, err := b.System().EntityInfo(req.EntityID)
+	if err != nil {
+		return "", fmt.Errorf("error looking up entity of the caller: %w", err)
+	}
+	if entity == nil {
+		return "", nil
+	}
+
+	for _, alias := range entity.Aliases {
+		if alias.MountAccessor == role.MemberAliasMountAccessor {
+			return alias.Name, nil
+		}
+	}
+	return "", nil
+}
+
+func (b *azureSecretBackend) groupMembershipRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleRaw, ok := req.Secret.InternalData["role"]
+	if !ok {
+		return nil, errors.New("internal data 'role' not found")
+	}
+
+	role, err := getRole(ctx, roleRaw.(string), req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == nil {
+		return nil, nil
+	}
+
+	resp := &logical.Response{Secret: req.Secret}
+	resp.Secret.TTL = role.TTL
+	resp.Secret.MaxTTL = role.MaxTTL
+	if role.ExplicitMaxTTL != 0 && (role.ExplicitMaxTTL < role.MaxTTL || role.MaxTTL == 0) {
+		resp.Secret.MaxTTL = role.ExplicitMaxTTL
+	}
+
+	return resp, nil
+}
+
+func (b *azureSecretBackend) groupMembershipRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	memberIDRaw, ok := req.Secret.InternalData["member_id"]
+	if !ok {
+		return nil, errors.New("internal data 'member_id' not found")
+	}
+
+	var groupIDs []string
+	if req.Secret.InternalData["group_membership_ids"] != nil {
+		for _, v := range req.Secret.InternalData["group_membership_ids"].([]interface{}) {
+			groupIDs = append(groupIDs, v.(string))
+		}
+	}
+
+	c, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error during revoke: %w", err)
+	}
+
+	// Unlike the memberships of dynamic service principals, these memberships
+	// are the only thing granted by the lease, so failing to remove them fails
+	// the revocation.
+	return nil, b.releaseGroupMemberships(ctx, req.Storage, c, memberIDRaw.(string), groupIDs)
+}
+
+// groupMembershipEntry counts the live leases that hold a group membership
+// added by the backend, so that the membership is only removed when the last
+// of them is revoked. Memberships the principal already had before any lease
+// have no entry and are never removed.
+type groupMembershipEntry struct {
+	Leases int `json:"leases"`
+}
+
+// acquireGroupMemberships adds the principal to the passed groups and takes a
+// lease count on each membership added by the backend. The IDs of the groups
+// whose memberships are held by the lease are returned. If a membership
+// cannot be added, the counts taken so far are released again.
+func (b *azureSecretBackend) acquireGroupMemberships(ctx context.Context, s logical.Storage, c *client, memberID string, groups []*AzureGroup) ([]string, error) {
+	var groupIDs []string
+
+	for _, group := range groups {
+		held, err := b.acquireGroupMembership(ctx, s, c, memberID, group)
+		if err != nil {
+			if relErr := b.releaseGroupMemberships(ctx, s, c, memberID, groupIDs); relErr != nil {
+				err = multierror.Append(err, relErr)
+			}
+			return nil, err
+		}
+
+		if held {
+			groupIDs = append(groupIDs, group.ObjectID)
+		}
+	}
+
+	return groupIDs, nil
+}
+
+func (b *azureSecretBackend) acquireGroupMembership(ctx context.Context, s logical.Storage, c *client, memberID string, group *AzureGroup) (bool, error) {
+	key := groupMembershipStorageKey(memberID, group.ObjectID)
+	lock := locksutil.LockForKey(b.groupMembershipLocks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := getGroupMembership(ctx, s, key)
+	if err != nil {
+		return false, err
+	}
+
+	// The membership is added even if other leases hold it, in case it was
+	// removed outside of the backend
+	added, err := c.addMemberToGroup(ctx, memberID, group)
+	if err != nil {
+		return false, err
+	}
+
+	if entry == nil {
+		if !added {
+			return false, nil
+		}
+		entry = new(groupMembershipEntry)
+	}
+	entry.Leases++
+
+	if err := saveGroupMembership(ctx, s, key, entry); err != nil {
+		if entry.Leases == 1 {
+			if rmErr := c.removeGroupMemberships(ctx, memberID, []string{group.ObjectID}); rmErr != nil {
+				err = multierror.Append(err, rmErr)
+			}
+		}
+		return false, fmt.Errorf("error recording group membership: %w", err)
+	}
+
+	return true, nil
+}
+
+// releaseGroupMemberships releases the lease counts on the memberships of the
+// principal in the passed groups, removing the memberships no other lease
+// holds. An attempt is made to release all memberships.
+func (b *azureSecretBackend) releaseGroupMemberships(ctx context.Context, s logical.Storage, c *client, memberID string, groupIDs []string) error {
+	var merr *multierror.Error
+
+	for _, groupID := range groupIDs {
+		if err := b.releaseGroupMembership(ctx, s, c, memberID, groupID); err != nil {
+			merr = multierror.Append(merr, err)
+		}
+	}
+
+	return merr.ErrorOrNil()
+}
+
+func (b *azureSecretBackend) releaseGroupMembership(ctx context.Context, s logical.Storage, c *client, memberID, groupID string) error {
+	key := groupMembershipStorageKey(memberID, groupID)
+	lock := locksutil.LockForKey(b.groupMembershipLocks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := getGroupMembership(ctx, s, key)
+	if err != nil {
+		return err
+	}
+
+	// Leases issued before memberships were counted have no entry and hold
+	// the membership alone
+	if entry != nil && entry.Leases > 1 {
+		entry.Leases--
+		return saveGroupMembership(ctx, s, key, entry)
+	}
+
+	if err := c.removeGroupMemberships(ctx, memberID, []string{groupID}); err != nil {
+		return err
+	}
+
+	return s.Delete(ctx, key)
+}
+
+func groupMembershipStorageKey(memberID, groupID string) string {
+	return groupMembershipStoragePath + "/" + strings.ToLower(memberID) + "/" + groupID
+}
+
+func getGroupMembership(ctx context.Context, s logical.Storage, key string) (*groupMembershipEntry, error) {
+	se, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if se == nil {
+		return nil, nil
+	}
+
+	entry := new(groupMembershipEntry)
+	if err := se.DecodeJSON(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func saveGroupMembership(ctx context.Context, s logical.Storage, key string, entry *groupMembershipEntry) error {
+	se, err := logical.StorageEntryJSON(key, entry)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, se)
+}
+
+const groupMembershipHelpSyn = `
+Temporarily add an existing user or service principal to the groups of a role.
+`
+
+const groupMembershipHelpDesc = `
+This path adds an existing user or service principal to the azure_groups of a
+role for the duration of the lease, and removes it from the groups when the
+lease is revoked. The principal is either the caller, identified by their
+entity alias on the member_alias_mount_accessor of the role, or one of the
+allowed_member_ids of the role. Memberships the principal already had are not
+removed on revocation, and memberships shared by several leases are removed
+when the last of them is revoked.
+`
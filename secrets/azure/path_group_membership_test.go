@@ -0,0 +1,219 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	testMemberAliasAccessor = "auth_jwt_0a1b2c3d"
+	testMemberID            = "0f5a3c2e-8d4b-4f7a-9e61-2b7c4d9a1e08"
+	testAllowedMemberID     = "7c1e9b4d-2a6f-4e3b-8d5c-1f0a9e7b6c24"
+)
+
+func TestGroupMembership(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	b.System().(*testSystemViewEnt).EntityVal = &logical.Entity{
+		ID: "entity",
+		Aliases: []*logical.Alias{
+			{MountAccessor: "auth_userpass_9f8e7d6c", Name: "alice"},
+			{MountAccessor: testMemberAliasAccessor, Name: testMemberID},
+		},
+	}
+
+	testRoleCreate(t, b, s, "jit", map[string]interface{}{
+		"azure_groups":                `[{"group_name": "ops"}, {"group_name": "dba"}]`,
+		"member_alias_mount_accessor": testMemberAliasAccessor,
+		"allowed_member_ids":          strings.ToUpper(testAllowedMemberID),
+		"ttl":                         "1h",
+	})
+	opsID := "00000000-1111-2222-3333-444444444444FAKE_GROUP-ops"
+	dbaID := "00000000-1111-2222-3333-444444444444FAKE_GROUP-dba"
+
+	t.Run("caller", func(t *testing.T) {
+		resp := testGroupMembershipRequest(t, b, s, "jit", nil)
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+		equal(t, testMemberID, resp.Data["member_id"])
+		if !mp.groupMembers[opsID][testMemberID] || !mp.groupMembers[dbaID][testMemberID] {
+			t.Fatal("expected caller to be added to the groups of the role")
+		}
+
+		testGroupMembershipRevoke(t, b, s, resp)
+		if mp.groupMembers[opsID][testMemberID] || mp.groupMembers[dbaID][testMemberID] {
+			t.Fatal("expected caller to be removed from the groups of the role")
+		}
+	})
+
+	t.Run("allowed member", func(t *testing.T) {
+		resp := testGroupMembershipRequest(t, b, s, "jit", map[string]interface{}{
+			"member_id": testAllowedMemberID,
+		})
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+		if !mp.groupMembers[opsID][testAllowedMemberID] {
+			t.Fatal("expected member to be added to the groups of the role")
+		}
+
+		testGroupMembershipRevoke(t, b, s, resp)
+		if mp.groupMembers[opsID][testAllowedMemberID] {
+			t.Fatal("expected member to be removed from the groups of the role")
+		}
+	})
+
+	t.Run("existing membership kept", func(t *testing.T) {
+		assertErrorIsNil(t, mp.AddGroupMember(context.Background(), opsID, testMemberID))
+
+		resp := testGroupMembershipRequest(t, b, s, "jit", nil)
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+
+		testGroupMembershipRevoke(t, b, s, resp)
+		if !mp.groupMembers[opsID][testMemberID] {
+			t.Fatal("expected existing membership to be kept")
+		}
+		if mp.groupMembers[dbaID][testMemberID] {
+			t.Fatal("expected granted membership to be removed")
+		}
+		assertErrorIsNil(t, mp.RemoveGroupMember(context.Background(), opsID, testMemberID))
+	})
+
+	t.Run("overlapping leases", func(t *testing.T) {
+		first := testGroupMembershipRequest(t, b, s, "jit", nil)
+		if first.IsError() {
+			t.Fatal(first.Error())
+		}
+		second := testGroupMembershipRequest(t, b, s, "jit", nil)
+		if second.IsError() {
+			t.Fatal(second.Error())
+		}
+
+		testGroupMembershipRevoke(t, b, s, first)
+		if !mp.groupMembers[opsID][testMemberID] || !mp.groupMembers[dbaID][testMemberID] {
+			t.Fatal("expected memberships to be kept while a lease holds them")
+		}
+
+		testGroupMembershipRevoke(t, b, s, second)
+		if mp.groupMembers[opsID][testMemberID] || mp.groupMembers[dbaID][testMemberID] {
+			t.Fatal("expected memberships to be removed with the last lease")
+		}
+
+		keys, err := s.List(context.Background(), groupMembershipStoragePath+"/"+testMemberID+"/")
+		assertErrorIsNil(t, err)
+		equal(t, 0, len(keys))
+	})
+
+	t.Run("existing membership kept with overlapping leases", func(t *testing.T) {
+		assertErrorIsNil(t, mp.AddGroupMember(context.Background(), opsID, testMemberID))
+
+		first := testGroupMembershipRequest(t, b, s, "jit", nil)
+		if first.IsError() {
+			t.Fatal(first.Error())
+		}
+		second := testGroupMembershipRequest(t, b, s, "jit", nil)
+		if second.IsError() {
+			t.Fatal(second.Error())
+		}
+
+		testGroupMembershipRevoke(t, b, s, first)
+		testGroupMembershipRevoke(t, b, s, second)
+		if !mp.groupMembers[opsID][testMemberID] {
+			t.Fatal("expected existing membership to be kept")
+		}
+		if mp.groupMembers[dbaID][testMemberID] {
+			t.Fatal("expected granted membership to be removed")
+		}
+		assertErrorIsNil(t, mp.RemoveGroupMember(context.Background(), opsID, testMemberID))
+	})
+
+	t.Run("member not allowed", func(t *testing.T) {
+		resp := testGroupMembershipRequest(t, b, s, "jit", map[string]interface{}{
+			"member_id": generateUUID(),
+		})
+		if !resp.IsError() || !strings.Contains(resp.Error().Error(), "not allowed by the role") {
+			t.Fatalf("expected error, got %#v", resp)
+		}
+	})
+
+	t.Run("caller without alias", func(t *testing.T) {
+		b.System().(*testSystemViewEnt).EntityVal = nil
+		defer func() {
+			b.System().(*testSystemViewEnt).EntityVal = &logical.Entity{
+				Aliases: []*logical.Alias{{MountAccessor: testMemberAliasAccessor, Name: testMemberID}},
+			}
+		}()
+
+		resp := testGroupMembershipRequest(t, b, s, "jit", nil)
+		if !resp.IsError() || !strings.Contains(resp.Error().Error(), "member_id is required") {
+			t.Fatalf("expected error, got %#v", resp)
+		}
+	})
+}
+
+func TestGroupMembershipRoleRequirements(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+
+	// Roles without member settings don't allow group memberships
+	testRoleCreate(t, b, s, "groups", map[string]interface{}{
+		"azure_groups": `[{"group_name": "ops"}]`,
+	})
+	resp := testGroupMembershipRequest(t, b, s, "groups", map[string]interface{}{"member_id": testAllowedMemberID})
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "does not allow group memberships") {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+
+	for name, data := range map[string]map[string]interface{}{
+		"no groups": {
+			"azure_roles":        testRole["azure_roles"],
+			"allowed_member_ids": testAllowedMemberID,
+		},
+		"invalid member ID": {
+			"azure_groups":       `[{"group_name": "ops"}]`,
+			"allowed_member_ids": "alice",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := testRoleCreateBasic(t, b, s, "bad", data)
+			if !resp.IsError() {
+				t.Fatalf("expected error, got %#v", resp)
+			}
+		})
+	}
+}
+
+func testGroupMembershipRequest(t *testing.T, b *azureSecretBackend, s logical.Storage, role string, d map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group-membership/" + role,
+		Data:      d,
+		Storage:   s,
+		EntityID:  "entity",
+	})
+	assertErrorIsNil(t, err)
+
+	return resp
+}
+
+func testGroupMembershipRevoke(t *testing.T, b *azureSecretBackend, s logical.Storage, resp *logical.Response) {
+	t.Helper()
+
+	fakeSaveLoad(resp.Secret)
+	_, err := b.groupMembershipRevoke(context.Background(), &logical.Request{
+		Secret:  resp.Secret,
+		Storage: s,
+	}, nil)
+	assertErrorIsNil(t, err)
+}
@@ -7,10 +7,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/hashicorp/go-uuid"
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/helper/jsonutil"
 	"github.com/openbao/openbao/sdk/v2/helper/strutil"
@@ -25,6 +28,16 @@ const (
 	credentialTypeSP = 0
 )
 
+const (
+	clientCredentialTypePassword    = "password"
+	clientCredentialTypeCertificate = "certificate"
+	clientCredentialTypeFederated   = "federated"
+
+	// defaultFederatedAudience is the audience recommended by Microsoft for
+	// federated identity credentials
+	defaultFederatedAudience = "api://AzureADTokenExchange"
+)
+
 // roleEntry is a Vault role construct that maps to Azure roles or Applications
 type roleEntry struct {
 	CredentialType      int           `json:"credential_type"` // Reserved. Always SP at this time.
@@ -40,6 +53,33 @@ type roleEntry struct {
 	PermanentlyDelete   bool          `json:"permanently_delete"`
 	PersistApp          bool          `json:"persist_app"`
 
+	// APIPermissions and EntraRoles are granted to dynamic service principals
+	APIPermissions []*APIPermission `json:"api_permissions"`
+	EntraRoles     []*EntraRole     `json:"entra_roles"`
+
+	// ClientCredentialType is the type of credential issued for the
+	// application. An empty value is treated as a password.
+	ClientCredentialType string `json:"client_credential_type"`
+	CertificateKeyBits   int    `json:"certificate_key_bits"`
+
+	// Federated identity credential constraints
+	FederatedIssuer    string   `json:"federated_issuer"`
+	FederatedSubjects  []string `json:"federated_subjects"`
+	FederatedAudiences []string `json:"federated_audiences"`
+
+	// AllowedScopes are the scopes for which access tokens can be requested
+	// from the token endpoint. Supports globs.
+	AllowedScopes []string `json:"allowed_scopes"`
+
+	// AllowedScopePatterns are the scopes that may be requested for the
+	// AzureRoles of dynamic service principals. Supports globs.
+	AllowedScopePatterns []string `json:"allowed_scope_patterns"`
+
+	// Existing principals that may be temporarily added to the AzureGroups of
+	// the role through the group membership endpoint
+	MemberAliasMountAccessor string   `json:"member_alias_mount_accessor"`
+	AllowedMemberIDs         []string `json:"allowed_member_ids"`
+
 	// Info for persisted apps
 	RoleAssignmentIDs          []string `json:"role_assignment_ids"`
 	GroupMembershipIDs         []string `json:"group_membership_ids"`
@@ -66,6 +106,37 @@ type AzureGroup struct {
 	ObjectID  string `json:"object_id"`  // e.g. 90820a30-352d-400f-89e5-2ca74ac14333
 }
 
+// APIPermission is an application permission of an API, such as Microsoft
+// Graph. It is granted to service principals as an assignment of an app role
+// of the API's service principal. Permission and AppRoleID are both traits of
+// the app role. AppRoleID is the unique identifier, but Permission is more
+// useful to a human.
+type APIPermission struct {
+	ResourceAppID    string `json:"resource_app_id"`    // e.g. 00000003-0000-0000-c000-000000000000 (Microsoft Graph)
+	Permission       string `json:"permission"`         // e.g. User.Read.All
+	AppRoleID        string `json:"app_role_id"`        // e.g. df021288-bdef-4463-88db-98f22de89214
+	ResourceObjectID string `json:"resource_object_id"` // object ID of the API's service principal in the tenant
+}
+
+// EntraRole is a Microsoft Entra directory role
+// (https://learn.microsoft.com/en-us/entra/identity/role-based-access-control/permissions-reference),
+// optionally scoped to an administrative unit. RoleName and TemplateID are
+// both traits of the role. TemplateID is the unique identifier, but RoleName
+// is more useful to a human.
+type EntraRole struct {
+	RoleName             string `json:"role_name"`              // e.g. Groups Administrator
+	TemplateID           string `json:"template_id"`            // e.g. fdd7a751-b60b-444a-984c-02652fe8fa1c
+	AdministrativeUnitID string `json:"administrative_unit_id"` // e.g. 4a6e9c1f-bd2d-4f5a-9f0b-0f8c7a3c0d11
+}
+
+// directoryScopeID is the scope of the assignment of the role.
+func (r *EntraRole) directoryScopeID() string {
+	if r.AdministrativeUnitID == "" {
+		return "/"
+	}
+	return "/administrativeUnits/" + r.AdministrativeUnitID
+}
+
 func pathsRole(b *azureSecretBackend) []*framework.Path {
 	return []*framework.Path{
 		{
@@ -91,6 +162,14 @@ func pathsRole(b *azureSecretBackend) []*framework.Path {
 					Type:        framework.TypeString,
 					Description: "JSON list of Azure groups to add the service principal to.",
 				},
+				"api_permissions": {
+					Type:        framework.TypeString,
+					Description: "JSON list of API application permissions to grant to the service principal.",
+				},
+				"entra_roles": {
+					Type:        framework.TypeString,
+					Description: "JSON list of Microsoft Entra directory roles to assign to the service principal.",
+				},
 				"sign_in_audience": {
 					Type:        framework.TypeString,
 					Description: "Specifies the security principal types that are allowed to sign in to the application. Valid values are: AzureADMyOrg, AzureADMultipleOrgs, AzureADandPersonalMicrosoftAccount, PersonalMicrosoftAccount",
@@ -121,6 +200,44 @@ func pathsRole(b *azureSecretBackend) []*framework.Path {
 					Description: "Persist the app between generated credentials. Useful if the app needs to maintain owner ship of resources it creates",
 					Default:     false,
 				},
+				"client_credential_type": {
+					Type:        framework.TypeString,
+					Description: "Type of credential issued for the application. Valid values are: password, certificate, federated. Defaults to password.",
+					Default:     clientCredentialTypePassword,
+				},
+				"certificate_key_bits": {
+					Type:        framework.TypeInt,
+					Description: "Size in bits of the RSA keys generated for certificate credentials. Valid values are: 2048, 3072, 4096. Defaults to 2048.",
+					Default:     defaultCertificateKeyBits,
+				},
+				"federated_issuer": {
+					Type:        framework.TypeString,
+					Description: "Issuer of the federated identity credentials issued for the application, e.g. https://token.actions.githubusercontent.com. Required if client_credential_type is federated.",
+				},
+				"federated_subjects": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Subjects allowed for the federated identity credentials issued for the application. Supports globs. Required if client_credential_type is federated.",
+				},
+				"federated_audiences": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Audiences allowed for the federated identity credentials issued for the application. Defaults to api://AzureADTokenExchange.",
+				},
+				"allowed_scopes": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Scopes for which access tokens can be requested from the token endpoint, e.g. https://management.azure.com/.default. Supports globs. Access tokens are disabled if empty.",
+				},
+				"allowed_scope_patterns": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Scopes under a subscription that may be requested for the azure_roles of dynamic service principals, e.g. /subscriptions/<id>/resourceGroups/team-*. Supports globs. If set, the requested scope replaces the scope of each Azure role.",
+				},
+				"member_alias_mount_accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the auth mount whose entity alias names are the Microsoft Entra object IDs of callers. Callers are added to azure_groups for the lease of the group membership endpoint.",
+				},
+				"allowed_member_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Microsoft Entra object IDs of users or service principals that may be added to azure_groups through the group membership endpoint.",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.ReadOperation:   b.pathRoleRead,
@@ -263,6 +380,84 @@ func (b *azureSecretBackend) pathRoleUpdate(ctx context.Context, req *logical.Re
 		role.Tags = tagsList
 	}
 
+	// update and verify the client credential type if provided
+	if credType, ok := d.GetOk("client_credential_type"); ok {
+		role.ClientCredentialType = credType.(string)
+	} else if req.Operation == logical.CreateOperation {
+		role.ClientCredentialType = d.Get("client_credential_type").(string)
+	}
+	if role.ClientCredentialType == "" {
+		role.ClientCredentialType = clientCredentialTypePassword
+	}
+	validCredentialTypes := []string{clientCredentialTypePassword, clientCredentialTypeCertificate, clientCredentialTypeFederated}
+	if !strutil.StrListContains(validCredentialTypes, role.ClientCredentialType) {
+		return logical.ErrorResponse("Invalid value for client_credential_type field. Valid values are: %s", strings.Join(validCredentialTypes, ", ")), nil
+	}
+
+	if keyBits, ok := d.GetOk("certificate_key_bits"); ok {
+		role.CertificateKeyBits = keyBits.(int)
+	} else if role.CertificateKeyBits == 0 {
+		role.CertificateKeyBits = d.Get("certificate_key_bits").(int)
+	}
+	if !slices.Contains(validCertificateKeyBits, role.CertificateKeyBits) {
+		return logical.ErrorResponse("Invalid value for certificate_key_bits field. Valid values are: 2048, 3072, 4096"), nil
+	}
+
+	// update and verify the federated identity credential constraints if provided
+	if issuer, ok := d.GetOk("federated_issuer"); ok {
+		role.FederatedIssuer = issuer.(string)
+	}
+	if subjects, ok := d.GetOk("federated_subjects"); ok {
+		role.FederatedSubjects = strutil.RemoveDuplicates(subjects.([]string), false)
+	}
+	if audiences, ok := d.GetOk("federated_audiences"); ok {
+		role.FederatedAudiences = strutil.RemoveDuplicates(audiences.([]string), false)
+	}
+	if role.ClientCredentialType == clientCredentialTypeFederated {
+		if len(role.FederatedAudiences) == 0 {
+			role.FederatedAudiences = []string{defaultFederatedAudience}
+		}
+		if err := validateFederatedConstraints(role); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	// update and verify the access token scopes if provided
+	if scopes, ok := d.GetOk("allowed_scopes"); ok {
+		role.AllowedScopes = strutil.RemoveDuplicates(scopes.([]string), false)
+		for _, scope := range role.AllowedScopes {
+			if scope == "" || scope == "*" {
+				return logical.ErrorResponse("invalid allowed scope %q", scope), nil
+			}
+		}
+	}
+	if len(role.AllowedScopes) > 0 && role.clientCredentialType() != clientCredentialTypePassword {
+		return logical.ErrorResponse("allowed_scopes is only valid for roles with client_credential_type '%s'", clientCredentialTypePassword), nil
+	}
+
+	// update and verify the Azure role scope patterns if provided
+	if patterns, ok := d.GetOk("allowed_scope_patterns"); ok {
+		role.AllowedScopePatterns = strutil.RemoveDuplicates(patterns.([]string), false)
+		for _, pattern := range role.AllowedScopePatterns {
+			if err := validateScopePattern(pattern); err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+	}
+
+	// update and verify the group membership principals if provided
+	if accessor, ok := d.GetOk("member_alias_mount_accessor"); ok {
+		role.MemberAliasMountAccessor = accessor.(string)
+	}
+	if memberIDs, ok := d.GetOk("allowed_member_ids"); ok {
+		role.AllowedMemberIDs = strutil.RemoveDuplicates(memberIDs.([]string), true)
+		for _, id := range role.AllowedMemberIDs {
+			if _, err := uuid.ParseUUID(id); err != nil {
+				return logical.ErrorResponse("invalid allowed member ID '%s'", id), nil
+			}
+		}
+	}
+
 	// update and verify Application Object ID if provided
 	if appObjectID, ok := d.GetOk("application_object_id"); ok {
 		role.ApplicationObjectID = appObjectID.(string)
@@ -311,6 +506,28 @@ func (b *azureSecretBackend) pathRoleUpdate(ctx context.Context, req *logical.Re
 		role.AzureGroups = parsedGroups
 	}
 
+	// Parse the Entra directory roles
+	if entraRoles, ok := d.GetOk("entra_roles"); ok {
+		parsedEntraRoles := make([]*EntraRole, 0)
+
+		err := jsonutil.DecodeJSON([]byte(entraRoles.(string)), &parsedEntraRoles)
+		if err != nil {
+			return logical.ErrorResponse("error parsing Entra roles '%s': %s", entraRoles.(string), err.Error()), nil
+		}
+		role.EntraRoles = parsedEntraRoles
+	}
+
+	// Parse the API permissions
+	if permissions, ok := d.GetOk("api_permissions"); ok {
+		parsedPermissions := make([]*APIPermission, 0)
+
+		err := jsonutil.DecodeJSON([]byte(permissions.(string)), &parsedPermissions)
+		if err != nil {
+			return logical.ErrorResponse("error parsing API permissions '%s': %s", permissions.(string), err.Error()), nil
+		}
+		role.APIPermissions = parsedPermissions
+	}
+
 	// update and verify Azure roles, including looking up each role by ID or name.
 	roleSet := make(map[string]bool)
 	for _, r := range role.AzureRoles {
@@ -387,7 +604,79 @@ func (b *azureSecretBackend) pathRoleUpdate(ctx context.Context, req *logical.Re
 		groupSet[r.ObjectID] = true
 	}
 
-	if role.ApplicationObjectID == "" && len(role.AzureRoles) == 0 && len(role.AzureGroups) == 0 {
+	// update and verify API permissions, including looking up the app role of
+	// each permission. Permissions are granted to dynamic service principals
+	// only, as the plugin doesn't own the service principals of existing
+	// applications.
+	if len(role.APIPermissions) > 0 && (role.ApplicationObjectID != "" || role.PersistApp) {
+		return logical.ErrorResponse("api_permissions cannot be used with application_object_id or persist_app"), nil
+	}
+	permissionSet := make(map[string]bool)
+	for _, p := range role.APIPermissions {
+		if err := client.resolveAPIPermission(ctx, p); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		pKey := p.ResourceObjectID + "||" + p.AppRoleID
+		if permissionSet[pKey] {
+			return logical.ErrorResponse("duplicate API permission: '%s', '%s'", p.ResourceAppID, p.Permission), nil
+		}
+		permissionSet[pKey] = true
+	}
+
+	// Persisted apps are assigned their Azure roles when the role is written,
+	// so their scopes cannot be requested
+	if len(role.AllowedScopePatterns) > 0 {
+		if role.ApplicationObjectID != "" || role.PersistApp {
+			return logical.ErrorResponse("allowed_scope_patterns cannot be used with application_object_id or persist_app"), nil
+		}
+		if len(role.AzureRoles) == 0 {
+			return logical.ErrorResponse("allowed_scope_patterns requires azure_roles"), nil
+		}
+	}
+
+	if role.allowsGroupMembership() && len(role.AzureGroups) == 0 {
+		return logical.ErrorResponse("member_alias_mount_accessor and allowed_member_ids require azure_groups"), nil
+	}
+
+	// update and verify Entra directory roles, including looking up each role
+	// by template ID or name.
+	if len(role.EntraRoles) > 0 && (role.ApplicationObjectID != "" || role.PersistApp) {
+		return logical.ErrorResponse("entra_roles cannot be used with application_object_id or persist_app"), nil
+	}
+	entraRoleSet := make(map[string]bool)
+	for _, r := range role.EntraRoles {
+		if r.AdministrativeUnitID != "" {
+			if _, err := uuid.ParseUUID(r.AdministrativeUnitID); err != nil {
+				return logical.ErrorResponse("invalid administrative_unit_id '%s'", r.AdministrativeUnitID), nil
+			}
+		}
+
+		var filter string
+		if r.TemplateID != "" {
+			filter = fmt.Sprintf("templateId eq '%s'", r.TemplateID)
+		} else {
+			filter = fmt.Sprintf("displayName eq '%s'", r.RoleName)
+		}
+		defs, err := client.provider.ListDirectoryRoleDefinitions(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("unable to lookup Entra role: %w", err)
+		}
+		if l := len(defs); l == 0 {
+			return logical.ErrorResponse("no Entra role found for role_name: '%s', template_id: '%s'", r.RoleName, r.TemplateID), nil
+		} else if l > 1 {
+			return logical.ErrorResponse("multiple matches found for Entra role_name: '%s'. Specify role by template_id instead.", r.RoleName), nil
+		}
+		r.RoleName, r.TemplateID = defs[0].DisplayName, defs[0].TemplateID
+
+		erKey := r.TemplateID + "||" + r.AdministrativeUnitID
+		if entraRoleSet[erKey] {
+			return logical.ErrorResponse("duplicate Entra role template_id and administrative_unit_id: '%s', '%s'", r.TemplateID, r.AdministrativeUnitID), nil
+		}
+		entraRoleSet[erKey] = true
+	}
+
+	if role.ApplicationObjectID == "" && len(role.AzureRoles) == 0 && len(role.AzureGroups) == 0 && len(role.APIPermissions) == 0 && len(role.EntraRoles) == 0 {
 		return logical.ErrorResponse("either Azure role definitions, group definitions, or an Application Object ID must be provided"), nil
 	}
 
@@ -409,6 +698,97 @@ func (b *azureSecretBackend) pathRoleUpdate(ctx context.Context, req *logical.Re
 	return resp, nil
 }
 
+// clientCredentialType returns the type of credential issued for the role.
+// Roles written before the field was introduced issue passwords.
+func (r *roleEntry) clientCredentialType() string {
+	if r.ClientCredentialType == "" {
+		return clientCredentialTypePassword
+	}
+	return r.ClientCredentialType
+}
+
+// allowsGroupMembership returns whether existing principals may be added to
+// the groups of the role.
+func (r *roleEntry) allowsGroupMembership() bool {
+	return r.MemberAliasMountAccessor != "" || len(r.AllowedMemberIDs) > 0
+}
+
+// azureRolesForScope returns the Azure roles to assign to a dynamic service
+// principal. If scope is set, it replaces the scope of each role, and roles
+// that only differed by scope are assigned once.
+func (r *roleEntry) azureRolesForScope(scope string) []*AzureRole {
+	if scope == "" {
+		return r.AzureRoles
+	}
+
+	roles := make([]*AzureRole, 0, len(r.AzureRoles))
+	for _, role := range r.AzureRoles {
+		if slices.ContainsFunc(roles, func(a *AzureRole) bool { return a.RoleID == role.RoleID }) {
+			continue
+		}
+		roles = append(roles, &AzureRole{
+			RoleName: role.RoleName,
+			RoleID:   role.RoleID,
+			Scope:    scope,
+		})
+	}
+	return roles
+}
+
+func (r *roleEntry) certificateKeyBits() int {
+	if r.CertificateKeyBits == 0 {
+		return defaultCertificateKeyBits
+	}
+	return r.CertificateKeyBits
+}
+
+// validateFederatedConstraints validates the federated identity credential
+// constraints of a role. Microsoft Entra ID requires the issuer to be an HTTPS
+// URL.
+func validateFederatedConstraints(role *roleEntry) error {
+	if role.FederatedIssuer == "" {
+		return errors.New("federated_issuer is required if client_credential_type is federated")
+	}
+	u, err := url.Parse(role.FederatedIssuer)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("federated_issuer must be an https URL, got %q", role.FederatedIssuer)
+	}
+	if len(role.FederatedSubjects) == 0 {
+		return errors.New("federated_subjects is required if client_credential_type is federated")
+	}
+	for _, subject := range role.FederatedSubjects {
+		if subject == "" || subject == "*" {
+			return fmt.Errorf("invalid federated subject %q", subject)
+		}
+	}
+	return nil
+}
+
+// validateScopePattern validates that an allowed scope pattern is under a
+// literal subscription, so that globs cannot match scopes of other
+// subscriptions.
+func validateScopePattern(pattern string) error {
+	parts := strings.SplitN(pattern, "/", 4)
+	if len(parts) < 3 || parts[0] != "" || !strings.EqualFold(parts[1], "subscriptions") {
+		return fmt.Errorf("allowed scope pattern %q must start with /subscriptions/<subscription ID>", pattern)
+	}
+	if _, err := uuid.ParseUUID(parts[2]); err != nil {
+		return fmt.Errorf("allowed scope pattern %q must start with /subscriptions/<subscription ID>", pattern)
+	}
+	return nil
+}
+
+// validateScope validates a scope requested for the Azure roles of a role.
+func validateScope(role *roleEntry, scope string) error {
+	if strings.Contains(scope, "*") || strings.Contains(scope, "//") || strings.Contains(scope, "/../") || strings.HasSuffix(scope, "/..") {
+		return fmt.Errorf("invalid scope %q", scope)
+	}
+	if !strutil.StrListContainsGlob(role.AllowedScopePatterns, scope) {
+		return fmt.Errorf("scope %q is not allowed by the role", scope)
+	}
+	return nil
+}
+
 func validateTags(tags interface{}) ([]string, error) {
 	if tags == nil {
 		return nil, nil
@@ -466,7 +846,11 @@ func (b *azureSecretBackend) createPersistedApp(ctx context.Context, req *logica
 		return nil
 	}
 
-	app, err := c.createAppWithName(ctx, name, role.SignInAudience, role.Tags)
+	tags, err := b.appTags(ctx, req.Storage, role)
+	if err != nil {
+		return err
+	}
+	app, err := c.createAppWithName(ctx, name, role.SignInAudience, tags)
 	if err != nil {
 		return err
 	}
@@ -488,8 +872,14 @@ func (b *azureSecretBackend) createPersistedApp(ctx context.Context, req *logica
 		spDuration = role.ExplicitMaxTTL
 	}
 
-	// Create the SP
-	spObjID, _, _, err := c.createSP(ctx, app, spDuration)
+	// Create the SP. Roles issuing credentials other than passwords must not
+	// leave a password on the service principal.
+	var spObjID string
+	if role.clientCredentialType() != clientCredentialTypePassword {
+		spObjID, err = c.createSPWithoutPassword(ctx, app)
+	} else {
+		spObjID, _, _, err = c.createSP(ctx, app, spDuration)
+	}
 	if err != nil {
 		return err
 	}
@@ -543,18 +933,35 @@ func (b *azureSecretBackend) pathRoleRead(ctx context.Context, req *logical.Requ
 
 	resp := &logical.Response{
 		Data: map[string]interface{}{
-			"ttl":                   r.TTL / time.Second,
-			"max_ttl":               r.MaxTTL / time.Second,
-			"explicit_max_ttl":      r.ExplicitMaxTTL / time.Second,
-			"azure_roles":           r.AzureRoles,
-			"azure_groups":          r.AzureGroups,
-			"application_object_id": r.ApplicationObjectID,
-			"permanently_delete":    r.PermanentlyDelete,
-			"persist_app":           r.PersistApp,
-			"sign_in_audience":      r.SignInAudience,
-			"tags":                  r.Tags,
+			"ttl":                    r.TTL / time.Second,
+			"max_ttl":                r.MaxTTL / time.Second,
+			"explicit_max_ttl":       r.ExplicitMaxTTL / time.Second,
+			"azure_roles":            r.AzureRoles,
+			"azure_groups":           r.AzureGroups,
+			"api_permissions":        r.APIPermissions,
+			"entra_roles":            r.EntraRoles,
+			"application_object_id":  r.ApplicationObjectID,
+			"permanently_delete":     r.PermanentlyDelete,
+			"persist_app":            r.PersistApp,
+			"sign_in_audience":       r.SignInAudience,
+			"tags":                   r.Tags,
+			"client_credential_type": r.clientCredentialType(),
+			"certificate_key_bits":   r.certificateKeyBits(),
+			"allowed_scopes":         r.AllowedScopes,
 		},
 	}
+	if len(r.AllowedScopePatterns) > 0 {
+		resp.Data["allowed_scope_patterns"] = r.AllowedScopePatterns
+	}
+	if r.allowsGroupMembership() {
+		resp.Data["member_alias_mount_accessor"] = r.MemberAliasMountAccessor
+		resp.Data["allowed_member_ids"] = r.AllowedMemberIDs
+	}
+	if r.clientCredentialType() == clientCredentialTypeFederated {
+		resp.Data["federated_issuer"] = r.FederatedIssuer
+		resp.Data["federated_subjects"] = r.FederatedSubjects
+		resp.Data["federated_audiences"] = r.FederatedAudiences
+	}
 	return resp, nil
 }
 
@@ -576,6 +983,25 @@ func (b *azureSecretBackend) pathRoleDelete(ctx context.Context, req *logical.Re
 		return nil, fmt.Errorf("error getting role: %w", err)
 	}
 
+	tokenCred, err := getRoleTokenCredential(ctx, name, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// The password used for access tokens is removed from existing
+	// applications. Persisted apps are deleted along with their passwords.
+	if tokenCred != nil && (role == nil || !role.PersistApp) {
+		c, err := b.getClient(ctx, req.Storage)
+		if err != nil {
+			return nil, fmt.Errorf("error during delete: %w", err)
+		}
+
+		if err := b.deleteTokenCredential(ctx, req.Storage, c, name); err != nil {
+			resp = new(logical.Response)
+			resp.AddWarning(fmt.Sprintf("failed to remove token password: %s", err))
+		}
+	}
+
 	if role != nil && role.PersistApp {
 		c, err := b.getClient(ctx, req.Storage)
 		if err != nil {
@@ -600,6 +1026,10 @@ func (b *azureSecretBackend) pathRoleDelete(ctx context.Context, req *logical.Re
 		return nil, fmt.Errorf("error deleting role: %w", err)
 	}
 
+	if err := req.Storage.Delete(ctx, tokenCredsStoragePath+"/"+name); err != nil {
+		return nil, fmt.Errorf("error deleting token credential: %w", err)
+	}
+
 	return resp, nil
 }
 
@@ -42,14 +42,25 @@ func TestRoleCreate(t *testing.T) {
 			"group_name": "bar",
 			"object_id": "31c5bf7e-e1e8-42c8-882c-856f776290afFAKE_GROUP-bar"
 		}]`),
-			"ttl":                   int64(0),
-			"max_ttl":               int64(0),
-			"explicit_max_ttl":      int64(0),
-			"application_object_id": "",
-			"permanently_delete":    true,
-			"persist_app":           false,
-			"sign_in_audience":      "AzureADMyOrg",
-			"tags":                  []string{"project:vault_test"},
+			"ttl":                    int64(0),
+			"max_ttl":                int64(0),
+			"explicit_max_ttl":       int64(0),
+			"application_object_id":  "",
+			"permanently_delete":     true,
+			"persist_app":            false,
+			"sign_in_audience":       "AzureADMyOrg",
+			"tags":                   []string{"project:vault_test"},
+			"client_credential_type": "password",
+			"certificate_key_bits":   2048,
+			"allowed_scopes":         []string{"https://management.azure.com/.default"},
+			"api_permissions": compactJSON(`[
+		{
+			"resource_app_id": "00000003-0000-0000-c000-000000000000",
+			"permission": "User.Read.All",
+			"app_role_id": "df021288-bdef-4463-88db-98f22de89214",
+			"resource_object_id": "8d2ad8fe-9cc5-4e4e-8b6c-5c7e1a8cd8a3"
+		}]`),
+			"entra_roles": "[]",
 		}
 
 		spRole2 := map[string]interface{}{
@@ -73,14 +84,19 @@ func TestRoleCreate(t *testing.T) {
 			"group_name": "bam",
 			"object_id": "a6a834a6-36c3-4575-8e2b-05095963d603FAKE_GROUP-bam"
 		}]`),
-			"ttl":                   int64(300),
-			"max_ttl":               int64(3000),
-			"explicit_max_ttl":      int64(3000),
-			"application_object_id": "",
-			"permanently_delete":    true,
-			"persist_app":           false,
-			"sign_in_audience":      "AzureADMultipleOrgs",
-			"tags":                  []string{"project:vault_test"},
+			"ttl":                    int64(300),
+			"max_ttl":                int64(3000),
+			"explicit_max_ttl":       int64(3000),
+			"application_object_id":  "",
+			"permanently_delete":     true,
+			"persist_app":            false,
+			"sign_in_audience":       "AzureADMultipleOrgs",
+			"tags":                   []string{"project:vault_test"},
+			"client_credential_type": "password",
+			"certificate_key_bits":   2048,
+			"allowed_scopes":         []string{"api://my-api/*", "https://graph.microsoft.com/.default"},
+			"api_permissions":        "[]",
+			"entra_roles":            "[]",
 		}
 
 		// Verify basic updates of the name role
@@ -199,16 +215,21 @@ func TestRoleCreate(t *testing.T) {
 
 	t.Run("Static SP role", func(t *testing.T) {
 		spRole1 := map[string]interface{}{
-			"application_object_id": "00000000-0000-0000-0000-000000000000",
-			"ttl":                   int64(300),
-			"max_ttl":               int64(3000),
-			"explicit_max_ttl":      int64(3000),
-			"azure_roles":           "[]",
-			"azure_groups":          "[]",
-			"sign_in_audience":      "PersonalMicrosoftAccount",
-			"tags":                  []string{"environment:production"},
-			"permanently_delete":    false,
-			"persist_app":           false,
+			"application_object_id":  "00000000-0000-0000-0000-000000000000",
+			"ttl":                    int64(300),
+			"max_ttl":                int64(3000),
+			"explicit_max_ttl":       int64(3000),
+			"azure_roles":            "[]",
+			"azure_groups":           "[]",
+			"sign_in_audience":       "PersonalMicrosoftAccount",
+			"tags":                   []string{"environment:production"},
+			"client_credential_type": "password",
+			"certificate_key_bits":   2048,
+			"allowed_scopes":         []string{"https://management.azure.com/.default"},
+			"api_permissions":        "[]",
+			"entra_roles":            "[]",
+			"permanently_delete":     false,
+			"persist_app":            false,
 		}
 
 		name := generateUUID()
@@ -230,11 +251,16 @@ func TestRoleCreate(t *testing.T) {
 					"scope":  "test_scope_3"
 				}]`,
 			),
-			"application_object_id": "",
-			"sign_in_audience":      "AzureADandPersonalMicrosoftAccount",
-			"tags":                  []string{"project:vault_testing"},
-			"azure_groups":          "[]",
-			"persist_app":           false,
+			"application_object_id":  "",
+			"sign_in_audience":       "AzureADandPersonalMicrosoftAccount",
+			"tags":                   []string{"project:vault_testing"},
+			"client_credential_type": "password",
+			"certificate_key_bits":   2048,
+			"allowed_scopes":         []string{"api://my-api/*", "https://graph.microsoft.com/.default"},
+			"api_permissions":        "[]",
+			"entra_roles":            "[]",
+			"azure_groups":           "[]",
+			"persist_app":            false,
 		}
 
 		// Verify that ttl, max_ttl and explicit_max_ttl are 0 if not provided
@@ -572,6 +598,47 @@ func TestRoleCreateBad(t *testing.T) {
 	if !strings.Contains(resp.Error().Error(), msg) {
 		t.Fatalf("expected to find: %s, got: %s", msg, resp.Error().Error())
 	}
+
+	// invalid client_credential_type
+	role = map[string]interface{}{"application_object_id": "abc", "client_credential_type": "secret"}
+	resp = testRoleCreateBasic(t, b, s, "test_role_1", role)
+	msg = "Invalid value for client_credential_type field. Valid values are: password, certificate, federated"
+	if !strings.Contains(resp.Error().Error(), msg) {
+		t.Fatalf("expected to find: %s, got: %s", msg, resp.Error().Error())
+	}
+
+	// invalid certificate_key_bits
+	role = map[string]interface{}{"application_object_id": "abc", "client_credential_type": "certificate", "certificate_key_bits": 1024}
+	resp = testRoleCreateBasic(t, b, s, "test_role_1", role)
+	msg = "Invalid value for certificate_key_bits field"
+	if !strings.Contains(resp.Error().Error(), msg) {
+		t.Fatalf("expected to find: %s, got: %s", msg, resp.Error().Error())
+	}
+
+	// invalid federated identity credential constraints
+	for msg, role := range map[string]map[string]interface{}{
+		"federated_issuer is required": {
+			"federated_subjects": "repo:openbao/openbao:ref:refs/heads/main",
+		},
+		"federated_issuer must be an https URL": {
+			"federated_issuer":   "http://token.actions.githubusercontent.com",
+			"federated_subjects": "repo:openbao/openbao:ref:refs/heads/main",
+		},
+		"federated_subjects is required": {
+			"federated_issuer": "https://token.actions.githubusercontent.com",
+		},
+		"invalid federated subject": {
+			"federated_issuer":   "https://token.actions.githubusercontent.com",
+			"federated_subjects": "*",
+		},
+	} {
+		role["application_object_id"] = "abc"
+		role["client_credential_type"] = "federated"
+		resp = testRoleCreateBasic(t, b, s, "test_role_1", role)
+		if !strings.Contains(resp.Error().Error(), msg) {
+			t.Fatalf("expected to find: %s, got: %s", msg, resp.Error().Error())
+		}
+	}
 }
 
 // TestRolesCreate_applicationObjectID tests that a role
@@ -876,6 +943,12 @@ func convertRespTypes(data map[string]interface{}) {
 	if data["azure_groups"] != nil {
 		data["azure_groups"] = encodeJSON(data["azure_groups"])
 	}
+	if data["api_permissions"] != nil {
+		data["api_permissions"] = encodeJSON(data["api_permissions"])
+	}
+	if data["entra_roles"] != nil {
+		data["entra_roles"] = encodeJSON(data["entra_roles"])
+	}
 	data["ttl"] = int64(data["ttl"].(time.Duration))
 	data["max_ttl"] = int64(data["max_ttl"].(time.Duration))
 	data["explicit_max_ttl"] = int64(data["explicit_max_ttl"].(time.Duration))
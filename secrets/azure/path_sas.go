@@ -0,0 +1,151 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// sasClockSkew backdates the start of shared access signatures, so that they
+// are valid on storage servers whose clock is behind.
+const sasClockSkew = 5 * time.Minute
+
+func pathSAS(b *azureSecretBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "sas/" + framework.GenericNameRegex("role"),
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAzure,
+			OperationVerb:   "generate",
+			OperationSuffix: "shared-access-signature",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the SAS role.",
+			},
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Lifetime of the shared access signature. Defaults to the ttl of the role and cannot exceed its max_ttl.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathSASRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathSASRead,
+			},
+		},
+		HelpSynopsis:    sasHelpSyn,
+		HelpDescription: sasHelpDesc,
+	}
+}
+
+func (b *azureSecretBackend) pathSASRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+
+	role, err := getSASRole(ctx, roleName, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("SAS role '%s' does not exist", roleName), nil
+	}
+
+	ttl, maxTTL := role.TTL, role.MaxTTL
+	if ttl == 0 {
+		ttl = b.System().DefaultLeaseTTL()
+	}
+	if maxTTL == 0 {
+		maxTTL = b.System().MaxLeaseTTL()
+	}
+	if role.SASType == sasTypeUserDelegation {
+		maxTTL = min(maxTTL, maxUserDelegationSASTTL)
+	}
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		ttl = time.Duration(ttlRaw.(int)) * time.Second
+		if ttl > maxTTL {
+			return logical.ErrorResponse("ttl cannot be greater than %d seconds", int(maxTTL.Seconds())), nil
+		}
+	}
+	ttl = min(ttl, maxTTL)
+	if ttl <= 0 {
+		return logical.ErrorResponse("ttl must be positive"), nil
+	}
+
+	c, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	sas := containerSAS{
+		account:     role.StorageAccount,
+		container:   role.Container,
+		permissions: role.Permissions,
+		start:       now.Add(-sasClockSkew),
+		expiry:      now.Add(ttl),
+	}
+
+	var token string
+	switch role.SASType {
+	case sasTypeAccountKey:
+		token, err = c.accountKeySAS(ctx, role, sas)
+	default:
+		token, err = c.userDelegationSAS(ctx, sas)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"sas_token":  token,
+			"url":        fmt.Sprintf("https://%s.blob.%s/%s?%s", role.StorageAccount, c.settings.StorageSuffix, role.Container, token),
+			"ttl":        int64(ttl.Seconds()),
+			"expiration": sas.expiry.UTC().Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// accountKeySAS signs the shared access signature with a key of the storage
+// account of the role.
+func (c *client) accountKeySAS(ctx context.Context, role *sasRoleEntry, sas containerSAS) (string, error) {
+	keys, err := c.provider.ListStorageAccountKeys(ctx, role.ResourceGroup, role.StorageAccount)
+	if err != nil {
+		return "", fmt.Errorf("error listing keys of storage account %q: %w", role.StorageAccount, err)
+	}
+
+	for _, key := range keys {
+		if strings.EqualFold(key.Permissions, "full") {
+			return sas.signWithAccountKey(key.Value)
+		}
+	}
+	return "", fmt.Errorf("storage account %q has no key with full permissions", role.StorageAccount)
+}
+
+// userDelegationSAS signs the shared access signature with a user delegation
+// key valid for the lifetime of the signature.
+func (c *client) userDelegationSAS(ctx context.Context, sas containerSAS) (string, error) {
+	key, err := c.provider.GetUserDelegationKey(ctx, sas.account, sas.start, sas.expiry)
+	if err != nil {
+		return "", fmt.Errorf("error requesting user delegation key of storage account %q: %w", sas.account, err)
+	}
+
+	return sas.signWithUserDelegationKey(key)
+}
+
+const sasHelpSyn = `Generate a shared access signature for the blob container of a SAS role.`
+const sasHelpDesc = `
+This path generates a shared access signature (SAS) for the blob container of
+a SAS role, with the permissions of the role. Shared access signatures cannot
+be revoked, so no lease is created; the signature is valid until it expires.
+The lifetime of the signature is bounded by the max_ttl of the role.
+`
@@ -0,0 +1,274 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	sasRolesStoragePath = "sas-roles"
+
+	sasTypeAccountKey     = "account_key"
+	sasTypeUserDelegation = "user_delegation"
+
+	// maxUserDelegationSASTTL is the longest lifetime of a user delegation key
+	// accepted by the Blob service.
+	maxUserDelegationSASTTL = 7 * 24 * time.Hour
+)
+
+var (
+	storageAccountNameRegex = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	containerNameRegex      = regexp.MustCompile(`^[a-z0-9](-?[a-z0-9])+$`)
+)
+
+// sasRoleEntry is a role that issues shared access signatures for a blob
+// container of a storage account.
+type sasRoleEntry struct {
+	SASType        string        `json:"sas_type"`
+	StorageAccount string        `json:"storage_account"`
+	ResourceGroup  string        `json:"resource_group"`
+	Container      string        `json:"container"`
+	Permissions    string        `json:"permissions"`
+	TTL            time.Duration `json:"ttl"`
+	MaxTTL         time.Duration `json:"max_ttl"`
+}
+
+func pathsSASRole(b *azureSecretBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: sasRolesStoragePath + "/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixAzure,
+				OperationSuffix: "sas-role",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeLowerCaseString,
+					Description: "Name of the SAS role.",
+				},
+				"sas_type": {
+					Type:        framework.TypeString,
+					Description: "Type of shared access signature issued for the role. Valid values are account_key, signed with a key of the storage account, and user_delegation, signed with a user delegation key. Defaults to user_delegation.",
+					Default:     sasTypeUserDelegation,
+				},
+				"storage_account": {
+					Type:        framework.TypeString,
+					Description: "Name of the storage account.",
+				},
+				"resource_group": {
+					Type:        framework.TypeString,
+					Description: "Resource group of the storage account in the configured subscription. Required if sas_type is account_key.",
+				},
+				"container": {
+					Type:        framework.TypeString,
+					Description: "Name of the blob container the shared access signatures grant access to.",
+				},
+				"permissions": {
+					Type:        framework.TypeString,
+					Description: "Permissions granted by the shared access signatures, e.g. rl for read and list.",
+				},
+				"ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Default lifetime of the shared access signatures. Defaults to the system/engine default TTL time.",
+				},
+				"max_ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Maximum lifetime of the shared access signatures. Defaults to the system/engine max TTL time, and at most 7 days for user_delegation.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathSASRoleRead,
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathSASRoleWrite,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathSASRoleWrite,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.pathSASRoleDelete,
+				},
+			},
+			ExistenceCheck:  b.pathSASRoleExistenceCheck,
+			HelpSynopsis:    sasRoleHelpSyn,
+			HelpDescription: sasRoleHelpDesc,
+		},
+		{
+			Pattern: sasRolesStoragePath + "/?",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixAzure,
+				OperationSuffix: "sas-roles",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathSASRoleList,
+				},
+			},
+			HelpSynopsis:    sasRoleListHelpSyn,
+			HelpDescription: sasRoleListHelpDesc,
+		},
+	}
+}
+
+func (b *azureSecretBackend) pathSASRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := getSASRole(ctx, name, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SAS role: %w", err)
+	}
+	if role == nil {
+		role = &sasRoleEntry{
+			SASType: d.Get("sas_type").(string),
+		}
+	}
+
+	if sasType, ok := d.GetOk("sas_type"); ok {
+		role.SASType = sasType.(string)
+	}
+	if role.SASType != sasTypeAccountKey && role.SASType != sasTypeUserDelegation {
+		return logical.ErrorResponse("invalid sas_type %q, must be %s or %s", role.SASType, sasTypeAccountKey, sasTypeUserDelegation), nil
+	}
+
+	if account, ok := d.GetOk("storage_account"); ok {
+		role.StorageAccount = account.(string)
+	}
+	if !storageAccountNameRegex.MatchString(role.StorageAccount) {
+		return logical.ErrorResponse("invalid storage_account %q", role.StorageAccount), nil
+	}
+
+	if resourceGroup, ok := d.GetOk("resource_group"); ok {
+		role.ResourceGroup = resourceGroup.(string)
+	}
+	if role.SASType == sasTypeAccountKey && role.ResourceGroup == "" {
+		return logical.ErrorResponse("resource_group is required if sas_type is %s", sasTypeAccountKey), nil
+	}
+
+	if container, ok := d.GetOk("container"); ok {
+		role.Container = container.(string)
+	}
+	if len(role.Container) > 63 || !containerNameRegex.MatchString(role.Container) {
+		return logical.ErrorResponse("invalid container %q", role.Container), nil
+	}
+
+	if permissions, ok := d.GetOk("permissions"); ok {
+		role.Permissions = permissions.(string)
+	}
+	role.Permissions, err = normalizeSASPermissions(role.Permissions)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		role.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if role.MaxTTL != 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+	if role.SASType == sasTypeUserDelegation && role.MaxTTL > maxUserDelegationSASTTL {
+		return logical.ErrorResponse("max_ttl cannot be greater than %d seconds if sas_type is %s", int(maxUserDelegationSASTTL.Seconds()), sasTypeUserDelegation), nil
+	}
+
+	if err := saveSASRole(ctx, req.Storage, name, role); err != nil {
+		return nil, fmt.Errorf("error storing SAS role: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (b *azureSecretBackend) pathSASRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := getSASRole(ctx, d.Get("name").(string), req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SAS role: %w", err)
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"sas_type":        role.SASType,
+			"storage_account": role.StorageAccount,
+			"resource_group":  role.ResourceGroup,
+			"container":       role.Container,
+			"permissions":     role.Permissions,
+			"ttl":             int64(role.TTL.Seconds()),
+			"max_ttl":         int64(role.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *azureSecretBackend) pathSASRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(ctx, sasRolesStoragePath+"/")
+	if err != nil {
+		return nil, fmt.Errorf("error listing SAS roles: %w", err)
+	}
+
+	return logical.ListResponse(roles), nil
+}
+
+func (b *azureSecretBackend) pathSASRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, sasRolesStoragePath+"/"+d.Get("name").(string)); err != nil {
+		return nil, fmt.Errorf("error deleting SAS role: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (b *azureSecretBackend) pathSASRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := getSASRole(ctx, d.Get("name").(string), req.Storage)
+	if err != nil {
+		return false, err
+	}
+
+	return role != nil, nil
+}
+
+func saveSASRole(ctx context.Context, s logical.Storage, name string, role *sasRoleEntry) error {
+	entry, err := logical.StorageEntryJSON(sasRolesStoragePath+"/"+name, role)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+func getSASRole(ctx context.Context, name string, s logical.Storage) (*sasRoleEntry, error) {
+	entry, err := s.Get(ctx, sasRolesStoragePath+"/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	role := new(sasRoleEntry)
+	if err := entry.DecodeJSON(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+const sasRoleHelpSyn = `Manage the roles that issue shared access signatures for Azure Storage.`
+const sasRoleHelpDesc = `
+This path lets you manage the roles that issue shared access signatures (SAS)
+for a blob container of an Azure Storage account. A role issues either service
+SAS signed with a key of the storage account, or user delegation SAS signed
+with a user delegation key obtained with the configured credentials.
+`
+
+const sasRoleListHelpSyn = `List the SAS roles.`
+const sasRoleListHelpDesc = `List the roles that issue shared access signatures for Azure Storage.`
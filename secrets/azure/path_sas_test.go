@@ -0,0 +1,382 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/openbao/openbao/sdk/v2/logical"
+
+	"github.com/openbao/openbao-plugins/secrets/azure/api"
+)
+
+const testStorageSubscriptionID = "b3a1c2d4-5e6f-4a7b-8c9d-0e1f2a3b4c5d"
+
+var (
+	testStorageAccountKey = base64.StdEncoding.EncodeToString([]byte("test-storage-account-key"))
+	testUserDelegationKey = api.UserDelegationKey{
+		SignedOID:     "4c1b6a4f-7f3e-4b6a-9d3c-0a1e2b3c4d5e",
+		SignedTID:     "72f988bf-86f1-41af-91ab-2d7cd011db47",
+		SignedStart:   "2026-01-01T00:00:00Z",
+		SignedExpiry:  "2026-01-02T00:00:00Z",
+		SignedService: "b",
+		SignedVersion: api.StorageServiceVersion,
+		Value:         base64.StdEncoding.EncodeToString([]byte("test-user-delegation-key")),
+	}
+)
+
+func TestSASRoles(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+
+	t.Run("defaults", func(t *testing.T) {
+		testSASRoleWrite(t, b, s, "reader", map[string]interface{}{
+			"storage_account": "testaccount",
+			"container":       "data",
+			"permissions":     "lr",
+		})
+
+		resp, err := testStaticRequest(b, s, logical.ReadOperation, "sas-roles/reader", nil)
+		assertRespNoError(t, resp, err)
+		equal(t, map[string]interface{}{
+			"sas_type":        sasTypeUserDelegation,
+			"storage_account": "testaccount",
+			"resource_group":  "",
+			"container":       "data",
+			"permissions":     "rl",
+			"ttl":             int64(0),
+			"max_ttl":         int64(0),
+		}, resp.Data)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		testSASRoleWrite(t, b, s, "reader", map[string]interface{}{
+			"sas_type":       sasTypeAccountKey,
+			"resource_group": "storage-rg",
+			"max_ttl":        "30d",
+		})
+
+		role, err := getSASRole(context.Background(), "reader", s)
+		assertErrorIsNil(t, err)
+		equal(t, &sasRoleEntry{
+			SASType:        sasTypeAccountKey,
+			StorageAccount: "testaccount",
+			ResourceGroup:  "storage-rg",
+			Container:      "data",
+			Permissions:    "rl",
+			MaxTTL:         30 * 24 * time.Hour,
+		}, role)
+	})
+
+	t.Run("list and delete", func(t *testing.T) {
+		resp, err := testStaticRequest(b, s, logical.ListOperation, "sas-roles/", nil)
+		assertRespNoError(t, resp, err)
+		equal(t, []string{"reader"}, resp.Data["keys"])
+
+		resp, err = testStaticRequest(b, s, logical.DeleteOperation, "sas-roles/reader", nil)
+		assertRespNoError(t, resp, err)
+
+		role, err := getSASRole(context.Background(), "reader", s)
+		assertErrorIsNil(t, err)
+		if role != nil {
+			t.Fatal("expected SAS role to be deleted")
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		valid := map[string]interface{}{
+			"storage_account": "testaccount",
+			"container":       "data",
+			"permissions":     "r",
+		}
+		tests := map[string]map[string]interface{}{
+			"invalid sas type":            {"sas_type": "account"},
+			"invalid storage account":     {"storage_account": "Test-Account"},
+			"invalid container":           {"container": "-data"},
+			"missing permissions":         {"permissions": ""},
+			"invalid permissions":         {"permissions": "rq"},
+			"missing resource group":      {"sas_type": sasTypeAccountKey},
+			"ttl greater than max_ttl":    {"ttl": "2h", "max_ttl": "1h"},
+			"user delegation over 7 days": {"max_ttl": "8d"},
+		}
+		for name, overrides := range tests {
+			t.Run(name, func(t *testing.T) {
+				d := make(map[string]interface{})
+				for k, v := range valid {
+					d[k] = v
+				}
+				for k, v := range overrides {
+					d[k] = v
+				}
+
+				resp, err := testStaticRequest(b, s, logical.CreateOperation, "sas-roles/invalid", d)
+				assertErrorIsNil(t, err)
+				if resp == nil || !resp.IsError() {
+					t.Fatalf("expected error response, got %#v", resp)
+				}
+			})
+		}
+	})
+}
+
+func TestSASRead(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+	srv := newTestStorageServer(t)
+	mp.storage = srv.client(t)
+
+	testSASRoleWrite(t, b, s, "key", map[string]interface{}{
+		"sas_type":        sasTypeAccountKey,
+		"storage_account": "testaccount",
+		"resource_group":  "storage-rg",
+		"container":       "data",
+		"permissions":     "rl",
+		"ttl":             "1h",
+		"max_ttl":         "2h",
+	})
+	testSASRoleWrite(t, b, s, "delegation", map[string]interface{}{
+		"storage_account": "testaccount",
+		"container":       "logs",
+		"permissions":     "racw",
+	})
+
+	t.Run("account key", func(t *testing.T) {
+		resp := testSASRead(t, b, s, "key", nil)
+		equal(t, int64(3600), resp.Data["ttl"])
+		assertKeyExists(t, resp.Data, "expiration")
+
+		token := resp.Data["sas_token"].(string)
+		query := testSASQuery(t, token, time.Hour)
+		equal(t, "c", query.Get("sr"))
+		equal(t, "rl", query.Get("sp"))
+		equal(t, "https", query.Get("spr"))
+
+		sas := testSASFromQuery(t, "testaccount", "data", query)
+		expected, err := sas.signWithAccountKey(testStorageAccountKey)
+		assertErrorIsNil(t, err)
+		equal(t, expected, token)
+
+		equal(t, "https://testaccount.blob."+b.settings.StorageSuffix+"/data?"+token, resp.Data["url"])
+		equal(t, fmt.Sprintf("/subscriptions/%s/resourceGroups/storage-rg/providers/Microsoft.Storage/storageAccounts/testaccount/listKeys", testStorageSubscriptionID), srv.lastPath)
+	})
+
+	t.Run("requested ttl", func(t *testing.T) {
+		resp := testSASRead(t, b, s, "key", map[string]interface{}{"ttl": "90m"})
+		equal(t, int64(5400), resp.Data["ttl"])
+		testSASQuery(t, resp.Data["sas_token"].(string), 90*time.Minute)
+
+		resp, err := testStaticRequest(b, s, logical.UpdateOperation, "sas/key", map[string]interface{}{"ttl": "3h"})
+		assertErrorIsNil(t, err)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected ttl over max_ttl to be rejected, got %#v", resp)
+		}
+	})
+
+	t.Run("user delegation", func(t *testing.T) {
+		resp := testSASRead(t, b, s, "delegation", nil)
+		equal(t, int64(defaultLeaseTTLHr.Seconds()), resp.Data["ttl"])
+
+		token := resp.Data["sas_token"].(string)
+		query := testSASQuery(t, token, defaultLeaseTTLHr)
+		equal(t, "racw", query.Get("sp"))
+		equal(t, testUserDelegationKey.SignedOID, query.Get("skoid"))
+		equal(t, testUserDelegationKey.SignedTID, query.Get("sktid"))
+
+		sas := testSASFromQuery(t, "testaccount", "logs", query)
+		expected, err := sas.signWithUserDelegationKey(testUserDelegationKey)
+		assertErrorIsNil(t, err)
+		equal(t, expected, token)
+
+		// The user delegation key is requested for the lifetime of the SAS
+		equal(t, "testaccount.blob.core.windows.net", srv.lastHost)
+		equal(t, query.Get("st"), srv.lastKeyInfo.Start)
+		equal(t, query.Get("se"), srv.lastKeyInfo.Expiry)
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		resp, err := testStaticRequest(b, s, logical.ReadOperation, "sas/unknown", nil)
+		assertErrorIsNil(t, err)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected error response, got %#v", resp)
+		}
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		testSASRoleWrite(t, b, s, "missing", map[string]interface{}{
+			"sas_type":        sasTypeAccountKey,
+			"storage_account": "missing",
+			"resource_group":  "storage-rg",
+			"container":       "data",
+			"permissions":     "r",
+		})
+
+		_, err := testStaticRequest(b, s, logical.ReadOperation, "sas/missing", nil)
+		if err == nil {
+			t.Fatal("expected error listing keys of unknown storage account")
+		}
+	})
+}
+
+// testStorageServer is a local stand-in for the Azure Resource Manager and
+// Blob service endpoints used to sign shared access signatures.
+type testStorageServer struct {
+	*httptest.Server
+
+	lastHost    string
+	lastPath    string
+	lastKeyInfo struct {
+		Start  string `xml:"Start"`
+		Expiry string `xml:"Expiry"`
+	}
+}
+
+func newTestStorageServer(t *testing.T) *testStorageServer {
+	srv := new(testStorageServer)
+	srv.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.lastHost = r.Host
+		srv.lastPath = r.URL.Path
+
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/storageAccounts/testaccount/listKeys"):
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []api.StorageAccountKey{
+					{KeyName: "key1", Value: base64.StdEncoding.EncodeToString([]byte("read-only")), Permissions: "READ"},
+					{KeyName: "key2", Value: testStorageAccountKey, Permissions: "FULL"},
+				},
+			})
+		case r.URL.Query().Get("comp") == "userdelegationkey":
+			body, _ := io.ReadAll(r.Body)
+			if err := xml.Unmarshal(body, &srv.lastKeyInfo); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			_ = xml.NewEncoder(w).Encode(struct {
+				XMLName xml.Name `xml:"UserDelegationKey"`
+				api.UserDelegationKey
+			}{UserDelegationKey: testUserDelegationKey})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// client returns a storage client whose requests are sent to the server.
+func (srv *testStorageServer) client(t *testing.T) api.StorageClient {
+	t.Helper()
+
+	u, err := url.Parse(srv.URL)
+	assertErrorIsNil(t, err)
+
+	client, err := api.NewStorageRESTClient(testStorageSubscriptionID, "core.windows.net", testTokenCredential{}, policy.ClientOptions{
+		Cloud: cloud.Configuration{
+			Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+				cloud.ResourceManager: {
+					Audience: "https://management.azure.com",
+					Endpoint: "https://management.azure.com",
+				},
+			},
+		},
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+		Transport: testRedirectTransport{client: srv.Client(), host: u.Host},
+	})
+	assertErrorIsNil(t, err)
+
+	return client
+}
+
+// testRedirectTransport sends all requests to host, keeping their original
+// Host header.
+type testRedirectTransport struct {
+	client *http.Client
+	host   string
+}
+
+func (t testRedirectTransport) Do(req *http.Request) (*http.Response, error) {
+	req.Host = req.URL.Host
+	req.URL.Host = t.host
+	return t.client.Do(req)
+}
+
+type testTokenCredential struct{}
+
+func (testTokenCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "test-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func testSASRoleWrite(t *testing.T, b *azureSecretBackend, s logical.Storage, name string, d map[string]interface{}) {
+	t.Helper()
+
+	resp, err := testStaticRequest(b, s, logical.UpdateOperation, "sas-roles/"+name, d)
+	assertRespNoError(t, resp, err)
+}
+
+func testSASRead(t *testing.T, b *azureSecretBackend, s logical.Storage, role string, d map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := testStaticRequest(b, s, logical.UpdateOperation, "sas/"+role, d)
+	assertRespNoError(t, resp, err)
+	if resp.Secret != nil {
+		t.Fatal("expected no lease for shared access signatures")
+	}
+
+	return resp
+}
+
+// testSASQuery parses the query of a shared access signature and checks its
+// lifetime.
+func testSASQuery(t *testing.T, token string, ttl time.Duration) url.Values {
+	t.Helper()
+
+	query, err := url.ParseQuery(token)
+	assertErrorIsNil(t, err)
+	equal(t, api.StorageServiceVersion, query.Get("sv"))
+
+	start, err := time.Parse(sasTimeFormat, query.Get("st"))
+	assertErrorIsNil(t, err)
+	expiry, err := time.Parse(sasTimeFormat, query.Get("se"))
+	assertErrorIsNil(t, err)
+	equal(t, ttl+sasClockSkew, expiry.Sub(start))
+
+	return query
+}
+
+func testSASFromQuery(t *testing.T, account, container string, query url.Values) containerSAS {
+	t.Helper()
+
+	start, err := time.Parse(sasTimeFormat, query.Get("st"))
+	assertErrorIsNil(t, err)
+	expiry, err := time.Parse(sasTimeFormat, query.Get("se"))
+	assertErrorIsNil(t, err)
+
+	return containerSAS{
+		account:     account,
+		container:   container,
+		permissions: query.Get("sp"),
+		start:       start,
+		expiry:      expiry,
+	}
+}
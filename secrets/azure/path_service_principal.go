@@ -5,13 +5,18 @@ package azure
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
+	"github.com/openbao/openbao/sdk/v2/helper/strutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
+
+	"github.com/openbao/openbao-plugins/secrets/azure/api"
 )
 
 const (
@@ -52,6 +57,22 @@ func pathServicePrincipal(b *azureSecretBackend) *framework.Path {
 				Type:        framework.TypeLowerCaseString,
 				Description: "Name of the Vault role",
 			},
+			"csr": {
+				Type:        framework.TypeString,
+				Description: "PEM encoded certificate signing request. Only valid for roles issuing certificates. If set, the certificate is issued for the public key of the request and no private key is returned.",
+			},
+			"subject": {
+				Type:        framework.TypeString,
+				Description: "Subject of the federated identity credential. Only valid for roles issuing federated identity credentials. Must match one of the federated_subjects of the role.",
+			},
+			"audience": {
+				Type:        framework.TypeString,
+				Description: "Audience of the federated identity credential. Only valid for roles issuing federated identity credentials. Must be one of the federated_audiences of the role. Defaults to the first audience of the role.",
+			},
+			"scope": {
+				Type:        framework.TypeString,
+				Description: "Scope of the Azure role assignments of the service principal. Only valid for roles with allowed_scope_patterns. Must match one of the allowed_scope_patterns of the role.",
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ReadOperation: &framework.PathOperation{
@@ -59,6 +80,11 @@ func pathServicePrincipal(b *azureSecretBackend) *framework.Path {
 				ForwardPerformanceSecondary: true,
 				ForwardPerformanceStandby:   true,
 			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                    b.pathSPRead,
+				ForwardPerformanceSecondary: true,
+				ForwardPerformanceStandby:   true,
+			},
 		},
 
 		HelpSynopsis:    pathServicePrincipalHelpSyn,
@@ -84,12 +110,17 @@ func (b *azureSecretBackend) pathSPRead(ctx context.Context, req *logical.Reques
 		return logical.ErrorResponse(fmt.Sprintf("role '%s' does not exist", roleName)), nil
 	}
 
+	params, err := credentialParamsFromRequest(role, d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
 	var resp *logical.Response
 
 	if role.ApplicationObjectID != "" {
-		resp, err = b.createStaticSPSecret(ctx, client, roleName, role)
+		resp, err = b.createStaticSPSecret(ctx, client, roleName, role, params)
 	} else {
-		resp, err = b.createSPSecret(ctx, req.Storage, client, roleName, role)
+		resp, err = b.createSPSecret(ctx, req.Storage, client, roleName, role, params)
 	}
 
 	if err != nil {
@@ -101,15 +132,97 @@ func (b *azureSecretBackend) pathSPRead(ctx context.Context, req *logical.Reques
 	if role.ExplicitMaxTTL != 0 && (role.ExplicitMaxTTL < role.MaxTTL || role.MaxTTL == 0) {
 		resp.Secret.MaxTTL = role.ExplicitMaxTTL
 	}
+
 	return resp, nil
 }
 
+// credentialParams are the request parameters used to issue credentials
+// other than passwords, and the requested scope of the Azure roles.
+type credentialParams struct {
+	csr      *x509.CertificateRequest
+	subject  string
+	audience string
+	scope    string
+}
+
+// credentialParamsFromRequest validates the request parameters against the
+// client credential type of the role.
+func credentialParamsFromRequest(role *roleEntry, d *framework.FieldData) (*credentialParams, error) {
+	params := &credentialParams{}
+	credType := role.clientCredentialType()
+
+	if scope, ok := d.GetOk("scope"); ok {
+		if len(role.AllowedScopePatterns) == 0 {
+			return nil, errors.New("scope is only valid for roles with allowed_scope_patterns")
+		}
+		params.scope = scope.(string)
+		if err := validateScope(role, params.scope); err != nil {
+			return nil, err
+		}
+	} else if len(role.AllowedScopePatterns) > 0 {
+		// Azure roles without a scope must be given one by the caller
+		for _, r := range role.AzureRoles {
+			if r.Scope == "" {
+				return nil, errors.New("scope is required")
+			}
+		}
+	}
+
+	if csrPEM, ok := d.GetOk("csr"); ok {
+		if credType != clientCredentialTypeCertificate {
+			return nil, fmt.Errorf("csr is only valid for roles with client_credential_type '%s'", clientCredentialTypeCertificate)
+		}
+		csr, err := parseCSR(csrPEM.(string), role.certificateKeyBits())
+		if err != nil {
+			return nil, err
+		}
+		params.csr = csr
+	}
+
+	subject, subjectOK := d.GetOk("subject")
+	audience, audienceOK := d.GetOk("audience")
+	if credType != clientCredentialTypeFederated {
+		if subjectOK || audienceOK {
+			return nil, fmt.Errorf("subject and audience are only valid for roles with client_credential_type '%s'", clientCredentialTypeFederated)
+		}
+		return params, nil
+	}
+
+	if subjectOK {
+		params.subject = subject.(string)
+		if !strutil.StrListContainsGlob(role.FederatedSubjects, params.subject) {
+			return nil, fmt.Errorf("subject %q is not allowed by the role", params.subject)
+		}
+	} else {
+		// A role with a single literal subject doesn't require the caller to
+		// supply one
+		if len(role.FederatedSubjects) != 1 || strings.Contains(role.FederatedSubjects[0], "*") {
+			return nil, errors.New("subject is required")
+		}
+		params.subject = role.FederatedSubjects[0]
+	}
+
+	params.audience = role.FederatedAudiences[0]
+	if audienceOK {
+		params.audience = audience.(string)
+		if !strutil.StrListContains(role.FederatedAudiences, params.audience) {
+			return nil, fmt.Errorf("audience %q is not allowed by the role", params.audience)
+		}
+	}
+
+	return params, nil
+}
+
 // createSPSecret generates a new App/Service Principal.
-func (b *azureSecretBackend) createSPSecret(ctx context.Context, s logical.Storage, c *client, roleName string, role *roleEntry) (*logical.Response, error) {
+func (b *azureSecretBackend) createSPSecret(ctx context.Context, s logical.Storage, c *client, roleName string, role *roleEntry, params *credentialParams) (*logical.Response, error) {
 	// Create the App, which is the top level object to be tracked in the secret
 	// and deleted upon revocation. If any subsequent step fails, the App will be
 	// deleted as part of WAL rollback.
-	app, err := c.createApp(ctx, role.SignInAudience, role.Tags)
+	tags, err := b.appTags(ctx, s, role)
+	if err != nil {
+		return nil, err
+	}
+	app, err := c.createApp(ctx, role.SignInAudience, tags)
 	if err != nil {
 		return nil, err
 	}
@@ -132,13 +245,32 @@ func (b *azureSecretBackend) createSPSecret(ctx context.Context, s logical.Stora
 		spDuration = role.ExplicitMaxTTL
 	}
 
-	// Create a service principal associated with the new App
-	spID, password, endDate, err := c.createSP(ctx, app, spDuration)
+	// Create a service principal associated with the new App. Credentials
+	// other than passwords are registered on the App, so the service
+	// principal is created without a password.
+	var spID string
+	var data map[string]interface{}
+	var endDate time.Time
+	if role.clientCredentialType() == clientCredentialTypePassword {
+		var password string
+		spID, password, endDate, err = c.createSP(ctx, app, spDuration)
+		data = map[string]interface{}{
+			"client_id":     appID,
+			"client_secret": password,
+		}
+	} else {
+		data, _, endDate, err = addAppCredential(ctx, c, app, role, params, spDuration)
+		if err != nil {
+			return nil, err
+		}
+		spID, err = c.createSPWithoutPassword(ctx, app)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	assignmentIDs, err := c.generateUUIDs(len(role.AzureRoles))
+	azureRoles := role.azureRolesForScope(params.scope)
+	assignmentIDs, err := c.generateUUIDs(len(azureRoles))
 	if err != nil {
 		return nil, fmt.Errorf("error generating assignment IDs; err=%w", err)
 	}
@@ -147,7 +279,7 @@ func (b *azureSecretBackend) createSPSecret(ctx context.Context, s logical.Stora
 	rWALID, err := framework.PutWAL(ctx, s, walAppRoleAssignment, &walAppRoleAssign{
 		SpID:          spID,
 		AssignmentIDs: assignmentIDs,
-		AzureRoles:    role.AzureRoles,
+		AzureRoles:    azureRoles,
 		Expiration:    time.Now().Add(maxWALAge),
 	})
 	if err != nil {
@@ -155,7 +287,7 @@ func (b *azureSecretBackend) createSPSecret(ctx context.Context, s logical.Stora
 	}
 
 	// Assign Azure roles to the new SP
-	raIDs, err := c.assignRoles(ctx, spID, role.AzureRoles, assignmentIDs)
+	raIDs, err := c.assignRoles(ctx, spID, azureRoles, assignmentIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -165,6 +297,55 @@ func (b *azureSecretBackend) createSPSecret(ctx context.Context, s logical.Stora
 		return nil, err
 	}
 
+	// Grant API permissions to the new SP. A third WAL entry removes them in
+	// case the grants don't complete.
+	var permissionIDs []string
+	if len(role.APIPermissions) > 0 {
+		pWALID, err := framework.PutWAL(ctx, s, walAPIPermissions, &walAPIPermissionAssign{
+			SpID:           spID,
+			APIPermissions: role.APIPermissions,
+			Expiration:     time.Now().Add(maxWALAge),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error writing WAL: %w", err)
+		}
+
+		permissionIDs, err = c.assignAPIPermissions(ctx, spID, role.APIPermissions)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := framework.DeleteWAL(ctx, s, pWALID); err != nil {
+			return nil, fmt.Errorf("error deleting API permission WAL: %w", err)
+		}
+	}
+
+	// Assign Entra directory roles to the new SP. Directory role assignments
+	// are removed along with their principal, so the app WAL covers them if
+	// the assignments don't complete.
+	entraRoleIDs, err := c.assignEntraRoles(ctx, spID, role.EntraRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the App until the lease can no longer be live, so that the App
+	// isn't tidied as an orphan. This is written while the App WAL exists, so
+	// that the App is rolled back if it fails.
+	maxTTL := role.MaxTTL
+	if role.ExplicitMaxTTL != 0 && (role.ExplicitMaxTTL < maxTTL || maxTTL == 0) {
+		maxTTL = role.ExplicitMaxTTL
+	}
+	if maxTTL == 0 {
+		maxTTL = b.System().MaxLeaseTTL()
+	}
+	err = saveLeasedApp(ctx, s, appObjID, &leasedAppEntry{
+		Role:       roleName,
+		Expiration: time.Now().Add(maxTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error recording leased application: %w", err)
+	}
+
 	// SP is fully created so delete the WALs
 	if err := framework.DeleteWAL(ctx, s, walID); err != nil {
 		return nil, fmt.Errorf("error deleting WAL: %w", err)
@@ -174,25 +355,28 @@ func (b *azureSecretBackend) createSPSecret(ctx context.Context, s logical.Stora
 		return nil, fmt.Errorf("error deleting role assignment WAL: %w", err)
 	}
 
-	data := map[string]interface{}{
-		"client_id":     appID,
-		"client_secret": password,
-	}
 	internalData := map[string]interface{}{
-		"app_object_id":        appObjID,
-		"sp_object_id":         spID,
-		"role_assignment_ids":  raIDs,
-		"group_membership_ids": groupObjectIDs(role.AzureGroups),
-		"role":                 roleName,
-		"permanently_delete":   role.PermanentlyDelete,
-		"key_end_date":         endDate.Format(time.RFC3339Nano),
+		"app_object_id":          appObjID,
+		"sp_object_id":           spID,
+		"role_assignment_ids":    raIDs,
+		"group_membership_ids":   groupObjectIDs(role.AzureGroups),
+		"api_permission_ids":     permissionIDs,
+		"entra_role_ids":         entraRoleIDs,
+		"role":                   roleName,
+		"permanently_delete":     role.PermanentlyDelete,
+		"key_end_date":           endDate.Format(time.RFC3339Nano),
+		"client_credential_type": role.clientCredentialType(),
+	}
+	if params.scope != "" {
+		internalData["scope"] = params.scope
 	}
 
 	return b.Secret(SecretTypeSP).Response(data, internalData), nil
 }
 
-// createStaticSPSecret adds a new password to the App associated with the role.
-func (b *azureSecretBackend) createStaticSPSecret(ctx context.Context, c *client, roleName string, role *roleEntry) (*logical.Response, error) {
+// createStaticSPSecret adds a new credential to the App associated with the
+// role.
+func (b *azureSecretBackend) createStaticSPSecret(ctx context.Context, c *client, roleName string, role *roleEntry, params *credentialParams) (*logical.Response, error) {
 	lock := locksutil.LockForKey(b.appLocks, role.ApplicationObjectID)
 	lock.Lock()
 	defer lock.Unlock()
@@ -203,25 +387,80 @@ func (b *azureSecretBackend) createStaticSPSecret(ctx context.Context, c *client
 		spDuration = role.ExplicitMaxTTL
 	}
 
-	keyID, password, endDate, err := c.addAppPassword(ctx, role.ApplicationObjectID, spDuration)
+	app := api.Application{
+		AppID:       role.ApplicationID,
+		AppObjectID: role.ApplicationObjectID,
+	}
+	data, keyID, endDate, err := addAppCredential(ctx, c, app, role, params, spDuration)
 	if err != nil {
 		return nil, err
 	}
 
-	data := map[string]interface{}{
-		"client_id":     role.ApplicationID,
-		"client_secret": password,
-	}
 	internalData := map[string]interface{}{
-		"app_object_id": role.ApplicationObjectID,
-		"key_id":        keyID,
-		"key_end_date":  endDate.Format(time.RFC3339Nano),
-		"role":          roleName,
+		"app_object_id":          role.ApplicationObjectID,
+		"key_id":                 keyID,
+		"key_end_date":           endDate.Format(time.RFC3339Nano),
+		"role":                   roleName,
+		"client_credential_type": role.clientCredentialType(),
 	}
 
 	return b.Secret(SecretTypeStaticSP).Response(data, internalData), nil
 }
 
+// addAppCredential adds a credential of the client credential type of the
+// role to the App. It returns the response data, the ID of the credential and
+// the time at which it expires in Azure.
+func addAppCredential(ctx context.Context, c *client, app api.Application, role *roleEntry, params *credentialParams, duration time.Duration) (map[string]interface{}, string, time.Time, error) {
+	data := map[string]interface{}{
+		"client_id": app.AppID,
+	}
+
+	switch role.clientCredentialType() {
+	case clientCredentialTypeCertificate:
+		cert, err := issueCertificate(app.AppID, role.certificateKeyBits(), params.csr, duration)
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+		keyID, err := c.addAppKeyCredential(ctx, app.AppObjectID, cert)
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+
+		data["certificate"] = cert.certPEM
+		data["certificate_thumbprint"] = cert.thumbprint
+		if cert.keyPEM != "" {
+			data["private_key"] = cert.keyPEM
+		}
+		if cert.issuerPEM != "" {
+			data["issuing_ca"] = cert.issuerPEM
+		}
+		return data, keyID, cert.notAfter, nil
+
+	case clientCredentialTypeFederated:
+		// Federated identity credentials don't expire in Azure, so the
+		// credential is bounded by the lease instead
+		cred, err := c.addAppFederatedIdentityCredential(ctx, app.AppObjectID, role.FederatedIssuer, params.subject, []string{params.audience})
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+
+		data["tenant_id"] = c.settings.TenantID
+		data["issuer"] = cred.Issuer
+		data["subject"] = cred.Subject
+		data["audience"] = params.audience
+		return data, cred.ID, time.Now().Add(duration), nil
+
+	default:
+		keyID, password, endDate, err := c.addAppPassword(ctx, app.AppObjectID, duration)
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+
+		data["client_secret"] = password
+		return data, keyID, endDate, nil
+	}
+}
+
 func (b *azureSecretBackend) spRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	roleRaw, ok := req.Secret.InternalData["role"]
 	if !ok {
@@ -253,6 +492,18 @@ func (b *azureSecretBackend) spRenew(ctx context.Context, req *logical.Request,
 	resp.Secret.MaxTTL = min(role.MaxTTL, keyLifetime)
 	resp.Secret.Renewable = role.TTL < keyLifetime // Lease cannot be renewed beyond service-side endDate
 
+	// The max TTL of the role or mount may have been raised since the lease
+	// was issued, so the App is recorded until the lease can no longer be live
+	if appObjectID, ok := req.Secret.InternalData["app_object_id"].(string); ok {
+		maxTTL := resp.Secret.MaxTTL
+		if maxTTL == 0 {
+			maxTTL = b.System().MaxLeaseTTL()
+		}
+		if err := extendLeasedApp(ctx, req.Storage, appObjectID, time.Now().Add(maxTTL)); err != nil {
+			return nil, fmt.Errorf("error recording leased application: %w", err)
+		}
+	}
+
 	return resp, nil
 }
 
@@ -294,7 +545,21 @@ func (b *azureSecretBackend) spRevoke(ctx context.Context, req *logical.Request,
 		}
 	}
 
-	if len(gmIDs) != 0 && spObjectID == "" {
+	var apIDs []string
+	if req.Secret.InternalData["api_permission_ids"] != nil {
+		for _, v := range req.Secret.InternalData["api_permission_ids"].([]interface{}) {
+			apIDs = append(apIDs, v.(string))
+		}
+	}
+
+	var erIDs []string
+	if req.Secret.InternalData["entra_role_ids"] != nil {
+		for _, v := range req.Secret.InternalData["entra_role_ids"].([]interface{}) {
+			erIDs = append(erIDs, v.(string))
+		}
+	}
+
+	if (len(gmIDs) != 0 || len(apIDs) != 0) && spObjectID == "" {
 		return nil, errors.New("internal data 'sp_object_id' not found")
 	}
 
@@ -316,6 +581,20 @@ func (b *azureSecretBackend) spRevoke(ctx context.Context, req *logical.Request,
 		resp.AddWarning(err.Error())
 	}
 
+	// removing API permissions is effectively a garbage collection
+	// operation. Errors will be noted but won't fail the revocation process.
+	// Deleting the app, however, *is* required to consider the secret revoked.
+	if err := c.unassignAPIPermissions(ctx, spObjectID, apIDs); err != nil {
+		resp.AddWarning(err.Error())
+	}
+
+	// removing Entra roles is effectively a garbage collection operation.
+	// Errors will be noted but won't fail the revocation process. Deleting the
+	// app, however, *is* required to consider the secret revoked.
+	if err := c.unassignEntraRoles(ctx, erIDs); err != nil {
+		resp.AddWarning(err.Error())
+	}
+
 	// removing the service principal is effectively a garbage collection
 	// operation. Errors will be noted but won't fail the revocation process.
 	// Deleting the app, however, *is* required to consider the secret revoked.
@@ -323,8 +602,11 @@ func (b *azureSecretBackend) spRevoke(ctx context.Context, req *logical.Request,
 		resp.AddWarning(err.Error())
 	}
 
-	err = c.deleteApp(ctx, appObjectID, permanentlyDelete)
-	return resp, err
+	if err := c.deleteApp(ctx, appObjectID, permanentlyDelete); err != nil {
+		return resp, err
+	}
+
+	return resp, req.Storage.Delete(ctx, leasedAppsStoragePath+"/"+appObjectID)
 }
 
 func (b *azureSecretBackend) staticSPRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
@@ -349,7 +631,16 @@ func (b *azureSecretBackend) staticSPRevoke(ctx context.Context, req *logical.Re
 	lock.Lock()
 	defer lock.Unlock()
 
-	return nil, c.deleteAppPassword(ctx, appObjectID, keyIDRaw.(string))
+	// Leases issued before certificate credentials were supported are
+	// passwords
+	switch req.Secret.InternalData["client_credential_type"] {
+	case clientCredentialTypeCertificate:
+		return nil, c.deleteAppKeyCredential(ctx, appObjectID, keyIDRaw.(string))
+	case clientCredentialTypeFederated:
+		return nil, c.deleteAppFederatedIdentityCredential(ctx, appObjectID, keyIDRaw.(string))
+	default:
+		return nil, c.deleteAppPassword(ctx, appObjectID, keyIDRaw.(string))
+	}
 }
 
 const pathServicePrincipalHelpSyn = `
@@ -359,6 +650,7 @@ Request Service Principal credentials for a given Vault role.
 const pathServicePrincipalHelpDesc = `
 This path creates or updates dynamic Service Principal credentials.
 The associated role can be configured to create a new App/Service Principal,
-or add a new password to an existing App. The Service Principal or password
-will be automatically deleted when the lease has expired.
+or add a new password, certificate or federated identity credential to an
+existing App. The Service Principal or credential will be automatically deleted
+when the lease has expired.
 `
@@ -5,6 +5,15 @@ package azure
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"net/url"
 	"os"
@@ -691,6 +700,641 @@ func TestStaticSPRevoke(t *testing.T) {
 	}
 }
 
+func TestSPReadCertificate(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+
+	client, err := b.getClient(context.Background(), s)
+	assertErrorIsNil(t, err)
+	mp := client.provider.(*mockProvider)
+
+	certRole := map[string]interface{}{
+		"azure_roles":            testRole["azure_roles"],
+		"client_credential_type": "certificate",
+	}
+
+	t.Run("Generated key", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, certRole)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + name,
+			Storage:   s,
+		})
+		assertRespNoError(t, resp, err)
+
+		if _, ok := resp.Data["client_secret"]; ok {
+			t.Fatalf("expected no client_secret for certificate credentials")
+		}
+		cert := assertCertificate(t, resp.Data)
+		equal(t, resp.Data["client_id"], cert.Subject.CommonName)
+
+		keyBlock, _ := pem.Decode([]byte(resp.Data["private_key"].(string)))
+		if keyBlock == nil {
+			t.Fatalf("private_key is not PEM encoded")
+		}
+		key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+		assertErrorIsNil(t, err)
+		if !key.(*rsa.PrivateKey).PublicKey.Equal(cert.PublicKey) {
+			t.Fatalf("private key does not match certificate")
+		}
+		if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+			t.Fatalf("expected self-signed certificate: %v", err)
+		}
+		if _, ok := resp.Data["issuing_ca"]; ok {
+			t.Fatalf("expected no issuing_ca for a self-signed certificate")
+		}
+
+		spID := resp.Secret.InternalData["sp_object_id"].(string)
+		if !mp.passwordlessSPs[spID] {
+			t.Fatalf("expected service principal to be created without a password")
+		}
+		if len(mp.keyCredentials) != 1 {
+			t.Fatalf("expected 1 key credential, got %d", len(mp.keyCredentials))
+		}
+	})
+
+	t.Run("CSR", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, certRole)
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		assertErrorIsNil(t, err)
+		csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: "my-workload"},
+		}, key)
+		assertErrorIsNil(t, err)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "creds/" + name,
+			Data: map[string]interface{}{
+				"csr": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})),
+			},
+			Storage: s,
+		})
+		assertRespNoError(t, resp, err)
+
+		if _, ok := resp.Data["private_key"]; ok {
+			t.Fatalf("expected no private_key when a CSR is supplied")
+		}
+		cert := assertCertificate(t, resp.Data)
+		equal(t, "my-workload", cert.Subject.CommonName)
+		if !key.PublicKey.Equal(cert.PublicKey) {
+			t.Fatalf("certificate was not issued for the CSR public key")
+		}
+
+		// The certificate is signed by the returned one-time issuer
+		assertKeyExists(t, resp.Data, "issuing_ca")
+		issuerBlock, _ := pem.Decode([]byte(resp.Data["issuing_ca"].(string)))
+		if issuerBlock == nil {
+			t.Fatalf("issuing_ca is not PEM encoded")
+		}
+		issuer, err := x509.ParseCertificate(issuerBlock.Bytes)
+		assertErrorIsNil(t, err)
+		if err := cert.CheckSignatureFrom(issuer); err != nil {
+			t.Fatalf("certificate was not signed by issuing_ca: %v", err)
+		}
+	})
+
+	t.Run("Invalid CSR", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, certRole)
+
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assertErrorIsNil(t, err)
+		ecCSR, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, ecKey)
+		assertErrorIsNil(t, err)
+
+		for _, csr := range []string{
+			"not a csr",
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: ecCSR})),
+		} {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "creds/" + name,
+				Data:      map[string]interface{}{"csr": csr},
+				Storage:   s,
+			})
+			assertErrorIsNil(t, err)
+			if !resp.IsError() {
+				t.Fatalf("expected error for csr %q", csr)
+			}
+		}
+	})
+
+	t.Run("CSR on password role", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, testRole)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "creds/" + name,
+			Data:      map[string]interface{}{"csr": "csr"},
+			Storage:   s,
+		})
+		assertErrorIsNil(t, err)
+		if !resp.IsError() || !strings.Contains(resp.Error().Error(), "client_credential_type") {
+			t.Fatalf("expected client_credential_type error, got %#v", resp)
+		}
+	})
+}
+
+func TestStaticSPRevokeCertificate(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+
+	testRoleCreate(t, b, s, "test_role", map[string]interface{}{
+		"application_object_id":  testStaticSPAppObjID,
+		"client_credential_type": "certificate",
+		"certificate_key_bits":   3072,
+		"explicit_max_ttl":       3600,
+	})
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test_role",
+		Storage:   s,
+	})
+	assertRespNoError(t, resp, err)
+
+	cert := assertCertificate(t, resp.Data)
+	equal(t, 3072, cert.PublicKey.(*rsa.PublicKey).N.BitLen())
+	assertDuration(t, time.Until(cert.NotAfter), time.Hour, time.Minute)
+
+	keyID := resp.Secret.InternalData["key_id"].(string)
+	client, err := b.getClient(context.Background(), s)
+	assertErrorIsNil(t, err)
+	mp := client.provider.(*mockProvider)
+
+	if !mp.keyCredentialExists(keyID) {
+		t.Fatalf("key credential was not created")
+	}
+	if mp.passwordExists(keyID) {
+		t.Fatalf("expected no password to be created")
+	}
+
+	// Serialize and deserialize the secret to remove typing, as will really happen.
+	fakeSaveLoad(resp.Secret)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Secret:    resp.Secret,
+		Storage:   s,
+	})
+	assertErrorIsNil(t, err)
+	if resp.IsError() {
+		t.Fatalf("receive response error: %v", resp.Error())
+	}
+
+	if mp.keyCredentialExists(keyID) {
+		t.Fatalf("key credential present but should have been deleted")
+	}
+}
+
+func TestSPReadFederated(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+
+	client, err := b.getClient(context.Background(), s)
+	assertErrorIsNil(t, err)
+	mp := client.provider.(*mockProvider)
+
+	t.Run("Dynamic", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, map[string]interface{}{
+			"azure_roles":            testRole["azure_roles"],
+			"client_credential_type": "federated",
+			"federated_issuer":       "https://token.actions.githubusercontent.com",
+			"federated_subjects":     "repo:openbao/openbao:ref:refs/heads/main",
+		})
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + name,
+			Storage:   s,
+		})
+		assertRespNoError(t, resp, err)
+
+		if _, ok := resp.Data["client_secret"]; ok {
+			t.Fatalf("expected no client_secret for federated credentials")
+		}
+		equal(t, "https://token.actions.githubusercontent.com", resp.Data["issuer"])
+		equal(t, "repo:openbao/openbao:ref:refs/heads/main", resp.Data["subject"])
+		equal(t, defaultFederatedAudience, resp.Data["audience"])
+
+		spID := resp.Secret.InternalData["sp_object_id"].(string)
+		if !mp.passwordlessSPs[spID] {
+			t.Fatalf("expected service principal to be created without a password")
+		}
+		appObjID := resp.Secret.InternalData["app_object_id"].(string)
+		if len(mp.federatedCredentials[appObjID]) != 1 {
+			t.Fatalf("expected 1 federated identity credential on the application")
+		}
+	})
+
+	t.Run("Static", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, map[string]interface{}{
+			"application_object_id":  testStaticSPAppObjID,
+			"client_credential_type": "federated",
+			"federated_issuer":       "https://oidc.example.com",
+			"federated_subjects":     "system:serviceaccount:payments:*",
+			"federated_audiences":    "api://AzureADTokenExchange,api://payments",
+		})
+
+		for subject, ok := range map[string]bool{
+			"system:serviceaccount:payments:api": true,
+			"system:serviceaccount:other:api":    false,
+			"":                                   false,
+		} {
+			data := map[string]interface{}{
+				"audience": "api://payments",
+			}
+			if subject != "" {
+				data["subject"] = subject
+			}
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "creds/" + name,
+				Data:      data,
+				Storage:   s,
+			})
+			assertErrorIsNil(t, err)
+			if resp.IsError() == ok {
+				t.Fatalf("subject %q: expected success %t, got %#v", subject, ok, resp)
+			}
+			if !ok {
+				continue
+			}
+
+			keyID := resp.Secret.InternalData["key_id"].(string)
+			cred, exists := mp.federatedCredential(testStaticSPAppObjID, keyID)
+			if !exists {
+				t.Fatalf("federated identity credential was not created")
+			}
+			equal(t, "https://oidc.example.com", cred.Issuer)
+			equal(t, subject, cred.Subject)
+			equal(t, []string{"api://payments"}, cred.Audiences)
+
+			// the same issuer and subject can't be leased twice on an app
+			_, err = b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "creds/" + name,
+				Data:      data,
+				Storage:   s,
+			})
+			if err == nil || !strings.Contains(err.Error(), "same issuer and subject") {
+				t.Fatalf("expected duplicate credential error, got %v", err)
+			}
+
+			// Serialize and deserialize the secret to remove typing, as will really happen.
+			fakeSaveLoad(resp.Secret)
+
+			resp, err = b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.RevokeOperation,
+				Secret:    resp.Secret,
+				Storage:   s,
+			})
+			assertErrorIsNil(t, err)
+			if resp.IsError() {
+				t.Fatalf("receive response error: %v", resp.Error())
+			}
+			if _, exists := mp.federatedCredential(testStaticSPAppObjID, keyID); exists {
+				t.Fatalf("federated identity credential present but should have been deleted")
+			}
+		}
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "creds/" + name,
+			Data: map[string]interface{}{
+				"subject":  "system:serviceaccount:payments:api",
+				"audience": "api://other",
+			},
+			Storage: s,
+		})
+		assertErrorIsNil(t, err)
+		if !resp.IsError() {
+			t.Fatalf("expected error for audience not allowed by the role")
+		}
+	})
+
+	t.Run("Subject on password role", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, testRole)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "creds/" + name,
+			Data:      map[string]interface{}{"subject": "repo:openbao/openbao:ref:refs/heads/main"},
+			Storage:   s,
+		})
+		assertErrorIsNil(t, err)
+		if !resp.IsError() {
+			t.Fatalf("expected error for subject on password role")
+		}
+	})
+}
+
+func TestSPReadAPIPermissions(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+
+	client, err := b.getClient(context.Background(), s)
+	assertErrorIsNil(t, err)
+	mp := client.provider.(*mockProvider)
+
+	t.Run("Grant and revoke", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, map[string]interface{}{
+			"api_permissions": encodeJSON([]APIPermission{
+				{ResourceAppID: testGraphAppID, Permission: "User.Read.All"},
+				{ResourceAppID: testGraphAppID, AppRoleID: "5b567255-7703-4780-807c-7be8301ae99b"},
+			}),
+		})
+
+		role, err := getRole(context.Background(), name, s)
+		assertErrorIsNil(t, err)
+		equal(t, "5b567255-7703-4780-807c-7be8301ae99b", role.APIPermissions[1].AppRoleID)
+		equal(t, "Group.Read.All", role.APIPermissions[1].Permission)
+		equal(t, testGraphSPObjectID, role.APIPermissions[1].ResourceObjectID)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + name,
+			Storage:   s,
+		})
+		assertRespNoError(t, resp, err)
+
+		spID := resp.Secret.InternalData["sp_object_id"].(string)
+		assignments, err := mp.ListAppRoleAssignments(context.Background(), spID)
+		assertErrorIsNil(t, err)
+		equal(t, 2, len(assignments))
+
+		// The WAL protecting the grants is removed once they complete
+		walIDs, err := framework.ListWAL(context.Background(), s)
+		assertErrorIsNil(t, err)
+		equal(t, 0, len(walIDs))
+
+		fakeSaveLoad(resp.Secret)
+		_, err = b.spRevoke(context.Background(), &logical.Request{
+			Secret:  resp.Secret,
+			Storage: s,
+		}, nil)
+		assertErrorIsNil(t, err)
+
+		assignments, err = mp.ListAppRoleAssignments(context.Background(), spID)
+		assertErrorIsNil(t, err)
+		equal(t, 0, len(assignments))
+	})
+
+	t.Run("WAL rollback", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, map[string]interface{}{
+			"azure_roles": testRole["azure_roles"],
+			"api_permissions": encodeJSON([]APIPermission{
+				{ResourceAppID: testGraphAppID, Permission: "User.Read.All"},
+			}),
+		})
+		role, err := getRole(context.Background(), name, s)
+		assertErrorIsNil(t, err)
+
+		spID, err := mp.CreateServicePrincipalWithoutPassword(context.Background(), generateUUID())
+		assertErrorIsNil(t, err)
+		_, err = client.assignAPIPermissions(context.Background(), spID, role.APIPermissions)
+		assertErrorIsNil(t, err)
+
+		// An assignment not granted by the role is left alone
+		_, err = mp.AddAppRoleAssignment(context.Background(), spID, testGraphSPObjectID, testGraphAppRoles[1].ID)
+		assertErrorIsNil(t, err)
+
+		walID, err := framework.PutWAL(context.Background(), s, walAPIPermissions, &walAPIPermissionAssign{
+			SpID:           spID,
+			APIPermissions: role.APIPermissions,
+			Expiration:     time.Now().Add(maxWALAge),
+		})
+		assertErrorIsNil(t, err)
+
+		entry, err := framework.GetWAL(context.Background(), s, walID)
+		assertErrorIsNil(t, err)
+		err = b.walRollback(context.Background(), &logical.Request{Storage: s}, entry.Kind, entry.Data)
+		assertErrorIsNil(t, err)
+
+		assignments, err := mp.ListAppRoleAssignments(context.Background(), spID)
+		assertErrorIsNil(t, err)
+		equal(t, 1, len(assignments))
+		equal(t, testGraphAppRoles[1].ID, assignments[0].AppRoleID)
+	})
+
+	t.Run("Invalid permissions", func(t *testing.T) {
+		for permissions, expected := range map[string]string{
+			encodeJSON([]APIPermission{{ResourceAppID: testGraphAppID, Permission: "Mail.Send.Everywhere"}}):       "no permission found",
+			encodeJSON([]APIPermission{{ResourceAppID: testGraphAppID, Permission: "Directory.AccessAsUser.All"}}): "cannot be granted to applications",
+			encodeJSON([]APIPermission{{ResourceAppID: generateUUID(), Permission: "User.Read.All"}}):              "no service principal found",
+			encodeJSON([]APIPermission{{Permission: "User.Read.All"}}):                                             "resource_app_id is required",
+			encodeJSON([]APIPermission{
+				{ResourceAppID: testGraphAppID, Permission: "User.Read.All"},
+				{ResourceAppID: testGraphAppID, AppRoleID: "df021288-bdef-4463-88db-98f22de89214"},
+			}): "duplicate API permission",
+		} {
+			resp := testRoleCreateBasic(t, b, s, generateUUID(), map[string]interface{}{
+				"api_permissions": permissions,
+			})
+			if !resp.IsError() || !strings.Contains(resp.Error().Error(), expected) {
+				t.Fatalf("expected error %q for %s, got %#v", expected, permissions, resp)
+			}
+		}
+
+		resp := testRoleCreateBasic(t, b, s, generateUUID(), map[string]interface{}{
+			"application_object_id": testStaticSPAppObjID,
+			"api_permissions":       encodeJSON([]APIPermission{{ResourceAppID: testGraphAppID, Permission: "User.Read.All"}}),
+		})
+		if !resp.IsError() {
+			t.Fatalf("expected error for api_permissions on a static role")
+		}
+	})
+}
+
+func TestSPReadScope(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	const sub = "/subscriptions/ce7d1612-67c1-4dc6-8d81-4e0a432e696b"
+
+	testRoleCreate(t, b, s, "scoped", map[string]interface{}{
+		"azure_roles": encodeJSON([]AzureRole{
+			{RoleName: "Owner"},
+			{RoleName: "Contributor"},
+		}),
+		"allowed_scope_patterns": sub + "/resourceGroups/team-*",
+	})
+
+	resp, err := testStaticRequest(b, s, logical.ReadOperation, "roles/scoped", nil)
+	assertRespNoError(t, resp, err)
+	equal(t, []string{sub + "/resourceGroups/team-*"}, resp.Data["allowed_scope_patterns"])
+
+	t.Run("requested scope", func(t *testing.T) {
+		scope := sub + "/resourceGroups/team-a"
+
+		resp, err := testStaticRequest(b, s, logical.UpdateOperation, "creds/scoped", map[string]interface{}{"scope": scope})
+		assertRespNoError(t, resp, err)
+		equal(t, scope, resp.Secret.InternalData["scope"])
+
+		raIDs := resp.Secret.InternalData["role_assignment_ids"].([]string)
+		equal(t, 2, len(raIDs))
+
+		var scopes []string
+		for _, raScope := range mp.roleAssignmentScopes {
+			scopes = append(scopes, raScope)
+		}
+		equal(t, []string{scope, scope}, scopes)
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		for _, scope := range []string{
+			"",
+			sub + "/resourceGroups/other",
+			sub + "/resourceGroups/team-*",
+			sub + "/resourceGroups/team-a/../other",
+			"/subscriptions/1a0e2d5c-5a8b-4f6e-9c3d-7b2a1f0e9d8c/resourceGroups/team-a",
+		} {
+			resp, err := testStaticRequest(b, s, logical.UpdateOperation, "creds/scoped", map[string]interface{}{"scope": scope})
+			assertErrorIsNil(t, err)
+			if !resp.IsError() {
+				t.Fatalf("expected scope %q to be rejected", scope)
+			}
+		}
+	})
+
+	t.Run("scope required", func(t *testing.T) {
+		resp, err := testStaticRequest(b, s, logical.ReadOperation, "creds/scoped", nil)
+		assertErrorIsNil(t, err)
+		if !resp.IsError() || !strings.Contains(resp.Error().Error(), "scope is required") {
+			t.Fatalf("expected error, got %#v", resp)
+		}
+	})
+
+	t.Run("scope not allowed", func(t *testing.T) {
+		testRoleCreate(t, b, s, "fixed", testRole)
+
+		resp, err := testStaticRequest(b, s, logical.UpdateOperation, "creds/fixed", map[string]interface{}{"scope": sub})
+		assertErrorIsNil(t, err)
+		if !resp.IsError() || !strings.Contains(resp.Error().Error(), "allowed_scope_patterns") {
+			t.Fatalf("expected error, got %#v", resp)
+		}
+	})
+
+	t.Run("role requirements", func(t *testing.T) {
+		for name, data := range map[string]map[string]interface{}{
+			"no subscription": {
+				"azure_roles":            testRole["azure_roles"],
+				"allowed_scope_patterns": "/subscriptions/*",
+			},
+			"not a scope": {
+				"azure_roles":            testRole["azure_roles"],
+				"allowed_scope_patterns": "resourceGroups/team-*",
+			},
+			"no azure roles": {
+				"azure_groups":           `[{"group_name": "ops"}]`,
+				"allowed_scope_patterns": sub + "/*",
+			},
+			"persist app": {
+				"azure_roles":            testRole["azure_roles"],
+				"persist_app":            true,
+				"allowed_scope_patterns": sub + "/*",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				resp := testRoleCreateBasic(t, b, s, "bad", data)
+				if !resp.IsError() {
+					t.Fatalf("expected error, got %#v", resp)
+				}
+			})
+		}
+	})
+}
+
+func TestSPReadEntraRoles(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+
+	client, err := b.getClient(context.Background(), s)
+	assertErrorIsNil(t, err)
+	mp := client.provider.(*mockProvider)
+
+	const auID = "4a6e9c1f-bd2d-4f5a-9f0b-0f8c7a3c0d11"
+
+	t.Run("Assign and revoke", func(t *testing.T) {
+		name := generateUUID()
+		testRoleCreate(t, b, s, name, map[string]interface{}{
+			"entra_roles": encodeJSON([]EntraRole{
+				{RoleName: "User Administrator"},
+				{TemplateID: "fdd7a751-b60b-444a-984c-02652fe8fa1c", AdministrativeUnitID: auID},
+			}),
+		})
+
+		role, err := getRole(context.Background(), name, s)
+		assertErrorIsNil(t, err)
+		equal(t, "fe930be7-5e62-47db-91af-98c3a49a38b1", role.EntraRoles[0].TemplateID)
+		equal(t, "Groups Administrator", role.EntraRoles[1].RoleName)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + name,
+			Storage:   s,
+		})
+		assertRespNoError(t, resp, err)
+
+		spID := resp.Secret.InternalData["sp_object_id"].(string)
+		scopes := map[string]string{}
+		for _, a := range mp.directoryRoleAssignments {
+			if a.principalID == spID {
+				scopes[a.roleDefinitionID] = a.directoryScopeID
+			}
+		}
+		equal(t, map[string]string{
+			"fe930be7-5e62-47db-91af-98c3a49a38b1": "/",
+			"fdd7a751-b60b-444a-984c-02652fe8fa1c": "/administrativeUnits/" + auID,
+		}, scopes)
+
+		fakeSaveLoad(resp.Secret)
+		_, err = b.spRevoke(context.Background(), &logical.Request{
+			Secret:  resp.Secret,
+			Storage: s,
+		}, nil)
+		assertErrorIsNil(t, err)
+
+		for _, a := range mp.directoryRoleAssignments {
+			if a.principalID == spID {
+				t.Fatalf("expected Entra roles of %s to be removed", spID)
+			}
+		}
+	})
+
+	t.Run("Invalid roles", func(t *testing.T) {
+		for roles, expected := range map[string]string{
+			encodeJSON([]EntraRole{{RoleName: "Global Janitor"}}):                                         "no Entra role found",
+			encodeJSON([]EntraRole{{RoleName: "User Administrator", AdministrativeUnitID: "not-a-uuid"}}): "invalid administrative_unit_id",
+			encodeJSON([]EntraRole{
+				{RoleName: "User Administrator"},
+				{TemplateID: "fe930be7-5e62-47db-91af-98c3a49a38b1"},
+			}): "duplicate Entra role",
+		} {
+			resp := testRoleCreateBasic(t, b, s, generateUUID(), map[string]interface{}{
+				"entra_roles": roles,
+			})
+			if !resp.IsError() || !strings.Contains(resp.Error().Error(), expected) {
+				t.Fatalf("expected error %q for %s, got %#v", expected, roles, resp)
+			}
+		}
+
+		resp := testRoleCreateBasic(t, b, s, generateUUID(), map[string]interface{}{
+			"application_object_id": testStaticSPAppObjID,
+			"entra_roles":           encodeJSON([]EntraRole{{RoleName: "User Administrator"}}),
+		})
+		if !resp.IsError() {
+			t.Fatalf("expected error for entra_roles on a static role")
+		}
+	})
+}
+
 func TestSPReadMissingRole(t *testing.T) {
 	b, s := getTestBackendMocked(t, true)
 
@@ -1163,6 +1807,21 @@ func assertClientSecret(tb testing.TB, data map[string]interface{}) {
 	}
 }
 
+func assertCertificate(tb testing.TB, data map[string]interface{}) *x509.Certificate {
+	tb.Helper()
+	assertKeyExists(tb, data, "certificate")
+	block, _ := pem.Decode([]byte(data["certificate"].(string)))
+	if block == nil {
+		tb.Fatalf("certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assertErrorIsNil(tb, err)
+
+	thumbprint := sha1.Sum(block.Bytes)
+	equal(tb, strings.ToUpper(hex.EncodeToString(thumbprint[:])), data["certificate_thumbprint"])
+	return cert
+}
+
 func findServicePrincipalID(t *testing.T, client api.ServicePrincipalClient, appID string) (spID string) {
 	t.Helper()
 
@@ -0,0 +1,193 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const staticCredsStoragePath = "static-creds"
+
+// staticCredentialEntry is the password of a static role. The previous
+// password is kept until the rotation overlap has passed.
+type staticCredentialEntry struct {
+	ClientSecret       string    `json:"client_secret"`
+	KeyID              string    `json:"key_id"`
+	KeyEndDate         time.Time `json:"key_end_date"`
+	LastRotated        time.Time `json:"last_rotated"`
+	PreviousKeyID      string    `json:"previous_key_id"`
+	PreviousKeyRemoval time.Time `json:"previous_key_removal"`
+}
+
+// nextAction returns the time at which the password of the role is rotated
+// or the previous password is removed, whichever is first.
+func (c *staticCredentialEntry) nextAction(role *staticRoleEntry) time.Time {
+	next := c.LastRotated.Add(role.RotationPeriod)
+	if c.PreviousKeyID != "" && c.PreviousKeyRemoval.Before(next) {
+		return c.PreviousKeyRemoval
+	}
+	return next
+}
+
+func pathStaticCreds(b *azureSecretBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: staticCredsStoragePath + "/" + framework.GenericNameRegex("name"),
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAzure,
+			OperationVerb:   "request",
+			OperationSuffix: "static-role-credentials",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the static role.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathStaticCredsRead,
+			},
+		},
+		HelpSynopsis:    staticCredsHelpSyn,
+		HelpDescription: staticCredsHelpDesc,
+	}
+}
+
+func pathRotateStaticRole(b *azureSecretBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rotate-static-role/" + framework.GenericNameRegex("name"),
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAzure,
+			OperationVerb:   "rotate",
+			OperationSuffix: "static-role",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the static role.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                    b.pathRotateStaticRole,
+				ForwardPerformanceSecondary: true,
+				ForwardPerformanceStandby:   true,
+			},
+		},
+		HelpSynopsis:    rotateStaticRoleHelpSyn,
+		HelpDescription: rotateStaticRoleHelpDesc,
+	}
+}
+
+func (b *azureSecretBackend) pathStaticCredsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.staticRolesLock.RLock()
+	defer b.staticRolesLock.RUnlock()
+
+	role, err := getStaticRole(ctx, name, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error reading static role: %w", err)
+	}
+	if role == nil {
+		return logical.ErrorResponse("static role '%s' does not exist", name), nil
+	}
+
+	creds, err := getStaticCredential(ctx, name, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil {
+		return logical.ErrorResponse("static role '%s' has no credentials yet", name), nil
+	}
+
+	nextRotation := creds.LastRotated.Add(role.RotationPeriod)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"client_id":     role.ApplicationID,
+			"client_secret": creds.ClientSecret,
+			"last_rotated":  creds.LastRotated.Format(time.RFC3339),
+			"ttl":           int64(time.Until(nextRotation).Round(time.Second).Seconds()),
+		},
+	}, nil
+}
+
+func (b *azureSecretBackend) pathRotateStaticRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.staticRolesLock.Lock()
+	defer b.staticRolesLock.Unlock()
+
+	role, err := getStaticRole(ctx, name, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error reading static role: %w", err)
+	}
+	if role == nil {
+		return logical.ErrorResponse("static role '%s' does not exist", name), nil
+	}
+
+	creds, err := getStaticCredential(ctx, name, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err = b.rotateStaticRole(ctx, req.Storage, role, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := b.staticRotationQueue.PopByKey(name); err != nil {
+		return nil, err
+	}
+	if err := b.pushStaticRole(role, creds); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func saveStaticCredential(ctx context.Context, s logical.Storage, name string, creds *staticCredentialEntry) error {
+	entry, err := logical.StorageEntryJSON(staticCredsStoragePath+"/"+name, creds)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+func getStaticCredential(ctx context.Context, name string, s logical.Storage) (*staticCredentialEntry, error) {
+	entry, err := s.Get(ctx, staticCredsStoragePath+"/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading static credentials: %w", err)
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	creds := new(staticCredentialEntry)
+	if err := entry.DecodeJSON(creds); err != nil {
+		return nil, fmt.Errorf("error decoding static credentials: %w", err)
+	}
+	return creds, nil
+}
+
+const staticCredsHelpSyn = `Read the current password of a static role.`
+const staticCredsHelpDesc = `
+This path reads the current password of the Application managed by a static
+role. The same password is returned until it is rotated. The ttl is the time
+until the next scheduled rotation.
+`
+
+const rotateStaticRoleHelpSyn = `Rotate the password of a static role.`
+const rotateStaticRoleHelpDesc = `
+This path rotates the password of the Application managed by a static role
+immediately and reschedules the next rotation. The previous password remains
+valid for the rotation_overlap of the role.
+`
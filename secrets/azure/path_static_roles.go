@@ -0,0 +1,376 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/sdk/v2/queue"
+)
+
+const (
+	staticRolesStoragePath = "static-roles"
+
+	// minStaticRotationPeriod is the shortest allowed rotation period. Azure
+	// replicates new passwords with a delay, so shorter periods are unlikely
+	// to leave consumers with a usable password.
+	minStaticRotationPeriod = time.Minute
+
+	defaultStaticRotationOverlap = 5 * time.Minute
+
+	// staticPasswordGracePeriod is added to the lifetime of static role
+	// passwords in Azure, so that passwords remain valid if a rotation is
+	// delayed.
+	staticPasswordGracePeriod = 24 * time.Hour
+)
+
+// staticRoleEntry is a role that manages a single password of an existing
+// Application, rotated on a schedule.
+type staticRoleEntry struct {
+	Name                string        `json:"name"`
+	ApplicationObjectID string        `json:"application_object_id"`
+	ApplicationID       string        `json:"application_id"`
+	RotationPeriod      time.Duration `json:"rotation_period"`
+	RotationOverlap     time.Duration `json:"rotation_overlap"`
+}
+
+// passwordLifetime is the lifetime of the passwords of the role in Azure.
+func (r *staticRoleEntry) passwordLifetime() time.Duration {
+	return r.RotationPeriod + r.RotationOverlap + staticPasswordGracePeriod
+}
+
+func pathsStaticRole(b *azureSecretBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: staticRolesStoragePath + "/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixAzure,
+				OperationSuffix: "static-role",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeLowerCaseString,
+					Description: "Name of the static role.",
+				},
+				"application_object_id": {
+					Type:        framework.TypeString,
+					Description: "Object ID of the existing Application whose password is managed by the role. Cannot be changed after the role is created.",
+				},
+				"rotation_period": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Period after which the password of the Application is rotated. Must be at least one minute.",
+				},
+				"rotation_overlap": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Time the previous password remains valid after a rotation, so that consumers can reload the new password. Must be less than rotation_period. Defaults to 5 minutes.",
+					Default:     int(defaultStaticRotationOverlap.Seconds()),
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathStaticRoleRead,
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback:                    b.pathStaticRoleWrite,
+					ForwardPerformanceSecondary: true,
+					ForwardPerformanceStandby:   true,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathStaticRoleWrite,
+					ForwardPerformanceSecondary: true,
+					ForwardPerformanceStandby:   true,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback:                    b.pathStaticRoleDelete,
+					ForwardPerformanceSecondary: true,
+					ForwardPerformanceStandby:   true,
+				},
+			},
+			ExistenceCheck:  b.pathStaticRoleExistenceCheck,
+			HelpSynopsis:    staticRoleHelpSyn,
+			HelpDescription: staticRoleHelpDesc,
+		},
+		{
+			Pattern: staticRolesStoragePath + "/?",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixAzure,
+				OperationSuffix: "static-roles",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathStaticRoleList,
+				},
+			},
+			HelpSynopsis:    staticRoleListHelpSyn,
+			HelpDescription: staticRoleListHelpDesc,
+		},
+	}
+}
+
+func (b *azureSecretBackend) pathStaticRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.staticRolesLock.Lock()
+	defer b.staticRolesLock.Unlock()
+
+	role, err := getStaticRole(ctx, name, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error reading static role: %w", err)
+	}
+
+	isCreate := role == nil
+	if isCreate {
+		role = &staticRoleEntry{
+			Name: name,
+		}
+	}
+
+	if appObjectID, ok := d.GetOk("application_object_id"); ok {
+		if !isCreate && appObjectID.(string) != role.ApplicationObjectID {
+			return logical.ErrorResponse("application_object_id cannot be changed after the static role is created"), nil
+		}
+		role.ApplicationObjectID = appObjectID.(string)
+	}
+	if role.ApplicationObjectID == "" {
+		return logical.ErrorResponse("missing application_object_id"), nil
+	}
+
+	if rotationPeriod, ok := d.GetOk("rotation_period"); ok {
+		role.RotationPeriod = time.Duration(rotationPeriod.(int)) * time.Second
+	} else if isCreate {
+		return logical.ErrorResponse("missing rotation_period"), nil
+	}
+	if role.RotationPeriod < minStaticRotationPeriod {
+		return logical.ErrorResponse("rotation_period must be at least %d seconds", int(minStaticRotationPeriod.Seconds())), nil
+	}
+
+	if rotationOverlap, ok := d.GetOk("rotation_overlap"); ok {
+		role.RotationOverlap = time.Duration(rotationOverlap.(int)) * time.Second
+	} else if isCreate {
+		role.RotationOverlap = time.Duration(d.Get("rotation_overlap").(int)) * time.Second
+	}
+	if role.RotationOverlap < 0 || role.RotationOverlap >= role.RotationPeriod {
+		return logical.ErrorResponse("rotation_overlap must be at least 0 and less than rotation_period"), nil
+	}
+
+	if isCreate {
+		client, err := b.getClient(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		app, err := client.provider.GetApplication(ctx, role.ApplicationObjectID)
+		if err != nil {
+			return nil, fmt.Errorf("error loading Application: %w", err)
+		}
+		role.ApplicationID = app.AppID
+	}
+
+	creds, err := getStaticCredential(ctx, role.Name, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bootstrap the initial password before the role is stored, so that no
+	// role is stored without a password. Passwords can only be read when
+	// they are created, so the role must own the password it serves.
+	bootstrapped := creds == nil
+	if bootstrapped {
+		creds, err = b.rotateStaticRole(ctx, req.Storage, role, creds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create password for static role %q: %w", role.Name, err)
+		}
+	}
+
+	if err := saveStaticRole(ctx, req.Storage, role); err != nil {
+		if bootstrapped {
+			if rmErr := b.removeStaticCredential(ctx, req.Storage, role, creds); rmErr != nil {
+				err = multierror.Append(err, rmErr)
+			}
+		}
+		return nil, fmt.Errorf("error storing static role: %w", err)
+	}
+
+	// Reschedule the role, as the rotation period may have changed
+	if _, err := b.staticRotationQueue.PopByKey(role.Name); err != nil {
+		return nil, err
+	}
+	if err := b.pushStaticRole(role, creds); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// removeStaticCredential removes the bootstrapped password of a static role
+// that could not be stored, along with its credentials entry.
+func (b *azureSecretBackend) removeStaticCredential(ctx context.Context, s logical.Storage, role *staticRoleEntry, creds *staticCredentialEntry) error {
+	c, err := b.getClient(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := b.deleteStaticPasswords(ctx, c, role, creds.KeyID); err != nil {
+		return err
+	}
+	return s.Delete(ctx, staticCredsStoragePath+"/"+role.Name)
+}
+
+func (b *azureSecretBackend) pathStaticRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.staticRolesLock.RLock()
+	defer b.staticRolesLock.RUnlock()
+
+	role, err := getStaticRole(ctx, d.Get("name").(string), req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error reading static role: %w", err)
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"application_object_id": role.ApplicationObjectID,
+		"application_id":        role.ApplicationID,
+		"rotation_period":       int64(role.RotationPeriod.Seconds()),
+		"rotation_overlap":      int64(role.RotationOverlap.Seconds()),
+	}
+
+	creds, err := getStaticCredential(ctx, role.Name, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		data["last_rotated"] = creds.LastRotated.Format(time.RFC3339)
+		data["next_rotation"] = creds.LastRotated.Add(role.RotationPeriod).Format(time.RFC3339)
+	}
+
+	return &logical.Response{Data: data}, nil
+}
+
+func (b *azureSecretBackend) pathStaticRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(ctx, staticRolesStoragePath+"/")
+	if err != nil {
+		return nil, fmt.Errorf("error listing static roles: %w", err)
+	}
+
+	return logical.ListResponse(roles), nil
+}
+
+func (b *azureSecretBackend) pathStaticRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.staticRolesLock.Lock()
+	defer b.staticRolesLock.Unlock()
+
+	role, err := getStaticRole(ctx, name, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error reading static role: %w", err)
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	creds, err := getStaticCredential(ctx, name, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the passwords owned by the role from the Application
+	if creds != nil {
+		c, err := b.getClient(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := b.deleteStaticPasswords(ctx, c, role, creds.PreviousKeyID, creds.KeyID); err != nil {
+			return nil, fmt.Errorf("failed to remove passwords of static role %q: %w", name, err)
+		}
+
+		if err := req.Storage.Delete(ctx, staticCredsStoragePath+"/"+name); err != nil {
+			return nil, fmt.Errorf("error deleting static credentials: %w", err)
+		}
+	}
+
+	if _, err := b.staticRotationQueue.PopByKey(name); err != nil {
+		return nil, err
+	}
+
+	if err := req.Storage.Delete(ctx, staticRolesStoragePath+"/"+name); err != nil {
+		return nil, fmt.Errorf("error deleting static role: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (b *azureSecretBackend) pathStaticRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := getStaticRole(ctx, d.Get("name").(string), req.Storage)
+	if err != nil {
+		return false, err
+	}
+
+	return role != nil, nil
+}
+
+// pushStaticRole schedules the next rotation or password removal of the role.
+func (b *azureSecretBackend) pushStaticRole(role *staticRoleEntry, creds *staticCredentialEntry) error {
+	// A role without credentials failed to bootstrap, so it is retried
+	// immediately
+	next := time.Now()
+	if creds != nil {
+		next = creds.nextAction(role)
+	}
+
+	err := b.staticRotationQueue.Push(&queue.Item{
+		Key:      role.Name,
+		Value:    role.Name,
+		Priority: next.Unix(),
+	})
+	if err != nil && !errors.Is(err, queue.ErrDuplicateItem) {
+		return fmt.Errorf("failed to add static role %q to the rotation queue: %w", role.Name, err)
+	}
+
+	return nil
+}
+
+func saveStaticRole(ctx context.Context, s logical.Storage, role *staticRoleEntry) error {
+	entry, err := logical.StorageEntryJSON(staticRolesStoragePath+"/"+role.Name, role)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+func getStaticRole(ctx context.Context, name string, s logical.Storage) (*staticRoleEntry, error) {
+	entry, err := s.Get(ctx, staticRolesStoragePath+"/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	role := new(staticRoleEntry)
+	if err := entry.DecodeJSON(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+const staticRoleHelpSyn = "Manage static roles that rotate the password of an existing Application."
+const staticRoleHelpDesc = `
+This path lets you manage static roles. A static role owns a single password
+of an existing Application, which is rotated every rotation_period. After a
+rotation, the previous password remains valid for rotation_overlap so that
+consumers can reload the new password. The current password is read from the
+static-creds endpoint.
+`
+const staticRoleListHelpSyn = `List existing static roles.`
+const staticRoleListHelpDesc = `List existing static roles by name.`
@@ -0,0 +1,388 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestStaticRoleCreate(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	app, err := mp.CreateApplication(context.Background(), "test", "", nil)
+	assertErrorIsNil(t, err)
+
+	testStaticRoleWrite(t, b, s, "test", map[string]interface{}{
+		"application_object_id": app.AppObjectID,
+		"rotation_period":       3600,
+	})
+
+	resp, err := testStaticRequest(b, s, logical.ReadOperation, "static-roles/test", nil)
+	assertErrorIsNil(t, err)
+	equal(t, app.AppObjectID, resp.Data["application_object_id"])
+	equal(t, app.AppID, resp.Data["application_id"])
+	equal(t, int64(3600), resp.Data["rotation_period"])
+	equal(t, int64(defaultStaticRotationOverlap.Seconds()), resp.Data["rotation_overlap"])
+	assertKeyExists(t, resp.Data, "last_rotated")
+	assertKeyExists(t, resp.Data, "next_rotation")
+
+	// The initial password is created with the role
+	resp, err = testStaticRequest(b, s, logical.ReadOperation, "static-creds/test", nil)
+	assertErrorIsNil(t, err)
+	equal(t, app.AppID, resp.Data["client_id"])
+	assertNotEmptyString(t, resp.Data["client_secret"].(string))
+
+	creds, err := getStaticCredential(context.Background(), "test", s)
+	assertErrorIsNil(t, err)
+	if !mp.passwordExists(creds.KeyID) {
+		t.Fatalf("password %q of static role does not exist", creds.KeyID)
+	}
+
+	// Updating the role keeps the password
+	testStaticRoleWrite(t, b, s, "test", map[string]interface{}{
+		"rotation_period":  7200,
+		"rotation_overlap": 0,
+	})
+	updated, err := getStaticCredential(context.Background(), "test", s)
+	assertErrorIsNil(t, err)
+	equal(t, creds.KeyID, updated.KeyID)
+
+	resp, err = testStaticRequest(b, s, logical.ListOperation, "static-roles/", nil)
+	assertErrorIsNil(t, err)
+	equal(t, []string{"test"}, resp.Data["keys"])
+}
+
+func TestStaticRoleCreateBad(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	app, err := mp.CreateApplication(context.Background(), "test", "", nil)
+	assertErrorIsNil(t, err)
+
+	tests := map[string]struct {
+		data map[string]interface{}
+		err  string
+	}{
+		"missing application": {
+			data: map[string]interface{}{"rotation_period": 3600},
+			err:  "missing application_object_id",
+		},
+		"missing rotation period": {
+			data: map[string]interface{}{"application_object_id": app.AppObjectID},
+			err:  "missing rotation_period",
+		},
+		"short rotation period": {
+			data: map[string]interface{}{"application_object_id": app.AppObjectID, "rotation_period": 59},
+			err:  "rotation_period must be at least 60 seconds",
+		},
+		"overlap exceeds period": {
+			data: map[string]interface{}{"application_object_id": app.AppObjectID, "rotation_period": 600, "rotation_overlap": 600},
+			err:  "rotation_overlap must be at least 0 and less than rotation_period",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := testStaticRequest(b, s, logical.CreateOperation, "static-roles/test", tc.data)
+			assertErrorIsNil(t, err)
+			if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), tc.err) {
+				t.Fatalf("expected error %q, got %#v", tc.err, resp)
+			}
+		})
+	}
+
+	// The application cannot be changed
+	testStaticRoleWrite(t, b, s, "test", map[string]interface{}{
+		"application_object_id": app.AppObjectID,
+		"rotation_period":       3600,
+	})
+	resp, err := testStaticRequest(b, s, logical.UpdateOperation, "static-roles/test", map[string]interface{}{
+		"application_object_id": generateUUID(),
+	})
+	assertErrorIsNil(t, err)
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error changing application_object_id, got %#v", resp)
+	}
+}
+
+func TestStaticRoleRotation(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	app, err := mp.CreateApplication(context.Background(), "test", "", nil)
+	assertErrorIsNil(t, err)
+
+	testStaticRoleWrite(t, b, s, "test", map[string]interface{}{
+		"application_object_id": app.AppObjectID,
+		"rotation_period":       3600,
+		"rotation_overlap":      300,
+	})
+
+	initial, err := getStaticCredential(context.Background(), "test", s)
+	assertErrorIsNil(t, err)
+
+	t.Run("not due", func(t *testing.T) {
+		assertErrorIsNil(t, b.periodicFunc(context.Background(), &logical.Request{Storage: s}))
+
+		creds, err := getStaticCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+		equal(t, initial.KeyID, creds.KeyID)
+	})
+
+	t.Run("due", func(t *testing.T) {
+		testStaticRoleBackdate(t, b, s, "test", time.Hour)
+		assertErrorIsNil(t, b.periodicFunc(context.Background(), &logical.Request{Storage: s}))
+
+		creds, err := getStaticCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+		if creds.KeyID == initial.KeyID {
+			t.Fatal("expected password to be rotated")
+		}
+		equal(t, initial.KeyID, creds.PreviousKeyID)
+
+		// The previous password remains valid during the overlap
+		if !mp.passwordExists(initial.KeyID) || !mp.passwordExists(creds.KeyID) {
+			t.Fatal("expected both passwords to exist during the overlap")
+		}
+	})
+
+	t.Run("overlap passed", func(t *testing.T) {
+		creds, err := getStaticCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+		creds.PreviousKeyRemoval = time.Now().Add(-time.Second)
+		assertErrorIsNil(t, saveStaticCredential(context.Background(), s, "test", creds))
+		testStaticRoleReschedule(t, b, s, "test")
+
+		assertErrorIsNil(t, b.periodicFunc(context.Background(), &logical.Request{Storage: s}))
+
+		if mp.passwordExists(initial.KeyID) {
+			t.Fatal("expected previous password to be removed")
+		}
+		if !mp.passwordExists(creds.KeyID) {
+			t.Fatal("expected current password to exist")
+		}
+
+		creds, err = getStaticCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+		equal(t, "", creds.PreviousKeyID)
+	})
+
+	t.Run("manual", func(t *testing.T) {
+		before, err := getStaticCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+
+		resp, err := testStaticRequest(b, s, logical.UpdateOperation, "rotate-static-role/test", nil)
+		assertErrorIsNil(t, err)
+		if resp != nil && resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+
+		creds, err := getStaticCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+		if creds.KeyID == before.KeyID {
+			t.Fatal("expected password to be rotated")
+		}
+		equal(t, before.KeyID, creds.PreviousKeyID)
+	})
+}
+
+func TestStaticRoleRotationNoOverlap(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	app, err := mp.CreateApplication(context.Background(), "test", "", nil)
+	assertErrorIsNil(t, err)
+
+	testStaticRoleWrite(t, b, s, "test", map[string]interface{}{
+		"application_object_id": app.AppObjectID,
+		"rotation_period":       3600,
+		"rotation_overlap":      0,
+	})
+
+	initial, err := getStaticCredential(context.Background(), "test", s)
+	assertErrorIsNil(t, err)
+
+	testStaticRoleBackdate(t, b, s, "test", time.Hour)
+	assertErrorIsNil(t, b.periodicFunc(context.Background(), &logical.Request{Storage: s}))
+
+	creds, err := getStaticCredential(context.Background(), "test", s)
+	assertErrorIsNil(t, err)
+	if creds.KeyID == initial.KeyID {
+		t.Fatal("expected password to be rotated")
+	}
+	if mp.passwordExists(initial.KeyID) {
+		t.Fatal("expected previous password to be removed immediately")
+	}
+}
+
+func TestStaticRoleCreateFailure(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	app, err := mp.CreateApplication(context.Background(), "test", "", nil)
+	assertErrorIsNil(t, err)
+
+	data := map[string]interface{}{
+		"application_object_id": app.AppObjectID,
+		"rotation_period":       3600,
+	}
+	passwords := len(mp.passwords)
+
+	// The role is not stored if its password cannot be bootstrapped
+	_, err = testStaticRequest(b, &failingPutStorage{Storage: s, prefix: staticCredsStoragePath + "/"}, logical.CreateOperation, "static-roles/test", data)
+	if err == nil || !strings.Contains(err.Error(), "failed to create password") {
+		t.Fatalf("expected error creating password, got: %v", err)
+	}
+	role, err := getStaticRole(context.Background(), "test", s)
+	assertErrorIsNil(t, err)
+	if role != nil {
+		t.Fatal("expected static role not to be stored")
+	}
+
+	// The bootstrapped password is removed if the role cannot be stored
+	_, err = testStaticRequest(b, &failingPutStorage{Storage: s, prefix: staticRolesStoragePath + "/"}, logical.CreateOperation, "static-roles/test", data)
+	if err == nil || !strings.Contains(err.Error(), "error storing static role") {
+		t.Fatalf("expected error storing static role, got: %v", err)
+	}
+	creds, err := getStaticCredential(context.Background(), "test", s)
+	assertErrorIsNil(t, err)
+	if creds != nil {
+		t.Fatal("expected static credentials to be removed")
+	}
+	equal(t, passwords, len(mp.passwords))
+	equal(t, 0, b.staticRotationQueue.Len())
+
+	// The role can be created once storage recovers
+	testStaticRoleWrite(t, b, s, "test", data)
+	equal(t, passwords+1, len(mp.passwords))
+}
+
+func TestStaticRoleDelete(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	app, err := mp.CreateApplication(context.Background(), "test", "", nil)
+	assertErrorIsNil(t, err)
+
+	testStaticRoleWrite(t, b, s, "test", map[string]interface{}{
+		"application_object_id": app.AppObjectID,
+		"rotation_period":       3600,
+	})
+
+	_, err = testStaticRequest(b, s, logical.UpdateOperation, "rotate-static-role/test", nil)
+	assertErrorIsNil(t, err)
+
+	creds, err := getStaticCredential(context.Background(), "test", s)
+	assertErrorIsNil(t, err)
+
+	_, err = testStaticRequest(b, s, logical.DeleteOperation, "static-roles/test", nil)
+	assertErrorIsNil(t, err)
+
+	for _, keyID := range []string{creds.KeyID, creds.PreviousKeyID} {
+		if mp.passwordExists(keyID) {
+			t.Fatalf("expected password %q to be removed", keyID)
+		}
+	}
+
+	creds, err = getStaticCredential(context.Background(), "test", s)
+	assertErrorIsNil(t, err)
+	if creds != nil {
+		t.Fatal("expected static credentials to be deleted")
+	}
+
+	resp, err := testStaticRequest(b, s, logical.ReadOperation, "static-creds/test", nil)
+	assertErrorIsNil(t, err)
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error reading credentials of deleted static role, got %#v", resp)
+	}
+
+	// The deleted role is dropped from the rotation queue
+	equal(t, 0, b.staticRotationQueue.Len())
+}
+
+func TestStaticRoleInitialize(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	app, err := mp.CreateApplication(context.Background(), "test", "", nil)
+	assertErrorIsNil(t, err)
+
+	testStaticRoleWrite(t, b, s, "test", map[string]interface{}{
+		"application_object_id": app.AppObjectID,
+		"rotation_period":       3600,
+	})
+
+	// A new backend rebuilds the queue from storage
+	nb := backend()
+	assertErrorIsNil(t, nb.Setup(context.Background(), &logical.BackendConfig{
+		Logger:      b.Logger(),
+		System:      b.System(),
+		StorageView: s,
+	}))
+	assertErrorIsNil(t, nb.Initialize(context.Background(), &logical.InitializationRequest{Storage: s}))
+	equal(t, 1, nb.staticRotationQueue.Len())
+}
+
+func testMockProvider(t *testing.T, b *azureSecretBackend, s logical.Storage) *mockProvider {
+	t.Helper()
+
+	client, err := b.getClient(context.Background(), s)
+	assertErrorIsNil(t, err)
+
+	return client.provider.(*mockProvider)
+}
+
+func testStaticRoleWrite(t *testing.T, b *azureSecretBackend, s logical.Storage, name string, d map[string]interface{}) {
+	t.Helper()
+
+	resp, err := testStaticRequest(b, s, logical.UpdateOperation, fmt.Sprintf("static-roles/%s", name), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil && resp.IsError() {
+		t.Fatal(resp.Error())
+	}
+}
+
+func testStaticRequest(b *azureSecretBackend, s logical.Storage, op logical.Operation, path string, d map[string]interface{}) (*logical.Response, error) {
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Data:      d,
+		Storage:   s,
+	})
+}
+
+// testStaticRoleBackdate moves the last rotation of the role into the past
+// and reschedules it.
+func testStaticRoleBackdate(t *testing.T, b *azureSecretBackend, s logical.Storage, name string, d time.Duration) {
+	t.Helper()
+
+	creds, err := getStaticCredential(context.Background(), name, s)
+	assertErrorIsNil(t, err)
+	creds.LastRotated = creds.LastRotated.Add(-d)
+	assertErrorIsNil(t, saveStaticCredential(context.Background(), s, name, creds))
+
+	testStaticRoleReschedule(t, b, s, name)
+}
+
+func testStaticRoleReschedule(t *testing.T, b *azureSecretBackend, s logical.Storage, name string) {
+	t.Helper()
+
+	role, err := getStaticRole(context.Background(), name, s)
+	assertErrorIsNil(t, err)
+	creds, err := getStaticCredential(context.Background(), name, s)
+	assertErrorIsNil(t, err)
+
+	_, err = b.staticRotationQueue.PopByKey(name)
+	assertErrorIsNil(t, err)
+	assertErrorIsNil(t, b.pushStaticRole(role, creds))
+}
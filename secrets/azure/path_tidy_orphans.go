@@ -0,0 +1,302 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-uuid"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+
+	"github.com/openbao/openbao-plugins/secrets/azure/api"
+)
+
+const (
+	mountIDStoragePath    = "mount-id"
+	leasedAppsStoragePath = "leased-apps"
+
+	// mountTagPrefix prefixes the mount ID in the tags of the applications
+	// created by the backend.
+	mountTagPrefix = "openbao-mount:"
+)
+
+// leasedAppEntry records the application of a dynamic service principal
+// lease. Expiration is the latest time the lease can expire.
+type leasedAppEntry struct {
+	Role       string    `json:"role"`
+	Expiration time.Time `json:"expiration"`
+}
+
+func pathTidyOrphans(b *azureSecretBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy/orphans",
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAzure,
+			OperationVerb:   "tidy",
+			OperationSuffix: "orphaned-applications",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"dry_run": {
+				Type:        framework.TypeBool,
+				Description: "If true, the orphaned applications are reported but not deleted.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                    b.pathTidyOrphansUpdate,
+				ForwardPerformanceSecondary: true,
+				ForwardPerformanceStandby:   true,
+			},
+		},
+		HelpSynopsis:    tidyOrphansHelpSyn,
+		HelpDescription: tidyOrphansHelpDesc,
+	}
+}
+
+func (b *azureSecretBackend) pathTidyOrphansUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	dryRun := d.Get("dry_run").(bool)
+
+	orphans, err := b.tidyOrphans(ctx, req.Storage, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make([]map[string]interface{}, 0, len(orphans))
+	for _, app := range orphans {
+		apps = append(apps, map[string]interface{}{
+			"app_object_id": app.AppObjectID,
+			"app_id":        app.AppID,
+			"display_name":  app.DisplayName,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"applications": apps,
+			"dry_run":      dryRun,
+		},
+	}, nil
+}
+
+// tidyOrphans deletes the applications created by the mount that no longer
+// belong to a lease or role, and returns them. Applications are only
+// considered once they are older than maxWALAge, so that applications of
+// failed requests are left to WAL rollback, and leases are given maxWALAge
+// past their expiration to be revoked.
+func (b *azureSecretBackend) tidyOrphans(ctx context.Context, s logical.Storage, dryRun bool) ([]api.Application, error) {
+	c, err := b.getClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := b.getMountTag(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := c.provider.ListApplications(ctx, fmt.Sprintf("tags/any(t:t eq '%s')", tag))
+	if err != nil {
+		return nil, fmt.Errorf("error listing applications: %w", err)
+	}
+
+	// Applications persisted by roles live as long as the role
+	roleApps, err := managedApplicationObjectIDs(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var merr *multierror.Error
+	var orphans []api.Application
+	for _, app := range apps {
+		if time.Since(app.CreatedDateTime) < maxWALAge || slices.Contains(roleApps, app.AppObjectID) {
+			continue
+		}
+
+		entry, err := getLeasedApp(ctx, s, app.AppObjectID)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil && time.Now().Before(entry.Expiration.Add(maxWALAge)) {
+			continue
+		}
+
+		orphans = append(orphans, app)
+		if dryRun {
+			continue
+		}
+
+		b.Logger().Info("deleting orphaned application", "app_object_id", app.AppObjectID, "display_name", app.DisplayName)
+		if err := c.deleteApp(ctx, app.AppObjectID, false); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("error deleting application %q: %w", app.AppObjectID, err))
+			continue
+		}
+		if err := s.Delete(ctx, leasedAppsStoragePath+"/"+app.AppObjectID); err != nil {
+			merr = multierror.Append(merr, err)
+		}
+	}
+
+	if !dryRun {
+		if err := tidyLeasedApps(ctx, s); err != nil {
+			merr = multierror.Append(merr, err)
+		}
+	}
+
+	return orphans, merr.ErrorOrNil()
+}
+
+// tidyOrphansPeriodic runs tidyOrphans if the orphan_tidy_interval of the
+// config has passed since the last run.
+func (b *azureSecretBackend) tidyOrphansPeriodic(ctx context.Context, s logical.Storage) error {
+	config, err := b.getConfig(ctx, s)
+	if err != nil || config == nil || config.OrphanTidyInterval == 0 {
+		return err
+	}
+
+	if time.Since(b.lastOrphanTidy) < config.OrphanTidyInterval {
+		return nil
+	}
+	b.lastOrphanTidy = time.Now()
+
+	orphans, err := b.tidyOrphans(ctx, s, false)
+	if len(orphans) > 0 {
+		b.Logger().Info("tidied orphaned applications", "count", len(orphans))
+	}
+	return err
+}
+
+// getMountTag returns the tag identifying the applications created by the
+// mount. The mount ID is created on first use.
+func (b *azureSecretBackend) getMountTag(ctx context.Context, s logical.Storage) (string, error) {
+	b.mountIDLock.Lock()
+	defer b.mountIDLock.Unlock()
+
+	if b.mountID == "" {
+		entry, err := s.Get(ctx, mountIDStoragePath)
+		if err != nil {
+			return "", fmt.Errorf("error reading mount ID: %w", err)
+		}
+
+		if entry != nil {
+			b.mountID = string(entry.Value)
+		} else {
+			id, err := uuid.GenerateUUID()
+			if err != nil {
+				return "", err
+			}
+			if err := s.Put(ctx, &logical.StorageEntry{Key: mountIDStoragePath, Value: []byte(id)}); err != nil {
+				return "", fmt.Errorf("error storing mount ID: %w", err)
+			}
+			b.mountID = id
+		}
+	}
+
+	return mountTagPrefix + b.mountID, nil
+}
+
+// appTags returns the tags of an application created for the role, including
+// the tag identifying the mount.
+func (b *azureSecretBackend) appTags(ctx context.Context, s logical.Storage, role *roleEntry) ([]string, error) {
+	tag, err := b.getMountTag(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(slices.Clone(role.Tags), tag), nil
+}
+
+// managedApplicationObjectIDs returns the applications persisted by roles.
+func managedApplicationObjectIDs(ctx context.Context, s logical.Storage) ([]string, error) {
+	names, err := s.List(ctx, rolesStoragePath+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, name := range names {
+		role, err := getRole(ctx, name, s)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil && role.ManagedApplicationObjectID != "" {
+			ids = append(ids, role.ManagedApplicationObjectID)
+		}
+	}
+	return ids, nil
+}
+
+// tidyLeasedApps removes the records of applications whose lease expired
+// more than maxWALAge ago.
+func tidyLeasedApps(ctx context.Context, s logical.Storage) error {
+	ids, err := s.List(ctx, leasedAppsStoragePath+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		entry, err := getLeasedApp(ctx, s, id)
+		if err != nil {
+			return err
+		}
+		if entry != nil && time.Now().After(entry.Expiration.Add(maxWALAge)) {
+			if err := s.Delete(ctx, leasedAppsStoragePath+"/"+id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func saveLeasedApp(ctx context.Context, s logical.Storage, appObjectID string, entry *leasedAppEntry) error {
+	se, err := logical.StorageEntryJSON(leasedAppsStoragePath+"/"+appObjectID, entry)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, se)
+}
+
+// extendLeasedApp moves the expiration of the record of a leased App to the
+// given time if it is later. Apps without a record, such as the persisted Apps
+// of roles, are left alone.
+func extendLeasedApp(ctx context.Context, s logical.Storage, appObjectID string, expiration time.Time) error {
+	entry, err := getLeasedApp(ctx, s, appObjectID)
+	if err != nil || entry == nil || !expiration.After(entry.Expiration) {
+		return err
+	}
+
+	entry.Expiration = expiration
+	return saveLeasedApp(ctx, s, appObjectID, entry)
+}
+
+func getLeasedApp(ctx context.Context, s logical.Storage, appObjectID string) (*leasedAppEntry, error) {
+	se, err := s.Get(ctx, leasedAppsStoragePath+"/"+appObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading leased application: %w", err)
+	}
+
+	if se == nil {
+		return nil, nil
+	}
+
+	entry := new(leasedAppEntry)
+	if err := se.DecodeJSON(entry); err != nil {
+		return nil, fmt.Errorf("error decoding leased application: %w", err)
+	}
+	return entry, nil
+}
+
+const tidyOrphansHelpSyn = `Delete applications created by the mount that no longer belong to a lease or role.`
+const tidyOrphansHelpDesc = `
+Applications created for dynamic service principals are deleted when their
+lease is revoked. If revocation fails permanently, the application remains in
+the tenant. This path finds the applications tagged with the identifier of the
+mount that have no live lease and aren't persisted by a role, and deletes
+them. Applications younger than 24 hours are left to the rollback of failed
+requests. With dry_run set, the applications are reported but not deleted.
+`
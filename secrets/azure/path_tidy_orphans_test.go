@@ -0,0 +1,290 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestTidyOrphans(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+	ctx := context.Background()
+
+	tag, err := b.getMountTag(ctx, s)
+	assertErrorIsNil(t, err)
+
+	testRoleCreate(t, b, s, "dynamic", map[string]interface{}{
+		"azure_roles": testRole["azure_roles"],
+		"tags":        "team:ops",
+		"max_ttl":     "1h",
+	})
+	testRoleCreate(t, b, s, "persistent", testPersistentRole)
+
+	// The applications of leases are tagged with the mount
+	live := testCredsRead(t, b, s, "dynamic")
+	liveAppObjID := live.Secret.InternalData["app_object_id"].(string)
+	equal(t, []string{"team:ops", tag}, mp.appTags[liveAppObjID])
+
+	// A lease that is past its expiration, e.g. because revocation failed
+	// permanently
+	expired := testCredsRead(t, b, s, "dynamic")
+	expiredAppObjID := expired.Secret.InternalData["app_object_id"].(string)
+	assertErrorIsNil(t, saveLeasedApp(ctx, s, expiredAppObjID, &leasedAppEntry{
+		Role:       "dynamic",
+		Expiration: time.Now().Add(-2 * maxWALAge),
+	}))
+
+	// An application of the mount without a lease
+	orphan, err := mp.CreateApplication(ctx, "orphan", "", []string{tag})
+	assertErrorIsNil(t, err)
+
+	// An application of another mount
+	other, err := mp.CreateApplication(ctx, "other", "", []string{mountTagPrefix + generateUUID()})
+	assertErrorIsNil(t, err)
+
+	// An application of the mount that may still be rolled back by the WAL
+	recent, err := mp.CreateApplication(ctx, "recent", "", []string{tag})
+	assertErrorIsNil(t, err)
+
+	for id := range mp.appCreated {
+		if id != recent.AppObjectID {
+			mp.appCreated[id] = time.Now().Add(-2 * maxWALAge)
+		}
+	}
+
+	role, err := getRole(ctx, "persistent", s)
+	assertErrorIsNil(t, err)
+	persistentAppObjID := role.ManagedApplicationObjectID
+
+	expectedOrphans := []string{expiredAppObjID, orphan.AppObjectID}
+	slices.Sort(expectedOrphans)
+
+	t.Run("dry run", func(t *testing.T) {
+		resp := testTidyOrphansRequest(t, b, s, true)
+		equal(t, expectedOrphans, testTidiedApps(resp))
+
+		for _, id := range expectedOrphans {
+			if _, ok := mp.applications[id]; !ok {
+				t.Fatalf("expected application %s to be kept in dry run", id)
+			}
+		}
+	})
+
+	t.Run("tidy", func(t *testing.T) {
+		resp := testTidyOrphansRequest(t, b, s, false)
+		equal(t, expectedOrphans, testTidiedApps(resp))
+
+		for _, id := range expectedOrphans {
+			if _, ok := mp.applications[id]; ok {
+				t.Fatalf("expected application %s to be deleted", id)
+			}
+		}
+		for _, id := range []string{liveAppObjID, persistentAppObjID, other.AppObjectID, recent.AppObjectID} {
+			if _, ok := mp.applications[id]; !ok {
+				t.Fatalf("expected application %s to be kept", id)
+			}
+		}
+
+		entry, err := getLeasedApp(ctx, s, expiredAppObjID)
+		assertErrorIsNil(t, err)
+		if entry != nil {
+			t.Fatal("expected leased application record to be deleted")
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		fakeSaveLoad(live.Secret)
+		_, err := b.spRevoke(ctx, &logical.Request{Secret: live.Secret, Storage: s}, nil)
+		assertErrorIsNil(t, err)
+
+		entry, err := getLeasedApp(ctx, s, liveAppObjID)
+		assertErrorIsNil(t, err)
+		if entry != nil {
+			t.Fatal("expected leased application record to be deleted on revoke")
+		}
+	})
+}
+
+func TestTidyOrphansPeriodic(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+	ctx := context.Background()
+
+	tag, err := b.getMountTag(ctx, s)
+	assertErrorIsNil(t, err)
+
+	newOrphan := func() string {
+		app, err := mp.CreateApplication(ctx, "orphan", "", []string{tag})
+		assertErrorIsNil(t, err)
+		mp.appCreated[app.AppObjectID] = time.Now().Add(-2 * maxWALAge)
+		return app.AppObjectID
+	}
+
+	// Disabled by default
+	first := newOrphan()
+	assertErrorIsNil(t, b.tidyOrphansPeriodic(ctx, s))
+	if _, ok := mp.applications[first]; !ok {
+		t.Fatal("expected periodic tidy to be disabled")
+	}
+
+	testConfigUpdate(t, b, s, map[string]interface{}{"orphan_tidy_interval": "1h"}, false)
+	assertErrorIsNil(t, b.tidyOrphansPeriodic(ctx, s))
+	if _, ok := mp.applications[first]; ok {
+		t.Fatal("expected orphaned application to be deleted")
+	}
+
+	// The next run waits for the interval to pass
+	second := newOrphan()
+	assertErrorIsNil(t, b.tidyOrphansPeriodic(ctx, s))
+	if _, ok := mp.applications[second]; !ok {
+		t.Fatal("expected periodic tidy to wait for the interval")
+	}
+
+	b.lastOrphanTidy = time.Now().Add(-time.Hour)
+	assertErrorIsNil(t, b.tidyOrphansPeriodic(ctx, s))
+	if _, ok := mp.applications[second]; ok {
+		t.Fatal("expected orphaned application to be deleted")
+	}
+}
+
+func TestTidyOrphansRenewedLease(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+	ctx := context.Background()
+
+	roleData := map[string]interface{}{
+		"azure_roles": testRole["azure_roles"],
+		"max_ttl":     "1h",
+	}
+	testRoleCreate(t, b, s, "dynamic", roleData)
+
+	resp := testCredsRead(t, b, s, "dynamic")
+	appObjID := resp.Secret.InternalData["app_object_id"].(string)
+
+	// The lease was issued long ago, when the max_ttl of the role was lower
+	mp.appCreated[appObjID] = time.Now().Add(-2 * maxWALAge)
+	assertErrorIsNil(t, saveLeasedApp(ctx, s, appObjID, &leasedAppEntry{
+		Role:       "dynamic",
+		Expiration: time.Now().Add(-2 * maxWALAge),
+	}))
+
+	roleData["max_ttl"] = "100h"
+	testRoleCreate(t, b, s, "dynamic", roleData)
+
+	fakeSaveLoad(resp.Secret)
+	_, err := b.spRenew(ctx, &logical.Request{Secret: resp.Secret, Storage: s}, nil)
+	assertErrorIsNil(t, err)
+
+	entry, err := getLeasedApp(ctx, s, appObjID)
+	assertErrorIsNil(t, err)
+	if time.Until(entry.Expiration) < 99*time.Hour {
+		t.Fatalf("expected the expiration of the renewed lease to be extended, got %s", entry.Expiration)
+	}
+
+	tidyResp := testTidyOrphansRequest(t, b, s, false)
+	equal(t, []string(nil), testTidiedApps(tidyResp))
+	if _, ok := mp.applications[appObjID]; !ok {
+		t.Fatal("expected the application of the renewed lease to be kept")
+	}
+}
+
+func TestLeasedAppRecordFailure(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+
+	testRoleCreate(t, b, s, "dynamic", map[string]interface{}{
+		"azure_roles": testRole["azure_roles"],
+	})
+
+	appCount := len(mp.applications)
+
+	// A failure to record the leased App must leave the App WAL so that the
+	// App is rolled back
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/dynamic",
+		Storage:   &failingPutStorage{Storage: s, prefix: leasedAppsStoragePath + "/"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "error recording leased application") {
+		t.Fatalf("expected error recording leased application, got: %v", err)
+	}
+
+	ctx := context.Background()
+	walIDs, err := framework.ListWAL(ctx, s)
+	assertErrorIsNil(t, err)
+	var rolledBack bool
+	for _, id := range walIDs {
+		entry, err := framework.GetWAL(ctx, s, id)
+		assertErrorIsNil(t, err)
+		if entry.Kind == walAppKey {
+			assertErrorIsNil(t, b.walRollback(ctx, &logical.Request{Storage: s}, entry.Kind, entry.Data))
+			rolledBack = true
+		}
+	}
+	if !rolledBack {
+		t.Fatal("expected an App WAL entry")
+	}
+	equal(t, appCount, len(mp.applications))
+}
+
+// failingPutStorage fails writes of the entries under prefix.
+type failingPutStorage struct {
+	logical.Storage
+	prefix string
+}
+
+func (s *failingPutStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if strings.HasPrefix(entry.Key, s.prefix) {
+		return errors.New("storage unavailable")
+	}
+	return s.Storage.Put(ctx, entry)
+}
+
+func testCredsRead(t *testing.T, b *azureSecretBackend, s logical.Storage, role string) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/" + role,
+		Storage:   s,
+	})
+	assertRespNoError(t, resp, err)
+
+	return resp
+}
+
+func testTidyOrphansRequest(t *testing.T, b *azureSecretBackend, s logical.Storage, dryRun bool) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy/orphans",
+		Data:      map[string]interface{}{"dry_run": dryRun},
+		Storage:   s,
+	})
+	assertRespNoError(t, resp, err)
+	equal(t, dryRun, resp.Data["dry_run"])
+
+	return resp
+}
+
+// testTidiedApps returns the sorted object IDs of the applications in a tidy
+// response.
+func testTidiedApps(resp *logical.Response) []string {
+	var ids []string
+	for _, app := range resp.Data["applications"].([]map[string]interface{}) {
+		ids = append(ids, app["app_object_id"].(string))
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,280 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
+	"github.com/openbao/openbao/sdk/v2/helper/strutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	tokenCredsStoragePath = "token-creds"
+
+	// tokenCredentialLifetime is the lifetime of the password used to obtain
+	// access tokens for a role. The password is replaced once less than
+	// tokenCredentialRenewBefore of its lifetime remains.
+	tokenCredentialLifetime    = 7 * 24 * time.Hour
+	tokenCredentialRenewBefore = 24 * time.Hour
+)
+
+// tokenCredentialEntry is the password used to obtain access tokens for the
+// application of a role. It is only known to the backend.
+type tokenCredentialEntry struct {
+	ApplicationObjectID string    `json:"application_object_id"`
+	ClientSecret        string    `json:"client_secret"`
+	KeyID               string    `json:"key_id"`
+	KeyEndDate          time.Time `json:"key_end_date"`
+	Created             time.Time `json:"created"`
+}
+
+func pathToken(b *azureSecretBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "token/" + framework.GenericNameRegex("role"),
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAzure,
+			OperationVerb:   "request",
+			OperationSuffix: "access-token",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeLowerCaseString,
+				Description: "Name of the role.",
+			},
+			"scope": {
+				Type:        framework.TypeString,
+				Description: "Scope of the access token, e.g. https://management.azure.com/.default. Must be allowed by the allowed_scopes of the role. May be omitted if the role allows a single scope without globs.",
+			},
+			"resource": {
+				Type:        framework.TypeString,
+				Description: "Resource of the access token, e.g. https://management.azure.com. Shorthand for the scope <resource>/.default. Mutually exclusive with scope.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback:                    b.pathTokenRead,
+				ForwardPerformanceSecondary: true,
+				ForwardPerformanceStandby:   true,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                    b.pathTokenRead,
+				ForwardPerformanceSecondary: true,
+				ForwardPerformanceStandby:   true,
+			},
+		},
+		HelpSynopsis:    tokenHelpSyn,
+		HelpDescription: tokenHelpDesc,
+	}
+}
+
+func (b *azureSecretBackend) pathTokenRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+
+	role, err := getRole(ctx, roleName, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role '%s' does not exist", roleName), nil
+	}
+
+	if len(role.AllowedScopes) == 0 {
+		return logical.ErrorResponse("role '%s' does not allow access tokens, allowed_scopes is empty", roleName), nil
+	}
+	if role.ApplicationObjectID == "" {
+		return logical.ErrorResponse("access tokens require a role with application_object_id or persist_app"), nil
+	}
+	// Access tokens are obtained with a password, which must not be added to
+	// the applications of roles issuing other credentials
+	if role.clientCredentialType() != clientCredentialTypePassword {
+		return logical.ErrorResponse("access tokens require a role with client_credential_type '%s'", clientCredentialTypePassword), nil
+	}
+
+	scope, err := tokenScopeFromRequest(role, d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	c, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := b.tokenCredential(ctx, req.Storage, c, roleName, role)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := b.requestAccessToken(ctx, c, role.ApplicationID, cred.ClientSecret, scope)
+	if err != nil {
+		return logical.ErrorResponse("unable to generate token - make sure the application of the role is still valid: %v", err), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"token":              token.Token,
+			"token_ttl":          int64(time.Until(token.ExpiresOn).Seconds()),
+			"expires_at_seconds": token.ExpiresOn.Unix(),
+		},
+	}, nil
+}
+
+// tokenScopeFromRequest returns the requested scope if it is allowed by the
+// role.
+func tokenScopeFromRequest(role *roleEntry, d *framework.FieldData) (string, error) {
+	scope, scopeOK := d.GetOk("scope")
+	resource, resourceOK := d.GetOk("resource")
+
+	switch {
+	case scopeOK && resourceOK:
+		return "", errors.New("scope and resource are mutually exclusive")
+	case resourceOK:
+		scope = strings.TrimSuffix(resource.(string), "/") + "/.default"
+	case !scopeOK:
+		// A role with a single literal scope doesn't require the caller to
+		// supply one
+		if len(role.AllowedScopes) != 1 || strings.Contains(role.AllowedScopes[0], "*") {
+			return "", errors.New("scope is required")
+		}
+		scope = role.AllowedScopes[0]
+	}
+
+	if !strutil.StrListContainsGlob(role.AllowedScopes, scope.(string)) {
+		return "", fmt.Errorf("scope %q is not allowed by the role", scope)
+	}
+
+	return scope.(string), nil
+}
+
+// tokenCredential returns the password used to obtain access tokens for
+// the role, creating a new password if there is none or it is about to
+// expire.
+func (b *azureSecretBackend) tokenCredential(ctx context.Context, s logical.Storage, c *client, roleName string, role *roleEntry) (*tokenCredentialEntry, error) {
+	lock := locksutil.LockForKey(b.appLocks, role.ApplicationObjectID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	cred, err := getRoleTokenCredential(ctx, roleName, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if cred != nil && cred.ApplicationObjectID == role.ApplicationObjectID &&
+		time.Until(cred.KeyEndDate) > tokenCredentialRenewBefore {
+		return cred, nil
+	}
+
+	keyID, password, endDate, err := c.addAppPassword(ctx, role.ApplicationObjectID, tokenCredentialLifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	newCred := &tokenCredentialEntry{
+		ApplicationObjectID: role.ApplicationObjectID,
+		ClientSecret:        password,
+		KeyID:               keyID,
+		KeyEndDate:          endDate,
+		Created:             time.Now(),
+	}
+	if err := saveRoleTokenCredential(ctx, s, roleName, newCred); err != nil {
+		if delErr := c.deleteAppPassword(ctx, role.ApplicationObjectID, keyID); delErr != nil {
+			b.Logger().Warn("failed to remove unused token password", "role", roleName, "key_id", keyID, "error", delErr)
+		}
+		return nil, fmt.Errorf("error storing token credential: %w", err)
+	}
+
+	// The previous password is only used by this backend, so it is removed
+	// right away. The application of the role may have changed, in which
+	// case the password is removed from the previous application.
+	if cred != nil {
+		if err := c.deleteAppPassword(ctx, cred.ApplicationObjectID, cred.KeyID); err != nil {
+			b.Logger().Warn("failed to remove previous token password", "role", roleName, "key_id", cred.KeyID, "error", err)
+		}
+	}
+
+	return newCred, nil
+}
+
+// requestAccessToken obtains an access token for the application. New
+// passwords take a while to propagate through Microsoft Entra ID, so requests
+// rejected because of an unknown application or password are retried.
+func (b *azureSecretBackend) requestAccessToken(ctx context.Context, c *client, clientID, clientSecret, scope string) (azcore.AccessToken, error) {
+	resultRaw, err := retry(ctx, func() (interface{}, bool, error) {
+		token, err := b.getAppToken(ctx, c.settings, clientID, clientSecret, scope)
+		if err != nil {
+			// AADSTS7000215: Invalid client secret provided
+			// AADSTS700016: Application not found in the directory
+			if strings.Contains(err.Error(), "AADSTS7000215") || strings.Contains(err.Error(), "AADSTS700016") {
+				return nil, false, err
+			}
+			return nil, true, err
+		}
+		return token, true, nil
+	})
+	if err != nil {
+		return azcore.AccessToken{}, err
+	}
+
+	return resultRaw.(azcore.AccessToken), nil
+}
+
+// deleteTokenCredential removes the token password of the role, if any.
+func (b *azureSecretBackend) deleteTokenCredential(ctx context.Context, s logical.Storage, c *client, roleName string) error {
+	cred, err := getRoleTokenCredential(ctx, roleName, s)
+	if err != nil || cred == nil {
+		return err
+	}
+
+	lock := locksutil.LockForKey(b.appLocks, cred.ApplicationObjectID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := c.deleteAppPassword(ctx, cred.ApplicationObjectID, cred.KeyID); err != nil {
+		return err
+	}
+
+	return s.Delete(ctx, tokenCredsStoragePath+"/"+roleName)
+}
+
+func saveRoleTokenCredential(ctx context.Context, s logical.Storage, roleName string, cred *tokenCredentialEntry) error {
+	entry, err := logical.StorageEntryJSON(tokenCredsStoragePath+"/"+roleName, cred)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+func getRoleTokenCredential(ctx context.Context, roleName string, s logical.Storage) (*tokenCredentialEntry, error) {
+	entry, err := s.Get(ctx, tokenCredsStoragePath+"/"+roleName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading token credential: %w", err)
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	cred := new(tokenCredentialEntry)
+	if err := entry.DecodeJSON(cred); err != nil {
+		return nil, fmt.Errorf("error decoding token credential: %w", err)
+	}
+	return cred, nil
+}
+
+const tokenHelpSyn = `Generate an OAuth2 access token for the application of a role.`
+const tokenHelpDesc = `
+This path generates an OAuth2 access token for the application of a role, for
+a scope allowed by the allowed_scopes of the role. The role must have an
+application_object_id or persist_app set. Access tokens cannot be revoked, so
+no lease is created; the token is valid until it expires.
+`
@@ -0,0 +1,265 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// mockAppTokens records the access tokens requested through the backend.
+type mockAppTokens struct {
+	requests []mockAppTokenRequest
+	err      error
+}
+
+type mockAppTokenRequest struct {
+	clientID     string
+	clientSecret string
+	scope        string
+}
+
+func (m *mockAppTokens) getAppToken(_ context.Context, _ *clientSettings, clientID, clientSecret, scope string) (azcore.AccessToken, error) {
+	m.requests = append(m.requests, mockAppTokenRequest{clientID, clientSecret, scope})
+	if m.err != nil {
+		return azcore.AccessToken{}, m.err
+	}
+	return azcore.AccessToken{
+		Token:     "token-" + scope,
+		ExpiresOn: time.Now().Add(time.Hour),
+	}, nil
+}
+
+func TestToken(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	mp := testMockProvider(t, b, s)
+	tokens := &mockAppTokens{}
+	b.getAppToken = tokens.getAppToken
+
+	app, err := mp.CreateApplication(context.Background(), "test", "", nil)
+	assertErrorIsNil(t, err)
+
+	testRoleCreate(t, b, s, "test", map[string]interface{}{
+		"application_object_id": app.AppObjectID,
+		"allowed_scopes":        "https://management.azure.com/.default,api://my-api/*",
+	})
+
+	t.Run("scope", func(t *testing.T) {
+		resp := testTokenRequest(t, b, s, "test", map[string]interface{}{
+			"scope": "https://management.azure.com/.default",
+		})
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+		equal(t, "token-https://management.azure.com/.default", resp.Data["token"])
+		assertKeyExists(t, resp.Data, "token_ttl")
+		assertKeyExists(t, resp.Data, "expires_at_seconds")
+		if resp.Secret != nil {
+			t.Fatal("expected no lease for access tokens")
+		}
+
+		last := tokens.requests[len(tokens.requests)-1]
+		equal(t, app.AppID, last.clientID)
+	})
+
+	t.Run("resource", func(t *testing.T) {
+		resp := testTokenRequest(t, b, s, "test", map[string]interface{}{
+			"resource": "api://my-api/orders/",
+		})
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+		equal(t, "token-api://my-api/orders/.default", resp.Data["token"])
+	})
+
+	t.Run("password reused", func(t *testing.T) {
+		testTokenRequest(t, b, s, "test", map[string]interface{}{"scope": "api://my-api/a"})
+		testTokenRequest(t, b, s, "test", map[string]interface{}{"scope": "api://my-api/b"})
+
+		secrets := map[string]bool{}
+		for _, r := range tokens.requests {
+			secrets[r.clientSecret] = true
+		}
+		equal(t, 1, len(secrets))
+
+		cred, err := getRoleTokenCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+		if !mp.passwordExists(cred.KeyID) {
+			t.Fatal("expected token password to exist")
+		}
+	})
+
+	t.Run("password renewed", func(t *testing.T) {
+		cred, err := getRoleTokenCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+		cred.KeyEndDate = time.Now().Add(time.Hour)
+		assertErrorIsNil(t, saveRoleTokenCredential(context.Background(), s, "test", cred))
+
+		resp := testTokenRequest(t, b, s, "test", map[string]interface{}{"scope": "api://my-api/a"})
+		if resp.IsError() {
+			t.Fatal(resp.Error())
+		}
+
+		renewed, err := getRoleTokenCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+		if renewed.KeyID == cred.KeyID {
+			t.Fatal("expected token password to be renewed")
+		}
+		if mp.passwordExists(cred.KeyID) {
+			t.Fatal("expected previous token password to be removed")
+		}
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		tests := map[string]struct {
+			data map[string]interface{}
+			err  string
+		}{
+			"scope not allowed": {
+				data: map[string]interface{}{"scope": "https://graph.microsoft.com/.default"},
+				err:  "not allowed by the role",
+			},
+			"missing scope": {
+				data: nil,
+				err:  "scope is required",
+			},
+			"scope and resource": {
+				data: map[string]interface{}{"scope": "api://my-api/a", "resource": "api://my-api"},
+				err:  "mutually exclusive",
+			},
+		}
+
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				resp := testTokenRequest(t, b, s, "test", tc.data)
+				if !resp.IsError() || !strings.Contains(resp.Error().Error(), tc.err) {
+					t.Fatalf("expected error %q, got %#v", tc.err, resp)
+				}
+			})
+		}
+	})
+
+	t.Run("token error", func(t *testing.T) {
+		tokens.err = errors.New("AADSTS7000222: The provided client secret keys are expired")
+		defer func() { tokens.err = nil }()
+
+		resp := testTokenRequest(t, b, s, "test", map[string]interface{}{"scope": "api://my-api/a"})
+		if !resp.IsError() {
+			t.Fatalf("expected error, got %#v", resp)
+		}
+	})
+
+	t.Run("role delete", func(t *testing.T) {
+		cred, err := getRoleTokenCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+
+		_, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "roles/test",
+			Storage:   s,
+		})
+		assertErrorIsNil(t, err)
+
+		if mp.passwordExists(cred.KeyID) {
+			t.Fatal("expected token password to be removed with the role")
+		}
+		cred, err = getRoleTokenCredential(context.Background(), "test", s)
+		assertErrorIsNil(t, err)
+		if cred != nil {
+			t.Fatal("expected token credential to be deleted")
+		}
+	})
+}
+
+func TestTokenRoleRequirements(t *testing.T) {
+	b, s := getTestBackendMocked(t, true)
+	b.getAppToken = (&mockAppTokens{}).getAppToken
+
+	// A single literal scope is used by default
+	testRoleCreate(t, b, s, "single", map[string]interface{}{
+		"application_object_id": testStaticSPAppObjID,
+		"allowed_scopes":        "https://management.azure.com/.default",
+	})
+	resp := testTokenRequest(t, b, s, "single", nil)
+	if resp.IsError() {
+		t.Fatal(resp.Error())
+	}
+	equal(t, "token-https://management.azure.com/.default", resp.Data["token"])
+
+	// Roles without allowed scopes don't issue access tokens
+	testRoleCreate(t, b, s, "no-scopes", testStaticSPRole)
+	resp = testTokenRequest(t, b, s, "no-scopes", map[string]interface{}{"scope": "https://management.azure.com/.default"})
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "allowed_scopes is empty") {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+
+	// Dynamic roles don't have an application to issue access tokens for
+	testRoleCreate(t, b, s, "dynamic", map[string]interface{}{
+		"azure_roles":    testRole["azure_roles"],
+		"allowed_scopes": "https://management.azure.com/.default",
+	})
+	resp = testTokenRequest(t, b, s, "dynamic", nil)
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "application_object_id or persist_app") {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+
+	// Invalid allowed scopes are rejected
+	resp = testRoleCreateBasic(t, b, s, "bad", map[string]interface{}{
+		"application_object_id": testStaticSPAppObjID,
+		"allowed_scopes":        "*",
+	})
+	if !resp.IsError() {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+
+	// Access tokens are obtained with a password, which roles issuing other
+	// credentials must not add to their application
+	resp = testRoleCreateBasic(t, b, s, "certificate", map[string]interface{}{
+		"application_object_id":  testStaticSPAppObjID,
+		"client_credential_type": clientCredentialTypeCertificate,
+		"allowed_scopes":         "https://management.azure.com/.default",
+	})
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "client_credential_type 'password'") {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+
+	testRoleCreate(t, b, s, "certificate", map[string]interface{}{
+		"application_object_id":  testStaticSPAppObjID,
+		"client_credential_type": clientCredentialTypeCertificate,
+	})
+	role, err := getRole(context.Background(), "certificate", s)
+	assertErrorIsNil(t, err)
+	role.AllowedScopes = []string{"https://management.azure.com/.default"}
+	assertErrorIsNil(t, saveRole(context.Background(), s, role, "certificate"))
+
+	resp = testTokenRequest(t, b, s, "certificate", nil)
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "client_credential_type 'password'") {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+	cred, err := getRoleTokenCredential(context.Background(), "certificate", s)
+	assertErrorIsNil(t, err)
+	if cred != nil {
+		t.Fatal("expected no token password for a certificate role")
+	}
+}
+
+func testTokenRequest(t *testing.T, b *azureSecretBackend, s logical.Storage, role string, d map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "token/" + role,
+		Data:      d,
+		Storage:   s,
+	})
+	assertErrorIsNil(t, err)
+
+	return resp
+}
@@ -28,6 +28,8 @@ type AzureProvider interface {
 	api.ApplicationsClient
 	api.GroupsClient
 	api.ServicePrincipalClient
+	api.DirectoryRolesClient
+	api.StorageClient
 
 	CreateRoleAssignment(
 		ctx context.Context,
@@ -50,6 +52,8 @@ type provider struct {
 	appClient    api.ApplicationsClient
 	spClient     api.ServicePrincipalClient
 	groupsClient api.GroupsClient
+	entraClient  api.DirectoryRolesClient
+	storage      api.StorageClient
 	raClient     *armauthorization.RoleAssignmentsClient
 	rdClient     *armauthorization.RoleDefinitionsClient
 }
@@ -80,10 +84,17 @@ func newAzureProvider(ctx context.Context, logger hclog.Logger, sys logical.Syst
 		return nil, err
 	}
 
+	storage, err := api.NewStorageRESTClient(settings.SubscriptionID, settings.StorageSuffix, cred, opts.ClientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
 	p := &provider{
 		appClient:    msGraphAppClient,
 		spClient:     msGraphAppClient,
 		groupsClient: msGraphAppClient,
+		entraClient:  msGraphAppClient,
+		storage:      storage,
 		raClient:     raClient,
 		rdClient:     rdClient,
 	}
@@ -120,6 +131,21 @@ func getTokenCredential(ctx context.Context, logger hclog.Logger, sys logical.Sy
 	return cred, nil
 }
 
+// getAppAccessToken obtains an access token for the given scope using the
+// client secret of an application.
+func getAppAccessToken(ctx context.Context, s *clientSettings, clientID, clientSecret, scope string) (azcore.AccessToken, error) {
+	options := &azidentity.ClientSecretCredentialOptions{
+		ClientOptions: azcore.ClientOptions{Cloud: s.CloudConfig},
+	}
+
+	cred, err := azidentity.NewClientSecretCredential(s.TenantID, clientID, clientSecret, options)
+	if err != nil {
+		return azcore.AccessToken{}, fmt.Errorf("failed to create client secret token credential: %w", err)
+	}
+
+	return cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}})
+}
+
 // transporter implements the azure exported.Transporter interface to send HTTP
 // requests. This allows us to set our custom http client and user agent.
 type transporter struct {
@@ -183,16 +209,58 @@ func (p *provider) RemoveApplicationPassword(ctx context.Context, applicationObj
 	return p.appClient.RemoveApplicationPassword(ctx, applicationObjectID, keyID)
 }
 
+func (p *provider) AddApplicationKeyCredential(ctx context.Context, applicationObjectID string, displayName string, certificate []byte, startDateTime, endDateTime time.Time) (result api.KeyCredential, err error) {
+	return p.appClient.AddApplicationKeyCredential(ctx, applicationObjectID, displayName, certificate, startDateTime, endDateTime)
+}
+
+func (p *provider) RemoveApplicationKeyCredential(ctx context.Context, applicationObjectID string, keyID string) (err error) {
+	return p.appClient.RemoveApplicationKeyCredential(ctx, applicationObjectID, keyID)
+}
+
+func (p *provider) AddApplicationFederatedIdentityCredential(ctx context.Context, applicationObjectID string, credential api.FederatedIdentityCredential) (result api.FederatedIdentityCredential, err error) {
+	return p.appClient.AddApplicationFederatedIdentityCredential(ctx, applicationObjectID, credential)
+}
+
+func (p *provider) RemoveApplicationFederatedIdentityCredential(ctx context.Context, applicationObjectID string, credentialID string) (err error) {
+	return p.appClient.RemoveApplicationFederatedIdentityCredential(ctx, applicationObjectID, credentialID)
+}
+
 // CreateServicePrincipal creates a new Azure service principal.
 // An Application must be created prior to calling this and pass in parameters.
 func (p *provider) CreateServicePrincipal(ctx context.Context, appID string, startDate time.Time, endDate time.Time) (id string, password string, err error) {
 	return p.spClient.CreateServicePrincipal(ctx, appID, startDate, endDate)
 }
 
+// CreateServicePrincipalWithoutPassword creates a new Azure service principal
+// without any credentials. This is used when the application authenticates
+// with credentials other than a client secret.
+func (p *provider) CreateServicePrincipalWithoutPassword(ctx context.Context, appID string) (id string, err error) {
+	return p.spClient.CreateServicePrincipalWithoutPassword(ctx, appID)
+}
+
 func (p *provider) DeleteServicePrincipal(ctx context.Context, spObjectID string, permanentlyDelete bool) error {
 	return p.spClient.DeleteServicePrincipal(ctx, spObjectID, permanentlyDelete)
 }
 
+// ListServicePrincipals returns the service principals of an application.
+func (p *provider) ListServicePrincipals(ctx context.Context, appID string) ([]api.ServicePrincipal, error) {
+	return p.spClient.ListServicePrincipals(ctx, appID)
+}
+
+// AddAppRoleAssignment grants an app role of a resource, such as an
+// application permission of Microsoft Graph, to a service principal.
+func (p *provider) AddAppRoleAssignment(ctx context.Context, spObjectID, resourceObjectID, appRoleID string) (api.AppRoleAssignment, error) {
+	return p.spClient.AddAppRoleAssignment(ctx, spObjectID, resourceObjectID, appRoleID)
+}
+
+func (p *provider) RemoveAppRoleAssignment(ctx context.Context, spObjectID, assignmentID string) error {
+	return p.spClient.RemoveAppRoleAssignment(ctx, spObjectID, assignmentID)
+}
+
+func (p *provider) ListAppRoleAssignments(ctx context.Context, spObjectID string) ([]api.AppRoleAssignment, error) {
+	return p.spClient.ListAppRoleAssignments(ctx, spObjectID)
+}
+
 // ListRoles like all Azure roles with a scope (often subscription).
 func (p *provider) ListRoleDefinitions(ctx context.Context, scope string, filter string) (result []*armauthorization.RoleDefinition, err error) {
 	options := armauthorization.RoleDefinitionsClientListOptions{
@@ -246,3 +314,32 @@ func (p *provider) GetGroup(ctx context.Context, objectID string) (result api.Gr
 func (p *provider) ListGroups(ctx context.Context, filter string) (result []api.Group, err error) {
 	return p.groupsClient.ListGroups(ctx, filter)
 }
+
+// ListDirectoryRoleDefinitions lists the Microsoft Entra directory roles
+// matching the filter.
+func (p *provider) ListDirectoryRoleDefinitions(ctx context.Context, filter string) ([]api.DirectoryRoleDefinition, error) {
+	return p.entraClient.ListDirectoryRoleDefinitions(ctx, filter)
+}
+
+// CreateDirectoryRoleAssignment assigns a Microsoft Entra directory role to a
+// principal.
+func (p *provider) CreateDirectoryRoleAssignment(ctx context.Context, principalID, roleDefinitionID, directoryScopeID string) (string, error) {
+	return p.entraClient.CreateDirectoryRoleAssignment(ctx, principalID, roleDefinitionID, directoryScopeID)
+}
+
+// DeleteDirectoryRoleAssignment deletes a Microsoft Entra directory role
+// assignment.
+func (p *provider) DeleteDirectoryRoleAssignment(ctx context.Context, assignmentID string) error {
+	return p.entraClient.DeleteDirectoryRoleAssignment(ctx, assignmentID)
+}
+
+// ListStorageAccountKeys lists the access keys of a storage account.
+func (p *provider) ListStorageAccountKeys(ctx context.Context, resourceGroup, account string) ([]api.StorageAccountKey, error) {
+	return p.storage.ListStorageAccountKeys(ctx, resourceGroup, account)
+}
+
+// GetUserDelegationKey requests a user delegation key for the Blob service of
+// a storage account.
+func (p *provider) GetUserDelegationKey(ctx context.Context, account string, start, expiry time.Time) (api.UserDelegationKey, error) {
+	return p.storage.GetUserDelegationKey(ctx, account, start, expiry)
+}
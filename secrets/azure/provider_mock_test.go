@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +26,16 @@ type mockProvider struct {
 	servicePrincipals          map[string]bool
 	deletedObjects             map[string]bool
 	passwords                  map[string]string
+	keyCredentials             map[string][]byte
+	passwordlessSPs            map[string]bool
+	federatedCredentials       map[string]map[string]api.FederatedIdentityCredential
+	appRoleAssignments         map[string]map[string]api.AppRoleAssignment
+	roleAssignmentScopes       map[string]string
+	directoryRoleAssignments   map[string]mockDirectoryRoleAssignment
+	groupMembers               map[string]map[string]bool
+	appTags                    map[string][]string
+	appCreated                 map[string]time.Time
+	storage                    api.StorageClient
 	failNextCreateApplication  bool
 	failUnassignRoles          bool
 	unassignRolesFailureParams failureParams
@@ -45,12 +56,46 @@ func newMockProvider() AzureProvider {
 			// not called and the test expects an app to exist.
 			testStaticSPAppObjID: testStaticSPAppObjID,
 		},
-		servicePrincipals: make(map[string]bool),
-		deletedObjects:    make(map[string]bool),
-		passwords:         make(map[string]string),
+		servicePrincipals:        make(map[string]bool),
+		deletedObjects:           make(map[string]bool),
+		passwords:                make(map[string]string),
+		keyCredentials:           make(map[string][]byte),
+		passwordlessSPs:          make(map[string]bool),
+		federatedCredentials:     make(map[string]map[string]api.FederatedIdentityCredential),
+		appRoleAssignments:       make(map[string]map[string]api.AppRoleAssignment),
+		roleAssignmentScopes:     make(map[string]string),
+		directoryRoleAssignments: make(map[string]mockDirectoryRoleAssignment),
+		groupMembers:             make(map[string]map[string]bool),
+		appTags:                  make(map[string][]string),
+		appCreated:               make(map[string]time.Time),
 	}
 }
 
+type mockDirectoryRoleAssignment struct {
+	principalID      string
+	roleDefinitionID string
+	directoryScopeID string
+}
+
+const (
+	testGraphAppID      = "00000003-0000-0000-c000-000000000000"
+	testGraphSPObjectID = "8d2ad8fe-9cc5-4e4e-8b6c-5c7e1a8cd8a3"
+)
+
+// testGraphAppRoles are the app roles of the mocked Microsoft Graph service
+// principal.
+var testGraphAppRoles = []api.AppRole{
+	{ID: "df021288-bdef-4463-88db-98f22de89214", Value: "User.Read.All", AllowedMemberTypes: []string{"Application"}, IsEnabled: true},
+	{ID: "5b567255-7703-4780-807c-7be8301ae99b", Value: "Group.Read.All", AllowedMemberTypes: []string{"Application"}, IsEnabled: true},
+	{ID: "0e263e50-5827-48a4-b97c-d940288653c7", Value: "Directory.AccessAsUser.All", AllowedMemberTypes: []string{"User"}, IsEnabled: true},
+}
+
+// testDirectoryRoles are the Entra directory roles of the mocked tenant.
+var testDirectoryRoles = []api.DirectoryRoleDefinition{
+	{ID: "fdd7a751-b60b-444a-984c-02652fe8fa1c", TemplateID: "fdd7a751-b60b-444a-984c-02652fe8fa1c", DisplayName: "Groups Administrator"},
+	{ID: "fe930be7-5e62-47db-91af-98c3a49a38b1", TemplateID: "fe930be7-5e62-47db-91af-98c3a49a38b1", DisplayName: "User Administrator"},
+}
+
 // ListRoles returns a single fake role based on the inbound filter
 func (m *mockProvider) ListRoleDefinitions(_ context.Context, _ string, filter string) ([]*armauthorization.RoleDefinition, error) {
 	reRoleName := regexp.MustCompile("roleName eq '(.*)'")
@@ -119,7 +164,123 @@ func (m *mockProvider) CreateServicePrincipal(_ context.Context, _ string, _ tim
 	return id, pass, nil
 }
 
-func (m *mockProvider) CreateApplication(_ context.Context, _ string, _ string, _ []string) (api.Application, error) {
+func (m *mockProvider) CreateServicePrincipalWithoutPassword(_ context.Context, _ string) (spID string, err error) {
+	id := generateUUID()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.servicePrincipals[id] = true
+	m.passwordlessSPs[id] = true
+
+	return id, nil
+}
+
+func (m *mockProvider) ListServicePrincipals(_ context.Context, appID string) ([]api.ServicePrincipal, error) {
+	if appID != testGraphAppID {
+		return nil, nil
+	}
+
+	return []api.ServicePrincipal{
+		{
+			ID:       testGraphSPObjectID,
+			AppID:    testGraphAppID,
+			AppRoles: testGraphAppRoles,
+		},
+	}, nil
+}
+
+func (m *mockProvider) AddAppRoleAssignment(_ context.Context, spObjectID, resourceObjectID, appRoleID string) (api.AppRoleAssignment, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.servicePrincipals[spObjectID] {
+		return api.AppRoleAssignment{}, fmt.Errorf("Request_ResourceNotFound: service principal %s does not exist", spObjectID)
+	}
+
+	assignment := api.AppRoleAssignment{
+		ID:         generateUUID(),
+		AppRoleID:  appRoleID,
+		ResourceID: resourceObjectID,
+	}
+	if m.appRoleAssignments[spObjectID] == nil {
+		m.appRoleAssignments[spObjectID] = make(map[string]api.AppRoleAssignment)
+	}
+	m.appRoleAssignments[spObjectID][assignment.ID] = assignment
+
+	return assignment, nil
+}
+
+func (m *mockProvider) RemoveAppRoleAssignment(_ context.Context, spObjectID, assignmentID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.appRoleAssignments[spObjectID][assignmentID]; !ok {
+		return fmt.Errorf("Request_ResourceNotFound: app role assignment %s does not exist", assignmentID)
+	}
+	delete(m.appRoleAssignments[spObjectID], assignmentID)
+
+	return nil
+}
+
+func (m *mockProvider) ListAppRoleAssignments(_ context.Context, spObjectID string) ([]api.AppRoleAssignment, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var assignments []api.AppRoleAssignment
+	for _, assignment := range m.appRoleAssignments[spObjectID] {
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+func (m *mockProvider) ListDirectoryRoleDefinitions(_ context.Context, filter string) ([]api.DirectoryRoleDefinition, error) {
+	reFilter := regexp.MustCompile("^(templateId|displayName) eq '(.*)'$")
+
+	match := reFilter.FindStringSubmatch(filter)
+	if match == nil {
+		return nil, fmt.Errorf("unsupported filter %q", filter)
+	}
+
+	var defs []api.DirectoryRoleDefinition
+	for _, def := range testDirectoryRoles {
+		if (match[1] == "templateId" && def.TemplateID == match[2]) || (match[1] == "displayName" && def.DisplayName == match[2]) {
+			defs = append(defs, def)
+		}
+	}
+	return defs, nil
+}
+
+func (m *mockProvider) CreateDirectoryRoleAssignment(_ context.Context, principalID, roleDefinitionID, directoryScopeID string) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.servicePrincipals[principalID] {
+		return "", fmt.Errorf("Request_ResourceNotFound: principal %s does not exist", principalID)
+	}
+
+	id := generateUUID()
+	m.directoryRoleAssignments[id] = mockDirectoryRoleAssignment{
+		principalID:      principalID,
+		roleDefinitionID: roleDefinitionID,
+		directoryScopeID: directoryScopeID,
+	}
+	return id, nil
+}
+
+func (m *mockProvider) DeleteDirectoryRoleAssignment(_ context.Context, assignmentID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.directoryRoleAssignments[assignmentID]; !ok {
+		return fmt.Errorf("Request_ResourceNotFound: role assignment %s does not exist", assignmentID)
+	}
+	delete(m.directoryRoleAssignments, assignmentID)
+
+	return nil
+}
+
+func (m *mockProvider) CreateApplication(_ context.Context, _ string, _ string, tags []string) (api.Application, error) {
 	if m.ctxTimeout != 0 {
 		// simulate a context deadline error by sleeping for timeout period
 		time.Sleep(m.ctxTimeout)
@@ -136,6 +297,8 @@ func (m *mockProvider) CreateApplication(_ context.Context, _ string, _ string,
 	defer m.lock.Unlock()
 
 	m.applications[appObjID] = appID
+	m.appTags[appObjID] = tags
+	m.appCreated[appObjID] = time.Now()
 
 	return api.Application{
 		AppID:       appID,
@@ -155,8 +318,27 @@ func (m *mockProvider) GetApplication(_ context.Context, applicationObjectID str
 	}, nil
 }
 
-func (m *mockProvider) ListApplications(_ context.Context, _ string) ([]api.Application, error) {
-	return nil, nil
+// ListApplications supports filtering applications by tag.
+func (m *mockProvider) ListApplications(_ context.Context, filter string) ([]api.Application, error) {
+	match := regexp.MustCompile(`^tags/any\(t:t eq '(.*)'\)$`).FindStringSubmatch(filter)
+	if match == nil {
+		return nil, nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var apps []api.Application
+	for appObjID, appID := range m.applications {
+		if slices.Contains(m.appTags[appObjID], match[1]) {
+			apps = append(apps, api.Application{
+				AppID:           appID,
+				AppObjectID:     appObjID,
+				CreatedDateTime: m.appCreated[appObjID],
+			})
+		}
+	}
+	return apps, nil
 }
 
 func (m *mockProvider) DeleteApplication(_ context.Context, applicationObjectID string, permanentlyDelete bool) error {
@@ -179,7 +361,7 @@ func (m *mockProvider) DeleteServicePrincipal(_ context.Context, spObjectID stri
 	return nil
 }
 
-func (m *mockProvider) AddApplicationPassword(_ context.Context, _ string, _ string, _ time.Time) (result api.PasswordCredential, err error) {
+func (m *mockProvider) AddApplicationPassword(_ context.Context, _ string, _ string, endDateTime time.Time) (result api.PasswordCredential, err error) {
 	keyID := uuid.New().String()
 	pass := uuid.New().String()
 
@@ -190,6 +372,7 @@ func (m *mockProvider) AddApplicationPassword(_ context.Context, _ string, _ str
 	return api.PasswordCredential{
 		KeyID:      keyID,
 		SecretText: pass,
+		EndDate:    endDateTime,
 	}, nil
 }
 
@@ -202,6 +385,75 @@ func (m *mockProvider) RemoveApplicationPassword(_ context.Context, _ string, ke
 	return nil
 }
 
+func (m *mockProvider) AddApplicationKeyCredential(_ context.Context, _ string, displayName string, certificate []byte, _ time.Time, endDateTime time.Time) (api.KeyCredential, error) {
+	keyID := uuid.New().String()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.keyCredentials[keyID] = certificate
+
+	return api.KeyCredential{
+		KeyID:       keyID,
+		EndDate:     endDateTime,
+		DisplayName: displayName,
+	}, nil
+}
+
+func (m *mockProvider) RemoveApplicationKeyCredential(_ context.Context, _ string, keyID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.keyCredentials, keyID)
+
+	return nil
+}
+
+func (m *mockProvider) AddApplicationFederatedIdentityCredential(_ context.Context, applicationObjectID string, credential api.FederatedIdentityCredential) (api.FederatedIdentityCredential, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	creds, ok := m.federatedCredentials[applicationObjectID]
+	if !ok {
+		creds = make(map[string]api.FederatedIdentityCredential)
+		m.federatedCredentials[applicationObjectID] = creds
+	}
+	for _, cred := range creds {
+		if cred.Issuer == credential.Issuer && cred.Subject == credential.Subject {
+			return api.FederatedIdentityCredential{}, errors.New("FederatedIdentityCredential with same issuer and subject already exists")
+		}
+	}
+
+	credential.ID = uuid.New().String()
+	creds[credential.ID] = credential
+
+	return credential, nil
+}
+
+func (m *mockProvider) RemoveApplicationFederatedIdentityCredential(_ context.Context, applicationObjectID string, credentialID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.federatedCredentials[applicationObjectID], credentialID)
+
+	return nil
+}
+
+func (m *mockProvider) federatedCredential(applicationObjectID, credentialID string) (api.FederatedIdentityCredential, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	cred, ok := m.federatedCredentials[applicationObjectID][credentialID]
+	return cred, ok
+}
+
+func (m *mockProvider) keyCredentialExists(s string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.keyCredentials[s]
+	return ok
+}
+
 func (m *mockProvider) deletedObjectExists(s string) bool {
 	return m.deletedObjects[s]
 }
@@ -220,6 +472,11 @@ func (m *mockProvider) passwordExists(s string) bool {
 }
 
 func (m *mockProvider) CreateRoleAssignment(_ context.Context, scope string, name string, params armauthorization.RoleAssignmentCreateParameters) (armauthorization.RoleAssignmentsClientCreateResponse, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.roleAssignmentScopes[name] = scope
+
 	return armauthorization.RoleAssignmentsClientCreateResponse{
 		RoleAssignment: armauthorization.RoleAssignment{
 			Properties: &armauthorization.RoleAssignmentProperties{
@@ -251,12 +508,31 @@ func (m *mockProvider) DeleteRoleAssignmentByID(_ context.Context, _ string) (ar
 }
 
 // AddGroupMember adds a member to a Group.
-func (m *mockProvider) AddGroupMember(_ context.Context, _ string, _ string) error {
+func (m *mockProvider) AddGroupMember(_ context.Context, groupObjectID string, memberObjectID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.groupMembers[groupObjectID][memberObjectID] {
+		return errors.New("One or more added object references already exist for the following modified properties: 'members'.")
+	}
+	if m.groupMembers[groupObjectID] == nil {
+		m.groupMembers[groupObjectID] = make(map[string]bool)
+	}
+	m.groupMembers[groupObjectID][memberObjectID] = true
+
 	return nil
 }
 
 // RemoveGroupMember removes a member from a Group.
-func (m *mockProvider) RemoveGroupMember(_ context.Context, _ string, _ string) error {
+func (m *mockProvider) RemoveGroupMember(_ context.Context, groupObjectID string, memberObjectID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.groupMembers[groupObjectID][memberObjectID] {
+		return fmt.Errorf("Status=404: %s is not a member of group %s", memberObjectID, groupObjectID)
+	}
+	delete(m.groupMembers[groupObjectID], memberObjectID)
+
 	return nil
 }
 
@@ -306,3 +582,21 @@ func (m *mockProvider) ListGroups(_ context.Context, filter string) ([]api.Group
 	return []api.Group{}, nil
 
 }
+
+// ListStorageAccountKeys lists the keys of a storage account of the storage
+// client of the test, if any.
+func (m *mockProvider) ListStorageAccountKeys(ctx context.Context, resourceGroup, account string) ([]api.StorageAccountKey, error) {
+	if m.storage == nil {
+		return nil, errors.New("no storage client")
+	}
+	return m.storage.ListStorageAccountKeys(ctx, resourceGroup, account)
+}
+
+// GetUserDelegationKey requests a user delegation key from the storage client
+// of the test, if any.
+func (m *mockProvider) GetUserDelegationKey(ctx context.Context, account string, start, expiry time.Time) (api.UserDelegationKey, error) {
+	if m.storage == nil {
+		return api.UserDelegationKey{}, errors.New("no storage client")
+	}
+	return m.storage.GetUserDelegationKey(ctx, account, start, expiry)
+}
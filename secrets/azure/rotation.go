@@ -0,0 +1,232 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/sdk/v2/queue"
+)
+
+// staticRotationRetryInterval is the delay before a failed rotation of a
+// static role is retried.
+const staticRotationRetryInterval = time.Minute
+
+// initStaticRotationQueue schedules the rotation of all stored static roles.
+func (b *azureSecretBackend) initStaticRotationQueue(ctx context.Context, req *logical.InitializationRequest) error {
+	b.staticRolesLock.Lock()
+	defer b.staticRolesLock.Unlock()
+
+	names, err := req.Storage.List(ctx, staticRolesStoragePath+"/")
+	if err != nil {
+		return fmt.Errorf("error listing static roles: %w", err)
+	}
+
+	for _, name := range names {
+		role, err := getStaticRole(ctx, name, req.Storage)
+		if err != nil {
+			return fmt.Errorf("error reading static role %q: %w", name, err)
+		}
+		if role == nil {
+			continue
+		}
+
+		creds, err := getStaticCredential(ctx, name, req.Storage)
+		if err != nil {
+			return err
+		}
+
+		if err := b.pushStaticRole(role, creds); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rotateExpiredStaticRoles rotates the passwords of static roles that are due
+// and removes previous passwords whose overlap has passed.
+func (b *azureSecretBackend) rotateExpiredStaticRoles(ctx context.Context, req *logical.Request) error {
+	var errs *multierror.Error
+
+	for {
+		keepGoing, err := b.rotateNextStaticRole(ctx, req.Storage)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+		if !keepGoing {
+			return errs.ErrorOrNil()
+		}
+	}
+}
+
+// rotateNextStaticRole processes the first item of the rotation queue if it
+// is due, and reports whether the next item should be processed.
+func (b *azureSecretBackend) rotateNextStaticRole(ctx context.Context, s logical.Storage) (bool, error) {
+	b.staticRolesLock.Lock()
+	defer b.staticRolesLock.Unlock()
+
+	item, err := b.staticRotationQueue.Pop()
+	if err != nil {
+		if errors.Is(err, queue.ErrEmpty) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if item.Priority > time.Now().Unix() {
+		// The first item is not due, so neither is the rest of the queue
+		if err := b.staticRotationQueue.Push(item); err != nil {
+			return false, fmt.Errorf("failed to add static role %q back to the rotation queue: %w", item.Key, err)
+		}
+		return false, nil
+	}
+
+	role, err := getStaticRole(ctx, item.Key, s)
+	if err != nil {
+		return true, b.retryStaticRole(item, fmt.Errorf("error reading static role %q: %w", item.Key, err))
+	}
+	if role == nil {
+		// The role was deleted, so it is not rescheduled
+		return true, nil
+	}
+
+	creds, err := getStaticCredential(ctx, role.Name, s)
+	if err != nil {
+		return true, b.retryStaticRole(item, err)
+	}
+
+	if creds == nil || !time.Now().Before(creds.LastRotated.Add(role.RotationPeriod)) {
+		creds, err = b.rotateStaticRole(ctx, s, role, creds)
+	} else {
+		err = b.removePreviousStaticPassword(ctx, s, role, creds)
+	}
+	if err != nil {
+		return true, b.retryStaticRole(item, fmt.Errorf("failed to rotate static role %q: %w", role.Name, err))
+	}
+
+	return true, b.pushStaticRole(role, creds)
+}
+
+// retryStaticRole schedules another attempt for a failed rotation and
+// returns the error that caused it.
+func (b *azureSecretBackend) retryStaticRole(item *queue.Item, err error) error {
+	b.Logger().Warn("static role rotation failed, retrying", "role", item.Key, "retry_in", staticRotationRetryInterval, "error", err)
+
+	item.Priority = time.Now().Add(staticRotationRetryInterval).Unix()
+	if pushErr := b.staticRotationQueue.Push(item); pushErr != nil {
+		return multierror.Append(err, pushErr)
+	}
+
+	return err
+}
+
+// rotateStaticRole adds a new password to the Application of the role and
+// stores it. The current password becomes the previous password, which is
+// removed once the rotation overlap has passed. The caller must hold the
+// static roles lock.
+func (b *azureSecretBackend) rotateStaticRole(ctx context.Context, s logical.Storage, role *staticRoleEntry, creds *staticCredentialEntry) (*staticCredentialEntry, error) {
+	c, err := b.getClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := locksutil.LockForKey(b.appLocks, role.ApplicationObjectID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Only one previous password is kept. If it is still present because
+	// the role is rotated manually within the overlap, it is removed now.
+	if creds != nil && creds.PreviousKeyID != "" {
+		if err := c.deleteAppPassword(ctx, role.ApplicationObjectID, creds.PreviousKeyID); err != nil {
+			return nil, err
+		}
+	}
+
+	keyID, password, endDate, err := c.addAppPassword(ctx, role.ApplicationObjectID, role.passwordLifetime())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	newCreds := &staticCredentialEntry{
+		ClientSecret: password,
+		KeyID:        keyID,
+		KeyEndDate:   endDate,
+		LastRotated:  now,
+	}
+
+	var oldKeyID string
+	if creds != nil {
+		oldKeyID = creds.KeyID
+	}
+	if oldKeyID != "" && role.RotationOverlap > 0 {
+		newCreds.PreviousKeyID = oldKeyID
+		newCreds.PreviousKeyRemoval = now.Add(role.RotationOverlap)
+	}
+
+	if err := saveStaticCredential(ctx, s, role.Name, newCreds); err != nil {
+		// The new password is unknown to consumers, so it must not linger
+		if delErr := c.deleteAppPassword(ctx, role.ApplicationObjectID, keyID); delErr != nil {
+			err = multierror.Append(err, delErr)
+		}
+		return nil, fmt.Errorf("error storing static credentials: %w", err)
+	}
+
+	if oldKeyID != "" && role.RotationOverlap == 0 {
+		if err := c.deleteAppPassword(ctx, role.ApplicationObjectID, oldKeyID); err != nil {
+			b.Logger().Warn("failed to remove previous password of static role", "role", role.Name, "key_id", oldKeyID, "error", err)
+		}
+	}
+
+	return newCreds, nil
+}
+
+// removePreviousStaticPassword removes the previous password of the role once
+// the rotation overlap has passed. The caller must hold the static roles lock.
+func (b *azureSecretBackend) removePreviousStaticPassword(ctx context.Context, s logical.Storage, role *staticRoleEntry, creds *staticCredentialEntry) error {
+	if creds.PreviousKeyID == "" || time.Now().Before(creds.PreviousKeyRemoval) {
+		return nil
+	}
+
+	c, err := b.getClient(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if err := b.deleteStaticPasswords(ctx, c, role, creds.PreviousKeyID); err != nil {
+		return err
+	}
+
+	creds.PreviousKeyID = ""
+	creds.PreviousKeyRemoval = time.Time{}
+
+	return saveStaticCredential(ctx, s, role.Name, creds)
+}
+
+// deleteStaticPasswords removes the given passwords from the Application of
+// the role. Empty key IDs are ignored.
+func (b *azureSecretBackend) deleteStaticPasswords(ctx context.Context, c *client, role *staticRoleEntry, keyIDs ...string) error {
+	lock := locksutil.LockForKey(b.appLocks, role.ApplicationObjectID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	var merr *multierror.Error
+	for _, keyID := range keyIDs {
+		if keyID == "" {
+			continue
+		}
+		if err := c.deleteAppPassword(ctx, role.ApplicationObjectID, keyID); err != nil {
+			merr = multierror.Append(merr, err)
+		}
+	}
+
+	return merr.ErrorOrNil()
+}
@@ -0,0 +1,169 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azure
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/openbao/openbao-plugins/secrets/azure/api"
+)
+
+const (
+	sasTimeFormat = "2006-01-02T15:04:05Z"
+
+	// sasPermissionsOrder is the order in which the Blob service expects
+	// container permissions.
+	sasPermissionsOrder = "racwdxltmeopi"
+)
+
+// containerSAS are the parameters of a shared access signature of a blob
+// container.
+type containerSAS struct {
+	account     string
+	container   string
+	permissions string
+	start       time.Time
+	expiry      time.Time
+}
+
+// normalizeSASPermissions validates the permissions of a shared access
+// signature and returns them in the order expected by the Blob service.
+func normalizeSASPermissions(permissions string) (string, error) {
+	if permissions == "" {
+		return "", fmt.Errorf("permissions are required")
+	}
+
+	for _, p := range permissions {
+		if !strings.ContainsRune(sasPermissionsOrder, p) {
+			return "", fmt.Errorf("invalid permission %q, valid permissions are %q", p, sasPermissionsOrder)
+		}
+	}
+
+	var normalized strings.Builder
+	for _, p := range sasPermissionsOrder {
+		if strings.ContainsRune(permissions, p) {
+			normalized.WriteRune(p)
+		}
+	}
+	return normalized.String(), nil
+}
+
+// signWithAccountKey returns the query of a service shared access signature
+// of the container, signed with a storage account key.
+func (s containerSAS) signWithAccountKey(accountKey string) (string, error) {
+	start, expiry := s.start.UTC().Format(sasTimeFormat), s.expiry.UTC().Format(sasTimeFormat)
+
+	stringToSign := strings.Join([]string{
+		s.permissions,
+		start,
+		expiry,
+		s.canonicalizedResource(),
+		"", // signed identifier
+		"", // signed IP
+		"https",
+		api.StorageServiceVersion,
+		"c", // signed resource
+		"",  // signed snapshot time
+		"",  // signed encryption scope
+		"",  // cache control
+		"",  // content disposition
+		"",  // content encoding
+		"",  // content language
+		"",  // content type
+	}, "\n")
+
+	sig, err := signSAS(accountKey, stringToSign)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"sv":  {api.StorageServiceVersion},
+		"sr":  {"c"},
+		"sp":  {s.permissions},
+		"st":  {start},
+		"se":  {expiry},
+		"spr": {"https"},
+		"sig": {sig},
+	}
+	return query.Encode(), nil
+}
+
+// signWithUserDelegationKey returns the query of a user delegation shared
+// access signature of the container.
+func (s containerSAS) signWithUserDelegationKey(key api.UserDelegationKey) (string, error) {
+	start, expiry := s.start.UTC().Format(sasTimeFormat), s.expiry.UTC().Format(sasTimeFormat)
+
+	stringToSign := strings.Join([]string{
+		s.permissions,
+		start,
+		expiry,
+		s.canonicalizedResource(),
+		key.SignedOID,
+		key.SignedTID,
+		key.SignedStart,
+		key.SignedExpiry,
+		key.SignedService,
+		key.SignedVersion,
+		"", // signed authorized user object ID
+		"", // signed unauthorized user object ID
+		"", // signed correlation ID
+		"", // signed IP
+		"https",
+		api.StorageServiceVersion,
+		"c", // signed resource
+		"",  // signed snapshot time
+		"",  // signed encryption scope
+		"",  // cache control
+		"",  // content disposition
+		"",  // content encoding
+		"",  // content language
+		"",  // content type
+	}, "\n")
+
+	sig, err := signSAS(key.Value, stringToSign)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"sv":    {api.StorageServiceVersion},
+		"sr":    {"c"},
+		"sp":    {s.permissions},
+		"st":    {start},
+		"se":    {expiry},
+		"spr":   {"https"},
+		"skoid": {key.SignedOID},
+		"sktid": {key.SignedTID},
+		"skt":   {key.SignedStart},
+		"ske":   {key.SignedExpiry},
+		"sks":   {key.SignedService},
+		"skv":   {key.SignedVersion},
+		"sig":   {sig},
+	}
+	return query.Encode(), nil
+}
+
+func (s containerSAS) canonicalizedResource() string {
+	return fmt.Sprintf("/blob/%s/%s", s.account, s.container)
+}
+
+// signSAS signs the string of a shared access signature with a base64
+// encoded key.
+func signSAS(key, stringToSign string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("error decoding signing key: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, decoded)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
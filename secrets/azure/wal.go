@@ -21,6 +21,7 @@ const (
 	walAppKey            = "appCreate"
 	walRotateRootCreds   = "rotateRootCreds"
 	walAppRoleAssignment = "appRoleAssign"
+	walAPIPermissions    = "apiPermissionAssign"
 )
 
 // Eventually expire the WAL if for some reason the rollback operation consistently fails
@@ -34,6 +35,8 @@ func (b *azureSecretBackend) walRollback(ctx context.Context, req *logical.Reque
 		return b.rollbackRootWAL(ctx, req, data)
 	case walAppRoleAssignment:
 		return b.rollbackRoleAssignWAL(ctx, req, data)
+	case walAPIPermissions:
+		return b.rollbackAPIPermissionsWAL(ctx, req, data)
 	default:
 		return fmt.Errorf("unknown rollback type %q", kind)
 	}
@@ -179,3 +182,69 @@ func (b *azureSecretBackend) rollbackRoleAssignWAL(ctx context.Context, req *log
 	}
 	return nil
 }
+
+type walAPIPermissionAssign struct {
+	SpID           string
+	APIPermissions []*APIPermission
+	Expiration     time.Time
+}
+
+func (b *azureSecretBackend) rollbackAPIPermissionsWAL(ctx context.Context, req *logical.Request, data interface{}) error {
+	// Decode the WAL data
+	var entry walAPIPermissionAssign
+	d, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeHookFunc(time.RFC3339),
+		Result:     &entry,
+		// API permissions are stored with their JSON field names
+		TagName: "json",
+	})
+	if err != nil {
+		return err
+	}
+	err = d.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+
+	b.Logger().Debug("rolling back API permissions for service principal", "ID", entry.SpID)
+
+	// App role assignment IDs are generated by Azure, so the assignments of
+	// the service principal are matched against the permissions of the role.
+	assignments, err := client.provider.ListAppRoleAssignments(ctx, entry.SpID)
+	if err != nil {
+		// The service principal was deleted along with its assignments
+		if strings.Contains(err.Error(), "Request_ResourceNotFound") {
+			return nil
+		}
+		if time.Now().After(entry.Expiration) {
+			b.Logger().Warn("API permission WAL expired prior to rollback; resources may still exist")
+			return nil
+		}
+		return err
+	}
+
+	var assignmentIDs []string
+	for _, assignment := range assignments {
+		for _, p := range entry.APIPermissions {
+			if p != nil && strings.EqualFold(assignment.ResourceID, p.ResourceObjectID) && strings.EqualFold(assignment.AppRoleID, p.AppRoleID) {
+				assignmentIDs = append(assignmentIDs, assignment.ID)
+				break
+			}
+		}
+	}
+
+	if err := client.unassignAPIPermissions(ctx, entry.SpID, assignmentIDs); err != nil {
+		if time.Now().After(entry.Expiration) {
+			b.Logger().Warn("API permission WAL expired prior to rollback; resources may still exist")
+			return nil
+		}
+		return fmt.Errorf("rollback error removing API permissions: %w", err)
+	}
+
+	return nil
+}